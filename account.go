@@ -0,0 +1,140 @@
+package memfs
+
+import (
+	"path"
+	"strings"
+	"sync/atomic"
+)
+
+// Accounting counts the bytes read from, and written to, the files of an FS
+// whose paths match any of a set of patterns, as set with WithAccounting.
+type Accounting struct {
+	patterns []*accountPattern
+}
+
+type accountPattern struct {
+	pattern       string
+	parts         []string
+	read, written atomic.Int64
+}
+
+// NewAccounting creates a new Accounting, counting the bytes read from, and
+// written to, the files matching each of the given patterns.
+//
+// The pattern syntax is that of path.Match, with a path element consisting
+// solely of ** matching zero or more path elements, and with each pattern
+// being matched against the path relative to the root of the FS created with
+// New; a leading / is ignored, such that /logs/** and logs/** both match every
+// file under the logs directory.
+//
+// Returns path.ErrBadPattern if any pattern is malformed.
+func NewAccounting(patterns ...string) (*Accounting, error) {
+	var a Accounting
+
+	for _, pattern := range patterns {
+		parts := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+
+		for _, part := range parts {
+			if _, err := path.Match(part, ""); err != nil {
+				return nil, err
+			}
+		}
+
+		a.patterns = append(a.patterns, &accountPattern{
+			pattern: pattern,
+			parts:   parts,
+		})
+	}
+
+	return &a, nil
+}
+
+// WithAccounting sets the Accounting that counts the bytes read from, and
+// written to, the files of the FS, and of any FS created from it with Sub.
+//
+// Bytes are counted when read or written with a File, and by ReadFile,
+// WriteFile, AppendFile, and CreateFromBytes, and are attributed to the path
+// by which the file was opened, even if the file is later renamed; the bytes
+// of a file reached through a symlink are attributed to the path of the
+// symlink.
+//
+// Throttling, as set with WithThrottle, is unaffected, such that accounting
+// can be used to assert that a caching layer reduced the I/O to an FS.
+func WithAccounting(a *Accounting) Option {
+	return func(f *FS) {
+		f.accounting = a
+	}
+}
+
+// Bytes returns the number of bytes read from, and written to, the files
+// matching the given pattern, which must be one of those given to
+// NewAccounting, since the Accounting was created or last Reset.
+//
+// Zero is returned for a pattern that is not being counted.
+func (a *Accounting) Bytes(pattern string) (read, written int64) {
+	for _, p := range a.patterns {
+		if p.pattern == pattern {
+			return p.read.Load(), p.written.Load()
+		}
+	}
+
+	return 0, 0
+}
+
+// Reset sets the counts of all patterns back to zero.
+func (a *Accounting) Reset() {
+	for _, p := range a.patterns {
+		p.read.Store(0)
+		p.written.Store(0)
+	}
+}
+
+// match returns the counters for all of the patterns that match the given
+// path.
+func (a *Accounting) match(p string) accounts {
+	if a == nil {
+		return nil
+	}
+
+	var matched accounts
+
+	names := strings.Split(p, "/")
+
+	for _, pattern := range a.patterns {
+		if matchParts(pattern.parts, names) {
+			matched = append(matched, pattern)
+		}
+	}
+
+	return matched
+}
+
+type accounts []*accountPattern
+
+// read counts the read of the pointed to number of bytes, taking a pointer so
+// that it can be deferred.
+func (a accounts) read(n *int) {
+	for _, p := range a {
+		p.read.Add(int64(*n))
+	}
+}
+
+func (a accounts) read64(n *int64) {
+	for _, p := range a {
+		p.read.Add(*n)
+	}
+}
+
+// write counts the write of the pointed to number of bytes, taking a pointer
+// so that it can be deferred.
+func (a accounts) write(n *int) {
+	for _, p := range a {
+		p.written.Add(int64(*n))
+	}
+}
+
+func (a accounts) write64(n *int64) {
+	for _, p := range a {
+		p.written.Add(*n)
+	}
+}
@@ -0,0 +1,84 @@
+package memfs
+
+import (
+	"io"
+	"path"
+	"testing"
+)
+
+func TestAccounting(t *testing.T) {
+	if _, err := NewAccounting("a/[b"); err != path.ErrBadPattern {
+		t.Fatalf("test 1: expecting error %v, got %v", path.ErrBadPattern, err)
+	}
+
+	a, err := NewAccounting("/logs/**", "*.txt", "data/file")
+	if err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	}
+
+	f := New(WithAccounting(a))
+
+	f.MkdirAll("logs/sub", 0o755)
+	f.Mkdir("data", 0o755)
+
+	f.WriteFile("logs/a", []byte("hello"), 0o644)
+	f.AppendFile("logs/sub/b", []byte("abc"), 0o644)
+	f.ReadFile("logs/a")
+	f.WriteFile("missing/file.txt", []byte("ignored"), 0o644)
+	f.CreateFromBytes("top.txt", []byte("1234567"), 0o644, false)
+
+	if of, err := f.OpenFile("data/file", ReadWrite|Create, 0o644); err != nil {
+		t.Fatalf("test 3: unexpected error: %s", err)
+	} else {
+		of.WriteString("0123456789")
+		of.Close()
+	}
+
+	if of, err := f.Open("data/file"); err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	} else {
+		io.ReadFull(of, make([]byte, 4))
+		of.Close()
+	}
+
+	if sub, err := f.Sub("logs"); err != nil {
+		t.Fatalf("test 5: unexpected error: %s", err)
+	} else {
+		sub.(*FS).WriteFile("c", []byte("xy"), 0o644)
+	}
+
+	for n, test := range [...]struct {
+		Pattern       string
+		Read, Written int64
+	}{
+		{ // 6
+			Pattern: "/logs/**",
+			Read:    5,
+			Written: 10,
+		},
+		{ // 7
+			Pattern: "*.txt",
+			Written: 7,
+		},
+		{ // 8
+			Pattern: "data/file",
+			Read:    4,
+			Written: 10,
+		},
+		{ // 9
+			Pattern: "logs/**",
+		},
+	} {
+		if read, written := a.Bytes(test.Pattern); read != test.Read {
+			t.Errorf("test %d: expecting %d bytes read, got %d", n+6, test.Read, read)
+		} else if written != test.Written {
+			t.Errorf("test %d: expecting %d bytes written, got %d", n+6, test.Written, written)
+		}
+	}
+
+	a.Reset()
+
+	if read, written := a.Bytes("/logs/**"); read != 0 || written != 0 {
+		t.Errorf("test 10: expecting counts to be reset, got %d and %d", read, written)
+	}
+}
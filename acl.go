@@ -0,0 +1,366 @@
+package memfs
+
+import (
+	"cmp"
+	"errors"
+	"io/fs"
+	"slices"
+)
+
+// ErrInvalidACL is returned by SetACL when given an ACL that is not Valid.
+var ErrInvalidACL = errors.New("invalid ACL")
+
+// ACLTag identifies the kind of an ACLEntry.
+type ACLTag uint8
+
+// The ACLTag values, in the order they are sorted in an ACL.
+const (
+	ACLUserObj ACLTag = iota + 1
+	ACLUser
+	ACLGroupObj
+	ACLGroup
+	ACLMask
+	ACLOther
+)
+
+// ACLEntry is a single entry of a POSIX access control list.
+type ACLEntry struct {
+	Tag ACLTag
+
+	// ID is the user, or group, ID of an ACLUser, or ACLGroup, entry, and
+	// is ignored for all other tags.
+	ID uint32
+
+	// Perm holds the read (4), write (2), and execute (1) permissions
+	// granted by the entry.
+	Perm fs.FileMode
+}
+
+// ACL is a POSIX access control list.
+//
+// The owner, group, and other permissions of the mode of an entry with an ACL
+// correspond to the ACLUserObj, ACLMask, and ACLOther entries, with the
+// ACLGroupObj entry taking the place of the ACLMask entry when the ACL has no
+// ACLMask entry.
+type ACL []ACLEntry
+
+// Valid reports whether the ACL is a valid POSIX access control list, having
+// exactly one each of the ACLUserObj, ACLGroupObj, and ACLOther entries, at most
+// one ACLMask entry, which is required when there are any ACLUser or ACLGroup
+// entries, no two ACLUser, or ACLGroup, entries with the same ID, and no
+// permissions other than read, write, and execute.
+func (a ACL) Valid() bool {
+	var (
+		counts [ACLOther + 1]int
+		users  = make(map[uint32]struct{})
+		groups = make(map[uint32]struct{})
+	)
+
+	for _, e := range a {
+		if e.Tag < ACLUserObj || e.Tag > ACLOther || e.Perm&^0o7 != 0 {
+			return false
+		}
+
+		counts[e.Tag]++
+
+		switch e.Tag {
+		case ACLUser:
+			if _, ok := users[e.ID]; ok {
+				return false
+			}
+
+			users[e.ID] = struct{}{}
+		case ACLGroup:
+			if _, ok := groups[e.ID]; ok {
+				return false
+			}
+
+			groups[e.ID] = struct{}{}
+		}
+	}
+
+	return counts[ACLUserObj] == 1 && counts[ACLGroupObj] == 1 && counts[ACLOther] == 1 && counts[ACLMask] <= 1 && (counts[ACLMask] == 1 || counts[ACLUser]+counts[ACLGroup] == 0)
+}
+
+// minimalACL returns the ACL equivalent to the given permissions.
+func minimalACL(mode fs.FileMode) ACL {
+	return ACL{
+		{Tag: ACLUserObj, Perm: mode >> 6 & 0o7},
+		{Tag: ACLGroupObj, Perm: mode >> 3 & 0o7},
+		{Tag: ACLOther, Perm: mode & 0o7},
+	}
+}
+
+// extended returns a sorted copy of the ACL, or nil when the ACL is equivalent
+// to its mode.
+func (a ACL) extended() ACL {
+	if len(a) == 3 {
+		return nil
+	}
+
+	a = slices.Clone(a)
+
+	slices.SortFunc(a, func(x, y ACLEntry) int {
+		return cmp.Or(cmp.Compare(x.Tag, y.Tag), cmp.Compare(x.ID, y.ID))
+	})
+
+	return a
+}
+
+// mode returns the permissions of the mode that correspond to the ACL.
+func (a ACL) mode() fs.FileMode {
+	var (
+		user, group, mask, other fs.FileMode
+		hasMask                  bool
+	)
+
+	for _, e := range a {
+		switch e.Tag {
+		case ACLUserObj:
+			user = e.Perm
+		case ACLGroupObj:
+			group = e.Perm
+		case ACLMask:
+			mask, hasMask = e.Perm, true
+		case ACLOther:
+			other = e.Perm
+		}
+	}
+
+	if hasMask {
+		group = mask
+	}
+
+	return user<<6 | group<<3 | other
+}
+
+// withMode returns a copy of the ACL with the entries corresponding to the
+// permissions of the mode set to those of the given mode.
+func (a ACL) withMode(mode fs.FileMode) ACL {
+	a = slices.Clone(a)
+	hasMask := slices.ContainsFunc(a, func(e ACLEntry) bool { return e.Tag == ACLMask })
+
+	for n, e := range a {
+		switch e.Tag {
+		case ACLUserObj:
+			a[n].Perm = mode >> 6 & 0o7
+		case ACLGroupObj:
+			if !hasMask {
+				a[n].Perm = mode >> 3 & 0o7
+			}
+		case ACLMask:
+			a[n].Perm = mode >> 3 & 0o7
+		case ACLOther:
+			a[n].Perm = mode & 0o7
+		}
+	}
+
+	return a
+}
+
+// perm returns the permissions used to grant access to an entry with the ACL,
+// in which the group permissions are those granted by any of the entries of
+// the group class, limited by the mask.
+func (a ACL) perm() fs.FileMode {
+	var user, group, mask, other fs.FileMode
+
+	for _, e := range a {
+		switch e.Tag {
+		case ACLUserObj:
+			user = e.Perm
+		case ACLUser, ACLGroupObj, ACLGroup:
+			group |= e.Perm
+		case ACLMask:
+			mask = e.Perm
+		case ACLOther:
+			other = e.Perm
+		}
+	}
+
+	return user<<6 | (group&mask)<<3 | other
+}
+
+// GetACL returns the access control list of the entry at the given path, as
+// with FS.GetACL.
+func (f *fsRO) GetACL(path string) (ACL, error) {
+	de, err := f.getEntry(path)
+	if err != nil {
+		return nil, &fs.PathError{Op: "getacl", Path: path, Err: err}
+	}
+
+	if acl := de.getACL(); acl != nil {
+		return acl, nil
+	}
+
+	return minimalACL(de.Mode()), nil
+}
+
+// GetACL returns the access control list of the entry at the given path,
+// following any symlinks.
+//
+// An entry without an ACL set with SetACL returns the minimal ACL equivalent to
+// its permissions.
+func (f *FS) GetACL(path string) (ACL, error) {
+	f.throttle.op()
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.fsRO.GetACL(path)
+}
+
+// SetACL sets the access control list of the entry at the given path,
+// following any symlinks, setting the permissions of its mode to match.
+// Setting a minimal ACL, of only the ACLUserObj, ACLGroupObj, and ACLOther
+// entries, removes any extended ACL.
+//
+// As an FS has no users, access is granted to an entry with an extended ACL
+// when it would be granted to any user; that is, when granted by the
+// ACLUserObj or ACLOther entries, or by any of the ACLUser, ACLGroupObj, or
+// ACLGroup entries, as limited by the ACLMask entry. Changing the permissions
+// with Chmod updates the corresponding entries of the ACL.
+//
+// ACLs are not kept by SealCompact, nor recorded by the journal, nor written by
+// WriteTar or WriteImage.
+func (f *FS) SetACL(path string, acl ACL) error {
+	f.throttle.op()
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.limit.op(); err != nil {
+		return &fs.PathError{Op: "setacl", Path: path, Err: err}
+	} else if !acl.Valid() {
+		return &fs.PathError{Op: "setacl", Path: path, Err: ErrInvalidACL}
+	}
+
+	de, err := f.getEntry(path)
+	if err != nil {
+		return &fs.PathError{Op: "setacl", Path: path, Err: err}
+	}
+
+	if err := de.setACL(acl); err != nil {
+		return &fs.PathError{Op: "setacl", Path: path, Err: err}
+	}
+
+	f.cache.invalidate()
+
+	de.touch(f.deterministic.now())
+
+	return nil
+}
+
+func (i *inode) getACL() ACL {
+	if i.meta == nil {
+		return nil
+	}
+
+	return slices.Clone(i.meta.acl)
+}
+
+func (i *inode) setACL(acl ACL) error {
+	if i.sealed {
+		return fs.ErrPermission
+	} else if i.meta == nil {
+		i.meta = new(inodeMeta)
+	}
+
+	i.meta.acl = acl.extended()
+	i.mode = i.mode&^fs.ModePerm | acl.mode()
+	i.gen = nextGeneration()
+
+	return nil
+}
+
+func (i *inode) perm() fs.FileMode {
+	if i.meta != nil && i.meta.acl != nil {
+		return i.meta.acl.perm()
+	}
+
+	return i.mode
+}
+
+func (i *inodeRW) getACL() ACL {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.inode.getACL()
+}
+
+func (i *inodeRW) setACL(acl ACL) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if err := i.inode.setACL(acl); err != nil {
+		return err
+	}
+
+	i.parents.changed(i.gen)
+
+	return nil
+}
+
+func (i *inodeRW) perm() fs.FileMode {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.inode.perm()
+}
+
+func (d *dnode) getACL() ACL {
+	return slices.Clone(d.acl)
+}
+
+func (d *dnode) setACL(acl ACL) error {
+	if d.sealed {
+		return fs.ErrPermission
+	}
+
+	d.acl = acl.extended()
+	d.mode = fs.ModeDir | acl.mode()
+	d.gen = nextGeneration()
+
+	return nil
+}
+
+func (d *dnode) perm() fs.FileMode {
+	if d.acl != nil {
+		return d.acl.perm()
+	}
+
+	return d.mode
+}
+
+func (d *dnodeRW) getACL() ACL {
+	return d.view().getACL()
+}
+
+func (d *dnodeRW) setACL(acl ACL) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	defer d.snapshot.Store(nil)
+
+	if err := d.dnode.setACL(acl); err != nil {
+		return err
+	}
+
+	d.changed(d.gen)
+
+	return nil
+}
+
+func (d *dnodeRW) perm() fs.FileMode {
+	return d.view().perm()
+}
+
+func (packedNode) getACL() ACL {
+	return nil
+}
+
+func (packedNode) setACL(_ ACL) error {
+	return fs.ErrPermission
+}
+
+func (p packedNode) perm() fs.FileMode {
+	return p.Mode()
+}
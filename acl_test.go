@@ -0,0 +1,124 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+)
+
+func TestACL(t *testing.T) {
+	f := New()
+
+	if err := f.WriteFile("file", []byte("data"), 0o640); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.MkdirAll("dir/sub", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if acl, err := f.GetACL("file"); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if expected := (ACL{{Tag: ACLUserObj, Perm: 6}, {Tag: ACLGroupObj, Perm: 4}, {Tag: ACLOther}}); !reflect.DeepEqual(acl, expected) {
+		t.Errorf("test 1: expecting ACL %v, got %v", expected, acl)
+	}
+
+	for n, acl := range [...]ACL{
+		{{Tag: ACLUserObj}, {Tag: ACLGroupObj}},
+		{{Tag: ACLUserObj}, {Tag: ACLUser, ID: 1}, {Tag: ACLGroupObj}, {Tag: ACLOther}},
+		{{Tag: ACLUserObj}, {Tag: ACLUser, ID: 1}, {Tag: ACLUser, ID: 1}, {Tag: ACLGroupObj}, {Tag: ACLMask}, {Tag: ACLOther}},
+		{{Tag: ACLUserObj, Perm: 0o10}, {Tag: ACLGroupObj}, {Tag: ACLOther}},
+		{{Tag: ACLUserObj}, {Tag: ACLGroupObj}, {Tag: ACLOther}, {Tag: ACLOther + 1}},
+	} {
+		if err := f.SetACL("file", acl); !errors.Is(err, ErrInvalidACL) {
+			t.Errorf("test %d: expecting error %v, got %v", n+2, ErrInvalidACL, err)
+		}
+	}
+
+	if err := f.SetACL("file", ACL{
+		{Tag: ACLOther},
+		{Tag: ACLMask, Perm: 6},
+		{Tag: ACLGroup, ID: 20, Perm: 4},
+		{Tag: ACLUser, ID: 1000, Perm: 6},
+		{Tag: ACLGroupObj},
+		{Tag: ACLUserObj},
+	}); err != nil {
+		t.Fatalf("test 7: unexpected error: %s", err)
+	} else if acl, err := f.GetACL("file"); err != nil {
+		t.Fatalf("test 7: unexpected error: %s", err)
+	} else if expected := (ACL{
+		{Tag: ACLUserObj},
+		{Tag: ACLUser, ID: 1000, Perm: 6},
+		{Tag: ACLGroupObj},
+		{Tag: ACLGroup, ID: 20, Perm: 4},
+		{Tag: ACLMask, Perm: 6},
+		{Tag: ACLOther},
+	}); !reflect.DeepEqual(acl, expected) {
+		t.Errorf("test 7: expecting ACL %v, got %v", expected, acl)
+	} else if fi, err := f.Stat("file"); err != nil {
+		t.Fatalf("test 8: unexpected error: %s", err)
+	} else if fi.Mode() != 0o060 {
+		t.Errorf("test 8: expecting mode %s, got %s", fs.FileMode(0o060), fi.Mode())
+	} else if err := f.WriteFile("file", []byte("new"), 0); err != nil {
+		t.Errorf("test 9: unexpected error: %s", err)
+	}
+
+	if err := f.Chmod("file", 0o040); err != nil {
+		t.Fatalf("test 10: unexpected error: %s", err)
+	} else if err := f.WriteFile("file", []byte("new"), 0); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("test 10: expecting error %v, got %v", fs.ErrPermission, err)
+	} else if data, err := f.ReadFile("file"); err != nil {
+		t.Errorf("test 11: unexpected error: %s", err)
+	} else if string(data) != "new" {
+		t.Errorf("test 11: expecting contents %q, got %q", "new", data)
+	} else if acl, err := f.GetACL("file"); err != nil {
+		t.Fatalf("test 12: unexpected error: %s", err)
+	} else if acl[4] != (ACLEntry{Tag: ACLMask, Perm: 4}) || acl[1] != (ACLEntry{Tag: ACLUser, ID: 1000, Perm: 6}) {
+		t.Errorf("test 12: expecting Chmod to only change the mask, got %v", acl)
+	}
+
+	if err := f.SetACL("dir", ACL{
+		{Tag: ACLUserObj},
+		{Tag: ACLGroupObj},
+		{Tag: ACLGroup, ID: 5, Perm: 4},
+		{Tag: ACLMask, Perm: 7},
+		{Tag: ACLOther},
+	}); err != nil {
+		t.Fatalf("test 13: unexpected error: %s", err)
+	} else if fi, err := f.Stat("dir"); err != nil {
+		t.Fatalf("test 13: unexpected error: %s", err)
+	} else if fi.Mode() != fs.ModeDir|0o070 {
+		t.Errorf("test 13: expecting mode %s, got %s", fs.ModeDir|0o070, fi.Mode())
+	} else if _, err := f.ReadDir("dir"); err != nil {
+		t.Errorf("test 14: unexpected error: %s", err)
+	} else if err := f.Mkdir("dir/new", 0o755); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("test 15: expecting error %v, got %v", fs.ErrPermission, err)
+	}
+
+	sealed := f.Seal()
+
+	if acl, err := sealed.GetACL("dir"); err != nil {
+		t.Errorf("test 16: unexpected error: %s", err)
+	} else if len(acl) != 5 {
+		t.Errorf("test 16: expecting extended ACL, got %v", acl)
+	} else if err := f.SetACL("file", ACL{{Tag: ACLUserObj, Perm: 6}, {Tag: ACLGroupObj}, {Tag: ACLOther}}); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("test 17: expecting error %v, got %v", fs.ErrPermission, err)
+	}
+
+	g := New()
+
+	if err := g.Mkdir("dir", 0o700); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := g.SetACL("dir", ACL{{Tag: ACLUserObj, Perm: 7}, {Tag: ACLUser, ID: 1, Perm: 7}, {Tag: ACLGroupObj}, {Tag: ACLMask, Perm: 7}, {Tag: ACLOther}}); err != nil {
+		t.Fatalf("test 18: unexpected error: %s", err)
+	} else if err := g.SetACL("dir", ACL{{Tag: ACLUserObj, Perm: 5}, {Tag: ACLGroupObj, Perm: 5}, {Tag: ACLOther, Perm: 5}}); err != nil {
+		t.Fatalf("test 18: unexpected error: %s", err)
+	} else if acl, err := g.GetACL("dir"); err != nil {
+		t.Fatalf("test 18: unexpected error: %s", err)
+	} else if len(acl) != 3 {
+		t.Errorf("test 18: expecting minimal ACL, got %v", acl)
+	} else if fi, err := g.Stat("dir"); err != nil {
+		t.Fatalf("test 19: unexpected error: %s", err)
+	} else if fi.Mode() != fs.ModeDir|0o555 {
+		t.Errorf("test 19: expecting mode %s, got %s", fs.ModeDir|0o555, fi.Mode())
+	}
+}
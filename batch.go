@@ -0,0 +1,86 @@
+package memfs
+
+import "io/fs"
+
+// Batch allows a number of changes to be made to an FS while holding its lock
+// only once.
+//
+// A Batch is only valid during the call to the function given to FS.Batch;
+// afterwards, all of its methods return fs.ErrClosed.
+type Batch struct {
+	fs *FS
+}
+
+// Batch calls the given function with a Batch through which changes can be
+// made to the FS, holding the write lock of the FS for the duration of the
+// call. As such, none of the changes are visible to other users of the FS
+// until all have been made, and the cost of locking is paid only once, which
+// is of use when creating large numbers of entries.
+//
+// The function must not call any of the methods of the FS itself, which would
+// deadlock.
+//
+// Any error returned by the function is returned by Batch. Changes made
+// before the error are not undone.
+func (f *FS) Batch(fn func(b *Batch) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b := &Batch{fs: f}
+
+	defer func() { b.fs = nil }()
+
+	return fn(b)
+}
+
+// Create creates, or truncates, the named file, as with FS.Create.
+func (b *Batch) Create(path string) (*File, error) {
+	if b.fs == nil {
+		return nil, &fs.PathError{Op: "create", Path: path, Err: fs.ErrClosed}
+	}
+
+	b.fs.throttle.open()
+
+	return b.fs.openFileEntry("create", path, ReadWrite|Create|Truncate, b.fs.defaultPerms())
+}
+
+// WriteFile writes the given data to the named file, as with FS.WriteFile.
+func (b *Batch) WriteFile(path string, data []byte, perm fs.FileMode) error {
+	if b.fs == nil {
+		return &fs.PathError{Op: "writefile", Path: path, Err: fs.ErrClosed}
+	}
+
+	n := len(data)
+
+	b.fs.throttle.open()
+	b.fs.throttle.write(&n)
+
+	err := b.fs.writeFile("writefile", path, data, perm, opWrite|opSeek)
+	if err == nil {
+		b.fs.accounts(path).write(&n)
+	}
+
+	return err
+}
+
+// Mkdir creates a new directory, as with FS.Mkdir.
+func (b *Batch) Mkdir(path string, perm fs.FileMode) error {
+	if b.fs == nil {
+		return &fs.PathError{Op: "mkdir", Path: path, Err: fs.ErrClosed}
+	}
+
+	b.fs.throttle.op()
+
+	return b.fs.makeDir(path, perm)
+}
+
+// Remove removes the named file or empty directory, as with FS.Remove.
+func (b *Batch) Remove(path string) error {
+	if b.fs == nil {
+		return &fs.PathError{Op: "remove", Path: path, Err: fs.ErrClosed}
+	}
+
+	b.fs.throttle.op()
+
+	return b.fs.remove(path)
+}
@@ -0,0 +1,90 @@
+package memfs
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	f := New()
+
+	if err := f.WriteFile("old", []byte("old"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var saved *Batch
+
+	if err := f.Batch(func(b *Batch) error {
+		saved = b
+
+		if err := b.Mkdir("dir", 0o755); err != nil {
+			return err
+		} else if err := b.WriteFile("dir/file", []byte("data"), 0o644); err != nil {
+			return err
+		} else if err := b.Remove("old"); err != nil {
+			return err
+		}
+
+		of, err := b.Create("dir/created")
+		if err != nil {
+			return err
+		} else if _, err := of.WriteString("created"); err != nil {
+			return err
+		}
+
+		return of.Close()
+	}); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	}
+
+	if data, err := f.ReadFile("dir/file"); err != nil {
+		t.Errorf("test 2: unexpected error: %s", err)
+	} else if string(data) != "data" {
+		t.Errorf("test 2: expecting contents %q, got %q", "data", data)
+	} else if data, err := f.ReadFile("dir/created"); err != nil {
+		t.Errorf("test 3: unexpected error: %s", err)
+	} else if string(data) != "created" {
+		t.Errorf("test 3: expecting contents %q, got %q", "created", data)
+	} else if _, err := f.Stat("old"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("test 4: expecting error %v, got %v", fs.ErrNotExist, err)
+	}
+
+	if err := saved.Mkdir("late", 0o755); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("test 5: expecting error %v, got %v", fs.ErrClosed, err)
+	} else if _, err := saved.Create("late"); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("test 5: expecting error %v, got %v", fs.ErrClosed, err)
+	} else if err := saved.WriteFile("late", nil, 0o644); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("test 5: expecting error %v, got %v", fs.ErrClosed, err)
+	} else if err := saved.Remove("dir/file"); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("test 5: expecting error %v, got %v", fs.ErrClosed, err)
+	}
+
+	if err := f.Batch(func(b *Batch) error {
+		if err := b.Mkdir("first", 0o755); err != nil {
+			return err
+		}
+
+		return b.Mkdir("missing/second", 0o755)
+	}); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("test 6: expecting error %v, got %v", fs.ErrNotExist, err)
+	} else if ok, err := f.IsDir("first"); err != nil {
+		t.Errorf("test 7: unexpected error: %s", err)
+	} else if !ok {
+		t.Errorf("test 7: expecting changes made before the error to remain")
+	}
+
+	var buf bytes.Buffer
+
+	j := New(WithJournal(&buf))
+
+	if err := j.Batch(func(b *Batch) error {
+		return b.WriteFile("file", []byte("data"), 0o644)
+	}); err != nil {
+		t.Fatalf("test 8: unexpected error: %s", err)
+	} else if !strings.Contains(buf.String(), `"Op":"writefile"`) {
+		t.Errorf("test 8: expecting writefile event to be journalled, got %q", buf.String())
+	}
+}
@@ -0,0 +1,86 @@
+package memfs
+
+import "time"
+
+// ChangeTimed is implemented by the value returned by the Sys method of the
+// fs.FileInfo of every entry of an FS, such as those returned by Stat, LStat
+// and ReadDir, allowing the metadata of an entry to be checked for changes
+// separately from its contents.
+type ChangeTimed interface {
+	// ChangeTime returns the time of the last change to the entry, being
+	// any change to its contents, as with its modification time, or to its
+	// metadata, such as by Chmod, Chown, Chtimes, SetACL, or the adding,
+	// removing, or renaming, of any of its names.
+	//
+	// For an FSRO created with SealCompact, which does not retain change
+	// times, this is the modification time of the entry.
+	ChangeTime() time.Time
+}
+
+func (d *dnode) ChangeTime() time.Time {
+	return d.ctime
+}
+
+func (d *dnode) touch(now time.Time) {
+	if !d.sealed {
+		d.ctime = now
+	}
+}
+
+func (d *dnodeRW) ChangeTime() time.Time {
+	return d.view().ctime
+}
+
+func (d *dnodeRW) touch(now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	defer d.snapshot.Store(nil)
+
+	d.dnode.touch(now)
+}
+
+func (i *inode) ChangeTime() time.Time {
+	return i.ctime
+}
+
+func (i *inode) touch(now time.Time) {
+	if !i.sealed {
+		i.ctime = now
+	}
+}
+
+func (i *inodeRW) ChangeTime() time.Time {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.ctime
+}
+
+func (i *inodeRW) touch(now time.Time) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.inode.touch(now)
+}
+
+func (p *fifo) ChangeTime() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.ctime
+}
+
+func (p *fifo) touch(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.sealed {
+		p.ctime = now
+	}
+}
+
+func (p packedNode) ChangeTime() time.Time {
+	return p.ModTime()
+}
+
+func (packedNode) touch(_ time.Time) {}
@@ -0,0 +1,151 @@
+package memfs
+
+import (
+	"testing"
+	"time"
+)
+
+func changeTime(t *testing.T, fsys FSRO, path string) (time.Time, time.Time) {
+	t.Helper()
+
+	fi, err := fsys.LStat(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	c, ok := fi.Sys().(ChangeTimed)
+	if !ok {
+		t.Fatalf("expecting Sys of %s to be ChangeTimed", path)
+	}
+
+	return fi.ModTime(), c.ChangeTime()
+}
+
+func TestChangeTime(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := New(WithDeterministic(start, time.Second))
+
+	f.Mkdir("dir", 0o755)
+	f.WriteFile("dir/file", []byte("data"), 0o644)
+
+	for n, test := range [...]struct {
+		Op      func() error
+		Path    string
+		Changed bool
+	}{
+		{ // 1
+			Op:   func() error { return nil },
+			Path: "dir/file",
+		},
+		{ // 2
+			Op:      func() error { return f.Chmod("dir/file", 0o600) },
+			Path:    "dir/file",
+			Changed: true,
+		},
+		{ // 3
+			Op:   func() error { return f.WriteFile("dir/file", []byte("more data"), 0o600) },
+			Path: "dir/file",
+		},
+		{ // 4
+			Op:      func() error { return f.Chown("dir/file", 1, 1) },
+			Path:    "dir/file",
+			Changed: true,
+		},
+		{ // 5
+			Op:      func() error { return f.Link("dir/file", "link") },
+			Path:    "dir/file",
+			Changed: true,
+		},
+		{ // 6
+			Op:      func() error { return f.Rename("link", "dir/other") },
+			Path:    "dir/file",
+			Changed: true,
+		},
+		{ // 7
+			Op:      func() error { return f.Remove("dir/other") },
+			Path:    "dir/file",
+			Changed: true,
+		},
+		{ // 8
+			Op: func() error {
+				fi, err := f.Stat("dir/file")
+				if err != nil {
+					return err
+				}
+
+				return f.Chtimes("dir/file", fi.ModTime(), fi.ModTime())
+			},
+			Path:    "dir/file",
+			Changed: true,
+		},
+		{ // 9
+			Op: func() error {
+				return f.SetACL("dir/file", ACL{{Tag: ACLUserObj, Perm: 6}, {Tag: ACLGroupObj, Perm: 4}, {Tag: ACLOther, Perm: 4}})
+			},
+			Path:    "dir/file",
+			Changed: true,
+		},
+		{ // 10
+			Op:      func() error { return f.Chmod("dir", 0o700) },
+			Path:    "dir",
+			Changed: true,
+		},
+		{ // 11
+			Op:   func() error { return f.Mkdir("dir/sub", 0o755) },
+			Path: "dir",
+		},
+		{ // 12
+			Op:      func() error { return f.Chown("dir/sub", 1, 1) },
+			Path:    "dir/sub",
+			Changed: true,
+		},
+		{ // 13
+			Op:   func() error { return f.Symlink("dir/file", "symlink") },
+			Path: "symlink",
+		},
+		{ // 14
+			Op:      func() error { return f.Lchown("symlink", 1, 1) },
+			Path:    "symlink",
+			Changed: true,
+		},
+	} {
+		var modtime, ctime time.Time
+
+		if test.Changed {
+			modtime, ctime = changeTime(t, f, test.Path)
+		}
+
+		if err := test.Op(); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+
+			continue
+		}
+
+		m, c := changeTime(t, f, test.Path)
+
+		if test.Changed {
+			if !m.Equal(modtime) || !c.After(ctime) {
+				t.Errorf("test %d: expecting only change time to advance, got modtime %s -> %s, change time %s -> %s", n+1, modtime, m, ctime, c)
+			}
+		} else if !c.Equal(m) {
+			t.Errorf("test %d: expecting change time to match modtime %s, got %s", n+1, m, c)
+		}
+	}
+
+	_, ctime := changeTime(t, f, "dir/file")
+
+	layer, err := Layer(f.Snapshot())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, fsys := range [...]FSRO{f.Snapshot(), layer, f.Seal()} {
+		if _, c := changeTime(t, fsys, "dir/file"); !c.Equal(ctime) {
+			t.Errorf("test %d: expecting change time %s, got %s", n+15, ctime, c)
+		}
+	}
+
+	if m, c := changeTime(t, sealCompact(t, f), "dir/file"); !c.Equal(m) {
+		t.Errorf("test 18: expecting change time to match modtime %s, got %s", m, c)
+	}
+}
@@ -0,0 +1,155 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Check walks the entire FS, ignoring permissions, and validates that the
+// structure of the tree is consistent, as with FS.Check.
+func (f *fsRO) Check() error {
+	c := checker{
+		seen:    map[directoryEntry]bool{f.de: true},
+		links:   make(map[directoryEntry]*linkCount),
+		partial: f.partial,
+	}
+
+	c.walk(".", f.de)
+	c.checkLinks()
+
+	return errors.Join(c.errs...)
+}
+
+// Check walks the entire FS, ignoring permissions, and validates that the
+// structure of the tree is consistent.
+//
+// The returned error, if not nil, wraps a *fs.PathError for each problem found,
+// with an Err of ErrNilEntry, ErrInvalidName, ErrDuplicateEntry,
+// ErrInvalidSymlink, ErrDirectoryLink or ErrLinkCount.
+//
+// The link count of each file is compared to the number of names found for it,
+// with an FS created with Sub, or SealSubtree, only reporting files with more
+// names than their link count, as other names may be outside of the subtree.
+func (f *FS) Check() error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.fsRO.Check()
+}
+
+type checker struct {
+	seen    map[directoryEntry]bool
+	links   map[directoryEntry]*linkCount
+	order   []*linkCount
+	partial bool
+	errs    []error
+}
+
+type linkCount struct {
+	path         string
+	links, found int
+}
+
+func (c *checker) walk(p string, de directoryEntry) {
+	names := make(map[string]bool)
+
+	for _, e := range childEntries(de) {
+		if e == nil || e.directoryEntry == nil {
+			c.error(p, ErrNilEntry)
+
+			continue
+		}
+
+		if isEmptyName(e.name) || e.name == ".." || strings.Contains(e.name, "/") {
+			c.error(p, ErrInvalidName)
+
+			continue
+		}
+
+		cp := path.Join(p, e.name)
+
+		if names[e.name] {
+			c.error(cp, ErrDuplicateEntry)
+
+			continue
+		}
+
+		names[e.name] = true
+
+		switch m := e.Mode(); {
+		case m.IsDir():
+			if c.seen[e.directoryEntry] {
+				c.error(cp, ErrDirectoryLink)
+
+				continue
+			}
+
+			c.seen[e.directoryEntry] = true
+
+			c.walk(cp, e.directoryEntry)
+		case m&fs.ModeSymlink != 0:
+			if !validSymlink(e.directoryEntry) {
+				c.error(cp, ErrInvalidSymlink)
+			}
+
+			c.link(cp, e.directoryEntry)
+		default:
+			c.link(cp, e.directoryEntry)
+		}
+	}
+}
+
+// link records the finding of a name for a file.
+func (c *checker) link(p string, de directoryEntry) {
+	l, ok := de.(Linked)
+	if !ok {
+		return
+	}
+
+	lc := c.links[de]
+	if lc == nil {
+		lc = &linkCount{path: p, links: l.Links()}
+		c.links[de] = lc
+		c.order = append(c.order, lc)
+	}
+
+	lc.found++
+}
+
+// checkLinks compares the number of names found for each file with its link
+// count.
+func (c *checker) checkLinks() {
+	for _, lc := range c.order {
+		if lc.found > lc.links || !c.partial && lc.found < lc.links {
+			c.error(lc.path, ErrLinkCount)
+		}
+	}
+}
+
+func (c *checker) error(p string, err error) {
+	c.errs = append(c.errs, &fs.PathError{Op: "check", Path: p, Err: err})
+}
+
+func validSymlink(de directoryEntry) bool {
+	var target string
+
+	withData(de, func(data []byte) error {
+		target = string(data)
+
+		return nil
+	})
+
+	return validTarget(target)
+}
+
+// Errors.
+var (
+	ErrNilEntry       = errors.New("nil entry")
+	ErrInvalidName    = errors.New("invalid entry name")
+	ErrDuplicateEntry = errors.New("duplicate entry")
+	ErrInvalidSymlink = errors.New("invalid symlink target")
+	ErrDirectoryLink  = errors.New("directory has multiple parents")
+	ErrLinkCount      = errors.New("link count does not match names")
+)
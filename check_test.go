@@ -0,0 +1,203 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("a/b", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("a/b/file", []byte("data"), 0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Link("a/b/file", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("../a/b", "a/symlink"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Chmod("a", 0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Check(); err != nil {
+		t.Errorf("test 1: unexpected error: %s", err)
+	} else if err := sealCompact(t, f).Check(); err != nil {
+		t.Errorf("test 2: unexpected error: %s", err)
+	}
+
+	dir := &dnodeRW{
+		dnode: dnode{
+			mode: fs.ModeDir | fs.ModePerm,
+		},
+	}
+
+	for n, test := range [...]struct {
+		Entries []*dirEnt
+		Path    string
+		Err     error
+	}{
+		{ // 3
+			Entries: []*dirEnt{nil},
+			Path:    ".",
+			Err:     ErrNilEntry,
+		},
+		{ // 4
+			Entries: []*dirEnt{{name: "a"}},
+			Path:    ".",
+			Err:     ErrNilEntry,
+		},
+		{ // 5
+			Entries: []*dirEnt{{directoryEntry: &inodeRW{}, name: "a/b"}},
+			Path:    ".",
+			Err:     ErrInvalidName,
+		},
+		{ // 6
+			Entries: []*dirEnt{{directoryEntry: &inodeRW{}, name: ".."}},
+			Path:    ".",
+			Err:     ErrInvalidName,
+		},
+		{ // 7
+			Entries: []*dirEnt{
+				{directoryEntry: &inodeRW{}, name: "a"},
+				{directoryEntry: &inodeRW{}, name: "a"},
+			},
+			Path: "a",
+			Err:  ErrDuplicateEntry,
+		},
+		{ // 8
+			Entries: []*dirEnt{
+				{
+					directoryEntry: &inodeRW{
+						inode: inode{
+							data: []byte("a\x00b"),
+							mode: fs.ModeSymlink | fs.ModePerm,
+						},
+					},
+					name: "link",
+				},
+			},
+			Path: "link",
+			Err:  ErrInvalidSymlink,
+		},
+		{ // 9
+			Entries: []*dirEnt{
+				{directoryEntry: dir, name: "a"},
+				{directoryEntry: dir, name: "b"},
+			},
+			Path: "b",
+			Err:  ErrDirectoryLink,
+		},
+	} {
+		f := FS{
+			fsRO: fsRO{
+				de: &dnodeRW{
+					dnode: dnode{
+						entries: test.Entries,
+						mode:    fs.ModeDir | fs.ModePerm,
+					},
+				},
+			},
+		}
+
+		var pe *fs.PathError
+
+		if err := f.Check(); !errors.As(err, &pe) {
+			t.Errorf("test %d: expecting PathError, got %v", n+3, err)
+		} else if pe.Path != test.Path {
+			t.Errorf("test %d: expecting path %q, got %q", n+3, test.Path, pe.Path)
+		} else if !errors.Is(err, test.Err) {
+			t.Errorf("test %d: expecting error %s, got %s", n+3, test.Err, err)
+		}
+	}
+}
+
+func TestCheckLinks(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("a/b", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("a/b/file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Link("a/b/file", "a/link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Link("a/b/file", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("a/b/file", "symlink"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.CreateSparse("lazy", 10); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Link("lazy", "a/lazy"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Remove("link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	sub, err := f.Sub("a")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, fsys := range [...]FSRO{
+		f,
+		sub.(FSRO),
+		f.Snapshot(),
+		sealCompact(t, f),
+	} {
+		if err := fsys.Check(); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		}
+	}
+
+	de, err := f.getLEntry("a/b/file")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	file := de.directoryEntry.(*inodeRW)
+
+	for n, test := range [...]struct {
+		Links int
+		Sub   bool
+		Path  string
+	}{
+		{ // 5
+			Links: 1,
+			Path:  "a/b/file",
+		},
+		{ // 6
+			Links: 3,
+			Path:  "a/b/file",
+		},
+		{ // 7
+			Links: 1,
+			Sub:   true,
+			Path:  "b/file",
+		},
+		{ // 8
+			Links: 3,
+			Sub:   true,
+		},
+	} {
+		file.links = test.Links
+
+		fsys := FSRO(f)
+
+		if test.Sub {
+			fsys = sub.(FSRO)
+		}
+
+		var pe *fs.PathError
+
+		if err := fsys.Check(); test.Path == "" {
+			if err != nil {
+				t.Errorf("test %d: unexpected error: %s", n+5, err)
+			}
+		} else if !errors.As(err, &pe) {
+			t.Errorf("test %d: expecting PathError, got %v", n+5, err)
+		} else if pe.Path != test.Path {
+			t.Errorf("test %d: expecting path %q, got %q", n+5, test.Path, pe.Path)
+		} else if !errors.Is(err, ErrLinkCount) {
+			t.Errorf("test %d: expecting error %s, got %s", n+5, ErrLinkCount, err)
+		}
+	}
+}
@@ -0,0 +1,400 @@
+package memfs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/fs"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	packedMode    = 0
+	packedNameLen = 4
+	packedNameOff = 8
+	packedModTime = 16
+	packedModNsec = 24
+	packedDataOff = 32
+	packedDataLen = 40
+
+	packedRecordSize = 48
+)
+
+// packed stores an entire tree in three slices.
+//
+// The nodes slice is a table of fixed size records, one for each entry in the
+// tree, with the root at index zero. The children of each directory are stored
+// contiguously, sorted by name, with the directory record storing the index of
+// the first child and the number of children in place of the data offset and
+// length.
+type packed struct {
+	nodes, names, data []byte
+
+	etagMu sync.Mutex
+	etags  map[uint64]string
+}
+
+func (p *packed) record(n uint64) []byte {
+	return p.nodes[n*packedRecordSize : (n+1)*packedRecordSize]
+}
+
+type packedNode struct {
+	*packed
+	index uint64
+}
+
+func (p packedNode) field(pos int) uint64 {
+	return binary.LittleEndian.Uint64(p.record(p.index)[pos:])
+}
+
+func (p packedNode) name() []byte {
+	r := p.record(p.index)
+	off := binary.LittleEndian.Uint64(r[packedNameOff:])
+
+	return p.names[off : off+uint64(binary.LittleEndian.Uint32(r[packedNameLen:]))]
+}
+
+func (p packedNode) contents() []byte {
+	off := p.field(packedDataOff)
+	end := off + p.field(packedDataLen)
+
+	return p.data[off:end:end]
+}
+
+func (p packedNode) child(n uint64) packedNode {
+	return packedNode{packed: p.packed, index: p.field(packedDataOff) + n}
+}
+
+func (p packedNode) children() []*dirEnt {
+	entries := make([]*dirEnt, p.field(packedDataLen))
+
+	for n := range entries {
+		c := p.child(uint64(n))
+
+		entries[n] = &dirEnt{
+			directoryEntry: c,
+			name:           string(c.name()),
+		}
+	}
+
+	return entries
+}
+
+func (p packedNode) IsDir() bool {
+	return p.Mode().IsDir()
+}
+
+func (p packedNode) ModTime() time.Time {
+	r := p.record(p.index)
+
+	return time.Unix(int64(binary.LittleEndian.Uint64(r[packedModTime:])), int64(binary.LittleEndian.Uint32(r[packedModNsec:])))
+}
+
+func (p packedNode) Type() fs.FileMode {
+	return p.Mode().Type()
+}
+
+func (p packedNode) Mode() fs.FileMode {
+	return fs.FileMode(binary.LittleEndian.Uint32(p.record(p.index)[packedMode:]))
+}
+
+func (p packedNode) Size() int64 {
+	if p.IsDir() {
+		return 0
+	}
+
+	return int64(p.field(packedDataLen))
+}
+
+func (p packedNode) open(name string, mode opMode) (fs.File, error) {
+	m := p.Mode()
+
+	if m.IsDir() {
+		if m&modeRead == 0 {
+			return nil, fs.ErrPermission
+		}
+
+		entries := p.children()
+
+		return &directory{
+			dnode: &dnode{
+				entries: entries,
+				modtime: p.ModTime(),
+				ctime:   p.ModTime(),
+				mode:    m,
+				sealed:  true,
+			},
+			name:    name,
+			entries: entries,
+		}, nil
+	}
+
+	if m&fs.ModeNamedPipe != 0 {
+		return nil, fs.ErrInvalid
+	} else if mode&opRead > 0 && m&modeRead == 0 || mode&opWrite > 0 {
+		return nil, fs.ErrPermission
+	}
+
+	return &file{
+		name: name,
+		inode: &inode{
+			modtime: p.ModTime(),
+			ctime:   p.ModTime(),
+			data:    p.contents(),
+			mode:    m,
+			sealed:  true,
+		},
+		opMode: mode,
+	}, nil
+}
+
+func (p packedNode) bytes() ([]byte, error) {
+	if m := p.Mode(); m.IsDir() {
+		return nil, fs.ErrInvalid
+	} else if m&modeRead == 0 {
+		return nil, fs.ErrPermission
+	}
+
+	return bytes.Clone(p.contents()), nil
+}
+
+func (p packedNode) string() (string, error) {
+	if m := p.Mode(); m.IsDir() {
+		return "", fs.ErrInvalid
+	} else if m&modeRead == 0 {
+		return "", fs.ErrPermission
+	}
+
+	return string(p.contents()), nil
+}
+
+func (p packedNode) appendData(buf []byte) ([]byte, error) {
+	if m := p.Mode(); m.IsDir() {
+		return nil, fs.ErrInvalid
+	} else if m&modeRead == 0 {
+		return nil, fs.ErrPermission
+	}
+
+	return append(buf, p.contents()...), nil
+}
+
+func (p packedNode) setMode(_ fs.FileMode) error {
+	return fs.ErrPermission
+}
+
+func (p packedNode) setTimes(_, _ time.Time) error {
+	return fs.ErrPermission
+}
+
+func (p packedNode) seal() directoryEntry {
+	return p
+}
+
+func (p packedNode) getEntry(name string) (*dirEnt, error) {
+	if m := p.Mode(); !m.IsDir() {
+		return nil, ErrNotDir
+	} else if m&modeRead == 0 {
+		return nil, fs.ErrPermission
+	}
+
+	count := int(p.field(packedDataLen))
+
+	if n := sort.Search(count, func(n int) bool {
+		return string(p.child(uint64(n)).name()) >= name
+	}); n < count {
+		if c := p.child(uint64(n)); string(c.name()) == name {
+			return &dirEnt{directoryEntry: c, name: name}, nil
+		}
+	}
+
+	return nil, fs.ErrNotExist
+}
+
+func (p packedNode) setEntry(_ *dirEnt, _ *deterministic) error {
+	return fs.ErrPermission
+}
+
+func (p packedNode) hasEntries() bool {
+	return p.IsDir() && p.field(packedDataLen) > 0
+}
+
+func (p packedNode) getEntries() ([]fs.DirEntry, error) {
+	if m := p.Mode(); !m.IsDir() {
+		return nil, ErrNotDir
+	} else if m&modeRead == 0 {
+		return nil, fs.ErrPermission
+	}
+
+	entries := p.children()
+	dirs := make([]fs.DirEntry, len(entries))
+
+	for n, e := range entries {
+		dirs[n] = e
+	}
+
+	return dirs, nil
+}
+
+func (p packedNode) scanEntries(fn func(fs.DirEntry) bool) error {
+	if m := p.Mode(); !m.IsDir() {
+		return ErrNotDir
+	} else if m&modeRead == 0 {
+		return fs.ErrPermission
+	}
+
+	for n, count := uint64(0), p.field(packedDataLen); n < count; n++ {
+		c := p.child(n)
+
+		if !fn(&dirEnt{directoryEntry: c, name: string(c.name())}) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (p packedNode) removeEntry(_ string, _ *deterministic) error {
+	return fs.ErrPermission
+}
+
+func (p packedNode) replaceEntry(_ *dirEnt, _ *deterministic) error {
+	return fs.ErrPermission
+}
+
+type packer struct {
+	packed
+	names map[string]uint64
+	files map[any][2]uint64
+}
+
+type packedData struct {
+	*packed
+	off, len uint64
+}
+
+type packerDir struct {
+	index   uint64
+	path    string
+	entries []*dirEnt
+}
+
+func pack(op string, root directoryEntry) (*packed, error) {
+	p := packer{
+		names: make(map[string]uint64),
+		files: make(map[any][2]uint64),
+	}
+
+	d, err := p.add("", root)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: ".", Err: err}
+	}
+
+	d.path = "."
+
+	for queue := []packerDir{d}; len(queue) > 0; queue = queue[1:] {
+		r := p.record(queue[0].index)
+
+		binary.LittleEndian.PutUint64(r[packedDataOff:], uint64(len(p.nodes)/packedRecordSize))
+		binary.LittleEndian.PutUint64(r[packedDataLen:], uint64(len(queue[0].entries)))
+
+		for _, e := range queue[0].entries {
+			name := path.Join(queue[0].path, e.name)
+
+			d, err := p.add(e.name, e.directoryEntry)
+			if err != nil {
+				return nil, &fs.PathError{Op: op, Path: name, Err: err}
+			} else if d.entries != nil {
+				d.path = name
+				queue = append(queue, d)
+			}
+		}
+	}
+
+	return &p.packed, nil
+}
+
+func (p *packer) add(name string, de directoryEntry) (packerDir, error) {
+	var (
+		mode    fs.FileMode
+		modtime time.Time
+		entries []*dirEnt
+		data    [2]uint64
+	)
+
+	switch de := de.(type) {
+	case *dnodeRW:
+		de.mu.RLock()
+		mode, modtime, entries = de.mode, de.modtime, slices.Clone(de.entries)
+		de.mu.RUnlock()
+	case *dnode:
+		mode, modtime, entries = de.mode, de.modtime, slices.Clone(de.entries)
+	case *inodeRW:
+		de.mu.RLock()
+		mode, modtime, data = de.mode, de.modtime, p.addData(de, de.data)
+		de.mu.RUnlock()
+	case *inode:
+		mode, modtime, data = de.mode, de.modtime, p.addData(de, de.data)
+	case *lazyNode:
+		de.mu.RLock()
+		buf, err := de.lockedBytes()
+		de.mu.RUnlock()
+
+		if err != nil {
+			return packerDir{}, err
+		}
+
+		mode, modtime, data = de.Mode(), de.ModTime(), p.addData(de, buf)
+	case *fifo:
+		mode, modtime = de.Mode(), de.ModTime()
+	case packedNode:
+		if mode, modtime = de.Mode(), de.ModTime(); mode.IsDir() {
+			entries = de.children()
+		} else {
+			data = p.addData(nodeKey(de), de.contents())
+		}
+	}
+
+	slices.SortFunc(entries, func(a, b *dirEnt) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	index := uint64(len(p.nodes) / packedRecordSize)
+	p.nodes = append(p.nodes, make([]byte, packedRecordSize)...)
+	r := p.record(index)
+
+	binary.LittleEndian.PutUint32(r[packedMode:], uint32(mode))
+	binary.LittleEndian.PutUint32(r[packedNameLen:], uint32(len(name)))
+	binary.LittleEndian.PutUint64(r[packedNameOff:], p.addName(name))
+	binary.LittleEndian.PutUint64(r[packedModTime:], uint64(modtime.Unix()))
+	binary.LittleEndian.PutUint32(r[packedModNsec:], uint32(modtime.Nanosecond()))
+	binary.LittleEndian.PutUint64(r[packedDataOff:], data[0])
+	binary.LittleEndian.PutUint64(r[packedDataLen:], data[1])
+
+	return packerDir{index: index, entries: entries}, nil
+}
+
+func (p *packer) addName(name string) uint64 {
+	off, ok := p.names[name]
+	if !ok {
+		off = uint64(len(p.packed.names))
+		p.names[name] = off
+		p.packed.names = append(p.packed.names, name...)
+	}
+
+	return off
+}
+
+func (p *packer) addData(key any, data []byte) [2]uint64 {
+	pos, ok := p.files[key]
+	if !ok {
+		pos = [2]uint64{uint64(len(p.data)), uint64(len(data))}
+		p.files[key] = pos
+		p.data = append(p.data, data...)
+	}
+
+	return pos
+}
@@ -0,0 +1,189 @@
+package memfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func sealCompact(t *testing.T, f *FS) FSRO {
+	t.Helper()
+
+	ro, err := f.SealCompact()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	return ro
+}
+
+func TestSealCompact(t *testing.T) {
+	f := New()
+
+	for n, fn := range [...]func() error{
+		func() error { return f.Mkdir("dir", fs.ModePerm) },
+		func() error { return f.Mkdir("dir/sub", fs.ModePerm) },
+		func() error { return writeFile(f, "b", "Hello") },
+		func() error { return writeFile(f, "a", "World") },
+		func() error { return writeFile(f, "dir/c", "Foo") },
+		func() error { return writeFile(f, "dir/sub/d", "Bar") },
+		func() error { return writeFile(f, "dir/e", "") },
+		func() error { return f.Link("dir/c", "dir/sub/f") },
+		func() error { return f.Symlink("dir/sub", "g") },
+		func() error { return f.Symlink("sub/d", "dir/h") },
+		func() error { return f.Symlink("/dir/c", "i") },
+		func() error { return f.Chtimes("a", time.Unix(1, 2), time.Unix(3, 4)) },
+	} {
+		if err := fn(); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		}
+	}
+
+	ro := sealCompact(t, f)
+
+	if err := fstest.TestFS(ro, "a", "b", "dir/c", "dir/e", "dir/h", "dir/sub/d", "dir/sub/f", "i"); err != nil {
+		t.Errorf("error during fstest: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Path, Contents string
+	}{
+		{"a", "World"},
+		{"b", "Hello"},
+		{"dir/c", "Foo"},
+		{"dir/sub/f", "Foo"},
+		{"g/d", "Bar"},
+		{"dir/h", "Bar"},
+		{"i", "Foo"},
+	} {
+		if data, err := ro.ReadFile(test.Path); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if string(data) != test.Contents {
+			t.Errorf("test %d: expecting contents %q, got %q", n+1, test.Contents, data)
+		}
+	}
+
+	if des, err := ro.ReadDir("."); err != nil {
+		t.Errorf("unexpected error reading root: %s", err)
+	} else if names := dirNames(des); !reflect.DeepEqual(names, []string{"a", "b", "dir", "g", "i"}) {
+		t.Errorf("expecting sorted names, got %v", names)
+	}
+
+	if fi, err := ro.Stat("a"); err != nil {
+		t.Errorf("unexpected error stating file: %s", err)
+	} else if !fi.ModTime().Equal(time.Unix(3, 4)) {
+		t.Errorf("expecting modtime %s, got %s", time.Unix(3, 4), fi.ModTime())
+	} else if fi.Mode() != defaultPerms {
+		t.Errorf("expecting mode %s, got %s", fs.FileMode(defaultPerms), fi.Mode())
+	} else if fi.Size() != 5 {
+		t.Errorf("expecting size 5, got %d", fi.Size())
+	}
+
+	if target, err := ro.Readlink("g"); err != nil {
+		t.Errorf("unexpected error reading link: %s", err)
+	} else if target != "dir/sub" {
+		t.Errorf("expecting link target %q, got %q", "dir/sub", target)
+	}
+
+	if fi, err := ro.LStat("dir/h"); err != nil {
+		t.Errorf("unexpected error lstating link: %s", err)
+	} else if fi.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("expecting symlink, got mode %s", fi.Mode())
+	}
+
+	if _, err := ro.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expecting error %s, got %s", fs.ErrNotExist, err)
+	}
+
+	if of, err := ro.Open("dir/c"); err != nil {
+		t.Errorf("unexpected error opening file: %s", err)
+	} else if _, ok := of.(FileRO); !ok {
+		t.Errorf("expecting opened file to be a FileRO")
+	} else if _, ok := of.(io.Writer); ok {
+		t.Errorf("expecting opened file to not be writable")
+	}
+
+	if err := writeFile(f, "dir/c", "Changed"); err != nil {
+		t.Errorf("unexpected error writing to original FS: %s", err)
+	} else if data, err := ro.ReadFile("dir/sub/f"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	} else if string(data) != "Foo" {
+		t.Errorf("expecting compact FS to be unchanged, got %q", data)
+	}
+}
+
+func TestSealCompactPermissions(t *testing.T) {
+	f := New()
+
+	if err := f.Mkdir("private", 0o300); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err = writeFile(f, "file", "data"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err = f.Chmod("file", 0o200); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ro := sealCompact(t, f)
+
+	if _, err := ro.ReadDir("private"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expecting error %s, got %s", fs.ErrPermission, err)
+	} else if _, err = ro.ReadFile("file"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expecting error %s, got %s", fs.ErrPermission, err)
+	} else if _, err = ro.Open("file"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expecting error %s, got %s", fs.ErrPermission, err)
+	} else if _, err = ro.ReadDir("file"); !errors.Is(err, ErrNotDir) {
+		t.Errorf("expecting error %s, got %s", ErrNotDir, err)
+	}
+}
+
+func TestSealCompactLazy(t *testing.T) {
+	f := New()
+	errFill := errors.New("fill error")
+
+	if err := f.Mkdir("dir", fs.ModePerm); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if err := f.CreateLazy("dir/private", 100, new(patternFill).fill); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if err := f.Chmod("dir/private", 0o200); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if fi, err := sealCompact(t, f).Stat("dir/private"); err != nil {
+		t.Errorf("test 1: unexpected error: %s", err)
+	} else if fi.Size() != 100 {
+		t.Errorf("test 1: expecting size 100, got %d", fi.Size())
+	} else if err := f.CreateLazy("dir/bad", 10, func(_ []byte, _ int64) (int, error) {
+		return 0, errFill
+	}); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if _, err := f.SealCompact(); !reflect.DeepEqual(err, &fs.PathError{Op: "sealcompact", Path: "dir/bad", Err: errFill}) {
+		t.Errorf("test 2: expecting error %v, got %v", &fs.PathError{Op: "sealcompact", Path: "dir/bad", Err: errFill}, err)
+	} else if _, err := f.WriteImage(io.Discard); !reflect.DeepEqual(err, &fs.PathError{Op: "writeimage", Path: "dir/bad", Err: errFill}) {
+		t.Errorf("test 3: expecting error %v, got %v", &fs.PathError{Op: "writeimage", Path: "dir/bad", Err: errFill}, err)
+	}
+}
+
+func writeFile(f *FS, path, contents string) error {
+	of, err := f.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err = of.WriteString(contents); err != nil {
+		return err
+	}
+
+	return of.Close()
+}
+
+func dirNames(des []fs.DirEntry) []string {
+	names := make([]string, len(des))
+
+	for n, de := range des {
+		names[n] = de.Name()
+	}
+
+	return names
+}
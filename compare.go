@@ -0,0 +1,108 @@
+package memfs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+)
+
+const compareChunkSize = 32 * 1024
+
+// ContentsEqual reports whether the files at the given paths have the same
+// contents, as with FS.ContentsEqual.
+func (f *fsRO) ContentsEqual(path1, path2 string) (bool, error) {
+	return ContentsEqual(f, path1, f, path2)
+}
+
+// ContentsEqual reports whether the files at the given paths, following any
+// symlinks, have the same contents.
+func (f *FS) ContentsEqual(path1, path2 string) (bool, error) {
+	return ContentsEqual(f, path1, f, path2)
+}
+
+// ContentsEqual reports whether the file at path1 in fsys1 has the same contents
+// as the file at path2 in fsys2.
+//
+// Files of differing sizes are never read, and for an FS from this package,
+// paths that lead to the same file, such as hard links, are always equal and
+// are not read. Otherwise, the files are read and compared in small chunks.
+func ContentsEqual(fsys1 fs.FS, path1 string, fsys2 fs.FS, path2 string) (bool, error) {
+	fi1, err := statRegular(fsys1, path1)
+	if err != nil {
+		return false, err
+	}
+
+	fi2, err := statRegular(fsys2, path2)
+	if err != nil {
+		return false, err
+	}
+
+	if fi1.Size() != fi2.Size() {
+		return false, nil
+	} else if de, ok := fi1.Sys().(directoryEntry); ok && de == fi2.Sys() {
+		return true, nil
+	}
+
+	f1, err := fsys1.Open(path1)
+	if err != nil {
+		return false, err
+	}
+
+	defer f1.Close()
+
+	f2, err := fsys2.Open(path2)
+	if err != nil {
+		return false, err
+	}
+
+	defer f2.Close()
+
+	size := fi1.Size()
+
+	if size > compareChunkSize {
+		size = compareChunkSize
+	} else if size < 1 {
+		size = 1
+	}
+
+	return readersEqual(f1, f2, int(size))
+}
+
+func statRegular(fsys fs.FS, path string) (fs.FileInfo, error) {
+	fi, err := fs.Stat(fsys, path)
+	if err != nil {
+		return nil, err
+	} else if !fi.Mode().IsRegular() {
+		return nil, &fs.PathError{Op: "contentsequal", Path: path, Err: fs.ErrInvalid}
+	}
+
+	return fi, nil
+}
+
+func readersEqual(r1, r2 io.Reader, size int) (bool, error) {
+	buf := make([]byte, size<<1)
+	buf1, buf2 := buf[:size], buf[size:]
+
+	for {
+		n1, err := io.ReadFull(r1, buf1)
+		if err != nil && !isEOF(err) {
+			return false, err
+		}
+
+		n2, err := io.ReadFull(r2, buf2)
+		if err != nil && !isEOF(err) {
+			return false, err
+		}
+
+		if !bytes.Equal(buf1[:n1], buf2[:n2]) {
+			return false, nil
+		} else if n1 < size {
+			return true, nil
+		}
+	}
+}
+
+func isEOF(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,81 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestContentsEqual(t *testing.T) {
+	f := New()
+	long := strings.Repeat("a", compareChunkSize*2+5)
+
+	for file, contents := range map[string]string{
+		"a":       "Hello, World",
+		"b":       "Hello, World",
+		"c":       "Hello, Earth",
+		"d":       "Hello",
+		"long1":   long,
+		"long2":   long,
+		"long3":   long[:len(long)-1] + "b",
+		"empty1":  "",
+		"empty2":  "",
+		"private": "Hello, World",
+	} {
+		if err := f.WriteFile(file, []byte(contents), 0o644); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if err := f.Link("a", "aLink"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("c", "cSymlink"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Mkdir("dir", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Chmod("private", 0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		A, B  string
+		Equal bool
+		Err   error
+	}{
+		{A: "a", B: "b", Equal: true},                 // 1
+		{A: "a", B: "c"},                              // 2
+		{A: "a", B: "d"},                              // 3
+		{A: "long1", B: "long2", Equal: true},         // 4
+		{A: "long1", B: "long3"},                      // 5
+		{A: "empty1", B: "empty2", Equal: true},       // 6
+		{A: "c", B: "cSymlink", Equal: true},          // 7
+		{A: "private", B: "b", Err: fs.ErrPermission}, // 8
+		{A: "a", B: "dir", Err: fs.ErrInvalid},        // 9
+		{A: "missing", B: "a", Err: fs.ErrNotExist},   // 10
+	} {
+		if equal, err := f.ContentsEqual(test.A, test.B); !errors.Is(err, test.Err) || (err == nil) != (test.Err == nil) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if equal != test.Equal {
+			t.Errorf("test %d: expecting equal %v, got %v", n+1, test.Equal, equal)
+		}
+	}
+
+	if err := f.Chmod("aLink", 0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if equal, err := f.ContentsEqual("a", "aLink"); err != nil || !equal {
+		t.Errorf("expecting hard links to be equal without being read, got %v (err = %v)", equal, err)
+	}
+
+	other := fstest.MapFS{
+		"x": {Data: []byte("Hello, World")},
+		"y": {Data: []byte(long)},
+	}
+
+	if equal, err := ContentsEqual(f, "b", other, "x"); err != nil || !equal {
+		t.Errorf("expecting files in different FSs to be equal, got %v (err = %v)", equal, err)
+	} else if equal, err := ContentsEqual(other, "y", f, "long3"); err != nil || equal {
+		t.Errorf("expecting files in different FSs to differ, got %v (err = %v)", equal, err)
+	}
+}
@@ -0,0 +1,85 @@
+package memfs
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithDeterministic makes the FS, and any FS created from it with Sub,
+// deterministic, such that the same sequence of changes always produces the
+// same tree, allowing for archives written by WriteTar, images written by
+// WriteImage, and any other serialisation of the FS, to be reproduced exactly.
+//
+// In place of the current time, the modification and change times of entries
+// are taken from a clock that starts at the given time, and that advances by
+// the given step each time it is read; a step of zero leaves the clock fixed.
+//
+// The entries of every directory are kept sorted by name, so that ReadDir, and
+// any walk of the tree, returns them in a stable order, and so that hard links
+// are always written against the first of their paths in that order.
+//
+// No inode numbers are exposed by the FS, nor written by any serialisation of
+// it, with hard links being identified by the file they share, and so there is
+// no inode numbering to be made stable.
+func WithDeterministic(start time.Time, step time.Duration) Option {
+	return func(f *FS) {
+		f.deterministic = &deterministic{
+			next: start,
+			step: step,
+		}
+	}
+}
+
+type deterministic struct {
+	mu   sync.Mutex
+	next time.Time
+	last time.Time
+	step time.Duration
+}
+
+// now returns the current time of the clock, advancing it by its step, or the
+// current time when not deterministic.
+func (d *deterministic) now() time.Time {
+	if d == nil {
+		return time.Now()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t := d.next
+	d.last = t
+	d.next = d.next.Add(d.step)
+
+	return t
+}
+
+// current returns the time last returned by now, without advancing the clock,
+// so that an operation that has already read the clock can use the same time
+// for each of its changes, or the current time when not deterministic.
+func (d *deterministic) current() time.Time {
+	if d == nil {
+		return time.Now()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.last
+}
+
+// insert adds the given entry to the list of entries, keeping them sorted by
+// name, or appending it when not deterministic.
+func (d *deterministic) insert(entries []*dirEnt, de *dirEnt) []*dirEnt {
+	if d == nil {
+		return append(entries, de)
+	}
+
+	pos, _ := slices.BinarySearchFunc(entries, de.name, func(e *dirEnt, name string) int {
+		return strings.Compare(e.name, name)
+	})
+
+	return slices.Insert(entries[:len(entries):len(entries)], pos, de)
+}
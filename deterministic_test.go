@@ -0,0 +1,67 @@
+package memfs
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"testing"
+	"time"
+)
+
+func TestWithDeterministic(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	build := func() *FS {
+		f := New(WithDeterministic(start, time.Second))
+
+		f.WriteFile("c", []byte("c"), 0o644)
+		f.Mkdir("b", 0o755)
+		f.WriteFile("b/z", []byte("z"), 0o644)
+		f.WriteFile("b/y", []byte("y"), 0o644)
+		f.Link("b/y", "a")
+		f.Remove("c")
+
+		of, _ := f.OpenFile("b/x", WriteOnly|Create, 0o600)
+
+		io.WriteString(of, "x")
+		of.Close()
+
+		return f
+	}
+
+	f := build()
+
+	if es, err := f.ReadDir("b"); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if names := [...]string{es[0].Name(), es[1].Name(), es[2].Name()}; names != [...]string{"x", "y", "z"} {
+		t.Errorf("test 1: expecting sorted entries, got %v", names)
+	} else if fi, err := f.Stat("."); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if expected := start.Add(10 * time.Second); !fi.ModTime().Equal(expected) {
+		t.Errorf("test 2: expecting modtime %s, got %s", expected, fi.ModTime())
+	} else if fi, err := f.Stat("b/x"); err != nil {
+		t.Fatalf("test 3: unexpected error: %s", err)
+	} else if expected := start.Add(13 * time.Second); !fi.ModTime().Equal(expected) {
+		t.Errorf("test 3: expecting modtime %s, got %s", expected, fi.ModTime())
+	}
+
+	var a, b bytes.Buffer
+
+	if err := f.WriteTar(&a); err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	} else if err := build().WriteTar(&b); err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	} else if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Errorf("test 4: expecting identical archives")
+	}
+
+	g := New(WithDeterministic(start, 0))
+
+	g.Mkdir("dir", fs.ModePerm)
+
+	if fi, err := g.Stat("dir"); err != nil {
+		t.Fatalf("test 5: unexpected error: %s", err)
+	} else if !fi.ModTime().Equal(start) {
+		t.Errorf("test 5: expecting modtime %s, got %s", start, fi.ModTime())
+	}
+}
@@ -3,37 +3,84 @@ package memfs
 import (
 	"io"
 	"io/fs"
+	"path"
 	"slices"
+	"strings"
+	"sync"
 	"time"
 )
 
 type directoryEntry interface {
 	IsDir() bool
 	ModTime() time.Time
+	ChangeTime() time.Time
 	Type() fs.FileMode
 	Mode() fs.FileMode
 	Size() int64
 	open(name string, mode opMode) (fs.File, error)
 	bytes() ([]byte, error)
 	string() (string, error)
-	setMode(fs.FileMode)
-	setTimes(time.Time, time.Time)
+	appendData([]byte) ([]byte, error)
+	setMode(fs.FileMode) error
+	setTimes(time.Time, time.Time) error
+	touch(time.Time)
 	seal() directoryEntry
 	getEntry(string) (*dirEnt, error)
+	Generation() uint64
+	getACL() ACL
+	setACL(ACL) error
+	perm() fs.FileMode
 }
 
 type dNode interface {
 	getEntry(string) (*dirEnt, error)
-	setEntry(*dirEnt) error
+	setEntry(*dirEnt, *deterministic) error
 	hasEntries() bool
 	getEntries() ([]fs.DirEntry, error)
-	removeEntry(string) error
+	scanEntries(func(fs.DirEntry) bool) error
+	removeEntry(string, *deterministic) error
+	replaceEntry(*dirEnt, *deterministic) error
 	Mode() fs.FileMode
+	perm() fs.FileMode
 }
 
 type dirEnt struct {
 	directoryEntry
 	name string
+	gen  uint64
+}
+
+// maxInternedNames is the number of distinct names that will be interned,
+// beyond which new names are copied, but not shared.
+const maxInternedNames = 1 << 16
+
+var internedNames = struct {
+	sync.Mutex
+	names map[string]string
+}{
+	names: make(map[string]string),
+}
+
+// entryName returns the last element of the given path, interned so that all
+// entries with the same name share the same memory, and so that no entry keeps
+// a larger path string alive.
+func entryName(p string) string {
+	name := path.Base(p)
+
+	internedNames.Lock()
+	defer internedNames.Unlock()
+
+	if interned, ok := internedNames.names[name]; ok {
+		return interned
+	}
+
+	name = strings.Clone(name)
+
+	if len(internedNames.names) < maxInternedNames {
+		internedNames.names[name] = name
+	}
+
+	return name
 }
 
 func (d *dirEnt) Info() (fs.FileInfo, error) {
@@ -53,19 +100,26 @@ func (d *dirEnt) String() string {
 }
 
 type dnode struct {
-	entries []*dirEnt
-	modtime time.Time
-	mode    fs.FileMode
+	entries    []*dirEnt
+	modtime    time.Time
+	ctime      time.Time
+	mode       fs.FileMode
+	sealed     bool
+	gen        uint64
+	acl        ACL
+	size       DirSizeFunc
+	maxEntries int
 }
 
 func (d *dnode) open(name string, _ opMode) (fs.File, error) {
-	if d.mode&modeRead == 0 {
+	if d.perm()&modeRead == 0 {
 		return nil, fs.ErrPermission
 	}
 
 	return &directory{
-		dnode: d,
-		name:  name,
+		dnode:   d,
+		name:    name,
+		entries: d.entries,
 	}, nil
 }
 
@@ -77,8 +131,12 @@ func (d *dnode) string() (string, error) {
 	return "", fs.ErrInvalid
 }
 
+func (d *dnode) appendData(_ []byte) ([]byte, error) {
+	return nil, fs.ErrInvalid
+}
+
 func (d *dnode) getEntry(name string) (*dirEnt, error) {
-	if d.mode&modeRead == 0 {
+	if d.perm()&modeRead == 0 {
 		return nil, fs.ErrPermission
 	}
 
@@ -91,13 +149,18 @@ func (d *dnode) getEntry(name string) (*dirEnt, error) {
 	return nil, fs.ErrNotExist
 }
 
-func (d *dnode) setEntry(de *dirEnt) error {
-	if d.mode&modeWrite == 0 {
+func (d *dnode) setEntry(de *dirEnt, det *deterministic) error {
+	if d.perm()&modeWrite == 0 || d.sealed {
 		return fs.ErrPermission
+	} else if d.maxEntries > 0 && len(d.entries) >= d.maxEntries {
+		return ErrDirFull
 	}
 
-	d.entries = append(d.entries, de)
-	d.modtime = time.Now()
+	d.gen = nextGeneration()
+	de.gen = d.gen
+	d.entries = det.insert(d.entries, de)
+	d.modtime = det.now()
+	d.ctime = d.modtime
 
 	return nil
 }
@@ -107,7 +170,7 @@ func (d *dnode) hasEntries() bool {
 }
 
 func (d *dnode) getEntries() ([]fs.DirEntry, error) {
-	if d.mode&modeRead == 0 {
+	if d.perm()&modeRead == 0 {
 		return nil, fs.ErrPermission
 	}
 
@@ -120,15 +183,55 @@ func (d *dnode) getEntries() ([]fs.DirEntry, error) {
 	return dirs, nil
 }
 
-func (d *dnode) removeEntry(name string) error {
-	if d.mode&modeWrite == 0 {
+func (d *dnode) scanEntries(fn func(fs.DirEntry) bool) error {
+	if d.perm()&modeRead == 0 {
+		return fs.ErrPermission
+	}
+
+	for _, e := range d.entries {
+		if !fn(e) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (d *dnode) removeEntry(name string, det *deterministic) error {
+	if d.perm()&modeWrite == 0 || d.sealed {
 		return fs.ErrPermission
 	}
 
 	for n, de := range d.entries {
 		if de.name == name {
-			d.entries = slices.Delete(d.entries, n, n+1)
-			d.modtime = time.Now()
+			// copy, rather than delete in place, so that any snapshot of
+			// the entries remains unchanged.
+			d.entries = append(d.entries[:n:n], d.entries[n+1:]...)
+			d.modtime = det.now()
+			d.ctime = d.modtime
+			d.gen = nextGeneration()
+
+			return nil
+		}
+	}
+
+	return fs.ErrNotExist
+}
+
+func (d *dnode) replaceEntry(de *dirEnt, det *deterministic) error {
+	if d.perm()&modeWrite == 0 || d.sealed {
+		return fs.ErrPermission
+	}
+
+	for n, e := range d.entries {
+		if e.name == de.name {
+			d.gen = nextGeneration()
+			de.gen = d.gen
+			entries := slices.Clone(d.entries)
+			entries[n] = de
+			d.entries = entries
+			d.modtime = det.now()
+			d.ctime = d.modtime
 
 			return nil
 		}
@@ -137,22 +240,46 @@ func (d *dnode) removeEntry(name string) error {
 	return fs.ErrNotExist
 }
 
-func (d *dnode) setMode(mode fs.FileMode) {
+func (d *dnode) setMode(mode fs.FileMode) error {
+	if d.sealed {
+		return fs.ErrPermission
+	}
+
 	d.mode = fs.ModeDir | mode
+	d.gen = nextGeneration()
+
+	if d.acl != nil {
+		d.acl = d.acl.withMode(mode)
+	}
+
+	return nil
 }
 
-func (d *dnode) setTimes(_, mtime time.Time) {
+func (d *dnode) setTimes(_, mtime time.Time) error {
+	if d.sealed {
+		return fs.ErrPermission
+	}
+
 	d.modtime = mtime
+	d.gen = nextGeneration()
+
+	return nil
 }
 
 func (d *dnode) seal() directoryEntry {
 	return d
 }
 
+// directory is an open directory.
+//
+// The entries field shadows that of the dnode, and holds the entries as they
+// were when the directory was opened, so that iterating over them is not
+// affected by any later changes to the directory.
 type directory struct {
 	*dnode
-	name string
-	pos  int
+	name    string
+	entries []*dirEnt
+	pos     int
 }
 
 func (d *directory) Info() (fs.FileInfo, error) {
@@ -172,7 +299,7 @@ func (d *directory) Close() error {
 }
 
 func (d *directory) ReadDir(n int) ([]fs.DirEntry, error) {
-	if d.mode&modeRead == 0 {
+	if d.perm()&modeRead == 0 {
 		return nil, fs.ErrPermission
 	}
 
@@ -202,12 +329,35 @@ func (d *directory) ReadDir(n int) ([]fs.DirEntry, error) {
 	return dirs, nil
 }
 
+// Seek allows for the iteration of the entries of the directory to be
+// restarted, with an offset of zero from io.SeekStart, and for the number of
+// entries already read to be retrieved, with an offset of zero from
+// io.SeekCurrent.
+//
+// Any other offset returns fs.ErrInvalid.
+func (d *directory) Seek(offset int64, whence int) (int64, error) {
+	switch {
+	case offset != 0:
+		return 0, fs.ErrInvalid
+	case whence == io.SeekStart:
+		d.pos = 0
+	case whence != io.SeekCurrent:
+		return 0, fs.ErrInvalid
+	}
+
+	return int64(d.pos), nil
+}
+
 func (d *directory) Name() string {
 	return d.name
 }
 
+func (d *directory) Size() int64 {
+	return dirSize(d.size, d.entries)
+}
+
 func (d *dnode) Size() int64 {
-	return 0
+	return dirSize(d.size, d.entries)
 }
 
 func (d *dnode) Type() fs.FileMode {
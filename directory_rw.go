@@ -1,118 +1,218 @@
 package memfs
 
 import (
+	"io"
 	"io/fs"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type dnodeRW struct {
 	dnode
-	mu sync.RWMutex
+	mu       sync.RWMutex
+	snapshot atomic.Pointer[dnode]
+	subtree  atomic.Uint64
+	parents  parents
+}
+
+// view returns an immutable copy of the directory, allowing lookups to proceed
+// without locking.
+//
+// The copy is made on the first call after the directory has changed, and is
+// shared until the next change.
+func (d *dnodeRW) view() *dnode {
+	if s := d.snapshot.Load(); s != nil {
+		return s
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	s := &dnode{
+		entries: d.entries[:len(d.entries):len(d.entries)],
+		modtime: d.modtime,
+		ctime:   d.ctime,
+		mode:    d.mode,
+		sealed:  d.sealed,
+		gen:     d.gen,
+		acl:     d.acl,
+		size:    d.size,
+	}
+
+	d.snapshot.Store(s)
+
+	return s
 }
 
 func (d *dnodeRW) open(name string, _ opMode) (fs.File, error) {
-	if d.mode&modeRead == 0 {
+	v := d.view()
+	if v.perm()&modeRead == 0 {
 		return nil, fs.ErrPermission
 	}
 
 	return &directoryRW{
 		mu: &d.mu,
 		directory: directory{
-			dnode: &d.dnode,
-			name:  name,
+			dnode:   &d.dnode,
+			name:    name,
+			entries: v.entries,
 		},
 	}, nil
 }
 
 func (d *dnodeRW) getEntry(name string) (*dirEnt, error) {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-
-	return d.dnode.getEntry(name)
+	return d.view().getEntry(name)
 }
 
-func (d *dnodeRW) setEntry(de *dirEnt) error {
+func (d *dnodeRW) setEntry(de *dirEnt, det *deterministic) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	defer d.snapshot.Store(nil)
+
+	if err := d.dnode.setEntry(de, det); err != nil {
+		return err
+	}
 
-	return d.dnode.setEntry(de)
+	addParent(de.directoryEntry, d)
+	d.changed(d.gen)
+
+	return nil
 }
 
 func (d *dnodeRW) hasEntries() bool {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-
-	return d.dnode.hasEntries()
+	return d.view().hasEntries()
 }
 
 func (d *dnodeRW) getEntries() ([]fs.DirEntry, error) {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
+	return d.view().getEntries()
+}
 
-	return d.dnode.getEntries()
+func (d *dnodeRW) scanEntries(fn func(fs.DirEntry) bool) error {
+	return d.view().scanEntries(fn)
 }
 
-func (d *dnodeRW) removeEntry(name string) error {
+func (d *dnodeRW) removeEntry(name string, det *deterministic) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	defer d.snapshot.Store(nil)
+
+	old := d.entry(name)
 
-	return d.dnode.removeEntry(name)
+	if err := d.dnode.removeEntry(name, det); err != nil {
+		return err
+	}
+
+	removeParent(old.directoryEntry, d)
+	d.changed(d.gen)
+
+	return nil
 }
 
-func (d *dnodeRW) setMode(mode fs.FileMode) {
+func (d *dnodeRW) replaceEntry(de *dirEnt, det *deterministic) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	defer d.snapshot.Store(nil)
+
+	old := d.entry(de.name)
 
-	d.mode = fs.ModeDir | mode
+	if err := d.dnode.replaceEntry(de, det); err != nil {
+		return err
+	}
+
+	removeParent(old.directoryEntry, d)
+	addParent(de.directoryEntry, d)
+	d.changed(d.gen)
+
+	return nil
+}
+
+// entry returns the entry with the given name, without checking permissions;
+// must be called with the lock held.
+func (d *dnodeRW) entry(name string) *dirEnt {
+	for _, e := range d.entries {
+		if e.name == name {
+			return e
+		}
+	}
+
+	return &dirEnt{}
 }
 
-func (d *dnodeRW) setTimes(_, mtime time.Time) {
+func (d *dnodeRW) setMode(mode fs.FileMode) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	defer d.snapshot.Store(nil)
 
-	d.modtime = mtime
+	if err := d.dnode.setMode(mode); err != nil {
+		return err
+	}
+
+	d.changed(d.gen)
+
+	return nil
+}
+
+func (d *dnodeRW) setTimes(atime, mtime time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	defer d.snapshot.Store(nil)
+
+	if err := d.dnode.setTimes(atime, mtime); err != nil {
+		return err
+	}
+
+	d.changed(d.gen)
+
+	return nil
 }
 
 func (d *dnodeRW) seal() directoryEntry {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	de := d.dnode
+	if !d.sealed {
+		for n, e := range d.entries {
+			d.entries[n].directoryEntry = e.seal()
+		}
 
-	for n, e := range de.entries {
-		de.entries[n].directoryEntry = e.seal()
+		d.sealed = true
+		d.snapshot.Store(nil)
 	}
 
-	d.dnode = dnode{}
-
-	return &de
+	return &d.dnode
 }
 
 func (d *dnodeRW) Type() fs.FileMode {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-
-	return d.mode.Type()
+	return d.view().mode.Type()
 }
 
 func (d *dnodeRW) Mode() fs.FileMode {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-
-	return d.mode
+	return d.view().mode
 }
 
 func (d *dnodeRW) ModTime() time.Time {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
+	return d.view().modtime
+}
 
-	return d.modtime
+func (d *dnodeRW) Size() int64 {
+	return d.view().Size()
 }
 
 type directoryRW struct {
 	directory
 	mu *sync.RWMutex
+
+	handles *openLimit
+	closed  atomic.Bool
+}
+
+func (d *directoryRW) Close() error {
+	if !d.closed.Swap(true) {
+		d.handles.release()
+	}
+
+	return nil
 }
 
 func (d *directoryRW) ReadDir(n int) ([]fs.DirEntry, error) {
@@ -122,6 +222,25 @@ func (d *directoryRW) ReadDir(n int) ([]fs.DirEntry, error) {
 	return d.directory.ReadDir(n)
 }
 
+// Seek allows for the iteration of the entries of the directory to be
+// restarted, with an offset of zero from io.SeekStart, and for the number of
+// entries already read to be retrieved, with an offset of zero from
+// io.SeekCurrent.
+//
+// Restarting the iteration takes a new snapshot of the entries, so that any
+// changes made since the directory was opened are seen.
+func (d *directoryRW) Seek(offset int64, whence int) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	pos, err := d.directory.Seek(offset, whence)
+	if err == nil && whence == io.SeekStart {
+		d.entries = d.dnode.entries[:len(d.dnode.entries):len(d.dnode.entries)]
+	}
+
+	return pos, err
+}
+
 func (d *directoryRW) Sys() any {
 	return d
 }
@@ -5,8 +5,10 @@ import (
 	"io"
 	"io/fs"
 	"reflect"
+	"strconv"
 	"sync"
 	"testing"
+	"time"
 )
 
 var _ dNode = &directoryRW{}
@@ -53,6 +55,7 @@ func makeDirectoryRW(dirs []*dirEnt) *directoryRW {
 				entries: dirs,
 				mode:    fs.ModeDir | fs.ModePerm,
 			},
+			entries: dirs,
 		},
 	}
 }
@@ -139,13 +142,13 @@ func TestDnodeRemoveRW(t *testing.T) {
 		},
 	}
 
-	if err := d.removeEntry("2"); err != nil {
+	if err := d.removeEntry("2", nil); err != nil {
 		t.Errorf("test 1: unexpected error: %s", err)
 
 		return
 	}
 
-	if err := d.removeEntry("2"); !errors.Is(err, fs.ErrNotExist) {
+	if err := d.removeEntry("2", nil); !errors.Is(err, fs.ErrNotExist) {
 		t.Errorf("test 2: unexpected Not Exist, got %s", err)
 
 		return
@@ -173,3 +176,392 @@ func TestDnodeRemoveRW(t *testing.T) {
 		t.Errorf("test 4: expecting %v, got %v", expecting, d.entries)
 	}
 }
+
+func TestDnodeRWSnapshot(t *testing.T) {
+	d := dnodeRW{
+		dnode: dnode{
+			entries: []*dirEnt{
+				{
+					name: "1",
+				},
+				{
+					name: "2",
+				},
+			},
+			mode: fs.ModeDir | fs.ModePerm,
+		},
+	}
+
+	before := d.view()
+
+	if d.view() != before {
+		t.Errorf("test 1: expecting snapshot to be reused")
+	}
+
+	if err := d.removeEntry("1", nil); err != nil {
+		t.Errorf("test 2: unexpected error: %s", err)
+	} else if err := d.setEntry(&dirEnt{name: "3"}, nil); err != nil {
+		t.Errorf("test 3: unexpected error: %s", err)
+	} else if _, err := before.getEntry("1"); err != nil {
+		t.Errorf("test 4: expecting old snapshot to be unchanged, got error: %s", err)
+	} else if _, err := before.getEntry("3"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("test 5: expecting Not Exist error, got %v", err)
+	} else if _, err := d.getEntry("1"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("test 6: expecting Not Exist error, got %v", err)
+	} else if _, err := d.getEntry("3"); err != nil {
+		t.Errorf("test 7: unexpected error: %s", err)
+	}
+}
+
+func TestConcurrentLookups(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("a/b", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 1000; i++ {
+				if _, err := f.Stat("a/b"); err != nil {
+					t.Errorf("unexpected error: %s", err)
+
+					return
+				}
+			}
+		}()
+	}
+
+	for n := 0; n < 1000; n++ {
+		name := "a/" + strconv.Itoa(n)
+
+		if err := f.Mkdir(name, fs.ModePerm); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		} else if err := f.Remove(name); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	wg.Wait()
+}
+
+func TestReadDirRWSnapshot(t *testing.T) {
+	f := New()
+
+	for _, name := range [...]string{"a", "b", "c", "d"} {
+		if err := f.Mkdir(name, fs.ModePerm); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	d, err := f.Open(".")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var names []string
+
+	for {
+		des, err := d.(fs.ReadDirFile).ReadDir(1)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		names = append(names, des[0].Name())
+
+		if len(names) == 1 {
+			if err := f.Remove("a"); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			} else if err := f.Remove("c"); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			} else if err := f.Mkdir("e", fs.ModePerm); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		}
+	}
+
+	if expected := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expecting to read %v, got %v", expected, names)
+	}
+}
+
+func TestReadDirRWPaginated(t *testing.T) {
+	for n, test := range [...]struct {
+		Counts []int
+		Change func(*FS) error
+		Names  [][]string
+		EOF    bool
+	}{
+		{ // 1
+			Counts: []int{1, 0, 0, 1},
+			Change: func(f *FS) error {
+				return f.Remove("a")
+			},
+			Names: [][]string{{"a"}, {"b", "c", "d"}, nil, nil},
+			EOF:   true,
+		},
+		{ // 2
+			Counts: []int{2, -1},
+			Change: func(f *FS) error {
+				if err := f.Remove("c"); err != nil {
+					return err
+				}
+
+				return f.Remove("d")
+			},
+			Names: [][]string{{"a", "b"}, {"c", "d"}},
+		},
+		{ // 3
+			Counts: []int{3, 3},
+			Change: func(f *FS) error {
+				return f.Mkdir("e", fs.ModePerm)
+			},
+			Names: [][]string{{"a", "b", "c"}, {"d"}},
+		},
+		{ // 4
+			Counts: []int{1, 1, 5},
+			Change: func(f *FS) error {
+				return f.Rename("d", "b")
+			},
+			Names: [][]string{{"a"}, {"b"}, {"c", "d"}},
+		},
+		{ // 5
+			Counts: []int{4, 1, 0},
+			Change: func(f *FS) error {
+				return f.RenameExchange("a", "b")
+			},
+			Names: [][]string{{"a", "b", "c", "d"}, nil, nil},
+			EOF:   true,
+		},
+		{ // 6
+			Counts: []int{2, 2, 2},
+			Change: func(f *FS) error {
+				if err := f.RemoveAll("b"); err != nil {
+					return err
+				}
+
+				return f.Mkdir("b", fs.ModePerm)
+			},
+			Names: [][]string{{"a", "b"}, {"c", "d"}, nil},
+			EOF:   true,
+		},
+	} {
+		f := New()
+
+		for _, name := range [...]string{"a", "b", "c", "d"} {
+			if err := f.Mkdir(name, fs.ModePerm); err != nil {
+				t.Fatalf("test %d: unexpected error: %s", n+1, err)
+			}
+		}
+
+		d, err := f.Open(".")
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		}
+
+		var (
+			names [][]string
+			eof   bool
+		)
+
+		for m, count := range test.Counts {
+			des, err := d.(fs.ReadDirFile).ReadDir(count)
+			if errors.Is(err, io.EOF) {
+				eof = true
+			} else if err != nil {
+				t.Fatalf("test %d: unexpected error: %s", n+1, err)
+			}
+
+			var read []string
+
+			for _, de := range des {
+				read = append(read, de.Name())
+			}
+
+			names = append(names, read)
+
+			if m == 0 {
+				if err := test.Change(f); err != nil {
+					t.Fatalf("test %d: unexpected error: %s", n+1, err)
+				}
+			}
+		}
+
+		if !reflect.DeepEqual(names, test.Names) {
+			t.Errorf("test %d: expecting to read %v, got %v", n+1, test.Names, names)
+		} else if eof != test.EOF {
+			t.Errorf("test %d: expecting EOF to be %v, got %v", n+1, test.EOF, eof)
+		}
+	}
+}
+
+func TestSeekDirRW(t *testing.T) {
+	f := New()
+
+	for _, name := range [...]string{"a", "b", "c"} {
+		if err := f.Mkdir(name, fs.ModePerm); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	of, err := f.Open(".")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	d := of.(interface {
+		fs.ReadDirFile
+		io.Seeker
+	})
+
+	readNames := func(n int) []string {
+		t.Helper()
+
+		des, err := d.ReadDir(n)
+		if err != nil && !errors.Is(err, io.EOF) {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		var names []string
+
+		for _, de := range des {
+			names = append(names, de.Name())
+		}
+
+		return names
+	}
+
+	if names := readNames(2); !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("test 1: expecting to read [a b], got %v", names)
+	} else if pos, err := d.Seek(0, io.SeekCurrent); err != nil {
+		t.Errorf("test 2: unexpected error: %s", err)
+	} else if pos != 2 {
+		t.Errorf("test 2: expecting position 2, got %d", pos)
+	} else if err := f.Remove("a"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Mkdir("d", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if names := readNames(-1); !reflect.DeepEqual(names, []string{"c"}) {
+		t.Errorf("test 3: expecting to read [c], got %v", names)
+	} else if pos, err := d.Seek(0, io.SeekStart); err != nil {
+		t.Errorf("test 4: unexpected error: %s", err)
+	} else if pos != 0 {
+		t.Errorf("test 4: expecting position 0, got %d", pos)
+	} else if names := readNames(-1); !reflect.DeepEqual(names, []string{"b", "c", "d"}) {
+		t.Errorf("test 5: expecting to read [b c d], got %v", names)
+	} else if _, err := d.Seek(1, io.SeekStart); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("test 6: expecting invalid error, got %v", err)
+	} else if _, err := d.Seek(0, io.SeekEnd); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("test 7: expecting invalid error, got %v", err)
+	}
+
+	sealed, err := f.Seal().Open(".")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	d = sealed.(interface {
+		fs.ReadDirFile
+		io.Seeker
+	})
+
+	if names := readNames(-1); !reflect.DeepEqual(names, []string{"b", "c", "d"}) {
+		t.Errorf("test 8: expecting to read [b c d], got %v", names)
+	} else if _, err := d.Seek(0, io.SeekStart); err != nil {
+		t.Errorf("test 9: unexpected error: %s", err)
+	} else if names := readNames(1); !reflect.DeepEqual(names, []string{"b"}) {
+		t.Errorf("test 9: expecting to read [b], got %v", names)
+	}
+}
+
+func TestReadDirRWPaginatedRemove(t *testing.T) {
+	for n, test := range [...]struct {
+		Options []Option
+		Remove  func(read, unread []string) []string
+	}{
+		{ // 1
+			Remove: func(read, _ []string) []string {
+				return read[len(read)-1:]
+			},
+		},
+		{ // 2
+			Remove: func(_, unread []string) []string {
+				if len(unread) > 1 {
+					unread = unread[:1]
+				}
+
+				return unread
+			},
+		},
+		{ // 3
+			Remove: func(read, unread []string) []string {
+				if len(unread) > 2 {
+					unread = unread[:2]
+				}
+
+				return append(read[:1:1], unread...)
+			},
+		},
+		{ // 4
+			Options: []Option{WithDeterministic(time.Unix(0, 0), time.Second)},
+			Remove: func(read, unread []string) []string {
+				if len(unread) > 2 {
+					unread = unread[:2]
+				}
+
+				return append(read[:1:1], unread...)
+			},
+		},
+	} {
+		f := New(test.Options...)
+		names := []string{"a", "b", "c", "d", "e", "f", "g"}
+
+		for _, name := range names {
+			if err := f.WriteFile(name, nil, fs.ModePerm); err != nil {
+				t.Fatalf("test %d: unexpected error: %s", n+1, err)
+			}
+		}
+
+		d, err := f.Open(".")
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		}
+
+		var all []string
+
+		for {
+			des, err := d.(fs.ReadDirFile).ReadDir(2)
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				t.Fatalf("test %d: unexpected error: %s", n+1, err)
+			}
+
+			var read []string
+
+			for _, de := range des {
+				read = append(read, de.Name())
+			}
+
+			all = append(all, read...)
+
+			for _, name := range test.Remove(read, names[len(all):]) {
+				if err := f.RemoveAll(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+					t.Fatalf("test %d: unexpected error: %s", n+1, err)
+				}
+			}
+		}
+
+		if !reflect.DeepEqual(all, names) {
+			t.Errorf("test %d: expecting to read %v, got %v", n+1, names, all)
+		}
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"io"
 	"io/fs"
 	"reflect"
+	"strings"
 	"testing"
+	"unsafe"
 )
 
 var _ dNode = &directory{}
@@ -50,6 +52,7 @@ func makeDirectory(dirs []*dirEnt) *directory {
 			entries: dirs,
 			mode:    fs.ModeDir | fs.ModePerm,
 		},
+		entries: dirs,
 	}
 }
 
@@ -123,3 +126,34 @@ func TestReadDir(t *testing.T) {
 		}
 	}
 }
+
+func TestEntryName(t *testing.T) {
+	f := New()
+
+	for n, path := range [...]string{
+		"a",
+		"b",
+		strings.Join([]string{"a", "index.js"}, "/"),
+		strings.Join([]string{"b", "index.js"}, "/"),
+	} {
+		if err := f.Mkdir(path, fs.ModePerm); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		}
+	}
+
+	a, err := f.getLEntry("a/index.js")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	b, err := f.getLEntry("b/index.js")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if a.name != "index.js" {
+		t.Errorf("expecting name %q, got %q", "index.js", a.name)
+	} else if unsafe.StringData(a.name) != unsafe.StringData(b.name) {
+		t.Errorf("expecting names to share memory")
+	}
+}
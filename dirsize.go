@@ -0,0 +1,81 @@
+package memfs
+
+import "iter"
+
+// DirSizeFunc is used to determine the size reported for a directory, given
+// the names of its entries, as set with WithDirSize.
+type DirSizeFunc func(names iter.Seq[string]) int64
+
+// WithDirSize sets the function used to determine the size reported for each
+// directory created in the FS, and in any FS created from it with Sub, in
+// place of the default of zero, for code that depends upon the directory sizes
+// of a particular file system.
+//
+// The size is reported by Stat, and by the fs.FileInfo of the entries returned
+// by ReadDir, of the FS and of any FSRO created from it with Seal or Snapshot.
+func WithDirSize(fn DirSizeFunc) Option {
+	return func(f *FS) {
+		f.dirSize = fn
+	}
+}
+
+// DirSizeEntries is a DirSizeFunc that reports the number of entries in a
+// directory.
+func DirSizeEntries(names iter.Seq[string]) int64 {
+	var size int64
+
+	names(func(string) bool {
+		size++
+
+		return true
+	})
+
+	return size
+}
+
+const (
+	ext4DirentHeader = 8
+	ext4BlockSize    = 4096
+	apfsDirent       = 32
+)
+
+// DirSizeExt4 is a DirSizeFunc that approximates the size reported by ext4 for
+// a linear directory, being the space taken by the entries, including '.' and
+// '..', rounded up to a whole number of 4KiB blocks.
+func DirSizeExt4(names iter.Seq[string]) int64 {
+	size := ext4Dirent(".") + ext4Dirent("..")
+
+	names(func(name string) bool {
+		size += ext4Dirent(name)
+
+		return true
+	})
+
+	return (size + ext4BlockSize - 1) / ext4BlockSize * ext4BlockSize
+}
+
+func ext4Dirent(name string) int64 {
+	return int64(ext4DirentHeader+len(name)+3) &^ 3
+}
+
+// DirSizeAPFS is a DirSizeFunc that approximates the size reported by APFS,
+// being 32 bytes for each entry, including '.' and '..'.
+func DirSizeAPFS(names iter.Seq[string]) int64 {
+	return (DirSizeEntries(names) + 2) * apfsDirent
+}
+
+// dirSize returns the size of a directory with the given entries, as
+// determined by the given DirSizeFunc, or zero when there is none.
+func dirSize(fn DirSizeFunc, entries []*dirEnt) int64 {
+	if fn == nil {
+		return 0
+	}
+
+	return fn(func(yield func(string) bool) {
+		for _, e := range entries {
+			if !yield(e.name) {
+				return
+			}
+		}
+	})
+}
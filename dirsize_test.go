@@ -0,0 +1,152 @@
+package memfs
+
+import (
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDirSizeFuncs(t *testing.T) {
+	for n, test := range [...]struct {
+		Names               []string
+		Entries, Ext4, APFS int64
+	}{
+		{ // 1
+			Ext4: 4096,
+			APFS: 64,
+		},
+		{ // 2
+			Names:   []string{"a", "bb", "ccc"},
+			Entries: 3,
+			Ext4:    4096,
+			APFS:    160,
+		},
+		{ // 3
+			Names:   slices.Repeat([]string{strings.Repeat("a", 20)}, 200),
+			Entries: 200,
+			Ext4:    8192,
+			APFS:    6464,
+		},
+	} {
+		names := slices.Values(test.Names)
+
+		if size := DirSizeEntries(names); size != test.Entries {
+			t.Errorf("test %d: expecting DirSizeEntries to return %d, got %d", n+1, test.Entries, size)
+		}
+
+		if size := DirSizeExt4(names); size != test.Ext4 {
+			t.Errorf("test %d: expecting DirSizeExt4 to return %d, got %d", n+1, test.Ext4, size)
+		}
+
+		if size := DirSizeAPFS(names); size != test.APFS {
+			t.Errorf("test %d: expecting DirSizeAPFS to return %d, got %d", n+1, test.APFS, size)
+		}
+	}
+}
+
+func TestWithDirSize(t *testing.T) {
+	f := New(WithDirSize(DirSizeEntries))
+
+	f.Mkdir("dir", 0o755)
+	f.WriteFile("dir/a", nil, 0o644)
+	f.WriteFile("dir/b", nil, 0o644)
+	f.Mkdir("dir/c", 0o755)
+
+	d, err := f.Open("dir")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	defer d.Close()
+
+	f.Remove("dir/a")
+
+	sub, _ := f.Sub("dir")
+	sub.(*FS).Mkdir("d", 0o755)
+	sub.(*FS).Mkdir("e", 0o755)
+
+	layer, err := Layer(f.Snapshot(), WithDirSize(DirSizeAPFS))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		FS   fs.FS
+		Path string
+		Size int64
+	}{
+		{ // 1
+			FS:   f,
+			Path: ".",
+			Size: 1,
+		},
+		{ // 2
+			FS:   f,
+			Path: "dir",
+			Size: 4,
+		},
+		{ // 3
+			FS:   f,
+			Path: "dir/a",
+			Size: -1,
+		},
+		{ // 4
+			FS:   f,
+			Path: "dir/c",
+			Size: 0,
+		},
+		{ // 5
+			FS:   New(),
+			Path: ".",
+			Size: 0,
+		},
+		{ // 6
+			FS:   f.Snapshot(),
+			Path: "dir",
+			Size: 4,
+		},
+		{ // 7
+			FS:   layer,
+			Path: "dir",
+			Size: 192,
+		},
+		{ // 8
+			FS:   layer,
+			Path: "dir/c",
+			Size: 64,
+		},
+	} {
+		fi, err := fs.Stat(test.FS, test.Path)
+		if test.Size < 0 {
+			if err == nil {
+				t.Errorf("test %d: expecting error, got nil", n+1)
+			}
+		} else if err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if size := fi.Size(); size != test.Size {
+			t.Errorf("test %d: expecting size %d, got %d", n+1, test.Size, size)
+		}
+	}
+
+	if fi, err := d.Stat(); err != nil {
+		t.Errorf("test 9: unexpected error: %s", err)
+	} else if size := fi.Size(); size != 3 {
+		t.Errorf("test 9: expecting open directory to report size 3, got %d", size)
+	}
+
+	entries, err := f.ReadDir(".")
+	if err != nil {
+		t.Errorf("test 10: unexpected error: %s", err)
+	} else if fi, err := entries[0].Info(); err != nil {
+		t.Errorf("test 10: unexpected error: %s", err)
+	} else if size := fi.Size(); size != 4 {
+		t.Errorf("test 10: expecting size 4, got %d", size)
+	}
+
+	if fi, err := fs.Stat(f.Seal(), "dir"); err != nil {
+		t.Errorf("test 11: unexpected error: %s", err)
+	} else if size := fi.Size(); size != 4 {
+		t.Errorf("test 11: expecting size 4, got %d", size)
+	}
+}
@@ -0,0 +1,202 @@
+package memfs
+
+import (
+	"archive/tar"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// WriteTar writes the entire FS, ignoring permissions, to the given io.Writer
+// as a tar archive, as with FS.WriteTar.
+func (f *fsRO) WriteTar(w io.Writer, opts ...TarOption) error {
+	t := tarWriter{
+		Writer: tar.NewWriter(w),
+		links:  make(map[any]string),
+	}
+
+	for _, opt := range opts {
+		opt(&t)
+	}
+
+	if err := t.writeDir("", f.de); err != nil {
+		return err
+	}
+
+	return t.Close()
+}
+
+// WriteTar writes the entire FS, ignoring permissions, to the given io.Writer
+// as a tar archive, as modified by the given TarOptions.
+//
+// Entries are written in order, sorted by name, with no owner, and so a user
+// and group ID of zero. Files that are hard linked are written once, with each
+// additional link written as a tar.TypeLink entry referring to the first.
+//
+// The archive is written from a Snapshot of the FS, and so contains the FS as
+// it was when WriteTar was called, with the FS only being locked while the
+// Snapshot is taken, allowing the FS to be modified while the archive is
+// written.
+func (f *FS) WriteTar(w io.Writer, opts ...TarOption) error {
+	return f.snapshot().WriteTar(w, opts...)
+}
+
+// TarOption is used to modify the archive written by WriteTar.
+type TarOption func(*tarWriter)
+
+// TarModTime sets the modification time of every entry written to the archive
+// to the given time, such as time.Unix(0, 0).
+func TarModTime(t time.Time) TarOption {
+	return func(tw *tarWriter) {
+		tw.modtime = func(time.Time) time.Time {
+			return t
+		}
+	}
+}
+
+// TarClampModTime sets the modification time of any entry written to the
+// archive that is later than the given time to that time, in the manner of
+// SOURCE_DATE_EPOCH.
+func TarClampModTime(t time.Time) TarOption {
+	return func(tw *tarWriter) {
+		tw.modtime = func(modtime time.Time) time.Time {
+			if modtime.After(t) {
+				return t
+			}
+
+			return modtime
+		}
+	}
+}
+
+// WriteTo writes the entire FS to the given io.Writer as a tar archive, as with
+// FS.WriteTo.
+func (f *fsRO) WriteTo(w io.Writer) (int64, error) {
+	cw := countWriter{Writer: w}
+	err := f.WriteTar(&cw)
+
+	return cw.n, err
+}
+
+// WriteTo writes the entire FS to the given io.Writer as a tar archive, in the
+// same way as WriteTar, streaming each file directly from memory, and returns
+// the number of bytes written.
+//
+// As with WriteTar, the archive is written from a Snapshot of the FS, which
+// can be modified while the archive is written.
+func (f *FS) WriteTo(w io.Writer) (int64, error) {
+	return f.snapshot().WriteTo(w)
+}
+
+type tarWriter struct {
+	*tar.Writer
+	links   map[any]string
+	modtime func(time.Time) time.Time
+}
+
+func (t *tarWriter) writeDir(dir string, de directoryEntry) error {
+	entries := slices.Clone(childEntries(de))
+
+	slices.SortFunc(entries, func(a, b *dirEnt) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	for _, e := range entries {
+		if err := t.writeEntry(path.Join(dir, e.name), e.directoryEntry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *tarWriter) writeEntry(name string, de directoryEntry) error {
+	mode := de.Mode()
+	hdr := tar.Header{
+		Name:    name,
+		Mode:    int64(mode.Perm()),
+		ModTime: de.ModTime(),
+	}
+
+	if t.modtime != nil {
+		hdr.ModTime = t.modtime(hdr.ModTime)
+	}
+
+	switch {
+	case mode.IsDir():
+		hdr.Typeflag = tar.TypeDir
+		hdr.Name += "/"
+
+		if err := t.WriteHeader(&hdr); err != nil {
+			return err
+		}
+
+		return t.writeDir(name, de)
+	case mode&fs.ModeSymlink != 0:
+		hdr.Typeflag = tar.TypeSymlink
+
+		withData(de, func(data []byte) error {
+			hdr.Linkname = string(data)
+
+			return nil
+		})
+
+		return t.WriteHeader(&hdr)
+	case mode&fs.ModeNamedPipe != 0:
+		hdr.Typeflag = tar.TypeFifo
+
+		return t.WriteHeader(&hdr)
+	}
+
+	key := nodeKey(de)
+
+	if first, ok := t.links[key]; ok {
+		hdr.Typeflag = tar.TypeLink
+		hdr.Linkname = first
+
+		return t.WriteHeader(&hdr)
+	}
+
+	t.links[key] = name
+
+	return withData(de, func(data []byte) error {
+		hdr.Typeflag = tar.TypeReg
+		hdr.Size = int64(len(data))
+
+		if err := t.WriteHeader(&hdr); err != nil {
+			return err
+		}
+
+		_, err := t.Write(data)
+
+		return err
+	})
+}
+
+// withData calls the given func with the data of the given file, which must
+// not be retained or modified.
+func withData(de directoryEntry, fn func([]byte) error) error {
+	switch de := de.(type) {
+	case *inodeRW:
+		de.mu.RLock()
+		defer de.mu.RUnlock()
+
+		return fn(de.data)
+	case *inode:
+		return fn(de.data)
+	case packedNode:
+		return fn(de.contents())
+	case *lazyNode:
+		data, err := de.bytes()
+		if err != nil {
+			return err
+		}
+
+		return fn(data)
+	}
+
+	return fs.ErrInvalid
+}
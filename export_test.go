@@ -0,0 +1,242 @@
+package memfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteTar(t *testing.T) {
+	f := New()
+	modtime := time.Unix(1234, 0).UTC()
+
+	if err := f.MkdirAll("a/b", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("a/b/file", []byte("Hello"), 0o640); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Link("a/b/file", "a/link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("a/b", "symlink"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("private", []byte("World"), 0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, p := range [...]string{"a", "a/b", "a/b/file", "symlink", "private"} {
+		if err := f.Lchtimes(p, time.Time{}, modtime); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	type entry struct {
+		Name     string
+		Type     byte
+		Mode     int64
+		Linkname string
+		Data     string
+	}
+
+	for n, fsys := range [...]interface {
+		WriteTar(io.Writer, ...TarOption) error
+	}{f, sealCompact(t, f)} {
+		var buf bytes.Buffer
+
+		if err := fsys.WriteTar(&buf); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+
+			continue
+		}
+
+		var entries []entry
+
+		r := tar.NewReader(&buf)
+
+		for {
+			hdr, err := r.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				t.Fatalf("test %d: unexpected error: %s", n+1, err)
+			}
+
+			data, _ := io.ReadAll(r)
+
+			if !hdr.ModTime.Equal(modtime) {
+				t.Errorf("test %d: expecting modtime %s for %s, got %s", n+1, modtime, hdr.Name, hdr.ModTime)
+			}
+
+			entries = append(entries, entry{Name: hdr.Name, Type: hdr.Typeflag, Mode: hdr.Mode, Linkname: hdr.Linkname, Data: string(data)})
+		}
+
+		expected := []entry{
+			{Name: "a/", Type: tar.TypeDir, Mode: 0o755},
+			{Name: "a/b/", Type: tar.TypeDir, Mode: 0o755},
+			{Name: "a/b/file", Type: tar.TypeReg, Mode: 0o640, Data: "Hello"},
+			{Name: "a/link", Type: tar.TypeLink, Mode: 0o640, Linkname: "a/b/file"},
+			{Name: "private", Type: tar.TypeReg, Data: "World"},
+			{Name: "symlink", Type: tar.TypeSymlink, Mode: int64(fs.ModePerm), Linkname: "a/b"},
+		}
+
+		if !reflect.DeepEqual(entries, expected) {
+			t.Errorf("test %d: expecting entries %v, got %v", n+1, expected, entries)
+		}
+	}
+}
+
+func TestFSWriteTo(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("a/b", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("a/b/file", bytes.Repeat([]byte("data"), 1000), 0o640); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("a/b", "symlink"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, fsys := range [...]FSRO{f, sealCompact(t, f), f.Seal()} {
+		var tarred, written bytes.Buffer
+
+		if err := fsys.WriteTar(&tarred); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if m, err := fsys.WriteTo(&written); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if m != int64(written.Len()) {
+			t.Errorf("test %d: expecting to have written %d bytes, reported %d", n+1, written.Len(), m)
+		} else if !bytes.Equal(tarred.Bytes(), written.Bytes()) {
+			t.Errorf("test %d: expecting WriteTo to match WriteTar", n+1)
+		}
+	}
+}
+
+type modifyingWriter struct {
+	bytes.Buffer
+	modify func() error
+}
+
+func (m *modifyingWriter) Write(p []byte) (int, error) {
+	if m.modify != nil {
+		if err := m.modify(); err != nil {
+			return 0, err
+		}
+
+		m.modify = nil
+	}
+
+	return m.Buffer.Write(p)
+}
+
+func TestFSWriteTarSnapshot(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("a/b", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("a/b/file", []byte("before"), 0o640); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("other", []byte("other"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var expected bytes.Buffer
+
+	if err := f.Snapshot().WriteTar(&expected); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, write := range [...]func(io.Writer) error{
+		func(w io.Writer) error { return f.WriteTar(w) },
+		func(w io.Writer) error {
+			_, err := f.WriteTo(w)
+
+			return err
+		},
+	} {
+		if err := f.WriteFile("a/b/file", []byte("before"), 0o640); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		}
+
+		w := modifyingWriter{
+			modify: func() error {
+				if err := f.WriteFile("a/b/file", []byte("after"), 0o640); err != nil {
+					return err
+				} else if err := f.Mkdir("new", 0o755); err != nil {
+					return err
+				}
+
+				return f.Remove("new")
+			},
+		}
+
+		if err := write(&w); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if !bytes.Equal(w.Bytes(), expected.Bytes()) {
+			t.Errorf("test %d: expecting archive to match the FS before modification", n+1)
+		} else if data, err := f.ReadFile("a/b/file"); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if string(data) != "after" {
+			t.Errorf("test %d: expecting contents %q, got %q", n+1, "after", data)
+		}
+	}
+}
+
+func TestWriteTarModTime(t *testing.T) {
+	f := New()
+
+	early := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	clamp := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if err := f.WriteFile("early", nil, 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("late", nil, 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Chtimes("early", early, early); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Chtimes("late", late, late); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Options []TarOption
+		Early   time.Time
+		Late    time.Time
+	}{
+		{ // 1
+			Early: early,
+			Late:  late,
+		},
+		{ // 2
+			Options: []TarOption{TarModTime(time.Unix(0, 0))},
+			Early:   time.Unix(0, 0),
+			Late:    time.Unix(0, 0),
+		},
+		{ // 3
+			Options: []TarOption{TarClampModTime(clamp)},
+			Early:   early,
+			Late:    clamp,
+		},
+	} {
+		var buf bytes.Buffer
+
+		if err := f.WriteTar(&buf, test.Options...); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		}
+
+		r := tar.NewReader(&buf)
+
+		for _, expected := range [...]time.Time{test.Early, test.Late} {
+			if hdr, err := r.Next(); err != nil {
+				t.Fatalf("test %d: unexpected error: %s", n+1, err)
+			} else if !hdr.ModTime.Equal(expected) {
+				t.Errorf("test %d: expecting %s to have modtime %s, got %s", n+1, hdr.Name, expected, hdr.ModTime)
+			} else if hdr.Uid != 0 || hdr.Gid != 0 {
+				t.Errorf("test %d: expecting %s to have uid and gid of 0, got %d and %d", n+1, hdr.Name, hdr.Uid, hdr.Gid)
+			}
+		}
+	}
+}
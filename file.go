@@ -1,6 +1,7 @@
 package memfs
 
 import (
+	"bytes"
 	"io"
 	"io/fs"
 	"time"
@@ -14,8 +15,29 @@ const (
 	opRead  opMode = 1 << iota
 	opWrite
 	opSeek
+	opAppend
 )
 
+// Errors returned by the methods of a file, or pipe, that was not opened with
+// the Mode required by the method, such as writing to a file opened with
+// ReadOnly.
+//
+// Both match fs.ErrInvalid with errors.Is.
+var (
+	ErrNotOpenForRead  error = modeError("file not opened for reading")
+	ErrNotOpenForWrite error = modeError("file not opened for writing")
+)
+
+type modeError string
+
+func (m modeError) Error() string {
+	return string(m)
+}
+
+func (modeError) Unwrap() error {
+	return fs.ErrInvalid
+}
+
 const (
 	modeRead  = 0o444
 	modeWrite = 0o222
@@ -23,12 +45,54 @@ const (
 
 type inode struct {
 	modtime time.Time
+	ctime   time.Time
 	data    []byte
 	mode    fs.FileMode
+	sealed  bool
+	gen     uint64
+	meta    *inodeMeta
+	links   int
+	opens   int
+	shared  bool
+
+	// parents holds the directories containing the names of a writable
+	// file, being created when the first name is added.
+	parents *parents
+
+	// etag holds the ETag of a sealed file, being created when it is
+	// sealed.
+	etag *etagOnce
+}
+
+// Linked is implemented by the value returned by the Sys method of the
+// fs.FileInfo of a file, or symlink, in an FS, or in an FSRO created with Seal.
+type Linked interface {
+	// Links returns the number of names, or hard links, the file has in the
+	// tree, which is zero for a file that has been removed but is still
+	// held open.
+	Links() int
+}
+
+func (i *inode) Links() int {
+	return i.links
+}
+
+// release discards the data of a file once it has neither any names nor any
+// open Files, returning it to the given Allocator, if any.
+func (i *inode) release(alloc Allocator) {
+	if i.links > 0 || i.opens > 0 || i.sealed {
+		return
+	}
+
+	if alloc != nil && i.data != nil && !i.shared {
+		alloc.Free(i.data)
+	}
+
+	i.data = nil
 }
 
 func (i *inode) open(name string, mode opMode) (fs.File, error) {
-	if mode&opRead > 0 && i.mode&modeRead == 0 || mode&opWrite > 0 && i.mode&modeWrite == 0 {
+	if mode&opRead > 0 && i.perm()&modeRead == 0 || mode&opWrite > 0 && (i.perm()&modeWrite == 0 || i.sealed) {
 		return nil, fs.ErrPermission
 	}
 
@@ -40,7 +104,7 @@ func (i *inode) open(name string, mode opMode) (fs.File, error) {
 }
 
 func (i *inode) bytes() ([]byte, error) {
-	if i.mode&modeRead == 0 {
+	if i.perm()&modeRead == 0 {
 		return nil, fs.ErrPermission
 	}
 
@@ -48,19 +112,45 @@ func (i *inode) bytes() ([]byte, error) {
 }
 
 func (i *inode) string() (string, error) {
-	if i.mode&modeRead == 0 {
+	if i.perm()&modeRead == 0 {
 		return "", fs.ErrPermission
 	}
 
 	return string(i.data), nil
 }
 
-func (i *inode) setMode(mode fs.FileMode) {
+func (i *inode) appendData(p []byte) ([]byte, error) {
+	if i.perm()&modeRead == 0 {
+		return nil, fs.ErrPermission
+	}
+
+	return append(p, i.data...), nil
+}
+
+func (i *inode) setMode(mode fs.FileMode) error {
+	if i.sealed {
+		return fs.ErrPermission
+	}
+
 	i.mode = i.mode&fs.ModeSymlink | mode
+	i.gen = nextGeneration()
+
+	if i.meta != nil && i.meta.acl != nil {
+		i.meta.acl = i.meta.acl.withMode(mode)
+	}
+
+	return nil
 }
 
-func (i *inode) setTimes(_, mtime time.Time) {
+func (i *inode) setTimes(_, mtime time.Time) error {
+	if i.sealed {
+		return fs.ErrPermission
+	}
+
 	i.modtime = mtime
+	i.gen = nextGeneration()
+
+	return nil
 }
 
 func (i *inode) seal() directoryEntry {
@@ -68,7 +158,7 @@ func (i *inode) seal() directoryEntry {
 }
 
 func (i *inode) getEntry(_ string) (*dirEnt, error) {
-	return nil, fs.ErrInvalid
+	return nil, ErrNotDir
 }
 
 type file struct {
@@ -84,10 +174,18 @@ func (f *file) validTo(m opMode, needValidPos bool) error {
 		return fs.ErrClosed
 	}
 
-	if f.opMode&m != m {
+	if missing := m &^ f.opMode; missing&opRead != 0 {
+		return ErrNotOpenForRead
+	} else if missing&opWrite != 0 {
+		return ErrNotOpenForWrite
+	} else if missing != 0 {
 		return fs.ErrInvalid
 	}
 
+	if m&opWrite != 0 && f.sealed {
+		return fs.ErrPermission
+	}
+
 	if needValidPos && f.pos >= int64(len(f.data)) {
 		return io.EOF
 	}
@@ -119,6 +217,8 @@ func (f *file) Read(p []byte) (int, error) {
 func (f *file) ReadAt(p []byte, off int64) (int, error) {
 	if err := f.validTo(opRead|opSeek, false); err != nil {
 		return 0, err
+	} else if off < 0 {
+		return 0, fs.ErrInvalid
 	}
 
 	if off >= int64(len(f.data)) {
@@ -190,6 +290,104 @@ func (f *file) UnreadRune() error {
 	return nil
 }
 
+// readDelim returns the data from the current position up to, and including,
+// the first instance of the given delimiter, advancing the position past it,
+// or up to the end of the file, along with io.EOF, when there is no delimiter.
+func (f *file) readDelim(delim byte) ([]byte, error) {
+	if err := f.validTo(opRead, true); err != nil {
+		return nil, err
+	}
+
+	data := f.data[f.pos:]
+
+	var err error
+
+	if n := bytes.IndexByte(data, delim); n < 0 {
+		err = io.EOF
+	} else {
+		data = data[:n+1]
+	}
+
+	f.pos += int64(len(data))
+	f.lastRead = 0
+
+	return data, err
+}
+
+func (f *file) ReadBytes(delim byte) ([]byte, error) {
+	data, err := f.readDelim(delim)
+
+	return bytes.Clone(data), err
+}
+
+func (f *file) ReadString(delim byte) (string, error) {
+	data, err := f.readDelim(delim)
+
+	return string(data), err
+}
+
+func (f *file) Peek(n int) ([]byte, error) {
+	if err := f.validTo(opRead, false); err != nil {
+		return nil, err
+	} else if n < 0 {
+		return nil, fs.ErrInvalid
+	}
+
+	f.lastRead = 0
+
+	if f.pos >= int64(len(f.data)) {
+		if n == 0 {
+			return nil, nil
+		}
+
+		return nil, io.EOF
+	}
+
+	data := f.data[f.pos:]
+
+	if n > len(data) {
+		return data, io.EOF
+	}
+
+	return data[:n], nil
+}
+
+func (f *file) Discard(n int) (int, error) {
+	if err := f.validTo(opRead, false); err != nil {
+		return 0, err
+	} else if n < 0 {
+		return 0, fs.ErrInvalid
+	}
+
+	f.lastRead = 0
+
+	var err error
+
+	if remaining := int64(len(f.data)) - f.pos; remaining < int64(n) {
+		n = 0
+
+		if remaining > 0 {
+			n = int(remaining)
+		}
+
+		err = io.EOF
+	}
+
+	f.pos += int64(n)
+
+	return n, err
+}
+
+func (f *file) Borrow(fn func(data []byte)) error {
+	if err := f.validTo(opRead, false); err != nil {
+		return err
+	}
+
+	fn(f.data)
+
+	return nil
+}
+
 func (f *file) WriteTo(w io.Writer) (int64, error) {
 	if err := f.validTo(opRead, true); err != nil {
 		return 0, err
@@ -1,9 +1,11 @@
 package memfs
 
 import (
+	"bytes"
 	"errors"
 	"io"
 	"io/fs"
+	"math"
 	"sync"
 	"time"
 	"unicode/utf8"
@@ -18,10 +20,12 @@ func (i *inodeRW) open(name string, mode opMode) (fs.File, error) {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
-	if mode&opRead > 0 && i.mode&modeRead == 0 || mode&opWrite > 0 && i.mode&modeWrite == 0 {
+	if mode&opRead > 0 && i.inode.perm()&modeRead == 0 || mode&opWrite > 0 && (i.inode.perm()&modeWrite == 0 || i.sealed) {
 		return nil, fs.ErrPermission
 	}
 
+	i.opens++
+
 	return &File{
 		mu: &i.mu,
 		file: file{
@@ -46,28 +50,49 @@ func (i *inodeRW) string() (string, error) {
 	return i.inode.string()
 }
 
-func (i *inodeRW) setMode(mode fs.FileMode) {
+func (i *inodeRW) appendData(p []byte) ([]byte, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.inode.appendData(p)
+}
+
+func (i *inodeRW) setMode(mode fs.FileMode) error {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
-	i.mode = i.mode&fs.ModeSymlink | mode
+	if err := i.inode.setMode(mode); err != nil {
+		return err
+	}
+
+	i.parents.changed(i.gen)
+
+	return nil
 }
 
-func (i *inodeRW) setTimes(_, mtime time.Time) {
+func (i *inodeRW) setTimes(atime, mtime time.Time) error {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
-	i.modtime = mtime
+	if err := i.inode.setTimes(atime, mtime); err != nil {
+		return err
+	}
+
+	i.parents.changed(i.gen)
+
+	return nil
 }
 
 func (i *inodeRW) seal() directoryEntry {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
-	de := i.inode
-	i.inode = inode{}
+	if !i.sealed {
+		i.sealed = true
+		i.etag = new(etagOnce)
+	}
 
-	return &de
+	return &i.inode
 }
 
 func (i *inodeRW) Size() int64 {
@@ -98,34 +123,204 @@ func (i *inodeRW) ModTime() time.Time {
 	return i.modtime
 }
 
+func (i *inodeRW) Links() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.links
+}
+
+// link records the addition of a name for the file.
+func (i *inodeRW) link() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.links++
+}
+
+// unlink records the removal of a name for the file, releasing its data if it
+// was the last name and the file is not held open.
+func (i *inodeRW) unlink(alloc Allocator) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.links--
+
+	i.release(alloc)
+}
+
+// orphaned reports whether the file has no names, but is still held open.
+func (i *inodeRW) orphaned() bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.links == 0 && i.opens > 0
+}
+
+// unlinkAll records the removal of the names of all of the files at and below
+// the given entry.
+func unlinkAll(de directoryEntry, alloc Allocator) {
+	switch de := de.(type) {
+	case *inodeRW:
+		de.unlink(alloc)
+	case *lazyNode:
+		de.unlink()
+	case *dnodeRW:
+		for _, e := range de.view().entries {
+			unlinkAll(e.directoryEntry, alloc)
+		}
+	}
+}
+
+// newFileEntry allocates a directory entry together with its inode.
+func newFileEntry(name string, i inode) *dirEnt {
+	e := &struct {
+		dirEnt
+		inodeRW
+	}{
+		dirEnt: dirEnt{
+			name: name,
+		},
+		inodeRW: inodeRW{
+			inode: i,
+		},
+	}
+
+	e.directoryEntry = &e.inodeRW
+	e.ctime = e.modtime
+	e.links = 1
+
+	return &e.dirEnt
+}
+
 // File represents an open file, that can be used for reading and writing
 // (depending on how it was opened).
 //
 // The file locks when making any changes, and so can be safely used from
 // multiple goroutines.
+//
+// Once closed, every method, other than Stat, returns fs.ErrClosed. Otherwise,
+// a method that requires the file to have been opened for reading, or for
+// writing, returns ErrNotOpenForRead, or ErrNotOpenForWrite, when it was not,
+// and a write to a file that has since been sealed returns fs.ErrPermission.
 type File struct {
 	mu *sync.RWMutex
 	file
+	growth   GrowthFunc
+	alloc    Allocator
+	limit    *writeLimit
+	throttle *throttle
+	accounts accounts
+	handles  *openLimit
+	orphans  *orphans
+	clock    *deterministic
+	journal  *journal
+	objects  *objectStore
+	tracked  *openPath
+	lazy     *lazyNode
+}
+
+// Stat returns the fs.FileInfo of the file, which reports the current size,
+// mode, and modification time of the file each time they are requested,
+// including any changes made through other handles.
+func (f *File) Stat() (fs.FileInfo, error) {
+	return fileInfo{f}, nil
+}
+
+type fileInfo struct {
+	f *File
+}
+
+func (fi fileInfo) Name() string {
+	return fi.f.name
+}
+
+func (fi fileInfo) Size() int64 {
+	fi.f.mu.RLock()
+	defer fi.f.mu.RUnlock()
+
+	if fi.f.lazy != nil {
+		return fi.f.lazy.size
+	}
+
+	return fi.f.Size()
+}
+
+func (fi fileInfo) Mode() fs.FileMode {
+	fi.f.mu.RLock()
+	defer fi.f.mu.RUnlock()
+
+	return fi.f.Mode()
+}
+
+func (fi fileInfo) ModTime() time.Time {
+	fi.f.mu.RLock()
+	defer fi.f.mu.RUnlock()
+
+	return fi.f.ModTime()
 }
 
-func (f *File) Read(p []byte) (int, error) {
+func (fi fileInfo) IsDir() bool {
+	return false
+}
+
+func (fi fileInfo) Sys() any {
+	return fi.f
+}
+
+func (fi fileInfo) String() string {
+	return fs.FormatFileInfo(fi)
+}
+
+func (f *File) Read(p []byte) (n int, err error) {
+	defer f.throttle.read(&n)
+	defer f.accounts.read(&n)
+
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	if f.lazy != nil {
+		return f.lazyRead(p)
+	}
+
 	return f.file.Read(p)
 }
 
-func (f *File) ReadAt(p []byte, off int64) (int, error) {
+// ReadAt reads into the given buffer from the given offset, without changing
+// the current position.
+//
+// A read that reaches the end of the file returns the number of bytes read
+// along with io.EOF, as does a read from an offset at or beyond the end of the
+// file, such as after the file has been truncated by another handle, with no
+// bytes read. A read that fills the buffer returns a nil error, and a negative
+// offset returns fs.ErrInvalid.
+func (f *File) ReadAt(p []byte, off int64) (n int, err error) {
+	defer f.throttle.read(&n)
+	defer f.accounts.read(&n)
+
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
+	if f.lazy != nil {
+		return f.lazyReadAt(p, off)
+	}
+
 	return f.file.ReadAt(p, off)
 }
 
 func (f *File) ReadByte() (byte, error) {
+	n := 1
+
+	defer f.throttle.read(&n)
+	defer f.accounts.read(&n)
+
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	if f.lazy != nil {
+		return f.lazyReadByte()
+	}
+
 	return f.file.ReadByte()
 }
 
@@ -136,10 +331,17 @@ func (f *File) UnreadByte() error {
 	return f.file.UnreadByte()
 }
 
-func (f *File) ReadRune() (rune, int, error) {
+func (f *File) ReadRune() (r rune, size int, err error) {
+	defer f.throttle.read(&size)
+	defer f.accounts.read(&size)
+
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	if f.lazy != nil {
+		return f.lazyReadRune()
+	}
+
 	return f.file.ReadRune()
 }
 
@@ -150,27 +352,159 @@ func (f *File) UnreadRune() error {
 	return f.file.UnreadRune()
 }
 
-func (f *File) WriteTo(w io.Writer) (int64, error) {
+// ReadBytes reads until the first instance of the given delimiter, returning
+// a copy of the data read, including the delimiter, as with
+// bufio.Reader.ReadBytes.
+//
+// If there is no delimiter before the end of the file, the remaining data is
+// returned along with io.EOF.
+func (f *File) ReadBytes(delim byte) (data []byte, err error) {
+	defer func() {
+		n := len(data)
+
+		f.throttle.read(&n)
+		f.accounts.read(&n)
+	}()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.lazy != nil {
+		return f.lazyReadDelim(delim)
+	}
+
+	return f.file.ReadBytes(delim)
+}
+
+// ReadString reads until the first instance of the given delimiter, returning
+// a string containing the data read, including the delimiter, as with
+// bufio.Reader.ReadString.
+//
+// If there is no delimiter before the end of the file, the remaining data is
+// returned along with io.EOF.
+func (f *File) ReadString(delim byte) (str string, err error) {
+	defer func() {
+		n := len(str)
+
+		f.throttle.read(&n)
+		f.accounts.read(&n)
+	}()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.lazy != nil {
+		data, err := f.lazyReadDelim(delim)
+
+		return string(data), err
+	}
+
+	return f.file.ReadString(delim)
+}
+
+// Peek returns a copy of the next n bytes of the file without advancing the
+// position, as with bufio.Reader.Peek.
+//
+// If fewer than n bytes remain, those bytes are returned along with io.EOF. A
+// call to Peek prevents a following call to UnreadByte or UnreadRune from
+// succeeding until the next read.
+func (f *File) Peek(n int) (data []byte, err error) {
+	defer func() {
+		n := len(data)
+
+		f.throttle.read(&n)
+		f.accounts.read(&n)
+	}()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.lazy != nil {
+		return f.lazyPeek(n)
+	}
+
+	data, err = f.file.Peek(n)
+
+	return bytes.Clone(data), err
+}
+
+// Discard skips the next n bytes of the file, returning the number of bytes
+// skipped, as with bufio.Reader.Discard.
+//
+// If fewer than n bytes remain, the position is moved to the end of the file,
+// and io.EOF is returned.
+func (f *File) Discard(n int) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.lazy != nil {
+		return f.lazyDiscard(n)
+	}
+
+	return f.file.Discard(n)
+}
+
+// Borrow calls the given function with the entire contents of the file,
+// without copying, holding a read lock on the file for the duration of the
+// call. The position of the file is not used, nor changed.
+//
+// The data must not be modified, nor retained after the function returns, and
+// the function must not write to the file, through any handle, nor call any
+// method of this File, which would deadlock.
+func (f *File) Borrow(fn func(data []byte)) error {
+	var n int
+
+	defer f.throttle.read(&n)
+	defer f.accounts.read(&n)
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.lazy != nil {
+		return f.lazyBorrow(func(data []byte) {
+			n = len(data)
+
+			fn(data)
+		})
+	}
+
+	return f.file.Borrow(func(data []byte) {
+		n = len(data)
+
+		fn(data)
+	})
+}
+
+func (f *File) WriteTo(w io.Writer) (n int64, err error) {
+	defer f.throttle.read64(&n)
+	defer f.accounts.read64(&n)
+
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	if err := f.validTo(opRead, true); err != nil {
+	if f.lazy != nil {
+		return f.lazyWriteTo(w)
+	} else if err := f.validTo(opRead, true); err != nil {
 		return 0, err
 	}
 
 	data := f.data[f.pos:]
 
-	n, err := w.Write(append(make([]byte, 0, len(data)), data...))
-	f.pos += int64(n)
+	written, err := w.Write(append(make([]byte, 0, len(data)), data...))
+	f.pos += int64(written)
 	f.lastRead = 0
 
-	return int64(n), err
+	return int64(written), err
 }
 
 func (f *File) Seek(offset int64, whence int) (int64, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	if f.lazy != nil {
+		return f.lazySeek(offset, whence)
+	}
+
 	return f.file.Seek(offset, whence)
 }
 
@@ -178,137 +512,440 @@ func (f *File) Close() error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	return f.file.Close()
+	writable := f.opMode&opWrite != 0
+
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+
+	f.handles.release()
+
+	defer f.unref()
+
+	if p, ok := f.tracked.close(); writable && ok && f.objects != nil {
+		data := f.data
+
+		if f.lazy != nil {
+			var err error
+
+			if data, err = f.lazy.lockedBytes(); err != nil {
+				return &fs.PathError{Op: "close", Path: f.name, Err: err}
+			}
+		}
+
+		if err := f.objects.Put(f.objects.prefix+p, data); err != nil {
+			return &fs.PathError{Op: "close", Path: f.name, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// unref records the closing of the File, releasing the data of the file if it
+// has no remaining names and is not held open elsewhere.
+func (f *File) unref() {
+	if f.opens > 0 {
+		f.opens--
+
+		if f.lazy != nil {
+			f.lazy.release()
+		} else {
+			f.release(f.alloc)
+		}
+
+		f.orphans.remove(f.inode)
+	}
+}
+
+// unshare gives the file its own copy of its data when the data is shared with
+// another file, such as that of the base of a Layer, so that it can be
+// modified.
+func (f *File) unshare() {
+	if !f.shared {
+		return
+	}
+
+	data := f.data
+
+	if f.alloc == nil {
+		f.data = make([]byte, len(data))
+	} else {
+		f.data = f.alloc.Alloc(len(data), len(data))
+	}
+
+	copy(f.data, data)
+
+	f.shared = false
+}
+
+// maxFileSize is the largest size to which a file can be grown, being the
+// largest allocation that can be made by the Go runtime.
+const maxFileSize = math.MaxInt & (1<<48 - 1)
+
+// checkSize returns ErrFileTooLarge when writing n bytes at the given offset
+// would grow the file beyond maxFileSize.
+func checkSize(off int64, n int) error {
+	if off > maxFileSize-int64(n) {
+		return ErrFileTooLarge
+	}
+
+	return nil
 }
 
 func (f *File) grow(size int) {
+	f.unshare()
+
 	if size > len(f.data) {
-		if size < cap(f.data) {
-			f.data = (f.data)[:size]
+		if size <= cap(f.data) {
+			grown := f.data[len(f.data):size]
+
+			for n := range grown {
+				grown[n] = 0
+			}
+
+			f.data = f.data[:size]
 		} else {
-			var newData []byte
+			growth := f.growth
+			if growth == nil {
+				growth = DefaultGrowth
+			}
+
+			f.resize(size, growth(len(f.data), size))
+		}
+	}
+}
+
+func (f *File) resize(size, capacity int) {
+	if capacity < size {
+		capacity = size
+	}
 
-			const simpleGrowLimit = 512
+	if f.alloc == nil {
+		newData := make([]byte, size, capacity)
 
-			if len(f.data) < simpleGrowLimit {
-				newData = make([]byte, size, size<<1)
-			} else {
-				const growShift = 2
+		copy(newData, f.data)
+		f.data = newData
 
-				newData = make([]byte, size, size+(size>>growShift))
+		return
+	}
+
+	newData := f.alloc.Alloc(size, capacity)
+	oldData := f.data
+
+	copy(newData, oldData)
+	f.data = newData
+
+	if cap(oldData) > 0 {
+		f.alloc.Free(oldData)
+	}
+}
+
+// Preallocate ensures that the capacity of the underlying data of the file is
+// at least the given size, so that writing up to that size will not require
+// the data to be copied.
+//
+// The size of the file is not changed, and a size larger than can be allocated
+// returns ErrFileTooLarge.
+func (f *File) Preallocate(size int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.validTo(opWrite, false); err != nil {
+		return err
+	} else if size < 0 {
+		return fs.ErrInvalid
+	} else if f.lazy != nil {
+		return nil
+	} else if err := checkSize(size, 0); err != nil {
+		return err
+	}
+
+	if int(size) > cap(f.data) {
+		f.unshare()
+		f.resize(len(f.data), int(size))
+	}
+
+	return nil
+}
+
+// Truncate changes the size of the file, either discarding data beyond the
+// given size or extending the file with zeros.
+//
+// The position of the file is not changed, and a size larger than can be
+// allocated returns ErrFileTooLarge.
+func (f *File) Truncate(size int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.validTo(opWrite, false); err != nil {
+		return err
+	} else if size < 0 {
+		return fs.ErrInvalid
+	} else if f.lazy != nil {
+		return f.lazyTruncate(size)
+	} else if err := checkSize(size, 0); err != nil {
+		return err
+	}
+
+	if int(size) < len(f.data) {
+		if err := f.limit.op(); err != nil {
+			return err
+		}
+
+		if f.shared {
+			f.data = f.data[:size:size]
+		} else {
+			removed := f.data[size:]
+
+			for n := range removed {
+				removed[n] = 0
 			}
 
-			copy(newData, f.data)
-			f.data = newData
+			f.data = f.data[:size]
 		}
+	} else if _, err := f.limit.write(int(size) - len(f.data)); err != nil {
+		return err
+	} else {
+		f.grow(int(size))
+	}
+
+	f.record(Event{Op: "truncate", Offset: size})
+
+	f.modified()
+
+	return nil
+}
+
+// record writes the given Event to the journal, if there is one, with the path
+// at which the file is currently found, unless that path has been removed.
+func (f *File) record(e Event) {
+	if f.journal == nil {
+		return
+	}
+
+	if p, ok := f.tracked.current(); ok {
+		e.Path = p
+
+		f.journal.record(e)
+	}
+}
+
+func (f *File) recordWrite(off int64, n int) {
+	if f.journal != nil && n > 0 {
+		f.record(Event{Op: "write", Offset: off, Data: f.data[off : off+int64(n)]})
 	}
 }
 
-func (f *File) Write(p []byte) (int, error) {
+func (f *File) Write(p []byte) (n int, err error) {
+	defer f.throttle.write(&n)
+	defer f.accounts.write(&n)
+
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
 	if err := f.validTo(opWrite, false); err != nil {
 		return 0, err
+	} else if f.lazy != nil {
+		return f.lazyWrite(p)
+	}
+
+	f.seekAppend()
+
+	if err := checkSize(f.pos, len(p)); err != nil {
+		return 0, err
 	}
 
+	n, err = f.limit.write(len(p))
+	p = p[:n]
+
 	f.grow(int(f.pos) + len(p))
 
-	n := copy(f.data[f.pos:], p)
+	n = copy(f.data[f.pos:], p)
+	f.recordWrite(f.pos, n)
 	f.pos += int64(n)
 	f.lastRead = 0
-	f.modtime = time.Now()
+	f.modified()
 
-	return n, nil
+	return n, err
 }
 
-func (f *File) WriteAt(p []byte, off int64) (int, error) {
+// WriteAt writes the given bytes at the given offset, growing the file as
+// needed, without changing the current position.
+//
+// Writing at an offset beyond the end of the file fills the gap between the
+// old end of the file and the offset with zeros. The modification time of the
+// file is updated by every write, and, as all handles to a file share its
+// data, the new size is reported immediately by Stat on every open handle.
+//
+// The offset is used even when the file was opened with Append, and a
+// negative offset returns fs.ErrInvalid.
+func (f *File) WriteAt(p []byte, off int64) (n int, err error) {
+	defer f.throttle.write(&n)
+	defer f.accounts.write(&n)
+
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
 	if err := f.validTo(opWrite|opSeek, false); err != nil {
 		return 0, err
+	} else if off < 0 {
+		return 0, fs.ErrInvalid
+	} else if f.lazy != nil {
+		return f.lazyWriteAt(p, off)
+	} else if err := checkSize(off, len(p)); err != nil {
+		return 0, err
 	}
 
+	n, err = f.limit.write(len(p))
+	p = p[:n]
+
 	f.grow(int(off) + len(p))
 
-	n := copy(f.data[off:], p)
-	f.modtime = time.Now()
+	n = copy(f.data[off:], p)
+	f.recordWrite(off, n)
+	f.modified()
 
-	return n, nil
+	return n, err
 }
 
-func (f *File) WriteString(str string) (int, error) {
+func (f *File) WriteString(str string) (n int, err error) {
+	defer f.throttle.write(&n)
+	defer f.accounts.write(&n)
+
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
 	if err := f.validTo(opWrite, false); err != nil {
 		return 0, err
+	} else if f.lazy != nil {
+		return f.lazyWrite([]byte(str))
 	}
 
+	f.seekAppend()
+
+	if err := checkSize(f.pos, len(str)); err != nil {
+		return 0, err
+	}
+
+	n, err = f.limit.write(len(str))
+	str = str[:n]
+
 	f.grow(int(f.pos) + len(str))
 
-	n := copy(f.data[f.pos:], str)
+	n = copy(f.data[f.pos:], str)
+	f.recordWrite(f.pos, n)
 	f.pos += int64(n)
 	f.lastRead = 0
-	f.modtime = time.Now()
+	f.modified()
 
-	return n, nil
+	return n, err
 }
 
 func (f *File) WriteByte(c byte) error {
+	n := 1
+
+	defer f.throttle.write(&n)
+	defer f.accounts.write(&n)
+
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
 	if err := f.validTo(opWrite, false); err != nil {
 		return err
+	} else if f.lazy != nil {
+		_, err := f.lazyWrite([]byte{c})
+
+		return err
+	}
+
+	f.seekAppend()
+
+	if err := checkSize(f.pos, 1); err != nil {
+		return err
+	} else if _, err := f.limit.write(1); err != nil {
+		return err
 	}
 
 	f.grow(int(f.pos) + 1)
 
 	f.data[f.pos] = c
+	f.recordWrite(f.pos, 1)
 	f.pos++
 	f.lastRead = 0
-	f.modtime = time.Now()
+	f.modified()
 
 	return nil
 }
 
-func (f *File) WriteRune(r rune) (int, error) {
+func (f *File) WriteRune(r rune) (n int, err error) {
+	defer f.throttle.write(&n)
+	defer f.accounts.write(&n)
+
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
 	if err := f.validTo(opWrite, false); err != nil {
 		return 0, err
+	} else if f.lazy != nil {
+		return f.lazyWrite(utf8.AppendRune([]byte{}, r))
 	}
 
+	f.seekAppend()
+
 	p := utf8.AppendRune([]byte{}, r)
 
+	if err := checkSize(f.pos, len(p)); err != nil {
+		return 0, err
+	} else if _, err := f.limit.write(len(p)); err != nil {
+		return 0, err
+	}
+
 	f.grow(int(f.pos) + len(p))
 
-	n := copy(f.data[f.pos:], p)
+	n = copy(f.data[f.pos:], p)
+	f.recordWrite(f.pos, n)
 	f.pos += int64(n)
 	f.lastRead = 0
-	f.modtime = time.Now()
+	f.modified()
 
 	return n, nil
 }
 
-func (f *File) ReadFrom(r io.Reader) (int64, error) {
+func (f *File) ReadFrom(r io.Reader) (count int64, err error) {
+	defer f.throttle.write64(&count)
+	defer f.accounts.write64(&count)
+
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
 	if err := f.validTo(opWrite, false); err != nil {
 		return 0, err
+	} else if err := f.limit.op(); err != nil {
+		return 0, err
+	} else if f.lazy != nil {
+		return f.lazyReadFrom(r)
 	}
 
-	var count int64
+	f.seekAppend()
 
 	for {
+		if err := checkSize(f.pos, 1); err != nil {
+			return count, err
+		}
+
 		f.grow(int(f.pos + 1))
 
 		n, err := r.Read(f.data[f.pos:cap(f.data)])
 
+		if m, lerr := f.limit.charge(0, n); lerr != nil {
+			n, err = m, lerr
+		}
+
+		if n > 0 {
+			f.recordWrite(f.pos, n)
+			f.modified()
+		}
+
 		count += int64(n)
 		f.pos += int64(n)
 		f.data = f.data[:f.pos]
@@ -323,10 +960,53 @@ func (f *File) ReadFrom(r io.Reader) (int64, error) {
 	}
 }
 
+// seekAppend moves to the end of the file when it was opened with Append, so
+// that the next write is appended to any data written by other handles.
+func (f *File) seekAppend() {
+	if f.opMode&opAppend != 0 {
+		f.pos = int64(len(f.data))
+	}
+}
+
+// modified marks the file as having been changed.
+func (f *File) modified() {
+	f.modtime = f.clock.now()
+	f.ctime = f.modtime
+	f.gen = nextGeneration()
+
+	f.parents.changed(f.gen)
+}
+
 func (f *File) handleOpenMode(mode Mode) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.lazy != nil {
+		if mode&Truncate != 0 {
+			f.lazy.truncate(0)
+			f.modified()
+		}
+
+		if mode&Append != 0 {
+			f.pos = f.lazy.size
+		}
+
+		return
+	}
+
 	if mode&Truncate != 0 {
-		f.data = f.data[:0]
-		f.modtime = time.Now()
+		if f.shared {
+			f.data = nil
+			f.shared = false
+		} else {
+			for n := range f.data {
+				f.data[n] = 0
+			}
+
+			f.data = f.data[:0]
+		}
+
+		f.modified()
 	}
 
 	if mode&Append != 0 {
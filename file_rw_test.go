@@ -9,6 +9,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 var _ interface {
@@ -44,7 +45,7 @@ func TestReadRW(t *testing.T) {
 		},
 		{
 			Mode: opSeek,
-			Err:  fs.ErrInvalid,
+			Err:  ErrNotOpenForRead,
 		},
 	} {
 		f := File{
@@ -97,7 +98,7 @@ func TestReadAtRW(t *testing.T) {
 			Output: [][]byte{
 				[]byte("H"),
 			},
-			Err: fs.ErrInvalid,
+			Err: ErrNotOpenForRead,
 		},
 		{
 			Mode: opRead | opSeek,
@@ -573,6 +574,76 @@ func TestUnreadRuneRW(t *testing.T) {
 	}
 }
 
+func TestReadBytesRW(t *testing.T) {
+	f := File{
+		mu: &sync.RWMutex{},
+		file: file{
+			inode: &inode{
+				data: []byte("abc\ndef"),
+			},
+			opMode: opRead | opSeek,
+		},
+	}
+
+	if data, err := f.ReadBytes('\n'); err != nil {
+		t.Errorf("test 1: unexpected error: %s", err)
+	} else if string(data) != "abc\n" {
+		t.Errorf("test 1: expecting to read %q, got %q", "abc\n", data)
+	} else if data[0] = 'X'; f.data[0] != 'a' {
+		t.Errorf("test 2: expecting returned data to be a copy")
+	} else if pos, err := f.Seek(0, io.SeekCurrent); err != nil {
+		t.Errorf("test 3: unexpected error: %s", err)
+	} else if pos != 4 {
+		t.Errorf("test 3: expecting position %d, got %d", 4, pos)
+	} else if str, err := f.ReadString('\n'); !errors.Is(err, io.EOF) {
+		t.Errorf("test 4: expecting error %s, got %s", io.EOF, err)
+	} else if str != "def" {
+		t.Errorf("test 4: expecting to read %q, got %q", "def", str)
+	} else if data, err := f.ReadBytes('\n'); !errors.Is(err, io.EOF) {
+		t.Errorf("test 5: expecting error %s, got %s", io.EOF, err)
+	} else if data != nil {
+		t.Errorf("test 5: expecting no data, got %q", data)
+	} else if _, err := f.Seek(-3, io.SeekEnd); err != nil {
+		t.Errorf("test 6: unexpected error: %s", err)
+	} else if _, err := f.ReadString('e'); err != nil {
+		t.Errorf("test 6: unexpected error: %s", err)
+	} else if err := f.UnreadByte(); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("test 7: expecting error %s, got %s", fs.ErrInvalid, err)
+	}
+}
+
+func TestPeekRW(t *testing.T) {
+	f := File{
+		mu: &sync.RWMutex{},
+		file: file{
+			inode: &inode{
+				data: []byte("abcdef"),
+			},
+			opMode: opRead,
+		},
+	}
+
+	if _, err := f.ReadByte(); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if data, err := f.Peek(2); err != nil {
+		t.Errorf("test 1: unexpected error: %s", err)
+	} else if string(data) != "bc" {
+		t.Errorf("test 1: expecting to peek %q, got %q", "bc", data)
+	} else if data[0] = 'X'; f.data[1] != 'b' {
+		t.Errorf("test 2: expecting peeked data to be a copy")
+	} else if err := f.UnreadByte(); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("test 3: expecting error %v, got %v", fs.ErrInvalid, err)
+	} else if m, err := f.Discard(3); err != nil {
+		t.Errorf("test 4: unexpected error: %s", err)
+	} else if m != 3 {
+		t.Errorf("test 4: expecting to discard %d bytes, got %d", 3, m)
+	} else if str, err := f.ReadString(0); !errors.Is(err, io.EOF) {
+		t.Errorf("test 5: expecting error %v, got %v", io.EOF, err)
+	} else if str != "ef" {
+		t.Errorf("test 5: expecting to read %q, got %q", "ef", str)
+	}
+}
+
 func TestWriteToRW(t *testing.T) {
 	f := File{
 		mu: &sync.RWMutex{},
@@ -1027,3 +1098,653 @@ func TestReadFrom(t *testing.T) {
 		t.Errorf("File data does not match buf data")
 	}
 }
+
+func TestPreallocate(t *testing.T) {
+	f := File{
+		mu: &sync.RWMutex{},
+		file: file{
+			inode: &inode{
+				data: []byte("Hello"),
+			},
+		},
+	}
+
+	if err := f.Preallocate(100); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("test 1: expecting ErrClosed, got %s", err)
+	}
+
+	f.opMode = opWrite
+
+	for n, test := range [...]struct {
+		Size, Cap int64
+		Err       error
+	}{
+		{
+			Size: -1,
+			Cap:  5,
+			Err:  fs.ErrInvalid,
+		},
+		{
+			Size: 2,
+			Cap:  5,
+		},
+		{
+			Size: 100,
+			Cap:  100,
+		},
+		{
+			Size: 50,
+			Cap:  100,
+		},
+		{
+			Size: 1 << 50,
+			Cap:  100,
+			Err:  ErrFileTooLarge,
+		},
+	} {
+		if err := f.Preallocate(test.Size); !errors.Is(err, test.Err) {
+			t.Errorf("test %d: expecting error %s, got %s", n+2, test.Err, err)
+		} else if c := int64(cap(f.data)); c != test.Cap {
+			t.Errorf("test %d: expecting capacity %d, got %d", n+2, test.Cap, c)
+		} else if string(f.data) != "Hello" {
+			t.Errorf("test %d: expecting data %q, got %q", n+2, "Hello", f.data)
+		}
+	}
+
+	data := f.data
+
+	if _, err := f.Write(bytes.Repeat([]byte{'!'}, 95)); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	} else if &data[:1][0] != &f.data[0] {
+		t.Errorf("expecting write to not reallocate")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	f := File{
+		mu: &sync.RWMutex{},
+		file: file{
+			inode: &inode{
+				data: []byte("Hello, World"),
+			},
+			opMode: opRead,
+		},
+	}
+
+	if err := f.Truncate(1); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("test 1: expecting ErrInvalid, got %s", err)
+	}
+
+	f.opMode = opWrite | opSeek
+
+	for n, test := range [...]struct {
+		Size int64
+		Data []byte
+		Err  error
+	}{
+		{
+			Size: -1,
+			Data: []byte("Hello, World"),
+			Err:  fs.ErrInvalid,
+		},
+		{
+			Size: 5,
+			Data: []byte("Hello"),
+		},
+		{
+			Size: 8,
+			Data: []byte("Hello\000\000\000"),
+		},
+		{
+			Size: 0,
+			Data: []byte{},
+		},
+		{
+			Size: 12,
+			Data: make([]byte, 12),
+		},
+		{
+			Size: 1 << 50,
+			Data: make([]byte, 12),
+			Err:  ErrFileTooLarge,
+		},
+	} {
+		if err := f.Truncate(test.Size); !errors.Is(err, test.Err) {
+			t.Errorf("test %d: expecting error %s, got %s", n+2, test.Err, err)
+		} else if !bytes.Equal(f.data, test.Data) {
+			t.Errorf("test %d: expecting data %q, got %q", n+2, test.Data, f.data)
+		}
+	}
+
+	if _, err := f.WriteAt([]byte("!"), 1<<50); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("test 8: expecting ErrFileTooLarge, got %s", err)
+	} else if _, err := f.Seek(1<<50, io.SeekStart); err != nil {
+		t.Errorf("test 9: unexpected error: %s", err)
+	} else if _, err := f.Write([]byte("!")); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("test 9: expecting ErrFileTooLarge, got %s", err)
+	} else if len(f.data) != 12 {
+		t.Errorf("test 10: expecting size 12, got %d", len(f.data))
+	}
+}
+
+func TestGrowth(t *testing.T) {
+	for n, test := range [...]struct {
+		Growth GrowthFunc
+		Data   []byte
+		Write  int
+		Cap    int
+	}{
+		{
+			Write: 10,
+			Cap:   20,
+		},
+		{
+			Data:  make([]byte, 1024),
+			Write: 1024,
+			Cap:   2560,
+		},
+		{
+			Growth: func(_, required int) int { return required },
+			Write:  10,
+			Cap:    10,
+		},
+		{
+			Growth: func(_, _ int) int { return 0 },
+			Write:  10,
+			Cap:    10,
+		},
+		{
+			Growth: func(_, required int) int { return required * 10 },
+			Write:  10,
+			Cap:    100,
+		},
+	} {
+		f := File{
+			mu: &sync.RWMutex{},
+			file: file{
+				inode: &inode{
+					data: test.Data,
+				},
+				opMode: opWrite | opSeek,
+				pos:    int64(len(test.Data)),
+			},
+			growth: test.Growth,
+		}
+
+		if _, err := f.Write(make([]byte, test.Write)); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if c := cap(f.data); c != test.Cap {
+			t.Errorf("test %d: expecting capacity %d, got %d", n+1, test.Cap, c)
+		}
+	}
+}
+
+func TestAppend(t *testing.T) {
+	f := New()
+
+	if err := f.WriteFile("file", []byte("start"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	a, err := f.OpenFile("file", WriteOnly|Append, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	b, err := f.OpenFile("file", ReadWrite|Append, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := a.Write([]byte("A")); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if _, err := b.WriteString("B"); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if err := a.WriteByte('C'); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if _, err := b.WriteRune('D'); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if _, err := a.WriteAt([]byte("S"), 0); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if _, err := b.ReadFrom(strings.NewReader("E")); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if _, err := a.WriteAt([]byte("xyz"), 11); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if _, err := a.Write([]byte("F")); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if data, err := f.ReadFile("file"); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if expected := "StartABCDE\x00xyzF"; string(data) != expected {
+		t.Errorf("test 1: expecting contents %q, got %q", expected, data)
+	}
+
+	buf := make([]byte, 5)
+
+	if _, err := b.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if _, err := io.ReadFull(b, buf); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if string(buf) != "Start" {
+		t.Errorf("test 2: expecting to read %q, got %q", "Start", buf)
+	} else if _, err := b.Write([]byte("G")); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if data, _ := f.ReadFile("file"); string(data) != "StartABCDE\x00xyzFG" {
+		t.Errorf("test 2: expecting write after read to append, got %q", data)
+	}
+}
+
+func TestAppendConcurrent(t *testing.T) {
+	const (
+		writers = 8
+		writes  = 100
+	)
+
+	f := New()
+
+	if err := f.WriteFile("file", []byte("header"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var wg sync.WaitGroup
+
+	for n := 0; n < writers; n++ {
+		of, err := f.OpenFile("file", WriteOnly|Append, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		wg.Add(1)
+
+		go func(n int) {
+			defer wg.Done()
+
+			for i := 0; i < writes; i++ {
+				of.Write([]byte{'0' + byte(n), '\n'})
+			}
+		}(n)
+	}
+
+	of, err := f.OpenFile("file", WriteOnly|Append, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < writes; i++ {
+			of.WriteAt([]byte("HEADER"), 0)
+		}
+	}()
+
+	wg.Wait()
+
+	data, err := f.ReadFile("file")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if !bytes.HasPrefix(data, []byte("HEADER")) {
+		t.Errorf("test 1: expecting WriteAt to overwrite header, got %q", data[:6])
+	} else if len(data) != 6+writers*writes*2 {
+		t.Errorf("test 2: expecting length %d, got %d", 6+writers*writes*2, len(data))
+	}
+
+	for n := 0; n < writers; n++ {
+		if count := bytes.Count(data, []byte{'0' + byte(n), '\n'}); count != writes {
+			t.Errorf("test 3: expecting %d writes from writer %d, got %d", writes, n, count)
+		}
+	}
+}
+
+func TestReadAtEOF(t *testing.T) {
+	f := New()
+
+	if err := f.WriteFile("file", []byte("Hello"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	reader, err := f.OpenFile("file", ReadOnly, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	writer, err := f.OpenFile("file", WriteOnly, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Truncate int64
+		Length   int
+		Offset   int64
+		Output   string
+		Err      error
+	}{
+		{ // 1
+			Truncate: -1,
+			Length:   5,
+			Output:   "Hello",
+		},
+		{ // 2
+			Truncate: -1,
+			Length:   6,
+			Output:   "Hello",
+			Err:      io.EOF,
+		},
+		{ // 3
+			Truncate: -1,
+			Length:   3,
+			Offset:   3,
+			Output:   "lo",
+			Err:      io.EOF,
+		},
+		{ // 4
+			Truncate: -1,
+			Length:   1,
+			Offset:   5,
+			Err:      io.EOF,
+		},
+		{ // 5
+			Truncate: -1,
+			Length:   1,
+			Offset:   10,
+			Err:      io.EOF,
+		},
+		{ // 6
+			Truncate: -1,
+			Offset:   2,
+		},
+		{ // 7
+			Truncate: -1,
+			Length:   1,
+			Offset:   -1,
+			Err:      fs.ErrInvalid,
+		},
+		{ // 8
+			Truncate: 2,
+			Length:   3,
+			Offset:   3,
+			Err:      io.EOF,
+		},
+		{ // 9
+			Truncate: 3,
+			Length:   3,
+			Offset:   1,
+			Output:   "e\x00",
+			Err:      io.EOF,
+		},
+		{ // 10
+			Truncate: 6,
+			Length:   4,
+			Offset:   2,
+			Output:   "\x00\x00\x00\x00",
+		},
+	} {
+		if test.Truncate >= 0 {
+			if err := writer.Truncate(test.Truncate); err != nil {
+				t.Fatalf("test %d: unexpected error: %s", n+1, err)
+			}
+		}
+
+		buf := make([]byte, test.Length)
+
+		m, err := reader.ReadAt(buf, test.Offset)
+		if !errors.Is(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if got := string(buf[:m]); got != test.Output {
+			t.Errorf("test %d: expecting to read %q, got %q", n+1, test.Output, got)
+		}
+	}
+}
+
+func TestBorrow(t *testing.T) {
+	f := New()
+
+	if err := f.WriteFile("file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	of, err := f.OpenFile("file", ReadWrite, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := of.Seek(2, io.SeekStart); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var borrowed []byte
+
+	if err := of.Borrow(func(data []byte) {
+		borrowed = data
+	}); err != nil {
+		t.Errorf("test 1: unexpected error: %s", err)
+	} else if string(borrowed) != "data" {
+		t.Errorf("test 1: expecting to borrow %q, got %q", "data", borrowed)
+	} else if &borrowed[0] != &of.data[0] {
+		t.Errorf("test 2: expecting borrowed data not to be copied")
+	} else if pos, err := of.Seek(0, io.SeekCurrent); err != nil {
+		t.Errorf("test 3: unexpected error: %s", err)
+	} else if pos != 2 {
+		t.Errorf("test 3: expecting position %d, got %d", 2, pos)
+	}
+
+	wo, err := f.OpenFile("file", WriteOnly, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := wo.Borrow(func([]byte) {}); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("test 4: expecting error %v, got %v", fs.ErrInvalid, err)
+	} else if err := of.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := of.Borrow(func([]byte) {}); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("test 5: expecting error %v, got %v", fs.ErrClosed, err)
+	}
+
+	sf, err := f.Seal().Open("file")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := sf.(FileRO).Borrow(func(data []byte) {
+		borrowed = data
+	}); err != nil {
+		t.Errorf("test 6: unexpected error: %s", err)
+	} else if string(borrowed) != "data" {
+		t.Errorf("test 6: expecting to borrow %q, got %q", "data", borrowed)
+	}
+}
+
+func TestWriteAtGap(t *testing.T) {
+	f := New()
+
+	if err := f.WriteFile("file", []byte("abc"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Chtimes("file", time.Time{}, time.Unix(0, 0)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	w, err := f.OpenFile("file", ReadWrite, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	r, err := f.Open("file")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	fi, err := r.Stat()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := w.Truncate(1); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if _, err := w.WriteAt([]byte("end"), 6); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if data, err := f.ReadFile("file"); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if expected := "a\x00\x00\x00\x00\x00end"; string(data) != expected {
+		t.Errorf("test 1: expecting contents %q, got %q", expected, data)
+	} else if size := fi.Size(); size != 9 {
+		t.Errorf("test 2: expecting size %d, got %d", 9, size)
+	} else if modtime := fi.ModTime(); !modtime.After(time.Unix(0, 0)) {
+		t.Errorf("test 3: expecting modification time to be updated, got %s", modtime)
+	} else if pos, err := w.Seek(0, io.SeekCurrent); err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	} else if pos != 0 {
+		t.Errorf("test 4: expecting position %d, got %d", 0, pos)
+	} else if _, err := w.WriteAt([]byte("x"), -1); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("test 5: expecting error %v, got %v", fs.ErrInvalid, err)
+	}
+}
+
+func TestFileModeErrors(t *testing.T) {
+	f := New()
+
+	if err := f.WriteFile("file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	r, err := f.OpenFile("file", ReadOnly, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	w, err := f.OpenFile("file", WriteOnly, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Op  func() error
+		Err error
+	}{
+		{ // 1
+			Op: func() error {
+				_, err := r.Write([]byte("x"))
+
+				return err
+			},
+			Err: ErrNotOpenForWrite,
+		},
+		{ // 2
+			Op: func() error {
+				return r.Truncate(0)
+			},
+			Err: ErrNotOpenForWrite,
+		},
+		{ // 3
+			Op: func() error {
+				_, err := r.ReadFrom(strings.NewReader("x"))
+
+				return err
+			},
+			Err: ErrNotOpenForWrite,
+		},
+		{ // 4
+			Op: func() error {
+				_, err := w.Read(make([]byte, 1))
+
+				return err
+			},
+			Err: ErrNotOpenForRead,
+		},
+		{ // 5
+			Op: func() error {
+				_, err := w.ReadAt(make([]byte, 1), 0)
+
+				return err
+			},
+			Err: ErrNotOpenForRead,
+		},
+		{ // 6
+			Op: func() error {
+				return w.Borrow(func([]byte) {})
+			},
+			Err: ErrNotOpenForRead,
+		},
+	} {
+		if err := test.Op(); err != test.Err {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("test %d: expecting error to match fs.ErrInvalid", n+1)
+		}
+	}
+}
+
+func TestFileClosedErrors(t *testing.T) {
+	f := New()
+
+	if err := f.WriteFile("file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	r, err := f.OpenFile("file", ReadOnly, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	w, err := f.OpenFile("file", WriteOnly, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	rw, err := f.OpenFile("file", ReadWrite, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	r.Close()
+	w.Close()
+
+	f.Seal()
+
+	for n, test := range [...]struct {
+		Op  func() error
+		Err error
+	}{
+		{ // 1
+			Op: func() error {
+				_, err := r.Write([]byte("x"))
+
+				return err
+			},
+			Err: fs.ErrClosed,
+		},
+		{ // 2
+			Op: func() error {
+				_, err := w.Read(make([]byte, 1))
+
+				return err
+			},
+			Err: fs.ErrClosed,
+		},
+		{ // 3
+			Op: func() error {
+				return r.Close()
+			},
+			Err: fs.ErrClosed,
+		},
+		{ // 4
+			Op: func() error {
+				_, err := rw.Write([]byte("x"))
+
+				return err
+			},
+			Err: fs.ErrPermission,
+		},
+		{ // 5
+			Op: func() error {
+				_, err := rw.Read(make([]byte, 1))
+
+				return err
+			},
+		},
+	} {
+		if err := test.Op(); err != test.Err {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		}
+	}
+}
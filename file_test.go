@@ -10,13 +10,17 @@ import (
 	"testing"
 )
 
-var _ interface {
-	io.ReadSeekCloser
-	io.ReaderAt
-	io.WriterTo
-	io.RuneScanner
-	io.ByteScanner
-} = &file{}
+var (
+	_ interface {
+		io.ReadSeekCloser
+		io.ReaderAt
+		io.WriterTo
+		io.RuneScanner
+		io.ByteScanner
+	} = &file{}
+	_ FileRO = &file{}
+	_ FileRO = &File{}
+)
 
 func TestRead(t *testing.T) {
 	for n, test := range [...]struct {
@@ -40,7 +44,7 @@ func TestRead(t *testing.T) {
 		},
 		{
 			Mode: opSeek,
-			Err:  fs.ErrInvalid,
+			Err:  ErrNotOpenForRead,
 		},
 	} {
 		f := file{
@@ -90,7 +94,7 @@ func TestReadAt(t *testing.T) {
 			Output: [][]byte{
 				[]byte("H"),
 			},
-			Err: fs.ErrInvalid,
+			Err: ErrNotOpenForRead,
 		},
 		{
 			Mode: opRead | opSeek,
@@ -551,6 +555,230 @@ func TestUnreadRune(t *testing.T) {
 	}
 }
 
+func TestReadString(t *testing.T) {
+	for n, test := range [...]struct {
+		Data   string
+		Mode   opMode
+		Delim  byte
+		Output []string
+		Err    error
+	}{
+		{ // 1
+			Mode: opWrite,
+			Err:  fs.ErrInvalid,
+		},
+		{ // 2
+			Data: "a\nb",
+			Err:  fs.ErrClosed,
+		},
+		{ // 3
+			Data:   "",
+			Mode:   opRead,
+			Delim:  '\n',
+			Output: []string{},
+		},
+		{ // 4
+			Data:   "abc\ndef\n",
+			Mode:   opRead,
+			Delim:  '\n',
+			Output: []string{"abc\n", "def\n"},
+		},
+		{ // 5
+			Data:   "abc\ndef",
+			Mode:   opRead,
+			Delim:  '\n',
+			Output: []string{"abc\n", "def"},
+		},
+		{ // 6
+			Data:   "a,,b",
+			Mode:   opRead,
+			Delim:  ',',
+			Output: []string{"a,", ",", "b"},
+		},
+	} {
+		f := file{
+			inode: &inode{
+				data: []byte(test.Data),
+			},
+			opMode: test.Mode,
+		}
+
+		if test.Err != nil {
+			if _, err := f.ReadString(test.Delim); !errors.Is(err, test.Err) {
+				t.Errorf("test %d: expecting error %s, got %s", n+1, test.Err, err)
+			}
+
+			continue
+		}
+
+		for i, expected := range test.Output {
+			str, err := f.ReadString(test.Delim)
+			if i == len(test.Output)-1 && !strings.HasSuffix(expected, string(test.Delim)) {
+				if !errors.Is(err, io.EOF) {
+					t.Errorf("test %d.%d: expecting error %s, got %s", n+1, i+1, io.EOF, err)
+				}
+			} else if err != nil {
+				t.Errorf("test %d.%d: unexpected error: %s", n+1, i+1, err)
+			}
+
+			if str != expected {
+				t.Errorf("test %d.%d: expecting to read %q, got %q", n+1, i+1, expected, str)
+			}
+		}
+
+		if str, err := f.ReadString(test.Delim); !errors.Is(err, io.EOF) {
+			t.Errorf("test %d: expecting error %s, got %s", n+1, io.EOF, err)
+		} else if str != "" {
+			t.Errorf("test %d: expecting to read %q, got %q", n+1, "", str)
+		}
+	}
+}
+
+func TestPeek(t *testing.T) {
+	for n, test := range [...]struct {
+		Data   string
+		Pos    int64
+		Mode   opMode
+		Peek   int
+		Output string
+		Err    error
+	}{
+		{ // 1
+			Data: "abc",
+			Err:  fs.ErrClosed,
+		},
+		{ // 2
+			Data: "abc",
+			Mode: opWrite,
+			Err:  fs.ErrInvalid,
+		},
+		{ // 3
+			Data: "abc",
+			Mode: opRead,
+			Peek: -1,
+			Err:  fs.ErrInvalid,
+		},
+		{ // 4
+			Data:   "abc",
+			Mode:   opRead,
+			Peek:   2,
+			Output: "ab",
+		},
+		{ // 5
+			Data:   "abc",
+			Pos:    1,
+			Mode:   opRead,
+			Peek:   2,
+			Output: "bc",
+		},
+		{ // 6
+			Data:   "abc",
+			Pos:    1,
+			Mode:   opRead,
+			Peek:   5,
+			Output: "bc",
+			Err:    io.EOF,
+		},
+		{ // 7
+			Data: "abc",
+			Pos:  3,
+			Mode: opRead,
+			Peek: 1,
+			Err:  io.EOF,
+		},
+		{ // 8
+			Data: "abc",
+			Pos:  3,
+			Mode: opRead,
+		},
+	} {
+		f := file{
+			inode: &inode{
+				data: []byte(test.Data),
+			},
+			opMode: test.Mode,
+			pos:    test.Pos,
+		}
+
+		if data, err := f.Peek(test.Peek); !errors.Is(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if string(data) != test.Output {
+			t.Errorf("test %d: expecting to peek %q, got %q", n+1, test.Output, data)
+		} else if f.pos != test.Pos {
+			t.Errorf("test %d: expecting position %d, got %d", n+1, test.Pos, f.pos)
+		}
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	for n, test := range [...]struct {
+		Data    string
+		Pos     int64
+		Mode    opMode
+		Discard int
+		Output  int
+		NewPos  int64
+		Err     error
+	}{
+		{ // 1
+			Data: "abc",
+			Err:  fs.ErrClosed,
+		},
+		{ // 2
+			Data:    "abc",
+			Mode:    opRead,
+			Discard: -1,
+			Err:     fs.ErrInvalid,
+		},
+		{ // 3
+			Data:    "abc",
+			Mode:    opRead,
+			Discard: 2,
+			Output:  2,
+			NewPos:  2,
+		},
+		{ // 4
+			Data:    "abc",
+			Pos:     1,
+			Mode:    opRead,
+			Discard: 5,
+			Output:  2,
+			NewPos:  3,
+			Err:     io.EOF,
+		},
+		{ // 5
+			Data:    "abc",
+			Pos:     5,
+			Mode:    opRead,
+			Discard: 1,
+			NewPos:  5,
+			Err:     io.EOF,
+		},
+		{ // 6
+			Data:   "abc",
+			Pos:    1,
+			Mode:   opRead,
+			NewPos: 1,
+		},
+	} {
+		f := file{
+			inode: &inode{
+				data: []byte(test.Data),
+			},
+			opMode: test.Mode,
+			pos:    test.Pos,
+		}
+
+		if m, err := f.Discard(test.Discard); !errors.Is(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if m != test.Output {
+			t.Errorf("test %d: expecting to discard %d bytes, got %d", n+1, test.Output, m)
+		} else if f.pos != test.NewPos {
+			t.Errorf("test %d: expecting position %d, got %d", n+1, test.NewPos, f.pos)
+		}
+	}
+}
+
 func TestWriteTo(t *testing.T) {
 	f := file{
 		inode: &inode{
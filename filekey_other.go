@@ -0,0 +1,11 @@
+//go:build !unix
+
+package memfs
+
+import "io/fs"
+
+// osFileKey returns a value identifying the underlying file of a hard linked
+// OS file, or nil if the file is not hard linked.
+func osFileKey(_ fs.FileInfo) any {
+	return nil
+}
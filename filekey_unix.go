@@ -0,0 +1,18 @@
+//go:build unix
+
+package memfs
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// osFileKey returns a value identifying the underlying file of a hard linked
+// OS file, or nil if the file is not hard linked.
+func osFileKey(fi fs.FileInfo) any {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok && st.Nlink > 1 {
+		return [2]uint64{uint64(st.Dev), uint64(st.Ino)}
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package memfs
+
+import (
+	"encoding/binary"
+	"io/fs"
+	"math/rand/v2"
+	"path"
+	"strconv"
+)
+
+// SizeFunc is used by GenerateTree to choose the size of each file, using the
+// given source of randomness.
+type SizeFunc func(r *rand.Rand) int64
+
+// UniformSize returns a SizeFunc that chooses sizes uniformly between min and
+// max, inclusive.
+func UniformSize(min, max int64) SizeFunc {
+	return func(r *rand.Rand) int64 {
+		if max <= min {
+			return min
+		}
+
+		return min + r.Int64N(max-min+1)
+	}
+}
+
+// ExponentialSize returns a SizeFunc that chooses sizes from an exponential
+// distribution with the given mean, such that most files are small, with a
+// few that are much larger, as is typical of real file systems.
+func ExponentialSize(mean int64) SizeFunc {
+	return func(r *rand.Rand) int64 {
+		return int64(r.ExpFloat64() * float64(mean))
+	}
+}
+
+// TreeSpec describes the tree of files created by GenerateTree.
+type TreeSpec struct {
+	// Files is the number of files to create.
+	Files int
+
+	// FilesPerDir is the number of files placed in each directory before
+	// moving on to the next, or zero to place all of the files in the root.
+	FilesPerDir int
+
+	// Fanout is the number of subdirectories created in each directory,
+	// with a Fanout of less than one being treated as one.
+	Fanout int
+
+	// Size chooses the size of each file, with all files being empty when
+	// it is nil.
+	Size SizeFunc
+}
+
+// GenerateTree populates the directory at root, creating it and any missing
+// parents, with files, as described by the given TreeSpec, whose sizes and
+// contents are chosen pseudo-randomly from the given seed, such that the same
+// TreeSpec and seed always produce the same tree, for benchmarking and load
+// testing code that processes files.
+//
+// Directories are filled in breadth-first order, with each directory being
+// named 'dirN' and each file 'fileN', numbered from zero. Directories are
+// created with the permissions 0o755, and files with 0o644.
+//
+// None of the files, nor directories, being created may already exist.
+func GenerateTree(f *FS, root string, spec TreeSpec, seed uint64) error {
+	if spec.Files < 0 || spec.FilesPerDir < 0 {
+		return &fs.PathError{Op: "generatetree", Path: root, Err: fs.ErrInvalid}
+	}
+
+	// MkdirAll rejects the root, which always exists.
+	if path.Join(slash, root) != slash {
+		if err := f.MkdirAll(root, 0o755); err != nil {
+			return err
+		}
+	}
+
+	g := treeGenerator{
+		FS:     f,
+		dirs:   []string{root},
+		fanout: spec.Fanout,
+		r:      rand.New(rand.NewPCG(seed, seed)),
+	}
+
+	if g.fanout < 1 {
+		g.fanout = 1
+	}
+
+	for n := 0; n < spec.Files; n++ {
+		dir := 0
+
+		if spec.FilesPerDir > 0 {
+			dir = n / spec.FilesPerDir
+		}
+
+		p, err := g.dir(dir)
+		if err != nil {
+			return err
+		}
+
+		var size int64
+
+		if spec.Size != nil {
+			size = spec.Size(g.r)
+		}
+
+		if size < 0 {
+			return &fs.PathError{Op: "generatetree", Path: root, Err: fs.ErrInvalid}
+		}
+
+		if err := f.CreateFromBytes(path.Join(p, "file"+strconv.Itoa(n)), g.data(size), 0o644, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type treeGenerator struct {
+	*FS
+	dirs   []string
+	fanout int
+	r      *rand.Rand
+}
+
+// dir returns the path of the directory with the given breadth-first index,
+// creating it, and any preceding directories, as required.
+func (t *treeGenerator) dir(n int) (string, error) {
+	for len(t.dirs) <= n {
+		m := len(t.dirs)
+		p := path.Join(t.dirs[(m-1)/t.fanout], "dir"+strconv.Itoa((m-1)%t.fanout))
+
+		if err := t.Mkdir(p, 0o755); err != nil {
+			return "", err
+		}
+
+		t.dirs = append(t.dirs, p)
+	}
+
+	return t.dirs[n], nil
+}
+
+// data returns pseudo-random data of the given size.
+func (t *treeGenerator) data(size int64) []byte {
+	data := make([]byte, size)
+
+	var buf [8]byte
+
+	for n := 0; n < len(data); n += len(buf) {
+		binary.LittleEndian.PutUint64(buf[:], t.r.Uint64())
+		copy(data[n:], buf[:])
+	}
+
+	return data
+}
@@ -0,0 +1,150 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func generatedTree(t *testing.T, spec TreeSpec, seed uint64) map[string]string {
+	t.Helper()
+
+	f := New()
+
+	if err := GenerateTree(f, "a/b", spec, seed); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tree := make(map[string]string)
+
+	fs.WalkDir(f, ".", func(p string, d fs.DirEntry, _ error) error {
+		if !d.IsDir() {
+			data, _ := f.ReadFile(p)
+			tree[p] = string(data)
+		}
+
+		return nil
+	})
+
+	return tree
+}
+
+func TestGenerateTree(t *testing.T) {
+	spec := TreeSpec{
+		Files:       25,
+		FilesPerDir: 10,
+		Fanout:      2,
+		Size:        UniformSize(10, 20),
+	}
+
+	tree := generatedTree(t, spec, 1)
+
+	var paths []string
+
+	for n := 0; n < 10; n++ {
+		paths = append(paths, "a/b/file"+strconv.Itoa(n))
+	}
+
+	for n := 0; n < 10; n++ {
+		paths = append(paths, "a/b/dir0/file"+strconv.Itoa(10+n))
+	}
+
+	for n := 0; n < 5; n++ {
+		paths = append(paths, "a/b/dir1/file"+strconv.Itoa(20+n))
+	}
+
+	slices.Sort(paths)
+
+	if got := slices.Sorted(maps.Keys(tree)); !reflect.DeepEqual(got, paths) {
+		t.Errorf("test 1: expecting paths %v, got %v", paths, got)
+	}
+
+	for p, data := range tree {
+		if len(data) < 10 || len(data) > 20 {
+			t.Errorf("test 2: expecting size of %q to be between 10 and 20, got %d", p, len(data))
+		}
+	}
+
+	if again := generatedTree(t, spec, 1); !reflect.DeepEqual(tree, again) {
+		t.Errorf("test 3: expecting same seed to produce the same tree")
+	} else if other := generatedTree(t, spec, 2); reflect.DeepEqual(tree, other) {
+		t.Errorf("test 4: expecting different seed to produce a different tree")
+	}
+
+	for p, data := range generatedTree(t, TreeSpec{Files: 3}, 1) {
+		if data != "" {
+			t.Errorf("test 5: expecting %q to be empty, got %d bytes", p, len(data))
+		}
+	}
+
+	for p, data := range generatedTree(t, TreeSpec{Files: 50, Size: ExponentialSize(100)}, 1) {
+		if strings.Count(p, "/") != 2 {
+			t.Errorf("test 6: expecting %q to be in the root", p)
+		} else if len(data) > 10000 {
+			t.Errorf("test 6: expecting %q to be of a reasonable size, got %d", p, len(data))
+		}
+	}
+
+	if got := generatedTree(t, TreeSpec{Files: 4, FilesPerDir: 1}, 1); !reflect.DeepEqual(slices.Sorted(maps.Keys(got)), []string{"a/b/dir0/dir0/dir0/file3", "a/b/dir0/dir0/file2", "a/b/dir0/file1", "a/b/file0"}) {
+		t.Errorf("test 7: expecting chain of directories, got %v", slices.Sorted(maps.Keys(got)))
+	}
+
+	for n, root := range [...]string{".", "/", ""} {
+		f := New()
+
+		if err := GenerateTree(f, root, TreeSpec{Files: 2, FilesPerDir: 1}, 1); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+8, err)
+		} else if _, err := f.Stat("dir0/file1"); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+8, err)
+		}
+	}
+}
+
+func TestGenerateTreeErrors(t *testing.T) {
+	f := New()
+
+	f.MkdirAll("root/dir0", 0o755)
+	f.WriteFile("root/file1", nil, 0o644)
+	f.WriteFile("file", nil, 0o644)
+
+	for n, test := range [...]struct {
+		Root string
+		Spec TreeSpec
+		Err  error
+	}{
+		{ // 1
+			Root: "a",
+			Spec: TreeSpec{Files: -1},
+			Err:  &fs.PathError{Op: "generatetree", Path: "a", Err: fs.ErrInvalid},
+		},
+		{ // 2
+			Root: "b",
+			Spec: TreeSpec{Files: 1, Size: UniformSize(-2, -1)},
+			Err:  &fs.PathError{Op: "generatetree", Path: "b", Err: fs.ErrInvalid},
+		},
+		{ // 3
+			Root: "root",
+			Spec: TreeSpec{Files: 2},
+			Err:  fs.ErrExist,
+		},
+		{ // 4
+			Root: "root",
+			Spec: TreeSpec{Files: 2, FilesPerDir: 1},
+			Err:  fs.ErrExist,
+		},
+		{ // 5
+			Root: "file",
+			Spec: TreeSpec{Files: 1},
+			Err:  ErrNotDir,
+		},
+	} {
+		if err := GenerateTree(f, test.Root, test.Spec, 0); !errors.Is(err, test.Err) && !reflect.DeepEqual(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		}
+	}
+}
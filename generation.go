@@ -0,0 +1,325 @@
+package memfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// generation is incremented for every change made to any node, so that each
+// change is given a unique, and increasing, number.
+var generation atomic.Uint64
+
+func nextGeneration() uint64 {
+	return generation.Add(1)
+}
+
+// Generation returns the generation of the last change made to the FS, which
+// can be given to Changes to retrieve all of the changes made after it.
+func (f *FS) Generation() uint64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.de.Generation()
+}
+
+// Generational is implemented by the value returned by the Sys method of the
+// fs.FileInfo of every entry of an FS, such as those returned by Stat, LStat
+// and ReadDir, allowing for the cheap detection of changes.
+type Generational interface {
+	// Generation returns the generation of the last change made to the
+	// entry, or, for a directory, to the directory or anything below it.
+	//
+	// The number only ever increases, and the generations of entries that
+	// have not changed are not affected by changes elsewhere.
+	Generation() uint64
+}
+
+func (i *inode) Generation() uint64 {
+	return i.gen
+}
+
+func (i *inodeRW) Generation() uint64 {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.gen
+}
+
+func (d *dnode) Generation() uint64 {
+	gen := d.gen
+
+	for _, e := range d.entries {
+		if e.gen > gen {
+			gen = e.gen
+		}
+
+		if g := e.Generation(); g > gen {
+			gen = g
+		}
+	}
+
+	return gen
+}
+
+// Generation returns the latest generation of the directory and everything
+// below it, which is kept current by each change being propagated up to the
+// directories above the changed entry.
+func (d *dnodeRW) Generation() uint64 {
+	return d.subtree.Load()
+}
+
+// changed raises the generation of the subtree rooted at the directory, and
+// those of the directories above it, to the given generation.
+func (d *dnodeRW) changed(gen uint64) {
+	for {
+		current := d.subtree.Load()
+		if current >= gen {
+			return
+		} else if d.subtree.CompareAndSwap(current, gen) {
+			break
+		}
+	}
+
+	d.parents.changed(gen)
+}
+
+// parents holds the directories containing the names of a node, with a
+// directory being listed once for each name, so that changes to the node can
+// be propagated to the subtree generations of each of them.
+type parents struct {
+	mu   sync.Mutex
+	dirs []*dnodeRW
+}
+
+type parented interface {
+	parentDirs() *parents
+}
+
+func (i *inodeRW) parentDirs() *parents {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.parents == nil {
+		i.parents = new(parents)
+	}
+
+	return i.parents
+}
+
+func (p *fifo) parentDirs() *parents {
+	return &p.parents
+}
+
+func (d *dnodeRW) parentDirs() *parents {
+	return &d.parents
+}
+
+// addParent records that the given directory contains a name for the given
+// node.
+func addParent(de directoryEntry, d *dnodeRW) {
+	if p, ok := de.(parented); ok {
+		p := p.parentDirs()
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		p.dirs = append(p.dirs[:len(p.dirs):len(p.dirs)], d)
+	}
+}
+
+// removeParent records the removal of a name for the given node from the given
+// directory.
+func removeParent(de directoryEntry, d *dnodeRW) {
+	if p, ok := de.(parented); ok {
+		p := p.parentDirs()
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		if n := slices.Index(p.dirs, d); n >= 0 {
+			p.dirs = slices.Delete(slices.Clone(p.dirs), n, n+1)
+		}
+	}
+}
+
+// changed propagates the given generation to each of the directories.
+func (p *parents) changed(gen uint64) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	dirs := p.dirs
+	p.mu.Unlock()
+
+	for _, d := range dirs {
+		d.changed(gen)
+	}
+}
+
+func (packedNode) Generation() uint64 {
+	return 0
+}
+
+func nodeGeneration(de directoryEntry) uint64 {
+	switch de := de.(type) {
+	case *inodeRW:
+		de.mu.RLock()
+		defer de.mu.RUnlock()
+
+		return de.gen
+	case *dnodeRW:
+		return de.view().gen
+	case *inode:
+		return de.gen
+	case *dnode:
+		return de.gen
+	case *fifo:
+		return de.Generation()
+	case *lazyNode:
+		return de.Generation()
+	}
+
+	return 0
+}
+
+// Changes returns a Patch containing only the entries that have changed since
+// the given generation, as returned by Generation or by a previous call to
+// Changes, along with the generation the Patch brings a copy up to.
+//
+// Applying the Patch to an FS that matches this FS at the given generation
+// results in an FS that matches this one, allowing a large, mostly static, FS
+// to be persisted as a single image followed by a series of small Patches.
+//
+// Any file or symlink that has changed is recreated, and each directory whose
+// entries have changed is given a PatchRetain operation listing its current
+// entries. Files that are hard linked are recreated as separate files.
+func (f *FS) Changes(since uint64) (Patch, uint64) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	gen := generation.Load()
+	c := changes{since: since}
+
+	c.changed(".", f.de)
+
+	for n := len(c.dirs) - 1; n >= 0; n-- {
+		c.patch = append(c.patch, c.dirs[n])
+	}
+
+	return c.patch, gen
+}
+
+type changes struct {
+	since       uint64
+	patch, dirs Patch
+}
+
+func (c *changes) changed(p string, de directoryEntry) {
+	if nodeGeneration(de) > c.since {
+		entries := childEntries(de)
+		names := make([]string, len(entries))
+
+		for n, e := range entries {
+			names[n] = e.name
+		}
+
+		c.patch = append(c.patch, PatchOp{Action: PatchRetain, Path: p, Names: names})
+		c.dirs = append(c.dirs, PatchOp{Action: PatchMetadata, Path: p, Mode: de.Mode(), ModTime: de.ModTime()})
+	}
+
+	for _, e := range childEntries(de) {
+		childPath := path.Join(p, e.name)
+
+		if e.gen > c.since || !e.IsDir() && nodeGeneration(e.directoryEntry) > c.since {
+			c.patch = append(c.patch, PatchOp{Action: PatchDelete, Path: childPath})
+
+			c.create(childPath, e.directoryEntry)
+		} else if e.IsDir() && e.Generation() > c.since {
+			c.changed(childPath, e.directoryEntry)
+		}
+	}
+}
+
+func (c *changes) create(p string, de directoryEntry) {
+	op := PatchOp{
+		Action:  PatchCreate,
+		Path:    p,
+		Mode:    de.Mode(),
+		ModTime: de.ModTime(),
+	}
+
+	if op.Mode.IsDir() {
+		c.patch = append(c.patch, PatchOp{Action: PatchCreate, Path: p, Mode: fs.ModeDir | fs.ModePerm})
+		op.Action = PatchMetadata
+		c.dirs = append(c.dirs, op)
+
+		for _, e := range childEntries(de) {
+			c.create(path.Join(p, e.name), e.directoryEntry)
+		}
+
+		return
+	}
+
+	withData(de, func(data []byte) error {
+		op.Data = bytes.Clone(data)
+
+		return nil
+	})
+
+	c.patch = append(c.patch, op)
+}
+
+// WriteTo writes the Patch to the given io.Writer, with each PatchOp encoded
+// as JSON and followed by a newline, so that it can be read by ReadPatch.
+func (p Patch) WriteTo(w io.Writer) (int64, error) {
+	cw := countWriter{Writer: w}
+	enc := json.NewEncoder(&cw)
+
+	for _, op := range p {
+		if err := enc.Encode(op); err != nil {
+			return cw.n, err
+		}
+	}
+
+	return cw.n, nil
+}
+
+type countWriter struct {
+	io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.Writer.Write(p)
+	c.n += int64(n)
+
+	return n, err
+}
+
+// ReadPatch reads a Patch, as written by Patch.WriteTo, from the given
+// io.Reader.
+func ReadPatch(r io.Reader) (Patch, error) {
+	var patch Patch
+
+	dec := json.NewDecoder(r)
+
+	for {
+		var op PatchOp
+
+		if err := dec.Decode(&op); errors.Is(err, io.EOF) {
+			return patch, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		patch = append(patch, op)
+	}
+}
@@ -0,0 +1,207 @@
+package memfs
+
+import (
+	"bytes"
+	"io/fs"
+	"testing"
+	"time"
+)
+
+func TestChanges(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("static/dir", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.MkdirAll("changing/gone", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for file, contents := range map[string]string{
+		"static/file":       "static",
+		"static/dir/file":   "static",
+		"changing/modified": "before",
+		"changing/open":     "before",
+		"changing/renamed":  "rename me",
+		"changing/removed":  "remove me",
+		"changing/gone/a":   "a",
+	} {
+		if err := f.WriteFile(file, []byte(contents), 0o644); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	mirror, err := FromFS(f, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	gen := f.Generation()
+
+	if patch, _ := f.Changes(gen); len(patch) != 0 {
+		t.Errorf("test 1: expecting no changes, got %v", patch)
+	}
+
+	of, err := f.OpenFile("changing/open", WriteOnly|Append, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if _, err := of.WriteString(" and after"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := of.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("changing/modified", []byte("after"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.MkdirAll("changing/new/dir", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Rename("changing/renamed", "changing/new/renamed"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Remove("changing/removed"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.RemoveAll("changing/gone"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("../static/file", "changing/link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Chmod("changing/new", 0o555); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Chtimes("changing", time.Time{}, time.Unix(1, 0)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	patch, next := f.Changes(gen)
+
+	for _, op := range patch {
+		if op.Path != "." && !isBelow(op.Path, "changing") && op.Path != "changing" {
+			t.Errorf("test 2: unexpected change to %s", op.Path)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if _, err := patch.WriteTo(&buf); err != nil {
+		t.Fatalf("test 3: unexpected error: %s", err)
+	} else if patch, err = ReadPatch(&buf); err != nil {
+		t.Fatalf("test 3: unexpected error: %s", err)
+	} else if err := ApplyPatch(mirror, patch); err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	}
+
+	var got, expected bytes.Buffer
+
+	if err := mirror.WriteTar(&got); err != nil {
+		t.Fatalf("test 5: unexpected error: %s", err)
+	} else if err := f.WriteTar(&expected); err != nil {
+		t.Fatalf("test 5: unexpected error: %s", err)
+	} else if !bytes.Equal(got.Bytes(), expected.Bytes()) {
+		t.Errorf("test 5: patched FS does not match")
+	}
+
+	if patch, _ := f.Changes(next); len(patch) != 0 {
+		t.Errorf("test 6: expecting no changes, got %v", patch)
+	}
+}
+
+func TestGeneration(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("a/b", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.MkdirAll("c", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("a/b/file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	generationOf := func(p string) uint64 {
+		t.Helper()
+
+		fi, err := f.LStat(p)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		g, ok := fi.Sys().(Generational)
+		if !ok {
+			t.Fatalf("expecting Sys of %s to be Generational", p)
+		}
+
+		return g.Generation()
+	}
+
+	a, c, file, root := generationOf("a"), generationOf("c"), generationOf("a/b/file"), f.Generation()
+
+	if a < file || root < a || root < c {
+		t.Errorf("test 1: expecting directory generations to include their contents")
+	}
+
+	of, err := f.OpenFile("a/b/file", WriteOnly, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if _, err := of.Write([]byte("new")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if g := generationOf("a/b/file"); g <= file {
+		t.Errorf("test 2: expecting file generation to increase from %d, got %d", file, g)
+	} else if g := generationOf("a"); g <= a {
+		t.Errorf("test 3: expecting parent generation to increase from %d, got %d", a, g)
+	} else if g := f.Generation(); g <= root {
+		t.Errorf("test 4: expecting FS generation to increase from %d, got %d", root, g)
+	} else if g := generationOf("c"); g != c {
+		t.Errorf("test 5: expecting unchanged generation %d, got %d", c, g)
+	}
+
+	sub, err := f.Sub("c")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if g := sub.(*FS).Generation(); g != c {
+		t.Errorf("test 6: expecting Sub generation %d, got %d", c, g)
+	} else if err := f.Chmod("c", 0o700); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if g := sub.(*FS).Generation(); g <= c {
+		t.Errorf("test 7: expecting Sub generation to increase from %d, got %d", c, g)
+	}
+
+	if err := f.Link("a/b/file", "c/link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	c = generationOf("c")
+
+	if _, err := of.Write([]byte("more")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if g := generationOf("c"); g <= c {
+		t.Errorf("test 8: expecting generation of linked parent to increase from %d, got %d", c, g)
+	} else if err := f.Remove("c/link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	c = generationOf("c")
+
+	if _, err := of.Write([]byte("again")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if g := generationOf("c"); g != c {
+		t.Errorf("test 9: expecting unchanged generation %d, got %d", c, g)
+	} else if err := f.Rename("a/b", "c/b"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	c = generationOf("c")
+
+	if err := f.Chmod("c/b/file", 0o600); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if g := generationOf("c"); g <= c {
+		t.Errorf("test 10: expecting generation of new parent to increase from %d, got %d", c, g)
+	}
+
+	layer, err := Layer(f.Seal())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	root = layer.Generation()
+
+	if err := layer.WriteFile("c/b/file", []byte("layer"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if g := layer.Generation(); g <= root {
+		t.Errorf("test 11: expecting Layer generation to increase from %d, got %d", root, g)
+	}
+}
@@ -0,0 +1,220 @@
+package memfs
+
+import (
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+const globStar = "**"
+
+// GlobStar returns the paths of all entries matching the given pattern, sorted,
+// as with FS.GlobStar.
+func (f *fsRO) GlobStar(pattern string) ([]string, error) {
+	patterns, err := expandBraces(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	g := globber{
+		fsRO:    f,
+		matches: make(map[string]struct{}),
+	}
+
+	for _, p := range patterns {
+		parts, err := splitPattern(p)
+		if err != nil {
+			return nil, err
+		}
+
+		if parts == nil {
+			g.matches["."] = struct{}{}
+		} else {
+			g.match(".", f.de, parts)
+		}
+	}
+
+	matches := make([]string, 0, len(g.matches))
+
+	for m := range g.matches {
+		matches = append(matches, m)
+	}
+
+	slices.Sort(matches)
+
+	return matches, nil
+}
+
+// GlobStar returns the paths of all entries matching the given pattern, sorted.
+//
+// The pattern syntax is that of path.Match, with two additions: a path element
+// consisting solely of ** matches zero or more path elements, and a
+// comma-separated list of alternatives between braces, such as {a,b}, expands
+// to one pattern for each alternative. Braces may be nested.
+//
+// A ** element does not follow symlinks to directories, but all other elements
+// do. Directories that cannot be read are skipped.
+//
+// The only possible error is path.ErrBadPattern.
+func (f *FS) GlobStar(pattern string) ([]string, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.fsRO.GlobStar(pattern)
+}
+
+func splitPattern(pattern string) ([]string, error) {
+	if !fs.ValidPath(pattern) {
+		return nil, path.ErrBadPattern
+	} else if pattern == "." {
+		return nil, nil
+	}
+
+	parts := strings.Split(pattern, "/")
+
+	for _, part := range parts {
+		if _, err := path.Match(part, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	return parts, nil
+}
+
+func expandBraces(pattern string) ([]string, error) {
+	start, end, alternatives := -1, -1, []int(nil)
+	depth := 0
+
+Loop:
+	for n := 0; n < len(pattern); n++ {
+		switch pattern[n] {
+		case '\\':
+			n++
+		case '{':
+			if depth == 0 {
+				start = n
+			}
+
+			depth++
+		case ',':
+			if depth == 1 {
+				alternatives = append(alternatives, n)
+			}
+		case '}':
+			if depth == 0 {
+				return nil, path.ErrBadPattern
+			}
+
+			if depth--; depth == 0 {
+				end = n
+
+				break Loop
+			}
+		}
+	}
+
+	if depth != 0 {
+		return nil, path.ErrBadPattern
+	} else if start == -1 {
+		return []string{pattern}, nil
+	}
+
+	var patterns []string
+
+	prefix, suffix := pattern[:start], pattern[end+1:]
+	last := start
+
+	for _, pos := range append(alternatives, end) {
+		expanded, err := expandBraces(prefix + pattern[last+1:pos] + suffix)
+		if err != nil {
+			return nil, err
+		}
+
+		patterns = append(patterns, expanded...)
+		last = pos
+	}
+
+	return patterns, nil
+}
+
+type globber struct {
+	*fsRO
+	matches map[string]struct{}
+}
+
+func (g *globber) match(dir string, de directoryEntry, parts []string) {
+	if len(parts) == 0 {
+		if dir != "." {
+			g.matches[dir] = struct{}{}
+		}
+
+		return
+	}
+
+	part, rest := parts[0], parts[1:]
+
+	if part == globStar {
+		g.match(dir, de, rest)
+	}
+
+	d, ok := de.(dNode)
+	if !ok {
+		return
+	}
+
+	if !hasMeta(part) {
+		if child, err := getEntryNorm(d, part, g.normInsensitive); err == nil {
+			g.matchChild(dir, child, rest)
+		}
+
+		return
+	}
+
+	entries, err := d.getEntries()
+	if err != nil {
+		return
+	}
+
+	for _, e := range entries {
+		child := e.(*dirEnt)
+
+		if part == globStar {
+			if child.IsDir() {
+				g.match(path.Join(dir, child.name), child.directoryEntry, parts)
+			} else if len(rest) == 0 {
+				g.matches[path.Join(dir, child.name)] = struct{}{}
+			}
+		} else if matched, _ := path.Match(part, child.name); matched {
+			g.matchChild(dir, child, rest)
+		}
+	}
+}
+
+func (g *globber) matchChild(dir string, child *dirEnt, rest []string) {
+	childPath := path.Join(dir, child.name)
+
+	if len(rest) == 0 {
+		g.matches[childPath] = struct{}{}
+
+		return
+	}
+
+	de := child.directoryEntry
+
+	if child.Mode()&fs.ModeSymlink != 0 {
+		var err error
+
+		if de, err = g.getEntry(childPath); err != nil {
+			return
+		}
+	}
+
+	if de.IsDir() {
+		g.match(childPath, de, rest)
+	}
+}
+
+func hasMeta(part string) bool {
+	return strings.ContainsAny(part, `*?[\`)
+}
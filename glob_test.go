@@ -0,0 +1,103 @@
+package memfs
+
+import (
+	"io/fs"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestGlobStar(t *testing.T) {
+	f := New()
+
+	for _, dir := range [...]string{"a/b/c", "a/d", "e", "private"} {
+		if err := f.MkdirAll(dir, fs.ModePerm); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	for _, file := range [...]string{"a/b/c/x.go", "a/b/y.go", "a/d/z.txt", "a/x.go", "e/w.md", "top.go", "private/p.go"} {
+		if err := f.WriteFile(file, nil, 0o644); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if err := f.Symlink("a/b", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Chmod("private", 0o111); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Pattern string
+		Matches []string
+		Err     error
+	}{
+		{ // 1
+			Pattern: ".",
+			Matches: []string{"."},
+		},
+		{ // 2
+			Pattern: "*.go",
+			Matches: []string{"top.go"},
+		},
+		{ // 3
+			Pattern: "**/*.go",
+			Matches: []string{"a/b/c/x.go", "a/b/y.go", "a/x.go", "top.go"},
+		},
+		{ // 4
+			Pattern: "a/**",
+			Matches: []string{"a", "a/b", "a/b/c", "a/b/c/x.go", "a/b/y.go", "a/d", "a/d/z.txt", "a/x.go"},
+		},
+		{ // 5
+			Pattern: "a/**/c",
+			Matches: []string{"a/b/c"},
+		},
+		{ // 6
+			Pattern: "**/*.{go,md}",
+			Matches: []string{"a/b/c/x.go", "a/b/y.go", "a/x.go", "e/w.md", "top.go"},
+		},
+		{ // 7
+			Pattern: "{a/{b,d},e}/*",
+			Matches: []string{"a/b/c", "a/b/y.go", "a/d/z.txt", "e/w.md"},
+		},
+		{ // 8
+			Pattern: "link/*.go",
+			Matches: []string{"link/y.go"},
+		},
+		{ // 9
+			Pattern: "**/y.go",
+			Matches: []string{"a/b/y.go"},
+		},
+		{ // 10
+			Pattern: "private/p.go",
+			Matches: []string{},
+		},
+		{ // 11
+			Pattern: "private/*",
+			Matches: []string{},
+		},
+		{ // 12
+			Pattern: "missing/**",
+			Matches: []string{},
+		},
+		{ // 13
+			Pattern: "a/{b",
+			Err:     path.ErrBadPattern,
+		},
+		{ // 14
+			Pattern: "a/[",
+			Err:     path.ErrBadPattern,
+		},
+		{ // 15
+			Pattern: "/a",
+			Err:     path.ErrBadPattern,
+		},
+	} {
+		if matches, err := f.GlobStar(test.Pattern); err != test.Err {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if !reflect.DeepEqual(matches, test.Matches) {
+			t.Errorf("test %d: expecting matches %v, got %v", n+1, test.Matches, matches)
+		}
+	}
+}
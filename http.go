@@ -0,0 +1,286 @@
+package memfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// inodeMeta holds the rarely used metadata of a file, so that files without it
+// only pay for a single pointer.
+type inodeMeta struct {
+	contentType string
+	etag        string
+	etagGen     uint64
+	acl         ACL
+}
+
+// SetContentType sets the MIME type to be sent in the Content-Type header when
+// the file at the given path is served by FileServer, with an empty string
+// restoring the default detection by file extension and contents.
+//
+// The type is kept by the file, following it through renames, and is shared
+// by all hard links to it.
+func (f *FS) SetContentType(path, contentType string) (err error) {
+	defer f.logOp("setcontenttype", path).end(&err)
+
+	f.throttle.op()
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.limit.op(); err != nil {
+		return &fs.PathError{Op: "setcontenttype", Path: path, Err: err}
+	}
+
+	de, err := f.getEntry(path)
+	if err != nil {
+		return &fs.PathError{Op: "setcontenttype", Path: path, Err: err}
+	}
+
+	i, ok := fileNode(de)
+	if !ok {
+		return &fs.PathError{Op: "setcontenttype", Path: path, Err: fs.ErrInvalid}
+	}
+
+	if err := i.setContentType(contentType); err != nil {
+		return &fs.PathError{Op: "setcontenttype", Path: path, Err: err}
+	}
+
+	f.cache.invalidate()
+
+	de.touch(f.deterministic.now())
+
+	return nil
+}
+
+func (i *inodeRW) setContentType(contentType string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.sealed {
+		return fs.ErrPermission
+	} else if i.meta == nil {
+		i.meta = new(inodeMeta)
+	}
+
+	i.meta.contentType = contentType
+	i.gen = nextGeneration()
+
+	i.parents.changed(i.gen)
+
+	return nil
+}
+
+// FileServer returns an http.Handler, like that returned by http.FileServerFS,
+// that serves the files of the given fs.FS.
+//
+// For an FS, or FSRO, from this package, each file is served with a strong
+// ETag, calculated from a hash of its contents, allowing for conditional
+// requests, and with any Content-Type set with SetContentType.
+//
+// The ETag of each file is calculated when first requested, and is kept until
+// the file is next changed, with the files of a sealed FSRO never changing.
+func FileServer(fsys fs.FS) http.Handler {
+	return &fileServer{
+		fsys:    fsys,
+		handler: http.FileServerFS(fsys),
+	}
+}
+
+type fileServer struct {
+	fsys    fs.FS
+	handler http.Handler
+}
+
+func (s *fileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if p == "" {
+		p = "."
+	}
+
+	if fi, err := fs.Stat(s.fsys, p); err == nil && fi.Mode().IsRegular() {
+		if de, ok := fi.Sys().(directoryEntry); ok {
+			setHeaders(w.Header(), de)
+		}
+	}
+
+	s.handler.ServeHTTP(w, r)
+}
+
+// ServeContent replies to the request with the contents of the file at the
+// given path, using http.ServeContent to handle Range and conditional
+// requests.
+//
+// Unlike serving through http.FS, the file is found with a single lookup and
+// read directly, with the Last-Modified, ETag and Content-Type headers all
+// taken from the file itself.
+func (f *FS) ServeContent(w http.ResponseWriter, r *http.Request, path string) {
+	f.throttle.open()
+
+	f.mu.RLock()
+	of, content, modtime, err := f.openContent(w.Header(), path)
+	f.mu.RUnlock()
+
+	if err != nil {
+		serveError(w, err)
+
+		return
+	}
+
+	defer of.Close()
+
+	http.ServeContent(w, r, path, modtime, content)
+}
+
+func (f *FS) openContent(h http.Header, p string) (fs.File, io.ReadSeeker, time.Time, error) {
+	de, err := f.getEntry(p)
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	} else if !de.Mode().IsRegular() {
+		return nil, nil, time.Time{}, fs.ErrInvalid
+	}
+
+	of, err := de.open(path.Base(p), opRead|opSeek)
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+
+	if ef, ok := of.(*File); ok {
+		ef.alloc = f.alloc
+		ef.throttle = f.throttle
+		ef.accounts = f.accounts(p)
+		ef.orphans = f.orphans
+	}
+
+	setHeaders(h, de)
+
+	return of, io.NewSectionReader(of.(io.ReaderAt), 0, de.Size()), de.ModTime(), nil
+}
+
+func serveError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+		code = http.StatusNotFound
+	} else if errors.Is(err, fs.ErrPermission) {
+		code = http.StatusForbidden
+	}
+
+	http.Error(w, http.StatusText(code), code)
+}
+
+func setHeaders(h http.Header, de directoryEntry) {
+	if etag := entityTag(de); etag != "" {
+		h.Set("Etag", etag)
+	}
+
+	if contentType := contentType(de); contentType != "" {
+		h.Set("Content-Type", contentType)
+	}
+}
+
+func entityTag(de directoryEntry) string {
+	switch de := de.(type) {
+	case *inodeRW:
+		de.mu.Lock()
+		defer de.mu.Unlock()
+
+		if de.sealed {
+			return de.etag.get(de.data)
+		} else if de.meta == nil {
+			de.meta = new(inodeMeta)
+		}
+
+		if de.meta.etag == "" || de.meta.etagGen != de.gen {
+			de.meta.etag = hashETag(de.data)
+			de.meta.etagGen = de.gen
+		}
+
+		return de.meta.etag
+	case *inode:
+		return de.etag.get(de.data)
+	case packedNode:
+		return de.entityTag()
+	}
+
+	return ""
+}
+
+// etagOnce calculates the ETag of a sealed file, whose contents cannot change,
+// on first use.
+type etagOnce struct {
+	once sync.Once
+	etag string
+}
+
+func (e *etagOnce) get(data []byte) string {
+	if e == nil {
+		return hashETag(data)
+	}
+
+	e.once.Do(func() {
+		e.etag = hashETag(data)
+	})
+
+	return e.etag
+}
+
+// entityTag returns the ETag of the file, which is calculated on first use and
+// kept by the image.
+func (p packedNode) entityTag() string {
+	p.etagMu.Lock()
+	etag, ok := p.etags[p.index]
+	p.etagMu.Unlock()
+
+	if ok {
+		return etag
+	}
+
+	etag = hashETag(p.contents())
+
+	p.etagMu.Lock()
+	defer p.etagMu.Unlock()
+
+	if p.etags == nil {
+		p.etags = make(map[uint64]string)
+	}
+
+	p.etags[p.index] = etag
+
+	return etag
+}
+
+func hashETag(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+func contentType(de directoryEntry) string {
+	if i, ok := fileNode(de); ok {
+		i.mu.RLock()
+		defer i.mu.RUnlock()
+
+		return i.inode.contentType()
+	} else if i, ok := de.(*inode); ok {
+		return i.contentType()
+	}
+
+	return ""
+}
+
+func (i *inode) contentType() string {
+	if i.meta == nil {
+		return ""
+	}
+
+	return i.meta.contentType
+}
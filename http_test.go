@@ -0,0 +1,223 @@
+package memfs
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFileServer(t *testing.T) {
+	f := New()
+
+	if err := f.Mkdir("dir", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("dir/data.bin", []byte("{}"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("page.html", []byte("<p>Hello</p>"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.SetContentType("dir/data.bin", "application/json"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.SetContentType("dir", "text/plain"); err == nil {
+		t.Errorf("expecting error setting content type of directory")
+	} else if err := f.CreateSparse("sparse.bin", 4); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	gen := f.Generation()
+
+	if err := f.SetContentType("sparse.bin", "application/x-sparse"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if g := f.Generation(); g <= gen {
+		t.Errorf("expecting generation to increase from %d, got %d", gen, g)
+	}
+
+	get := func(handler http.Handler, path, etag string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	handler := FileServer(f)
+
+	w := get(handler, "/dir/data.bin", "")
+	etag := w.Header().Get("Etag")
+
+	if w.Code != http.StatusOK {
+		t.Errorf("test 1: expecting status 200, got %d", w.Code)
+	} else if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("test 1: expecting Content-Type %q, got %q", "application/json", ct)
+	} else if len(etag) != 34 || etag[0] != '"' {
+		t.Errorf("test 1: expecting strong ETag, got %q", etag)
+	} else if body := w.Body.String(); body != "{}" {
+		t.Errorf("test 1: expecting body %q, got %q", "{}", body)
+	}
+
+	if w := get(handler, "/page.html", ""); w.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("test 2: expecting default Content-Type, got %q", w.Header().Get("Content-Type"))
+	} else if w.Header().Get("Etag") == etag {
+		t.Errorf("test 2: expecting different ETags for different files")
+	}
+
+	if w := get(handler, "/dir/data.bin", etag); w.Code != http.StatusNotModified {
+		t.Errorf("test 3: expecting status 304, got %d", w.Code)
+	} else if w := get(handler, "/sparse.bin", ""); w.Header().Get("Content-Type") != "application/x-sparse" {
+		t.Errorf("test 3: expecting Content-Type %q, got %q", "application/x-sparse", w.Header().Get("Content-Type"))
+	}
+
+	if err := f.WriteFile("dir/data.bin", []byte("[]"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	w = get(handler, "/dir/data.bin", etag)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("test 4: expecting status 200, got %d", w.Code)
+	} else if newTag := w.Header().Get("Etag"); newTag == etag || newTag == "" {
+		t.Errorf("test 4: expecting new ETag, got %q", newTag)
+	}
+
+	sealed := f.Seal()
+
+	if w := get(FileServer(sealed), "/dir/data.bin", ""); w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("test 5: expecting Content-Type to survive Seal, got %q", w.Header().Get("Content-Type"))
+	} else if w.Header().Get("Etag") == "" {
+		t.Errorf("test 5: expecting ETag from sealed FS")
+	}
+
+	etag = hashETag([]byte("[]"))
+
+	for n, fsys := range [...]FSRO{
+		sealed,
+		f.Snapshot(),
+		sealCompact(t, f),
+	} {
+		handler := FileServer(fsys)
+
+		for i := 0; i < 2; i++ {
+			if w := get(handler, "/dir/data.bin", ""); w.Header().Get("Etag") != etag {
+				t.Errorf("test %d: expecting ETag %q, got %q", n+6, etag, w.Header().Get("Etag"))
+			}
+		}
+
+		fi, err := fs.Stat(fsys, "dir/data.bin")
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+6, err)
+		}
+
+		switch de := fi.Sys().(type) {
+		case *inode:
+			if de.etag.etag != etag {
+				t.Errorf("test %d: expecting ETag to be kept by the file, got %q", n+6, de.etag.etag)
+			}
+		case packedNode:
+			if de.etags[de.index] != etag {
+				t.Errorf("test %d: expecting ETag to be kept by the image, got %q", n+6, de.etags[de.index])
+			}
+		default:
+			t.Errorf("test %d: unexpected node type %T", n+6, de)
+		}
+	}
+}
+
+func TestServeContent(t *testing.T) {
+	f := New()
+
+	if err := f.Mkdir("dir", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("dir/file.txt", []byte("0123456789"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("secret", []byte("secret"), 0o200); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("dir/file.txt", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Chtimes("dir/file.txt", time.Time{}, time.Unix(1000, 0)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	etag := hashETag([]byte("0123456789"))
+
+	for n, test := range [...]struct {
+		Path, Range, IfNoneMatch string
+		Code                     int
+		Body                     string
+		Headers                  map[string]string
+	}{
+		{ // 1
+			Path: "dir/file.txt",
+			Code: http.StatusOK,
+			Body: "0123456789",
+			Headers: map[string]string{
+				"Etag":           etag,
+				"Last-Modified":  time.Unix(1000, 0).UTC().Format(http.TimeFormat),
+				"Content-Type":   "text/plain; charset=utf-8",
+				"Content-Length": "10",
+			},
+		},
+		{ // 2
+			Path:  "dir/file.txt",
+			Range: "bytes=2-5",
+			Code:  http.StatusPartialContent,
+			Body:  "2345",
+			Headers: map[string]string{
+				"Content-Range": "bytes 2-5/10",
+			},
+		},
+		{ // 3
+			Path:        "link",
+			IfNoneMatch: etag,
+			Code:        http.StatusNotModified,
+		},
+		{ // 4
+			Path: "missing",
+			Code: http.StatusNotFound,
+			Body: "Not Found\n",
+		},
+		{ // 5
+			Path: "dir",
+			Code: http.StatusNotFound,
+			Body: "Not Found\n",
+		},
+		{ // 6
+			Path: "secret",
+			Code: http.StatusForbidden,
+			Body: "Forbidden\n",
+		},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		if test.Range != "" {
+			r.Header.Set("Range", test.Range)
+		}
+
+		if test.IfNoneMatch != "" {
+			r.Header.Set("If-None-Match", test.IfNoneMatch)
+		}
+
+		w := httptest.NewRecorder()
+
+		f.ServeContent(w, r, test.Path)
+
+		if w.Code != test.Code {
+			t.Errorf("test %d: expecting status %d, got %d", n+1, test.Code, w.Code)
+		} else if body := w.Body.String(); body != test.Body {
+			t.Errorf("test %d: expecting body %q, got %q", n+1, test.Body, body)
+		}
+
+		for header, value := range test.Headers {
+			if got := w.Header().Get(header); got != value {
+				t.Errorf("test %d: expecting header %s to be %q, got %q", n+1, header, value, got)
+			}
+		}
+	}
+}
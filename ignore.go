@@ -0,0 +1,140 @@
+package memfs
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Ignore is a set of gitignore style patterns, used to exclude paths when
+// importing or walking a filesystem.
+type Ignore struct {
+	patterns []ignorePattern
+}
+
+type ignorePattern struct {
+	parts   []string
+	negate  bool
+	dirOnly bool
+}
+
+// NewIgnore parses the given lines, using the semantics of a gitignore file.
+//
+// Blank lines and those beginning with a # are skipped; a leading ! negates
+// the pattern, re-including any path excluded by an earlier pattern; a trailing
+// / matches only directories; and a pattern containing a / elsewhere is
+// matched relative to the root, while one without is matched against the name
+// at any depth. Each path element is matched with path.Match, and an element
+// of ** matches zero or more elements.
+//
+// Returns path.ErrBadPattern if any pattern is malformed.
+func NewIgnore(lines ...string) (*Ignore, error) {
+	var i Ignore
+
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		var p ignorePattern
+
+		if line[0] == '!' {
+			p.negate = true
+			line = line[1:]
+		} else if line[0] == '\\' && len(line) > 1 && (line[1] == '#' || line[1] == '!') {
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+
+		if !strings.Contains(line, "/") {
+			line = globStar + "/" + line
+		}
+
+		p.parts = strings.Split(strings.TrimPrefix(line, "/"), "/")
+
+		for _, part := range p.parts {
+			if _, err := path.Match(part, ""); err != nil {
+				return nil, err
+			}
+		}
+
+		i.patterns = append(i.patterns, p)
+	}
+
+	return &i, nil
+}
+
+// Match returns true if the given slash-separated path, relative to the root,
+// or any of its parent directories, is excluded by the patterns.
+//
+// A nil Ignore matches nothing.
+func (i *Ignore) Match(p string, isDir bool) bool {
+	if i == nil || !fs.ValidPath(p) || p == "." {
+		return false
+	}
+
+	for n := 0; n < len(p); n++ {
+		if p[n] == '/' && i.match(p[:n], true) {
+			return true
+		}
+	}
+
+	return i.match(p, isDir)
+}
+
+func (i *Ignore) match(p string, isDir bool) bool {
+	names := strings.Split(p, "/")
+
+	for n := len(i.patterns) - 1; n >= 0; n-- {
+		if pattern := i.patterns[n]; (isDir || !pattern.dirOnly) && matchParts(pattern.parts, names) {
+			return !pattern.negate
+		}
+	}
+
+	return false
+}
+
+func matchParts(parts, names []string) bool {
+	for len(parts) > 0 {
+		if parts[0] == globStar {
+			for n := 0; n <= len(names); n++ {
+				if matchParts(parts[1:], names[n:]) {
+					return true
+				}
+			}
+
+			return false
+		} else if len(names) == 0 {
+			return false
+		} else if matched, _ := path.Match(parts[0], names[0]); !matched {
+			return false
+		}
+
+		parts, names = parts[1:], names[1:]
+	}
+
+	return len(names) == 0
+}
+
+// WalkDirFunc wraps the given fs.WalkDirFunc so that, when used with
+// fs.WalkDir, any excluded paths are skipped; excluded directories are not
+// descended into.
+func (i *Ignore) WalkDirFunc(fn fs.WalkDirFunc) fs.WalkDirFunc {
+	return func(p string, d fs.DirEntry, err error) error {
+		if d != nil && i.Match(p, d.IsDir()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		return fn(p, d, err)
+	}
+}
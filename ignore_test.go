@@ -0,0 +1,100 @@
+package memfs
+
+import (
+	"io/fs"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestIgnore(t *testing.T) {
+	ignore, err := NewIgnore(
+		"# comment",
+		"",
+		"*.log",
+		"!keep.log",
+		"vendor/",
+		"/build",
+		"docs/**/*.tmp",
+		"\\#hash",
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Path  string
+		IsDir bool
+		Match bool
+	}{
+		{Path: "a.log", Match: true},                   // 1
+		{Path: "a/b/c.log", Match: true},               // 2
+		{Path: "keep.log"},                             // 3
+		{Path: "a/keep.log"},                           // 4
+		{Path: "vendor", IsDir: true, Match: true},     // 5
+		{Path: "a/vendor", IsDir: true, Match: true},   // 6
+		{Path: "vendor"},                               // 7
+		{Path: "vendor/file", Match: true},             // 8
+		{Path: "build", Match: true},                   // 9
+		{Path: "a/build"},                              // 10
+		{Path: "docs/a.tmp", Match: true},              // 11
+		{Path: "docs/a/b/c.tmp", Match: true},          // 12
+		{Path: "a/docs/c.tmp"},                         // 13
+		{Path: "#hash", Match: true},                   // 14
+		{Path: "# comment"},                            // 15
+		{Path: "."},                                    // 16
+		{Path: "build/keep.log", Match: true},          // 17
+		{Path: "main.go"},                              // 18
+		{Path: "a/vendor/b", IsDir: true, Match: true}, // 19
+		{Path: "vendorx/file"},                         // 20
+	} {
+		if match := ignore.Match(test.Path, test.IsDir); match != test.Match {
+			t.Errorf("test %d: expecting match %v for %q, got %v", n+1, test.Match, test.Path, match)
+		}
+	}
+
+	if _, err := NewIgnore("a/["); err != path.ErrBadPattern {
+		t.Errorf("expecting error %v, got %v", path.ErrBadPattern, err)
+	}
+
+	var nilIgnore *Ignore
+
+	if nilIgnore.Match("a", false) {
+		t.Errorf("expecting nil Ignore to match nothing")
+	}
+}
+
+func TestIgnoreWalkDirFunc(t *testing.T) {
+	f := New()
+
+	for _, dir := range [...]string{"a/vendor/x", "b"} {
+		if err := f.MkdirAll(dir, fs.ModePerm); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	for _, file := range [...]string{"a/vendor/x/file", "a/main.go", "a/debug.log", "b/file"} {
+		if err := f.WriteFile(file, nil, 0o644); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	ignore, err := NewIgnore("vendor/", "*.log")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var walked []string
+
+	if err := fs.WalkDir(f, ".", ignore.WalkDirFunc(func(p string, _ fs.DirEntry, err error) error {
+		walked = append(walked, p)
+
+		return err
+	})); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if expected := []string{".", "a", "a/main.go", "b", "b/file"}; !reflect.DeepEqual(walked, expected) {
+		t.Errorf("expecting to walk %v, got %v", expected, walked)
+	}
+}
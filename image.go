@@ -0,0 +1,144 @@
+package memfs
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+)
+
+const (
+	imageMagic   = "MEMFS\x00"
+	imageVersion = 1
+
+	imageHeaderSize = 32
+)
+
+// WriteImage writes the FS to the given io.Writer as an image, as with
+// FS.WriteImage.
+func (f *fsRO) WriteImage(w io.Writer) (int64, error) {
+	p, err := pack("writeimage", f.de)
+	if err != nil {
+		return 0, err
+	}
+
+	return p.writeTo(w)
+}
+
+// WriteImage writes the FS to the given io.Writer as an image that can be read
+// by LoadImage.
+func (f *FS) WriteImage(w io.Writer) (int64, error) {
+	f.mu.RLock()
+	p, err := pack("writeimage", f.de)
+	f.mu.RUnlock()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return p.writeTo(w)
+}
+
+func (p *packed) writeTo(w io.Writer) (int64, error) {
+	var header [imageHeaderSize]byte
+
+	copy(header[:], imageMagic)
+	binary.LittleEndian.PutUint16(header[6:], imageVersion)
+	binary.LittleEndian.PutUint64(header[8:], uint64(len(p.nodes)))
+	binary.LittleEndian.PutUint64(header[16:], uint64(len(p.names)))
+	binary.LittleEndian.PutUint64(header[24:], uint64(len(p.data)))
+
+	var total int64
+
+	for _, buf := range [...][]byte{header[:], p.nodes, p.names, p.data} {
+		n, err := w.Write(buf)
+		total += int64(n)
+
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+// LoadImage creates a Read-only FS from an image created by WriteImage.
+//
+// No data is copied during loading; entries are read from the image
+// as they are accessed, so the given slice can be a memory-mapped file. As
+// such, the slice must not be modified while the FSRO is in use.
+//
+// Each of the records of the image is validated during loading, ensuring that
+// the names, data, and directory entries they refer to are within the image,
+// returning ErrInvalidImage otherwise. The contents of names and data are not
+// checked.
+func LoadImage(data []byte) (FSRO, error) {
+	if len(data) < imageHeaderSize || string(data[:len(imageMagic)]) != imageMagic {
+		return nil, ErrInvalidImage
+	} else if binary.LittleEndian.Uint16(data[6:]) != imageVersion {
+		return nil, ErrUnsupportedImage
+	}
+
+	nodes := binary.LittleEndian.Uint64(data[8:])
+	names := binary.LittleEndian.Uint64(data[16:])
+	contents := binary.LittleEndian.Uint64(data[24:])
+	data = data[imageHeaderSize:]
+
+	if nodes < packedRecordSize || nodes%packedRecordSize != 0 || nodes > uint64(len(data)) || names > uint64(len(data))-nodes || contents != uint64(len(data))-nodes-names {
+		return nil, ErrInvalidImage
+	}
+
+	p := &packed{
+		nodes: data[:nodes:nodes],
+		names: data[nodes : nodes+names : nodes+names],
+		data:  data[nodes+names:],
+	}
+
+	if !p.valid() {
+		return nil, ErrInvalidImage
+	}
+
+	return &fsRO{
+		de: packedNode{
+			packed: p,
+		},
+	}, nil
+}
+
+// valid checks that each record refers only to names and data within the
+// image, that the root is a directory, and that the children of each
+// directory are within the node table and follow the directory, so that the
+// tree contains no cycles.
+func (p *packed) valid() bool {
+	count := uint64(len(p.nodes)) / packedRecordSize
+
+	for n := uint64(0); n < count; n++ {
+		r := p.record(n)
+		mode := fs.FileMode(binary.LittleEndian.Uint32(r[packedMode:]))
+		nameOff := binary.LittleEndian.Uint64(r[packedNameOff:])
+		nameLen := uint64(binary.LittleEndian.Uint32(r[packedNameLen:]))
+		off := binary.LittleEndian.Uint64(r[packedDataOff:])
+		length := binary.LittleEndian.Uint64(r[packedDataLen:])
+
+		switch {
+		case nameOff > uint64(len(p.names)) || nameLen > uint64(len(p.names))-nameOff,
+			binary.LittleEndian.Uint32(r[packedModNsec:]) >= 1e9,
+			n == 0 && !mode.IsDir():
+			return false
+		case mode.IsDir():
+			if off > count || length > count-off || length > 0 && off <= n {
+				return false
+			}
+		case off > uint64(len(p.data)) || length > uint64(len(p.data))-off:
+			return false
+		}
+	}
+
+	return true
+}
+
+// Errors.
+var (
+	ErrInvalidImage     = errors.New("invalid image")
+	ErrUnsupportedImage = errors.New("unsupported image version")
+)
@@ -0,0 +1,206 @@
+package memfs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestImage(t *testing.T) {
+	f := New()
+
+	for n, fn := range [...]func() error{
+		func() error { return f.Mkdir("dir", fs.ModePerm) },
+		func() error { return writeFile(f, "a", "Hello") },
+		func() error { return writeFile(f, "dir/b", "World") },
+		func() error { return f.Link("dir/b", "c") },
+		func() error { return f.Symlink("dir/b", "d") },
+		func() error { return f.Chtimes("a", time.Unix(1, 2), time.Unix(3, 4)) },
+		func() error { return f.Chtimes("dir/b", time.Time{}, time.Time{}) },
+		func() error { return f.Chtimes("dir", time.Time{}, time.Date(3000, 1, 2, 3, 4, 5, 6, time.UTC)) },
+	} {
+		if err := fn(); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if n, err := f.WriteImage(&buf); err != nil {
+		t.Fatalf("unexpected error writing image: %s", err)
+	} else if n != int64(buf.Len()) {
+		t.Errorf("expecting to have written %d bytes, reported %d", buf.Len(), n)
+	}
+
+	ro, err := LoadImage(buf.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error loading image: %s", err)
+	}
+
+	if err := fstest.TestFS(ro, "a", "c", "d", "dir/b"); err != nil {
+		t.Errorf("error during fstest: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Path, Contents string
+	}{
+		{"a", "Hello"},
+		{"c", "World"},
+		{"d", "World"},
+		{"dir/b", "World"},
+	} {
+		if data, err := ro.ReadFile(test.Path); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if string(data) != test.Contents {
+			t.Errorf("test %d: expecting contents %q, got %q", n+1, test.Contents, data)
+		}
+	}
+
+	for n, test := range [...]struct {
+		Path    string
+		ModTime time.Time
+	}{
+		{"a", time.Unix(3, 4)},
+		{"dir/b", time.Time{}},
+		{"dir", time.Date(3000, 1, 2, 3, 4, 5, 6, time.UTC)},
+	} {
+		if fi, err := ro.Stat(test.Path); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+5, err)
+		} else if !fi.ModTime().Equal(test.ModTime) {
+			t.Errorf("test %d: expecting modtime %s, got %s", n+5, test.ModTime, fi.ModTime())
+		}
+	}
+
+	var rewritten bytes.Buffer
+
+	if _, err := f.Seal().WriteImage(&rewritten); err != nil {
+		t.Errorf("unexpected error writing sealed image: %s", err)
+	} else if !bytes.Equal(buf.Bytes(), rewritten.Bytes()) {
+		t.Errorf("expecting sealed image to match original")
+	}
+
+	rewritten.Reset()
+
+	if _, err := ro.WriteImage(&rewritten); err != nil {
+		t.Errorf("unexpected error rewriting image: %s", err)
+	} else if !bytes.Equal(buf.Bytes(), rewritten.Bytes()) {
+		t.Errorf("expecting rewritten image to match original")
+	}
+}
+
+func TestLoadImageErrors(t *testing.T) {
+	valid := make([]byte, imageHeaderSize+packedRecordSize)
+
+	copy(valid, imageMagic)
+	valid[6] = imageVersion
+	valid[8] = packedRecordSize
+	valid[imageHeaderSize+packedMode+3] = 0x80
+
+	withRecord := func(fields map[int]uint64, extra ...[]byte) []byte {
+		data := append(append([]byte{}, valid...), make([]byte, packedRecordSize)...)
+		data[8] = 2 * packedRecordSize
+		r := data[imageHeaderSize+packedRecordSize:]
+
+		for pos, value := range fields {
+			if pos == packedMode || pos == packedNameLen || pos == packedModNsec {
+				binary.LittleEndian.PutUint32(r[pos:], uint32(value))
+			} else {
+				binary.LittleEndian.PutUint64(r[pos:], value)
+			}
+		}
+
+		binary.LittleEndian.PutUint64(data[imageHeaderSize+packedDataOff:], 1)
+		binary.LittleEndian.PutUint64(data[imageHeaderSize+packedDataLen:], 1)
+
+		for n, e := range extra {
+			binary.LittleEndian.PutUint64(data[16+8*n:], uint64(len(e)))
+			data = append(data, e...)
+		}
+
+		return data
+	}
+
+	for n, test := range [...]struct {
+		Data []byte
+		Err  error
+	}{
+		{ // 1
+			Err: ErrInvalidImage,
+		},
+		{ // 2
+			Data: []byte("MEMFS\x00\x01\x00"),
+			Err:  ErrInvalidImage,
+		},
+		{ // 3
+			Data: append([]byte("NOTFS\x00"), valid[6:]...),
+			Err:  ErrInvalidImage,
+		},
+		{ // 4
+			Data: append(append([]byte{}, valid[:6]...), append([]byte{2}, valid[7:]...)...),
+			Err:  ErrUnsupportedImage,
+		},
+		{ // 5
+			Data: valid[:imageHeaderSize+packedRecordSize-1],
+			Err:  ErrInvalidImage,
+		},
+		{ // 6
+			Data: append(append([]byte{}, valid...), 0),
+			Err:  ErrInvalidImage,
+		},
+		{ // 7
+			Data: valid,
+		},
+		{ // 8
+			Data: append(append([]byte{}, valid[:imageHeaderSize]...), make([]byte, packedRecordSize)...),
+			Err:  ErrInvalidImage,
+		},
+		{ // 9
+			Data: withRecord(map[int]uint64{packedNameLen: 1}, []byte("a"), []byte("data")),
+		},
+		{ // 10
+			Data: withRecord(map[int]uint64{packedNameLen: 2}, []byte("a"), []byte("data")),
+			Err:  ErrInvalidImage,
+		},
+		{ // 11
+			Data: withRecord(map[int]uint64{packedNameLen: 1, packedNameOff: 1 << 63}, []byte("a"), []byte("data")),
+			Err:  ErrInvalidImage,
+		},
+		{ // 12
+			Data: withRecord(map[int]uint64{packedNameLen: 1, packedDataOff: 2, packedDataLen: 2}, []byte("a"), []byte("data")),
+		},
+		{ // 13
+			Data: withRecord(map[int]uint64{packedNameLen: 1, packedDataOff: 2, packedDataLen: 3}, []byte("a"), []byte("data")),
+			Err:  ErrInvalidImage,
+		},
+		{ // 14
+			Data: withRecord(map[int]uint64{packedNameLen: 1, packedDataOff: 1<<64 - 1, packedDataLen: 2}, []byte("a"), []byte("data")),
+			Err:  ErrInvalidImage,
+		},
+		{ // 15
+			Data: withRecord(map[int]uint64{packedModNsec: 1e9}),
+			Err:  ErrInvalidImage,
+		},
+		{ // 16
+			Data: withRecord(map[int]uint64{packedMode: uint64(fs.ModeDir), packedDataOff: 2}),
+		},
+		{ // 17
+			Data: withRecord(map[int]uint64{packedMode: uint64(fs.ModeDir), packedDataOff: 2, packedDataLen: 1}),
+			Err:  ErrInvalidImage,
+		},
+		{ // 18
+			Data: withRecord(map[int]uint64{packedMode: uint64(fs.ModeDir), packedDataOff: 1, packedDataLen: 1}),
+			Err:  ErrInvalidImage,
+		},
+		{ // 19
+			Data: withRecord(map[int]uint64{packedMode: uint64(fs.ModeDir), packedDataOff: 0, packedDataLen: 1}),
+			Err:  ErrInvalidImage,
+		},
+	} {
+		if _, err := LoadImage(test.Data); err != test.Err {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		}
+	}
+}
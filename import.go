@@ -0,0 +1,129 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"time"
+)
+
+// FromFS creates a new FS, configured with the given Options, containing a copy
+// of the directories, regular files and symlinks of the given fs.FS, skipping
+// any paths matched by the given Ignore, which may be nil. Other types of entry
+// are skipped.
+//
+// Permissions and modification times are copied. Copying a symlink requires
+// that the source has a ReadLink or Readlink method.
+//
+// Files that are hard linked in the source, when it is an FS from this package
+// or, on Unix systems, from the OS, are recreated as hard links.
+//
+// To import a directory from the OS, use os.DirFS.
+func FromFS(src fs.FS, ignore *Ignore, opts ...Option) (*FS, error) {
+	i := importer{
+		FS:    New(opts...),
+		src:   src,
+		links: make(map[any]string),
+	}
+
+	if err := fs.WalkDir(src, ".", ignore.WalkDirFunc(i.add)); err != nil {
+		return nil, err
+	}
+
+	for n := len(i.dirs) - 1; n >= 0; n-- {
+		d := i.dirs[n]
+
+		if err := i.Chtimes(d.path, time.Time{}, d.modtime); err != nil {
+			return nil, err
+		} else if err := i.Chmod(d.path, d.mode); err != nil {
+			return nil, err
+		}
+	}
+
+	return i.FS, nil
+}
+
+type importedDir struct {
+	path    string
+	mode    fs.FileMode
+	modtime time.Time
+}
+
+type importer struct {
+	*FS
+	src   fs.FS
+	dirs  []importedDir
+	links map[any]string
+}
+
+func (i *importer) add(p string, d fs.DirEntry, err error) error {
+	if err != nil {
+		return err
+	}
+
+	fi, err := d.Info()
+	if err != nil {
+		return err
+	}
+
+	switch mode := fi.Mode(); {
+	case mode.IsDir():
+		if p != "." {
+			if err := i.Mkdir(p, fs.ModePerm); err != nil {
+				return err
+			}
+		}
+
+		i.dirs = append(i.dirs, importedDir{path: p, mode: mode.Perm(), modtime: fi.ModTime()})
+
+		return nil
+	case mode&fs.ModeSymlink != 0:
+		target, err := readLink(i.src, p)
+		if err != nil {
+			return err
+		} else if err := i.Symlink(target, p); err != nil {
+			return err
+		}
+
+		return i.Lchtimes(p, time.Time{}, fi.ModTime())
+	case mode.IsRegular():
+		key := fileKey(fi)
+
+		if first, ok := i.links[key]; ok {
+			return i.Link(first, p)
+		} else if key != nil {
+			i.links[key] = p
+		}
+
+		data, err := fs.ReadFile(i.src, p)
+		if err != nil {
+			return err
+		} else if err := i.WriteFile(p, data, mode.Perm()); err != nil {
+			return err
+		}
+
+		return i.Lchtimes(p, time.Time{}, fi.ModTime())
+	}
+
+	return nil
+}
+
+func readLink(src fs.FS, p string) (string, error) {
+	switch src := src.(type) {
+	case interface{ ReadLink(string) (string, error) }:
+		return src.ReadLink(p)
+	case interface{ Readlink(string) (string, error) }:
+		return src.Readlink(p)
+	}
+
+	return "", &fs.PathError{Op: "readlink", Path: p, Err: errors.ErrUnsupported}
+}
+
+// fileKey returns a value identifying the underlying file of a hard linked
+// file, or nil if it cannot be determined.
+func fileKey(fi fs.FileInfo) any {
+	if de, ok := fi.Sys().(directoryEntry); ok {
+		return nodeKey(de)
+	}
+
+	return osFileKey(fi)
+}
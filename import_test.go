@@ -0,0 +1,131 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestFromFS(t *testing.T) {
+	src := New()
+	modtime := time.Unix(1234, 0)
+
+	for _, dir := range [...]string{"a/b", "cache/x", "ro"} {
+		if err := src.MkdirAll(dir, fs.ModePerm); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	for file, contents := range map[string]string{
+		"a/b/file":     "Hello",
+		"a/debug.log":  "log",
+		"cache/x/data": "cached",
+		"ro/file":      "World",
+	} {
+		if err := src.WriteFile(file, []byte(contents), 0o640); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		} else if err := src.Chtimes(file, time.Time{}, modtime); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if err := src.Link("a/b/file", "ro/link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := src.Symlink("a/b", "zlink"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := src.Chmod("ro", 0o555); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := src.Chtimes("ro", time.Time{}, modtime); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ignore, err := NewIgnore("cache/", "*.log")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	f, err := FromFS(src, ignore)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := fstest.TestFS(f, "a/b/file", "ro/file", "zlink"); err != nil {
+		t.Errorf("test 1: %s", err)
+	}
+
+	if ok, _ := f.Exists("cache"); ok {
+		t.Errorf("test 2: expecting ignored directory to not exist")
+	} else if ok, _ := f.Exists("a/debug.log"); ok {
+		t.Errorf("test 3: expecting ignored file to not exist")
+	} else if data, err := f.ReadFile("ro/file"); err != nil || string(data) != "World" {
+		t.Errorf("test 4: expecting contents %q, got %q (err = %v)", "World", data, err)
+	} else if target, err := f.Readlink("zlink"); err != nil || target != "a/b" {
+		t.Errorf("test 5: expecting link target %q, got %q (err = %v)", "a/b", target, err)
+	}
+
+	if a, err := f.Stat("a/b/file"); err != nil {
+		t.Errorf("test 6: unexpected error: %s", err)
+	} else if b, err := f.Stat("ro/link"); err != nil {
+		t.Errorf("test 6: unexpected error: %s", err)
+	} else if a.Sys() != b.Sys() {
+		t.Errorf("test 6: expecting hard linked files to share an inode")
+	}
+
+	for p, expected := range map[string][2]any{
+		"ro":       {fs.ModeDir | 0o555, modtime},
+		"ro/file":  {fs.FileMode(0o640), modtime},
+		"a/b/file": {fs.FileMode(0o640), modtime},
+	} {
+		if fi, err := f.Stat(p); err != nil {
+			t.Errorf("test 7: unexpected error for %s: %s", p, err)
+		} else if got := [2]any{fi.Mode(), fi.ModTime()}; !reflect.DeepEqual(got, expected) {
+			t.Errorf("test 7: expecting mode and time %v for %s, got %v", expected, p, got)
+		}
+	}
+
+	if _, err := FromFS(struct{ fs.FS }{fstest.MapFS{"link": {Mode: fs.ModeSymlink}}}, nil); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("test 8: expecting unsupported error, got %v", err)
+	}
+}
+
+func TestFromFSOSLinks(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		t.Skip("hard links cannot be detected on " + runtime.GOOS)
+	}
+
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "a"), []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := os.Link(filepath.Join(dir, "a"), filepath.Join(dir, "b")); err != nil {
+		t.Skipf("cannot create hard link: %s", err)
+	} else if err := os.WriteFile(filepath.Join(dir, "c"), []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	f, err := FromFS(os.DirFS(dir), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	stat := func(p string) any {
+		fi, err := f.Stat(p)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		return fi.Sys()
+	}
+
+	if stat("a") != stat("b") {
+		t.Errorf("expecting hard linked files to share an inode")
+	} else if stat("a") == stat("c") {
+		t.Errorf("expecting separate files to not share an inode")
+	}
+}
@@ -0,0 +1,228 @@
+// Command normgen generates the tables used for the canonical decomposition
+// of names from the UnicodeData.txt file of the Unicode Character Database.
+//
+// Usage:
+//
+//	normgen [-version 14.0.0] [-ucd UnicodeData.txt] [-o normtables.go]
+//
+// When no file is given with -ucd, UnicodeData.txt is downloaded from
+// unicode.org for the given version.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	hangulBase  = 0xAC00
+	hangulCount = 11172
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var version, ucd, output string
+
+	flag.StringVar(&version, "version", "14.0.0", "version of the Unicode Character Database")
+	flag.StringVar(&ucd, "ucd", "", "path to UnicodeData.txt, downloaded when not given")
+	flag.StringVar(&output, "o", "normtables.go", "file to write")
+	flag.Parse()
+
+	r, err := openUCD(version, ucd)
+	if err != nil {
+		return err
+	}
+
+	defer r.Close()
+
+	decomps, classes, err := parse(r)
+	if err != nil {
+		return err
+	}
+
+	src, err := format.Source(generate(version, decomps, classes))
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(output, src, 0o644)
+}
+
+func openUCD(version, path string) (io.ReadCloser, error) {
+	if path != "" {
+		return os.Open(path)
+	}
+
+	resp, err := http.Get("https://www.unicode.org/Public/" + version + "/ucd/UnicodeData.txt")
+	if err != nil {
+		return nil, err
+	} else if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+
+		return nil, fmt.Errorf("error downloading UnicodeData.txt: %s", resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// parse reads the canonical decomposition mappings, and the non-zero canonical
+// combining classes, from UnicodeData.txt.
+func parse(r io.Reader) (map[rune][]rune, map[rune]uint8, error) {
+	var (
+		decomps = make(map[rune][]rune)
+		classes = make(map[rune]uint8)
+		s       = bufio.NewScanner(r)
+	)
+
+	for s.Scan() {
+		fields := strings.Split(s.Text(), ";")
+		if len(fields) < 6 {
+			continue
+		}
+
+		code, err := parseRune(fields[0])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		class, err := strconv.ParseUint(fields[3], 10, 8)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid combining class for %04X: %w", code, err)
+		} else if class != 0 {
+			classes[code] = uint8(class)
+		}
+
+		if fields[5] == "" || strings.HasPrefix(fields[5], "<") {
+			continue
+		}
+
+		var decomp []rune
+
+		for _, c := range strings.Fields(fields[5]) {
+			r, err := parseRune(c)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			decomp = append(decomp, r)
+		}
+
+		decomps[code] = decomp
+	}
+
+	return decomps, classes, s.Err()
+}
+
+func parseRune(s string) (rune, error) {
+	r, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid code point %q: %w", s, err)
+	}
+
+	return rune(r), nil
+}
+
+// fullDecomposition recursively applies the canonical decomposition mappings.
+func fullDecomposition(decomps map[rune][]rune, r rune) []rune {
+	d, ok := decomps[r]
+	if !ok {
+		return []rune{r}
+	}
+
+	var full []rune
+
+	for _, c := range d {
+		full = append(full, fullDecomposition(decomps, c)...)
+	}
+
+	return full
+}
+
+func generate(version string, decomps map[rune][]rune, classes map[rune]uint8) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "// Code generated from the Unicode Character Database, version %s. DO NOT EDIT.\n\n", version)
+	buf.WriteString("package memfs\n\n")
+	buf.WriteString("// decompositions contains the full canonical decomposition of each character\n")
+	buf.WriteString("// that has one, other than the Hangul syllables, which are decomposed\n")
+	buf.WriteString("// algorithmically.\n")
+	buf.WriteString("var decompositions = map[rune]string{")
+
+	codes := make([]rune, 0, len(decomps))
+
+	for r := range decomps {
+		if r < hangulBase || r >= hangulBase+hangulCount {
+			codes = append(codes, r)
+		}
+	}
+
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+
+	for n, r := range codes {
+		if n%4 == 0 {
+			buf.WriteString("\n\t")
+		} else {
+			buf.WriteString(" ")
+		}
+
+		fmt.Fprintf(&buf, "0x%04X: \"", r)
+
+		for _, c := range fullDecomposition(decomps, r) {
+			if c > 0xFFFF {
+				fmt.Fprintf(&buf, "\\U%08X", c)
+			} else {
+				fmt.Fprintf(&buf, "\\u%04X", c)
+			}
+		}
+
+		buf.WriteString("\",")
+	}
+
+	buf.WriteString("\n}\n\n")
+	buf.WriteString("// combiningClasses contains the ranges of characters with a non-zero canonical\n")
+	buf.WriteString("// combining class, sorted.\n")
+	buf.WriteString("var combiningClasses = [...]combiningClass{")
+
+	codes = codes[:0]
+
+	for r := range classes {
+		codes = append(codes, r)
+	}
+
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+
+	for n := 0; len(codes) > 0; n++ {
+		first, last := codes[0], codes[0]
+
+		for codes = codes[1:]; len(codes) > 0 && codes[0] == last+1 && classes[codes[0]] == classes[first]; codes = codes[1:] {
+			last = codes[0]
+		}
+
+		if n%4 == 0 {
+			buf.WriteString("\n\t")
+		} else {
+			buf.WriteString(" ")
+		}
+
+		fmt.Fprintf(&buf, "{0x%04X, 0x%04X, %d},", first, last, classes[first])
+	}
+
+	buf.WriteString("\n}\n")
+
+	return buf.Bytes()
+}
@@ -0,0 +1,184 @@
+package memfs
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// Event is a record of a single change made to an FS, as written by an FS
+// created with the WithJournal Option.
+type Event struct {
+	// Op is the name of the method that made the change, in lowercase, or
+	// one of "write" or "truncate" for changes made to an open File.
+	Op string
+
+	// Path is the path of the changed entry, relative to the root of the
+	// journalled FS, or the first path given to Link, or to any of the
+	// Rename methods.
+	Path string
+
+	// NewPath is the second path given to Link, or to any of the Rename
+	// methods.
+	NewPath string `json:",omitempty"`
+
+	// Mode is the permissions of a created entry, or the new mode of an
+	// entry given to Chmod.
+	Mode fs.FileMode `json:",omitzero"`
+
+	// Flags is the Mode used to open a file.
+	Flags Mode `json:",omitzero"`
+
+	// Time is the modification time given to Chtimes or Lchtimes.
+	Time time.Time `json:",omitzero"`
+
+	// Offset is the position at which Data was written, or the new size of
+	// a truncated file.
+	Offset int64 `json:",omitzero"`
+
+	// Data is the data that was written, or the target of a Symlink.
+	Data []byte `json:",omitempty"`
+}
+
+// WithJournal sets an io.Writer that will be sent a record of every change
+// made to the FS, and to any FS created from it with Sub, in the order in which
+// they were made. Each change is written as a JSON encoded Event, followed by
+// a newline, and can be applied to another FS with Replay.
+//
+// Records are written while the changed entry is locked, so the Writer should
+// not block for long, and must not use the FS. If a write fails, no further
+// records are written.
+//
+// Writes to a File are recorded with the path at which the file is found at
+// the time of the write, following any renames, and are not recorded once that
+// path has been removed.
+func WithJournal(w io.Writer) Option {
+	return func(f *FS) {
+		f.journal = &journal{enc: json.NewEncoder(w)}
+		f.root = "."
+
+		if f.opens == nil {
+			f.opens = new(openPaths)
+		}
+	}
+}
+
+type journal struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+func (j *journal) record(e Event) {
+	if j == nil {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.err == nil {
+		j.err = j.enc.Encode(e)
+	}
+}
+
+// record writes the given Event to the journal, if there is one, after making
+// its paths relative to the root of the journalled FS.
+func (f *FS) record(e Event) {
+	if f.journal == nil {
+		return
+	}
+
+	e.Path = f.rootPath(e.Path)
+
+	if e.NewPath != "" {
+		e.NewPath = f.rootPath(e.NewPath)
+	}
+
+	f.journal.record(e)
+}
+
+// Replay reads a journal, as written by an FS created with the WithJournal
+// Option, from the given io.Reader and applies each of the recorded changes
+// to the FS, stopping at the first error.
+func (f *FS) Replay(r io.Reader) error {
+	dec := json.NewDecoder(r)
+
+	for {
+		var e Event
+
+		if err := dec.Decode(&e); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		} else if err := f.apply(e); err != nil {
+			return err
+		}
+	}
+}
+
+func (f *FS) apply(e Event) error {
+	switch e.Op {
+	case "mkdir":
+		return f.Mkdir(e.Path, e.Mode)
+	case "mkfifo":
+		return f.Mkfifo(e.Path, e.Mode)
+	case "openfile":
+		of, err := f.OpenFile(e.Path, e.Flags, e.Mode)
+		if err != nil {
+			return err
+		}
+
+		return of.Close()
+	case "writefile":
+		return f.WriteFile(e.Path, e.Data, e.Mode)
+	case "appendfile":
+		return f.AppendFile(e.Path, e.Data, e.Mode)
+	case "touch":
+		return f.Touch(e.Path)
+	case "link":
+		return f.Link(e.Path, e.NewPath)
+	case "symlink":
+		return f.Symlink(string(e.Data), e.Path)
+	case "rename":
+		return f.Rename(e.Path, e.NewPath)
+	case "renamenoreplace":
+		return f.RenameNoReplace(e.Path, e.NewPath)
+	case "renameexchange":
+		return f.RenameExchange(e.Path, e.NewPath)
+	case "remove":
+		return f.Remove(e.Path)
+	case "removeall":
+		return f.RemoveAll(e.Path)
+	case "chmod":
+		return f.Chmod(e.Path, e.Mode)
+	case "chtimes":
+		return f.Chtimes(e.Path, time.Time{}, e.Time)
+	case "lchtimes":
+		return f.Lchtimes(e.Path, time.Time{}, e.Time)
+	case "write", "truncate":
+		of, err := f.OpenFile(e.Path, WriteOnly, 0)
+		if err != nil {
+			return err
+		}
+
+		if e.Op == "write" {
+			_, err = of.WriteAt(e.Data, e.Offset)
+		} else {
+			err = of.Truncate(e.Offset)
+		}
+
+		if err != nil {
+			of.Close()
+
+			return &fs.PathError{Op: e.Op, Path: e.Path, Err: err}
+		}
+
+		return of.Close()
+	}
+
+	return &fs.PathError{Op: "replay", Path: e.Path, Err: errors.ErrUnsupported}
+}
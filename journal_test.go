@@ -0,0 +1,301 @@
+package memfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func journalTree(t *testing.T, f *FS) map[string]string {
+	t.Helper()
+
+	tree := make(map[string]string)
+
+	if err := fs.WalkDir(f, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		fi, err := f.LStat(p)
+		if err != nil {
+			return err
+		}
+
+		entry := fi.Mode().String()
+
+		switch {
+		case fi.Mode()&fs.ModeSymlink != 0:
+			target, err := f.Readlink(p)
+			if err != nil {
+				return err
+			}
+
+			entry += " -> " + target
+		case fi.Mode().IsRegular():
+			data, err := f.ReadFile(p)
+			if err != nil {
+				return err
+			}
+
+			entry += " " + string(data)
+		}
+
+		if fi.ModTime().Equal(time.Unix(1, 0)) {
+			entry += " @1"
+		}
+
+		tree[p] = entry
+
+		return nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	return tree
+}
+
+func TestJournal(t *testing.T) {
+	var buf bytes.Buffer
+
+	f := New(WithJournal(&buf))
+
+	if err := f.MkdirAll("a/b", 0o755); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if err := f.WriteFile("a/file", []byte("Hello"), 0o644); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if err := f.WriteFile("a/file", []byte("Hello, World"), 0o644); err != nil {
+		t.Fatalf("test 3: unexpected error: %s", err)
+	}
+
+	sub, err := f.Sub("a/b")
+	if err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	}
+
+	of, err := sub.(*FS).Create("created")
+	if err != nil {
+		t.Fatalf("test 5: unexpected error: %s", err)
+	} else if _, err := of.WriteString("abcdef"); err != nil {
+		t.Fatalf("test 6: unexpected error: %s", err)
+	} else if _, err := of.WriteAt([]byte("XY"), 1); err != nil {
+		t.Fatalf("test 7: unexpected error: %s", err)
+	} else if err := of.WriteByte('!'); err != nil {
+		t.Fatalf("test 8: unexpected error: %s", err)
+	} else if err := of.Truncate(5); err != nil {
+		t.Fatalf("test 9: unexpected error: %s", err)
+	} else if _, err := of.ReadFrom(strings.NewReader("123")); err != nil {
+		t.Fatalf("test 10: unexpected error: %s", err)
+	} else if err := of.Close(); err != nil {
+		t.Fatalf("test 11: unexpected error: %s", err)
+	}
+
+	if err := f.Symlink("b/created", "a/link"); err != nil {
+		t.Fatalf("test 12: unexpected error: %s", err)
+	} else if err := f.Link("a/file", "hard"); err != nil {
+		t.Fatalf("test 13: unexpected error: %s", err)
+	} else if err := f.Rename("a/file", "a/b/renamed"); err != nil {
+		t.Fatalf("test 14: unexpected error: %s", err)
+	} else if err := f.Touch("a/touched"); err != nil {
+		t.Fatalf("test 15: unexpected error: %s", err)
+	} else if err := f.Remove("a/touched"); err != nil {
+		t.Fatalf("test 16: unexpected error: %s", err)
+	} else if err := f.Chmod("hard", 0o600); err != nil {
+		t.Fatalf("test 17: unexpected error: %s", err)
+	} else if err := f.Chtimes("a/b", time.Time{}, time.Unix(1, 0)); err != nil {
+		t.Fatalf("test 18: unexpected error: %s", err)
+	}
+
+	var first Event
+
+	if err := json.NewDecoder(bytes.NewReader(buf.Bytes())).Decode(&first); err != nil {
+		t.Fatalf("test 19: unexpected error: %s", err)
+	} else if expected := (Event{Op: "mkdir", Path: "a", Mode: 0o755}); !reflect.DeepEqual(first, expected) {
+		t.Errorf("test 19: expecting first event %v, got %v", expected, first)
+	}
+
+	mirror := New()
+
+	if err := mirror.Replay(&buf); err != nil {
+		t.Fatalf("test 20: unexpected error: %s", err)
+	}
+
+	expected := map[string]string{
+		".":           "drwxrwxrwx",
+		"a":           "drwxr-xr-x",
+		"a/b":         "drwxr-xr-x @1",
+		"a/b/created": "-rw-rw-rw- aXYde\x00\x00123",
+		"a/b/renamed": "-rw------- Hello, World",
+		"a/link":      "Lrwxrwxrwx -> b/created",
+		"hard":        "-rw------- Hello, World",
+	}
+
+	if tree := journalTree(t, f); !reflect.DeepEqual(tree, expected) {
+		t.Errorf("test 21: expecting tree %v, got %v", expected, tree)
+	} else if tree = journalTree(t, mirror); !reflect.DeepEqual(tree, expected) {
+		t.Errorf("test 22: expecting mirrored tree %v, got %v", expected, tree)
+	}
+}
+
+func TestJournalPaths(t *testing.T) {
+	var buf bytes.Buffer
+
+	f := New(WithJournal(&buf))
+
+	if err := f.MkdirAll("a/b", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Touch("a/file"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Rename("a/file", "a/b/file"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Link("a/b/file", "a/link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("b/file", "a/symlink"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.RenameExchange("a/link", "a/symlink"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Remove("a/link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var events []Event
+
+	for dec := json.NewDecoder(&buf); dec.More(); {
+		var e Event
+
+		if err := dec.Decode(&e); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		events = append(events, e)
+	}
+
+	expected := []Event{
+		{Op: "mkdir", Path: "a", Mode: 0o755},
+		{Op: "mkdir", Path: "a/b", Mode: 0o755},
+		{Op: "touch", Path: "a/file"},
+		{Op: "rename", Path: "a/file", NewPath: "a/b/file"},
+		{Op: "link", Path: "a/b/file", NewPath: "a/link"},
+		{Op: "symlink", Path: "a/symlink", Data: []byte("b/file")},
+		{Op: "renameexchange", Path: "a/link", NewPath: "a/symlink"},
+		{Op: "remove", Path: "a/link"},
+	}
+
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("expecting events %v, got %v", expected, events)
+	}
+}
+
+func TestJournalOpenFile(t *testing.T) {
+	var buf bytes.Buffer
+
+	f := New(WithJournal(&buf))
+
+	if err := f.Mkdir("dir", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("dir", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	a, err := f.Create("a")
+	if err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if _, err := a.WriteString("Hello"); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if err := f.Rename("a", "b"); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if _, err := a.WriteString(", World"); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	}
+
+	c, err := f.Create("link/c")
+	if err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if err := f.Rename("dir", "moved"); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if _, err := c.WriteString("moved"); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if err := f.Remove("moved/c"); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if _, err := c.WriteString(" and removed"); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if err := c.Truncate(0); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	}
+
+	a.Close()
+	c.Close()
+
+	mirror := New()
+
+	if err := mirror.Replay(&buf); err != nil {
+		t.Fatalf("test 3: unexpected error: %s", err)
+	}
+
+	expected := map[string]string{
+		".":     "drwxrwxrwx",
+		"b":     "-rw-rw-rw- Hello, World",
+		"link":  "Lrwxrwxrwx -> dir",
+		"moved": "drwxr-xr-x",
+	}
+
+	if tree := journalTree(t, f); !reflect.DeepEqual(tree, expected) {
+		t.Errorf("test 4: expecting tree %v, got %v", expected, tree)
+	} else if tree = journalTree(t, mirror); !reflect.DeepEqual(tree, expected) {
+		t.Errorf("test 5: expecting mirrored tree %v, got %v", expected, tree)
+	}
+}
+
+func TestJournalSparse(t *testing.T) {
+	var buf bytes.Buffer
+
+	f := New(WithJournal(&buf))
+
+	if err := f.CreateSparse("sparse", 8); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if err := f.CreateLazy("lazy", 4, new(patternFill).fill); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	}
+
+	of, err := f.OpenFile("sparse", WriteOnly, 0)
+	if err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if _, err := of.WriteAt([]byte("ab"), 2); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if err := of.Truncate(6); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	}
+
+	of.Close()
+
+	if of, err = f.OpenFile("lazy", WriteOnly, 0); err != nil {
+		t.Fatalf("test 3: unexpected error: %s", err)
+	} else if _, err := of.WriteAt([]byte("c"), 1); err != nil {
+		t.Fatalf("test 3: unexpected error: %s", err)
+	}
+
+	of.Close()
+
+	mirror := New()
+
+	if err := mirror.Replay(&buf); err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	}
+
+	expected := map[string]string{
+		".":      "drwxrwxrwx",
+		"lazy":   "-rw-rw-rw- \x00c\x00\x00",
+		"sparse": "-rw-rw-rw- \x00\x00ab\x00\x00",
+	}
+
+	if tree := journalTree(t, mirror); !reflect.DeepEqual(tree, expected) {
+		t.Errorf("test 5: expecting mirrored tree %q, got %q", expected, tree)
+	} else if data, err := f.ReadFile("sparse"); err != nil {
+		t.Fatalf("test 6: unexpected error: %s", err)
+	} else if string(data) != "\x00\x00ab\x00\x00" {
+		t.Errorf("test 6: expecting contents %q, got %q", "\x00\x00ab\x00\x00", data)
+	}
+}
@@ -0,0 +1,156 @@
+package memfs
+
+import (
+	"io/fs"
+	"slices"
+	"strings"
+)
+
+// Layer creates a new FS, configured with the given Options, as a writable
+// layer over the given base, which must be an FSRO created with Seal,
+// SealCompact, SealSubtree, or Snapshot.
+//
+// Only the directory structure of the base is copied, with the data of each
+// file being shared with the base until the file is first modified, at which
+// point it is given its own copy. As such, many layers can be cheaply created
+// from a single base, such as one for each test of a parallel test suite, with
+// the changes made to each being isolated from the base, and from each other.
+//
+// Files that are hard linked in the base remain linked in the layer.
+func Layer(base FSRO, opts ...Option) (*FS, error) {
+	b, ok := base.(*fsRO)
+	if !ok {
+		return nil, &fs.PathError{Op: "layer", Path: ".", Err: fs.ErrInvalid}
+	}
+
+	f := &FS{
+		fsRO: fsRO{
+			resolveConfig: b.resolveConfig,
+		},
+		config: config{
+			tree: new(treeLock),
+		},
+	}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	l := layerer{
+		sorted:     f.deterministic != nil,
+		size:       f.dirSize,
+		maxEntries: f.maxEntries,
+		files:      make(map[any]*inodeRW),
+		lazy:       make(map[*lazyNode]*lazyNode),
+	}
+
+	f.de = l.dir(b.de)
+
+	return f, nil
+}
+
+type layerer struct {
+	sorted     bool
+	size       DirSizeFunc
+	maxEntries int
+	files      map[any]*inodeRW
+	lazy       map[*lazyNode]*lazyNode
+}
+
+func (l *layerer) dir(de directoryEntry) *dnodeRW {
+	entries := childEntries(de)
+	d := &dnodeRW{
+		dnode: dnode{
+			entries:    make([]*dirEnt, len(entries)),
+			modtime:    de.ModTime(),
+			ctime:      de.ChangeTime(),
+			mode:       de.Mode(),
+			acl:        de.getACL(),
+			size:       l.size,
+			maxEntries: l.maxEntries,
+		},
+	}
+
+	for n, e := range entries {
+		d.entries[n] = &dirEnt{
+			directoryEntry: l.entry(e.directoryEntry),
+			name:           e.name,
+		}
+
+		addParent(d.entries[n].directoryEntry, d)
+	}
+
+	d.subtree.Store(d.dnode.Generation())
+
+	if l.sorted {
+		slices.SortFunc(d.entries, func(a, b *dirEnt) int {
+			return strings.Compare(a.name, b.name)
+		})
+	}
+
+	return d
+}
+
+func (l *layerer) entry(de directoryEntry) directoryEntry {
+	mode := de.Mode()
+
+	switch {
+	case mode.IsDir():
+		return l.dir(de)
+	case mode&fs.ModeNamedPipe != 0:
+		return &fifo{
+			modtime: de.ModTime(),
+			ctime:   de.ChangeTime(),
+			mode:    mode,
+			acl:     de.getACL(),
+		}
+	}
+
+	if base, ok := de.(*lazyNode); ok {
+		if l, ok := l.lazy[base]; ok {
+			l.links++
+
+			return l
+		}
+
+		lazy := base.share(false)
+		lazy.links = 1
+		l.lazy[base] = lazy
+
+		return lazy
+	}
+
+	key := nodeKey(de)
+
+	if i, ok := l.files[key]; ok {
+		i.links++
+
+		return i
+	}
+
+	i := &inodeRW{
+		inode: inode{
+			modtime: de.ModTime(),
+			ctime:   de.ChangeTime(),
+			mode:    mode,
+			links:   1,
+			shared:  true,
+		},
+	}
+
+	if base, ok := de.(*inode); ok && base.meta != nil {
+		meta := *base.meta
+		meta.acl = slices.Clone(meta.acl)
+		i.meta = &meta
+	}
+
+	withData(de, func(data []byte) error {
+		i.data = data[:len(data):len(data)]
+
+		return nil
+	})
+
+	l.files[key] = i
+
+	return i
+}
@@ -0,0 +1,104 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+type freeCounter struct {
+	frees int
+}
+
+func (f *freeCounter) Alloc(size, capacity int) []byte {
+	return make([]byte, size, capacity)
+}
+
+func (f *freeCounter) Free(_ []byte) {
+	f.frees++
+}
+
+func TestLayer(t *testing.T) {
+	f := New()
+
+	if err := f.Mkdir("dir", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("file", []byte("base"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("dir/other", []byte("other"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Link("file", "dir/link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("../file", "dir/symlink"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := Layer(f); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("test 1: expecting error %v, got %v", fs.ErrInvalid, err)
+	}
+
+	for n, base := range [...]FSRO{sealCompact(t, f), f.Seal()} {
+		var alloc freeCounter
+
+		a, err := Layer(base, WithAllocator(&alloc))
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+2, err)
+		}
+
+		b, err := Layer(base)
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+2, err)
+		}
+
+		if err := a.WriteFile("file", []byte("changed"), 0o644); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+2, err)
+		} else if data, err := a.ReadFile("dir/link"); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+2, err)
+		} else if string(data) != "changed" {
+			t.Errorf("test %d: expecting hard link to read %q, got %q", n+2, "changed", data)
+		} else if data, err := a.ReadFile("dir/symlink"); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+2, err)
+		} else if string(data) != "changed" {
+			t.Errorf("test %d: expecting symlink to read %q, got %q", n+2, "changed", data)
+		} else if data, err := b.ReadFile("file"); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+2, err)
+		} else if string(data) != "base" {
+			t.Errorf("test %d: expecting other layer to read %q, got %q", n+2, "base", data)
+		} else if data, err := base.ReadFile("file"); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+2, err)
+		} else if string(data) != "base" {
+			t.Errorf("test %d: expecting base to read %q, got %q", n+2, "base", data)
+		}
+
+		of, err := b.OpenFile("dir/other", ReadWrite, 0)
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+2, err)
+		}
+
+		if _, err := of.WriteAt([]byte("O"), 0); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+2, err)
+		} else if err := of.Truncate(2); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+2, err)
+		} else if err := of.Truncate(4); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+2, err)
+		} else if err := of.Close(); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+2, err)
+		} else if data, err := b.ReadFile("dir/other"); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+2, err)
+		} else if string(data) != "Ot\x00\x00" {
+			t.Errorf("test %d: expecting contents %q, got %q", n+2, "Ot\x00\x00", data)
+		} else if data, err := base.ReadFile("dir/other"); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+2, err)
+		} else if string(data) != "other" {
+			t.Errorf("test %d: expecting base to read %q, got %q", n+2, "other", data)
+		} else if err := a.Remove("dir/other"); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+2, err)
+		} else if alloc.frees != 0 {
+			t.Errorf("test %d: expecting shared data to not be freed, got %d frees", n+2, alloc.frees)
+		} else if fi, err := b.Stat("dir/other"); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+2, err)
+		} else if fi.Mode() != 0o600 {
+			t.Errorf("test %d: expecting mode %s, got %s", n+2, fs.FileMode(0o600), fi.Mode())
+		}
+	}
+}
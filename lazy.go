@@ -0,0 +1,792 @@
+package memfs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"math"
+	"slices"
+	"sync"
+	"unicode/utf8"
+)
+
+// LazyFunc is used to produce the contents of a file created with CreateLazy,
+// filling p with the contents starting at the given offset, in the manner of
+// io.ReaderAt.
+type LazyFunc func(p []byte, off int64) (int, error)
+
+// CreateLazy creates a new file, with the permissions set with
+// WithDefaultPerms, or 0o666 if none were set, at the given path, which must
+// not already exist, with the given size, and whose contents are produced by
+// the given func as they are first read.
+//
+// The contents are produced, and then cached, in regions of 64KiB, such that
+// only those regions that are read take any memory, allowing for large
+// synthetic files to be created cheaply.
+//
+// A lazy file can be opened, written, renamed, linked, removed, or have its
+// metadata changed, as any other file. Each region of the file is copied when
+// it is first written, with the data already in the region counting as
+// written for any limit set with WithWriteLimit, such that only the regions
+// that are written take any memory of their own.
+//
+// Operations that require all of the contents, such as ReadFile, WriteTar,
+// and SealCompact, produce, and cache, all of the contents, returning
+// ErrFileTooLarge when the file is larger than can be allocated.
+//
+// The creation of a lazy file is recorded by the journal as the creation of an
+// empty file that is then truncated to the given size, such that the contents
+// produced by the func are not recorded, and are replayed as zeros.
+func (f *FS) CreateLazy(path string, size int64, fill LazyFunc) (err error) {
+	defer f.logOp("createlazy", path).end(&err)
+
+	if fill == nil {
+		return &fs.PathError{Op: "createlazy", Path: path, Err: fs.ErrInvalid}
+	}
+
+	return f.createLazy("createlazy", path, size, fill)
+}
+
+// CreateSparse creates a new file, in the same way as CreateLazy, of the given
+// size, whose contents are all zeros.
+//
+// Reading the file takes no memory, allowing for code that checks the sizes
+// of files, or that seeks around large files, to be tested cheaply, with the
+// file only taking memory for each region of 64KiB that is written.
+func (f *FS) CreateSparse(path string, size int64) (err error) {
+	defer f.logOp("createsparse", path).end(&err)
+
+	return f.createLazy("createsparse", path, size, nil)
+}
+
+func (f *FS) createLazy(op, path string, size int64, fill LazyFunc) error {
+	f.throttle.op()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.limit.op(); err != nil {
+		return &fs.PathError{Op: op, Path: path, Err: err}
+	} else if size < 0 {
+		return &fs.PathError{Op: op, Path: path, Err: fs.ErrInvalid}
+	}
+
+	d, _, err := f.getEntryWithParent(path, mustNotExist)
+	if err != nil {
+		return &fs.PathError{Op: op, Path: path, Err: err}
+	}
+
+	now := f.deterministic.now()
+	perm := f.defaultPerms()
+
+	if err := d.setEntry(&dirEnt{
+		directoryEntry: &lazyNode{
+			inodeRW: inodeRW{
+				inode: inode{
+					modtime: now,
+					ctime:   now,
+					mode:    perm,
+					links:   1,
+				},
+			},
+			lazy: &lazyData{
+				size: size,
+				fill: fill,
+			},
+			size: size,
+			base: size,
+		},
+		name: entryName(path),
+	}, f.deterministic); err != nil {
+		return &fs.PathError{Op: op, Path: path, Err: err}
+	}
+
+	f.record(Event{Op: "openfile", Path: path, Mode: perm, Flags: WriteOnly | Create | Excl})
+	f.record(Event{Op: "truncate", Path: path, Offset: size})
+
+	return f.writeThrough(op, path)
+}
+
+const lazyChunk = 1 << 16
+
+// minInt64 returns the smaller of the given values.
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// zeroChunk is the region returned for the contents of a sparse file, which
+// must never be modified.
+var zeroChunk = make([]byte, lazyChunk)
+
+// lazyData holds the contents of a lazy file, which can be shared between
+// the nodes of an FS, and of any snapshot or layer made from it.
+//
+// A nil fill func produces zeros, which are not cached.
+type lazyData struct {
+	mu     sync.Mutex
+	size   int64
+	fill   LazyFunc
+	chunks map[int64][]byte
+}
+
+// readAt reads the contents at the given offset into p, producing any regions
+// of the contents that have not yet been read.
+func (l *lazyData) readAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fs.ErrInvalid
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var n int
+
+	for n < len(p) && off < l.size {
+		chunk, err := l.chunk(off / lazyChunk)
+		if err != nil {
+			return n, err
+		}
+
+		m := copy(p[n:], chunk[off%lazyChunk:])
+		n += m
+		off += int64(m)
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// chunk returns the given region of the contents, producing it if it has not
+// yet been read; must be called with the lock held.
+func (l *lazyData) chunk(n int64) ([]byte, error) {
+	off := n * lazyChunk
+	size := minInt64(lazyChunk, l.size-off)
+
+	if l.fill == nil {
+		return zeroChunk[:size], nil
+	} else if chunk, ok := l.chunks[n]; ok {
+		return chunk, nil
+	}
+
+	chunk := make([]byte, size)
+
+	for filled := 0; filled < len(chunk); {
+		m, err := l.fill(chunk[filled:], off+int64(filled))
+
+		if filled += m; filled == len(chunk) {
+			break
+		} else if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		} else if err != nil {
+			return nil, err
+		} else if m == 0 {
+			return nil, io.ErrNoProgress
+		}
+	}
+
+	if l.chunks == nil {
+		l.chunks = make(map[int64][]byte)
+	}
+
+	l.chunks[n] = chunk
+
+	return chunk, nil
+}
+
+// lazyNode is a file whose contents are read from a lazyData, except for those
+// regions that have been written, which are held by the node.
+type lazyNode struct {
+	inodeRW
+	lazy *lazyData
+	size int64
+
+	// base is the size below which the contents not yet written are read
+	// from the lazyData, with any beyond being zeros, as after the file is
+	// truncated.
+	base int64
+
+	// chunks holds the regions that have been written, each of lazyChunk
+	// bytes, with any bytes beyond the size of the file being zero, and
+	// which are shared with another node when shared is set.
+	chunks map[int64][]byte
+}
+
+// share returns a new node, with the same metadata, that shares the contents
+// of the current node, with each node copying the regions that have been
+// written before writing to them again.
+func (l *lazyNode) share(sealed bool) *lazyNode {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c := &lazyNode{
+		inodeRW: inodeRW{
+			inode: inode{
+				modtime: l.modtime,
+				ctime:   l.ctime,
+				mode:    l.mode,
+				sealed:  sealed,
+				gen:     l.gen,
+				links:   l.links,
+				shared:  true,
+			},
+		},
+		lazy:   l.lazy,
+		size:   l.size,
+		base:   l.base,
+		chunks: l.chunks,
+	}
+
+	if l.meta != nil {
+		meta := *l.meta
+		meta.acl = slices.Clone(meta.acl)
+		c.meta = &meta
+	}
+
+	l.shared = true
+
+	return c
+}
+
+// readAt reads the contents at the given offset into p; must be called with
+// the lock held.
+func (l *lazyNode) readAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fs.ErrInvalid
+	}
+
+	var n int
+
+	for n < len(p) && off < l.size {
+		m := int(minInt64(minInt64(int64(len(p)-n), lazyChunk-off%lazyChunk), l.size-off))
+
+		if err := l.readChunk(p[n:n+m], off); err != nil {
+			return n, err
+		}
+
+		n += m
+		off += int64(m)
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// readChunk fills p, which must not extend beyond the region containing the
+// given offset, with the contents at that offset.
+func (l *lazyNode) readChunk(p []byte, off int64) error {
+	if chunk, ok := l.chunks[off/lazyChunk]; ok {
+		copy(p, chunk[off%lazyChunk:])
+
+		return nil
+	}
+
+	var fromBase int
+
+	if off < l.base {
+		fromBase = int(minInt64(int64(len(p)), l.base-off))
+
+		if _, err := l.lazy.readAt(p[:fromBase], off); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+
+	beyond := p[fromBase:]
+
+	for n := range beyond {
+		beyond[n] = 0
+	}
+
+	return nil
+}
+
+// writeAt writes p at the given offset, copying each region that has not
+// already been written, and charging the data copied to the given limit; must
+// be called with the lock held.
+func (l *lazyNode) writeAt(p []byte, off int64, limit *writeLimit) (int, error) {
+	l.unshare()
+
+	var n int
+
+	for n < len(p) {
+		chunk, err := l.chunk(off/lazyChunk, limit)
+		if err != nil {
+			return n, err
+		}
+
+		m := copy(chunk[off%lazyChunk:], p[n:])
+		n += m
+		off += int64(m)
+
+		if off > l.size {
+			l.size = off
+		}
+	}
+
+	return n, nil
+}
+
+// chunk returns the given written region, copying it from the contents of
+// the file if it has not already been written.
+func (l *lazyNode) chunk(n int64, limit *writeLimit) ([]byte, error) {
+	if chunk, ok := l.chunks[n]; ok {
+		return chunk, nil
+	}
+
+	existing := int(minInt64(l.size-n*lazyChunk, lazyChunk))
+
+	if existing < 0 {
+		existing = 0
+	}
+
+	if _, err := limit.charge(0, existing); err != nil {
+		return nil, err
+	}
+
+	chunk := make([]byte, lazyChunk)
+
+	if err := l.readChunk(chunk[:existing], n*lazyChunk); err != nil {
+		return nil, err
+	}
+
+	if l.chunks == nil {
+		l.chunks = make(map[int64][]byte)
+	}
+
+	l.chunks[n] = chunk
+
+	return chunk, nil
+}
+
+// truncate changes the size of the file, discarding any written regions
+// beyond the new size; must be called with the lock held.
+func (l *lazyNode) truncate(size int64) {
+	if size < l.size {
+		l.unshare()
+
+		for n, chunk := range l.chunks {
+			if n*lazyChunk >= size {
+				delete(l.chunks, n)
+			} else if n == size/lazyChunk {
+				removed := chunk[size%lazyChunk:]
+
+				for m := range removed {
+					removed[m] = 0
+				}
+			}
+		}
+
+		l.base = minInt64(l.base, size)
+	}
+
+	l.size = size
+}
+
+// unshare gives the node its own copy of the regions that have been written
+// when they are shared with another node.
+func (l *lazyNode) unshare() {
+	if !l.shared {
+		return
+	}
+
+	chunks := make(map[int64][]byte, len(l.chunks))
+
+	for n, chunk := range l.chunks {
+		chunks[n] = slices.Clone(chunk)
+	}
+
+	l.chunks = chunks
+	l.shared = false
+}
+
+// lockedBytes returns all of the contents of the file; must be called with
+// the lock held.
+func (l *lazyNode) lockedBytes() ([]byte, error) {
+	if l.size > maxFileSize {
+		return nil, ErrFileTooLarge
+	}
+
+	data := make([]byte, l.size)
+
+	if _, err := l.readAt(data, 0); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// release discards the written regions of the file once it has neither any
+// names nor any open Files; must be called with the lock held.
+func (l *lazyNode) release() {
+	if l.links == 0 && l.opens == 0 && !l.sealed {
+		l.chunks = nil
+	}
+}
+
+// unlink records the removal of a name for the file, releasing its written
+// regions if it was the last name and the file is not held open.
+func (l *lazyNode) unlink() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.links--
+
+	l.release()
+}
+
+func (l *lazyNode) open(name string, mode opMode) (fs.File, error) {
+	of, err := l.inodeRW.open(name, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	ef := of.(*File)
+	ef.lazy = l
+
+	return ef, nil
+}
+
+func (l *lazyNode) Size() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.size
+}
+
+func (l *lazyNode) bytes() ([]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.inode.perm()&modeRead == 0 {
+		return nil, fs.ErrPermission
+	}
+
+	return l.lockedBytes()
+}
+
+func (l *lazyNode) string() (string, error) {
+	data, err := l.bytes()
+
+	return string(data), err
+}
+
+func (l *lazyNode) appendData(buf []byte) ([]byte, error) {
+	data, err := l.bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(buf, data...), nil
+}
+
+func (l *lazyNode) seal() directoryEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sealed = true
+
+	return l
+}
+
+// The following methods implement the methods of a File opened from a lazy
+// file, and are called with the lock of the file held.
+
+func (f *File) lazyRead(p []byte) (int, error) {
+	if err := f.validTo(opRead, false); err != nil {
+		return 0, err
+	} else if f.pos >= f.lazy.size {
+		return 0, io.EOF
+	}
+
+	n, err := f.lazy.readAt(p[:minInt64(int64(len(p)), f.lazy.size-f.pos)], f.pos)
+	f.pos += int64(n)
+	f.lastRead = 0
+
+	return n, err
+}
+
+func (f *File) lazyReadAt(p []byte, off int64) (int, error) {
+	if err := f.validTo(opRead|opSeek, false); err != nil {
+		return 0, err
+	}
+
+	return f.lazy.readAt(p, off)
+}
+
+func (f *File) lazyReadByte() (byte, error) {
+	var b [1]byte
+
+	if _, err := f.lazyRead(b[:]); err != nil {
+		return 0, err
+	}
+
+	f.lastRead = 1
+
+	return b[0], nil
+}
+
+func (f *File) lazyReadRune() (rune, int, error) {
+	var buf [utf8.UTFMax]byte
+
+	if err := f.validTo(opRead, false); err != nil {
+		return 0, 0, err
+	} else if f.pos >= f.lazy.size {
+		return 0, 0, io.EOF
+	}
+
+	n, err := f.lazy.readAt(buf[:minInt64(utf8.UTFMax, f.lazy.size-f.pos)], f.pos)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, 0, err
+	}
+
+	r, s := utf8.DecodeRune(buf[:n])
+
+	f.lastRead = uint8(s)
+	f.pos += int64(s)
+
+	return r, s, nil
+}
+
+func (f *File) lazyReadDelim(delim byte) ([]byte, error) {
+	if err := f.validTo(opRead, false); err != nil {
+		return nil, err
+	} else if f.pos >= f.lazy.size {
+		return nil, io.EOF
+	}
+
+	var data []byte
+
+	f.lastRead = 0
+
+	for f.pos < f.lazy.size {
+		buf := make([]byte, minInt64(lazyChunk, f.lazy.size-f.pos))
+
+		if _, err := f.lazy.readAt(buf, f.pos); err != nil && !errors.Is(err, io.EOF) {
+			return data, err
+		}
+
+		if n := bytes.IndexByte(buf, delim); n >= 0 {
+			f.pos += int64(n + 1)
+
+			return append(data, buf[:n+1]...), nil
+		}
+
+		f.pos += int64(len(buf))
+		data = append(data, buf...)
+	}
+
+	return data, io.EOF
+}
+
+func (f *File) lazyPeek(n int) ([]byte, error) {
+	if err := f.validTo(opRead, false); err != nil {
+		return nil, err
+	} else if n < 0 {
+		return nil, fs.ErrInvalid
+	}
+
+	f.lastRead = 0
+
+	if f.pos >= f.lazy.size {
+		if n == 0 {
+			return nil, nil
+		}
+
+		return nil, io.EOF
+	}
+
+	data := make([]byte, minInt64(int64(n), f.lazy.size-f.pos))
+
+	if _, err := f.lazy.readAt(data, f.pos); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	} else if len(data) < n {
+		return data, io.EOF
+	}
+
+	return data, nil
+}
+
+func (f *File) lazyDiscard(n int) (int, error) {
+	if err := f.validTo(opRead, false); err != nil {
+		return 0, err
+	} else if n < 0 {
+		return 0, fs.ErrInvalid
+	}
+
+	f.lastRead = 0
+
+	var err error
+
+	if remaining := f.lazy.size - f.pos; remaining < int64(n) {
+		n = 0
+
+		if remaining > 0 {
+			n = int(remaining)
+		}
+
+		err = io.EOF
+	}
+
+	f.pos += int64(n)
+
+	return n, err
+}
+
+func (f *File) lazyBorrow(fn func(data []byte)) error {
+	if err := f.validTo(opRead, false); err != nil {
+		return err
+	}
+
+	data, err := f.lazy.lockedBytes()
+	if err != nil {
+		return err
+	}
+
+	fn(data)
+
+	return nil
+}
+
+func (f *File) lazyWriteTo(w io.Writer) (int64, error) {
+	if err := f.validTo(opRead, false); err != nil {
+		return 0, err
+	} else if f.pos >= f.lazy.size {
+		return 0, io.EOF
+	}
+
+	var total int64
+
+	f.lastRead = 0
+
+	for f.pos < f.lazy.size {
+		buf := make([]byte, minInt64(lazyChunk, f.lazy.size-f.pos))
+
+		if _, err := f.lazy.readAt(buf, f.pos); err != nil && !errors.Is(err, io.EOF) {
+			return total, err
+		}
+
+		n, err := w.Write(buf)
+		f.pos += int64(n)
+		total += int64(n)
+
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+func (f *File) lazySeek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekEnd {
+		offset += f.lazy.size
+		whence = io.SeekStart
+	}
+
+	return f.file.Seek(offset, whence)
+}
+
+func (f *File) lazyTruncate(size int64) error {
+	if size < f.lazy.size {
+		if err := f.limit.op(); err != nil {
+			return err
+		}
+	} else if _, err := f.limit.write(int(size - f.lazy.size)); err != nil {
+		return err
+	}
+
+	f.lazy.truncate(size)
+	f.record(Event{Op: "truncate", Offset: size})
+	f.modified()
+
+	return nil
+}
+
+// lazyWrite writes p at the current position, or at the end of the file when
+// it was opened with Append.
+func (f *File) lazyWrite(p []byte) (int, error) {
+	if f.opMode&opAppend != 0 {
+		f.pos = f.lazy.size
+	}
+
+	n, err := f.lazyWriteAt(p, f.pos)
+	f.pos += int64(n)
+	f.lastRead = 0
+
+	return n, err
+}
+
+func (f *File) lazyWriteAt(p []byte, off int64) (int, error) {
+	if off > math.MaxInt64-int64(len(p)) {
+		return 0, ErrFileTooLarge
+	}
+
+	n, err := f.limit.write(len(p))
+
+	n, werr := f.lazy.writeAt(p[:n], off, f.limit)
+	if werr != nil {
+		err = werr
+	}
+
+	if n > 0 {
+		f.record(Event{Op: "write", Offset: off, Data: p[:n]})
+		f.modified()
+	}
+
+	return n, err
+}
+
+func (f *File) lazyReadFrom(r io.Reader) (int64, error) {
+	var count int64
+
+	if f.opMode&opAppend != 0 {
+		f.pos = f.lazy.size
+	}
+
+	buf := make([]byte, lazyChunk)
+
+	for {
+		n, err := r.Read(buf)
+
+		if n > 0 {
+			if f.pos > math.MaxInt64-int64(n) {
+				return count, ErrFileTooLarge
+			}
+
+			n, lerr := f.limit.charge(0, n)
+
+			n, werr := f.lazy.writeAt(buf[:n], f.pos, f.limit)
+			if werr != nil {
+				lerr = werr
+			}
+
+			if n > 0 {
+				f.record(Event{Op: "write", Offset: f.pos, Data: buf[:n]})
+				f.modified()
+			}
+
+			count += int64(n)
+			f.pos += int64(n)
+
+			if lerr != nil {
+				return count, lerr
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return count, nil
+		} else if err != nil {
+			return count, err
+		}
+	}
+}
@@ -0,0 +1,444 @@
+package memfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+)
+
+type patternFill struct {
+	calls int
+}
+
+func (p *patternFill) fill(b []byte, off int64) (int, error) {
+	p.calls++
+
+	for n := range b {
+		b[n] = byte((off + int64(n)) % 251)
+	}
+
+	return len(b), nil
+}
+
+func pattern(off int64, n int) []byte {
+	b := make([]byte, n)
+
+	new(patternFill).fill(b, off)
+
+	return b
+}
+
+func TestCreateLazy(t *testing.T) {
+	var p patternFill
+
+	f := New()
+
+	f.Mkdir("dir", fs.ModePerm)
+
+	const size = 10 << 30
+
+	if err := f.CreateLazy("dir/large", size, p.fill); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if fi, err := f.Stat("dir/large"); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if fi.Size() != size {
+		t.Errorf("test 1: expecting size %d, got %d", size, fi.Size())
+	} else if p.calls != 0 {
+		t.Errorf("test 1: expecting no calls to fill, got %d", p.calls)
+	}
+
+	of, err := f.Open("dir/large")
+	if err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	}
+
+	defer of.Close()
+
+	ra := of.(io.ReaderAt)
+
+	for n, test := range [...]struct {
+		Off   int64
+		Len   int
+		Read  int
+		Err   error
+		Calls int
+	}{
+		{ // 3
+			Off:   size - 100,
+			Len:   100,
+			Read:  100,
+			Calls: 1,
+		},
+		{ // 4
+			Off:   size - 50,
+			Len:   100,
+			Read:  50,
+			Err:   io.EOF,
+			Calls: 1,
+		},
+		{ // 5
+			Off:   lazyChunk - 10,
+			Len:   20,
+			Read:  20,
+			Calls: 3,
+		},
+		{ // 6
+			Off:   size,
+			Len:   10,
+			Err:   io.EOF,
+			Calls: 3,
+		},
+	} {
+		buf := make([]byte, test.Len)
+
+		if read, err := ra.ReadAt(buf, test.Off); !errors.Is(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+3, test.Err, err)
+		} else if read != test.Read {
+			t.Errorf("test %d: expecting to read %d bytes, read %d", n+3, test.Read, read)
+		} else if expected := pattern(test.Off, test.Read); !reflect.DeepEqual(buf[:read], expected) {
+			t.Errorf("test %d: read incorrect data", n+3)
+		} else if p.calls != test.Calls {
+			t.Errorf("test %d: expecting %d calls to fill, got %d", n+3, test.Calls, p.calls)
+		}
+	}
+
+	if pos, err := of.(io.Seeker).Seek(-10, io.SeekEnd); err != nil {
+		t.Errorf("test 7: unexpected error: %s", err)
+	} else if pos != size-10 {
+		t.Errorf("test 7: expecting position %d, got %d", size-10, pos)
+	} else if data, err := io.ReadAll(of); err != nil {
+		t.Errorf("test 7: unexpected error: %s", err)
+	} else if !reflect.DeepEqual(data, pattern(size-10, 10)) {
+		t.Errorf("test 7: read incorrect data")
+	} else if p.calls != 3 {
+		t.Errorf("test 7: expecting 3 calls to fill, got %d", p.calls)
+	}
+
+	if rf, err := f.OpenFile("dir/large", ReadOnly, 0); err != nil {
+		t.Errorf("test 8: unexpected error: %s", err)
+	} else if data, err := rf.Peek(10); err != nil {
+		t.Errorf("test 8: unexpected error: %s", err)
+	} else if !reflect.DeepEqual(data, pattern(0, 10)) {
+		t.Errorf("test 8: read incorrect data")
+	} else if err := rf.Close(); err != nil {
+		t.Errorf("test 8: unexpected error: %s", err)
+	}
+
+	h := New()
+
+	h.CreateLazy("small", 100, new(patternFill).fill)
+
+	hf, err := h.Open("small")
+	if err != nil {
+		t.Fatalf("test 9: unexpected error: %s", err)
+	}
+
+	defer hf.Close()
+
+	expected := pattern(0, 100)
+	copy(expected[10:], "hello")
+
+	if wf, err := h.OpenFile("small", WriteOnly, 0); err != nil {
+		t.Errorf("test 9: unexpected error: %s", err)
+	} else if _, err := wf.WriteAt([]byte("hello"), 10); err != nil {
+		t.Errorf("test 9: unexpected error: %s", err)
+	} else if err := wf.Close(); err != nil {
+		t.Errorf("test 9: unexpected error: %s", err)
+	} else if data, err := h.ReadFile("small"); err != nil {
+		t.Errorf("test 9: unexpected error: %s", err)
+	} else if !reflect.DeepEqual(data, expected) {
+		t.Errorf("test 9: read incorrect data")
+	} else if data, err := io.ReadAll(hf); err != nil {
+		t.Errorf("test 9: unexpected error: %s", err)
+	} else if !reflect.DeepEqual(data, expected) {
+		t.Errorf("test 9: expecting open file to read written data")
+	}
+
+	layer, err := Layer(f.Snapshot())
+	if err != nil {
+		t.Fatalf("test 10: unexpected error: %s", err)
+	} else if err := layer.Rename("dir/large", "large"); err != nil {
+		t.Errorf("test 10: unexpected error: %s", err)
+	} else if lf, err := layer.Open("large"); err != nil {
+		t.Errorf("test 10: unexpected error: %s", err)
+	} else {
+		buf := make([]byte, 100)
+
+		if _, err := lf.(io.ReaderAt).ReadAt(buf, size-100); err != nil {
+			t.Errorf("test 10: unexpected error: %s", err)
+		} else if !reflect.DeepEqual(buf, pattern(size-100, 100)) {
+			t.Errorf("test 10: read incorrect data")
+		} else if p.calls != 3 {
+			t.Errorf("test 10: expecting cached data to be shared, got %d calls to fill", p.calls)
+		}
+
+		lf.Close()
+	}
+
+	if _, err := f.Stat("dir/large"); err != nil {
+		t.Errorf("test 11: expecting entry in FS to be unaffected by layer, got %s", err)
+	}
+
+	g := New()
+
+	if err := g.CreateLazy("small", 100, new(patternFill).fill); err != nil {
+		t.Errorf("test 12: unexpected error: %s", err)
+	} else if data, err := sealCompact(t, g).ReadFile("small"); err != nil {
+		t.Errorf("test 12: unexpected error: %s", err)
+	} else if !reflect.DeepEqual(data, pattern(0, 100)) {
+		t.Errorf("test 12: read incorrect data")
+	}
+}
+
+func TestCreateLazyErrors(t *testing.T) {
+	f := New()
+
+	f.WriteFile("file", nil, 0o644)
+
+	errFill := errors.New("fill error")
+
+	for n, test := range [...]struct {
+		Path    string
+		Size    int64
+		Fill    LazyFunc
+		Err     error
+		ReadErr error
+	}{
+		{ // 1
+			Path: "file",
+			Fill: new(patternFill).fill,
+			Err:  &fs.PathError{Op: "createlazy", Path: "file", Err: fs.ErrExist},
+		},
+		{ // 2
+			Path: "a",
+			Size: -1,
+			Fill: new(patternFill).fill,
+			Err:  &fs.PathError{Op: "createlazy", Path: "a", Err: fs.ErrInvalid},
+		},
+		{ // 3
+			Path: "b",
+			Err:  &fs.PathError{Op: "createlazy", Path: "b", Err: fs.ErrInvalid},
+		},
+		{ // 4
+			Path: "missing/c",
+			Fill: new(patternFill).fill,
+			Err:  &fs.PathError{Op: "createlazy", Path: "missing/c", Err: fs.ErrNotExist},
+		},
+		{ // 5
+			Path: "d",
+			Size: 10,
+			Fill: func(_ []byte, _ int64) (int, error) {
+				return 0, errFill
+			},
+			ReadErr: errFill,
+		},
+		{ // 6
+			Path: "e",
+			Size: 10,
+			Fill: func(b []byte, _ int64) (int, error) {
+				return 5, io.EOF
+			},
+			ReadErr: io.ErrUnexpectedEOF,
+		},
+		{ // 7
+			Path: "f",
+			Size: 100,
+			Fill: func(b []byte, off int64) (int, error) {
+				if len(b) > 30 {
+					b = b[:30]
+				}
+
+				return new(patternFill).fill(b, off)
+			},
+		},
+	} {
+		if err := f.CreateLazy(test.Path, test.Size, test.Fill); !reflect.DeepEqual(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if err != nil {
+			continue
+		} else if data, err := f.ReadFile(test.Path); !errors.Is(err, test.ReadErr) {
+			t.Errorf("test %d: expecting read error %v, got %v", n+1, test.ReadErr, err)
+		} else if err == nil && !reflect.DeepEqual(data, pattern(0, int(test.Size))) {
+			t.Errorf("test %d: read incorrect data", n+1)
+		}
+	}
+}
+
+func TestCreateSparse(t *testing.T) {
+	f := New()
+
+	const size = 1 << 40
+
+	if err := f.CreateSparse("large", -1); !reflect.DeepEqual(err, &fs.PathError{Op: "createsparse", Path: "large", Err: fs.ErrInvalid}) {
+		t.Errorf("test 1: expecting invalid error, got %v", err)
+	} else if err := f.CreateSparse("large", size); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if fi, err := f.Stat("large"); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if fi.Size() != size {
+		t.Errorf("test 2: expecting size %d, got %d", size, fi.Size())
+	} else if err := f.CreateSparse("large", 10); !reflect.DeepEqual(err, &fs.PathError{Op: "createsparse", Path: "large", Err: fs.ErrExist}) {
+		t.Errorf("test 3: expecting exist error, got %v", err)
+	}
+
+	of, err := f.Open("large")
+	if err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	}
+
+	defer of.Close()
+
+	zeros := make([]byte, 100)
+
+	if pos, err := of.(io.Seeker).Seek(-10, io.SeekEnd); err != nil {
+		t.Errorf("test 4: unexpected error: %s", err)
+	} else if pos != size-10 {
+		t.Errorf("test 4: expecting position %d, got %d", size-10, pos)
+	} else if data, err := io.ReadAll(of); err != nil {
+		t.Errorf("test 4: unexpected error: %s", err)
+	} else if !reflect.DeepEqual(data, zeros[:10]) {
+		t.Errorf("test 4: expecting zeros, got %v", data)
+	}
+
+	buf := make([]byte, 100)
+
+	if n, err := of.(io.ReaderAt).ReadAt(buf, size/2-50); err != nil {
+		t.Errorf("test 5: unexpected error: %s", err)
+	} else if n != 100 || !reflect.DeepEqual(buf, zeros) {
+		t.Errorf("test 5: expecting 100 zeros, got %v", buf[:n])
+	}
+
+	if chunks := f.de.(*dnodeRW).entries[0].directoryEntry.(*lazyNode).lazy.chunks; chunks != nil {
+		t.Errorf("test 6: expecting no memory to be used, got %d chunks", len(chunks))
+	}
+
+	f.CreateSparse("small", 20)
+
+	snap := f.Snapshot()
+	expected := append(make([]byte, 5), "hello"...)
+	expected = append(expected, make([]byte, 10)...)
+
+	if wf, err := f.OpenFile("small", WriteOnly, 0); err != nil {
+		t.Errorf("test 7: unexpected error: %s", err)
+	} else if _, err := wf.WriteAt([]byte("hello"), 5); err != nil {
+		t.Errorf("test 7: unexpected error: %s", err)
+	} else if err := wf.Close(); err != nil {
+		t.Errorf("test 7: unexpected error: %s", err)
+	} else if data, err := f.ReadFile("small"); err != nil {
+		t.Errorf("test 7: unexpected error: %s", err)
+	} else if !reflect.DeepEqual(data, expected) {
+		t.Errorf("test 7: expecting %v, got %v", expected, data)
+	} else if data, err := snap.ReadFile("small"); err != nil {
+		t.Errorf("test 8: unexpected error: %s", err)
+	} else if !reflect.DeepEqual(data, zeros[:20]) {
+		t.Errorf("test 8: expecting snapshot to be unaffected, got %v", data)
+	} else if data, err := f.Snapshot().ReadFile("small"); err != nil {
+		t.Errorf("test 9: unexpected error: %s", err)
+	} else if !reflect.DeepEqual(data, expected) {
+		t.Errorf("test 9: expecting %v, got %v", expected, data)
+	}
+
+	f.CreateSparse("readonly", 10)
+	f.Chmod("readonly", 0o444)
+
+	if err := f.WriteFile("readonly", nil, 0o644); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("test 10: expecting error %v, got %v", fs.ErrPermission, err)
+	}
+
+	if data, err := f.Seal().ReadFile("small"); err != nil {
+		t.Errorf("test 11: unexpected error: %s", err)
+	} else if !reflect.DeepEqual(data, expected) {
+		t.Errorf("test 11: expecting %v, got %v", expected, data)
+	}
+}
+
+func TestLazyWrite(t *testing.T) {
+	f := New(WithWriteLimit(1<<20, 0))
+
+	const size = 1 << 50
+
+	if err := f.CreateSparse("large", size); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	}
+
+	wf, err := f.OpenFile("large", ReadWrite, 0)
+	if err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if _, err := wf.WriteAt([]byte("hello"), size-5); err != nil {
+		t.Errorf("test 3: unexpected error: %s", err)
+	} else if _, err := wf.Seek(lazyChunk-2, io.SeekStart); err != nil {
+		t.Errorf("test 4: unexpected error: %s", err)
+	} else if _, err := wf.WriteString("world"); err != nil {
+		t.Errorf("test 4: unexpected error: %s", err)
+	}
+
+	node := f.de.(*dnodeRW).entries[0].directoryEntry.(*lazyNode)
+
+	if len(node.chunks) != 3 {
+		t.Errorf("test 5: expecting 3 written regions, got %d", len(node.chunks))
+	}
+
+	buf := make([]byte, 9)
+
+	if _, err := wf.ReadAt(buf, lazyChunk-4); err != nil {
+		t.Errorf("test 6: unexpected error: %s", err)
+	} else if expected := []byte("\x00\x00world\x00\x00"); !reflect.DeepEqual(buf, expected) {
+		t.Errorf("test 6: expecting %q, got %q", expected, buf)
+	} else if _, err := wf.ReadAt(buf[:5], size-5); err != nil {
+		t.Errorf("test 7: unexpected error: %s", err)
+	} else if string(buf[:5]) != "hello" {
+		t.Errorf("test 7: expecting %q, got %q", "hello", buf[:5])
+	} else if fi, err := wf.Stat(); err != nil {
+		t.Errorf("test 8: unexpected error: %s", err)
+	} else if fi.Size() != size {
+		t.Errorf("test 8: expecting size %d, got %d", int64(size), fi.Size())
+	} else if _, err := f.ReadFile("large"); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("test 9: expecting error %v, got %v", ErrFileTooLarge, err)
+	} else if err := wf.Truncate(lazyChunk); err != nil {
+		t.Errorf("test 10: unexpected error: %s", err)
+	} else if err := wf.Truncate(lazyChunk + 10); err != nil {
+		t.Errorf("test 10: unexpected error: %s", err)
+	} else if data, err := f.ReadFile("large"); err != nil {
+		t.Errorf("test 11: unexpected error: %s", err)
+	} else if expected := append(append(make([]byte, lazyChunk-2), "wo"...), make([]byte, 10)...); !reflect.DeepEqual(data, expected) {
+		t.Errorf("test 11: read incorrect data")
+	} else if len(node.chunks) != 1 {
+		t.Errorf("test 12: expecting 1 written region, got %d", len(node.chunks))
+	}
+
+	if err := f.Link("large", "link"); err != nil {
+		t.Fatalf("test 13: unexpected error: %s", err)
+	} else if fi, err := f.Stat("link"); err != nil {
+		t.Errorf("test 13: unexpected error: %s", err)
+	} else if links := fi.Sys().(Linked).Links(); links != 2 {
+		t.Errorf("test 13: expecting 2 links, got %d", links)
+	} else if err := f.Remove("large"); err != nil {
+		t.Errorf("test 14: unexpected error: %s", err)
+	} else if err := f.Remove("link"); err != nil {
+		t.Errorf("test 14: unexpected error: %s", err)
+	} else if node.chunks == nil {
+		t.Errorf("test 15: expecting written data to be held while open")
+	} else if err := wf.Close(); err != nil {
+		t.Errorf("test 16: unexpected error: %s", err)
+	} else if node.chunks != nil {
+		t.Errorf("test 16: expecting written data to be released")
+	}
+
+	g := New(WithWriteLimit(lazyChunk-10, 0))
+
+	if err := g.CreateLazy("lazy", 3*lazyChunk, new(patternFill).fill); err != nil {
+		t.Fatalf("test 17: unexpected error: %s", err)
+	} else if err := g.AppendFile("lazy", []byte("!"), 0); err != nil {
+		t.Errorf("test 18: unexpected error: %s", err)
+	} else if wf, err := g.OpenFile("lazy", WriteOnly, 0); err != nil {
+		t.Errorf("test 19: unexpected error: %s", err)
+	} else if _, err := wf.WriteAt([]byte("!"), 0); !errors.Is(err, ErrWriteLimit) {
+		t.Errorf("test 20: expecting error %v, got %v", ErrWriteLimit, err)
+	} else if data, err := g.ReadFile("lazy"); err != nil {
+		t.Errorf("test 21: unexpected error: %s", err)
+	} else if !reflect.DeepEqual(data, append(pattern(0, 3*lazyChunk), '!')) {
+		t.Errorf("test 21: read incorrect data")
+	}
+}
@@ -0,0 +1,121 @@
+package memfs
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrWriteLimit is returned by any operation that would modify an FS, or a File
+// opened from it, once the limits set with WithWriteLimit have been exceeded.
+var ErrWriteLimit = errors.New("write limit exceeded")
+
+// WithWriteLimit sets a budget for the number of bytes written to files, and
+// the number of modifying operations, for the FS and any FS created from it
+// with Sub. A limit of zero or less is treated as unlimited.
+//
+// Each call to a method that modifies the tree, and each call to a write
+// method of a File, counts as a single operation, and truncating a file to a
+// larger size counts the added bytes as written.
+//
+// An operation that would exceed either limit fails, and once either limit has
+// been exceeded all subsequent modifying operations fail, returning
+// ErrWriteLimit; the FS can still be read. File writes that would exceed the
+// byte limit write as much as the limit allows before failing.
+func WithWriteLimit(bytes, ops int64) Option {
+	return func(f *FS) {
+		f.limit = &writeLimit{
+			bytes: limitOrUnlimited(bytes),
+			ops:   limitOrUnlimited(ops),
+		}
+	}
+}
+
+func limitOrUnlimited(limit int64) int64 {
+	if limit <= 0 {
+		return -1
+	}
+
+	return limit
+}
+
+// writeLimit tracks the remaining budget of an FS, with a negative value being
+// unlimited.
+type writeLimit struct {
+	mu       sync.Mutex
+	bytes    int64
+	ops      int64
+	exceeded bool
+}
+
+// op records a single modifying operation that writes no data.
+func (w *writeLimit) op() error {
+	_, err := w.charge(1, 0)
+
+	return err
+}
+
+// write records a single operation that writes up to n bytes, returning the
+// number of bytes that may be written.
+func (w *writeLimit) write(n int) (int, error) {
+	return w.charge(1, n)
+}
+
+func (w *writeLimit) charge(ops, n int) (int, error) {
+	if w == nil {
+		return n, nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.exceeded {
+		return 0, ErrWriteLimit
+	}
+
+	if w.ops >= 0 {
+		if w.ops < int64(ops) {
+			w.exceeded = true
+
+			return 0, ErrWriteLimit
+		}
+
+		w.ops -= int64(ops)
+	}
+
+	if w.bytes >= 0 {
+		if w.bytes < int64(n) {
+			n = int(w.bytes)
+			w.bytes = 0
+			w.exceeded = true
+
+			return n, ErrWriteLimit
+		}
+
+		w.bytes -= int64(n)
+	}
+
+	return n, nil
+}
+
+// ErrDirFull is returned when adding an entry to a directory would exceed the
+// limit set with WithMaxDirEntries.
+var ErrDirFull = errors.New("directory full")
+
+// WithMaxDirEntries sets the maximum number of entries that each directory,
+// including the root, created in the FS, and in any FS created from it with
+// Sub, can hold, such as to simulate the fixed size root directory of a FAT
+// file system. A limit of zero or less is treated as unlimited.
+//
+// Any operation that would add an entry to a full directory, such as creating
+// a file, making a directory, linking, or renaming an entry into it, fails
+// with ErrDirFull. Renaming an entry to a new name within a full directory
+// succeeds, as the old name is removed before the new one is added.
+func WithMaxDirEntries(n int) Option {
+	return func(f *FS) {
+		if n < 0 {
+			n = 0
+		}
+
+		f.maxEntries = n
+	}
+}
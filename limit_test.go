@@ -0,0 +1,179 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteLimitBytes(t *testing.T) {
+	f := New(WithWriteLimit(10, 0))
+
+	if err := f.WriteFile("a", []byte("1234"), 0o644); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	}
+
+	of, err := f.Create("b")
+	if err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if n, err := of.WriteString("5678"); err != nil {
+		t.Fatalf("test 3: unexpected error: %s", err)
+	} else if n != 4 {
+		t.Errorf("test 3: expecting to write 4 bytes, wrote %d", n)
+	}
+
+	if n, err := of.Write([]byte("90ab")); !errors.Is(err, ErrWriteLimit) {
+		t.Errorf("test 4: expecting error ErrWriteLimit, got %v", err)
+	} else if n != 2 {
+		t.Errorf("test 4: expecting to write 2 bytes, wrote %d", n)
+	} else if data, err := f.ReadFile("b"); err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	} else if string(data) != "567890" {
+		t.Errorf("test 4: expecting file to contain %q, got %q", "567890", data)
+	}
+
+	if err := of.WriteByte('c'); !errors.Is(err, ErrWriteLimit) {
+		t.Errorf("test 5: expecting error ErrWriteLimit, got %v", err)
+	} else if err := f.Mkdir("dir", fs.ModePerm); !errors.Is(err, ErrWriteLimit) {
+		t.Errorf("test 6: expecting error ErrWriteLimit, got %v", err)
+	} else if err := f.Remove("a"); !errors.Is(err, ErrWriteLimit) {
+		t.Errorf("test 7: expecting error ErrWriteLimit, got %v", err)
+	} else if data, err := f.ReadFile("a"); err != nil {
+		t.Errorf("test 8: unexpected error: %s", err)
+	} else if string(data) != "1234" {
+		t.Errorf("test 8: expecting file to contain %q, got %q", "1234", data)
+	}
+}
+
+func TestWriteLimitOps(t *testing.T) {
+	f := New(WithWriteLimit(0, 3))
+
+	if err := f.Mkdir("a", fs.ModePerm); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	}
+
+	sub, err := f.Sub("a")
+	if err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if err := sub.(*FS).WriteFile("file", []byte(strings.Repeat("a", 1000)), 0o644); err != nil {
+		t.Fatalf("test 3: unexpected error: %s", err)
+	} else if err := f.Chmod("a/file", 0o600); err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	} else if _, err := f.Open("a/file"); err != nil {
+		t.Fatalf("test 5: unexpected error: %s", err)
+	}
+
+	expected := &fs.PathError{Op: "symlink", Path: "link", Err: ErrWriteLimit}
+
+	if err := f.Symlink("a/file", "link"); !reflect.DeepEqual(err, expected) {
+		t.Errorf("test 6: expecting error %v, got %v", expected, err)
+	} else if err := sub.(*FS).Remove("file"); !errors.Is(err, ErrWriteLimit) {
+		t.Errorf("test 7: expecting error ErrWriteLimit, got %v", err)
+	}
+}
+
+func TestWriteLimitReadFrom(t *testing.T) {
+	f := New(WithWriteLimit(5, 0))
+
+	of, err := f.Create("file")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if n, err := of.ReadFrom(strings.NewReader("123456789")); !errors.Is(err, ErrWriteLimit) {
+		t.Errorf("expecting error ErrWriteLimit, got %v", err)
+	} else if n != 5 {
+		t.Errorf("expecting to read 5 bytes, read %d", n)
+	} else if data, err := f.ReadFile("file"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if string(data) != "12345" {
+		t.Errorf("expecting file to contain %q, got %q", "12345", data)
+	}
+}
+
+func TestMaxDirEntries(t *testing.T) {
+	f := New(WithMaxDirEntries(3))
+
+	if err := f.Mkdir("dir", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("file", nil, 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("file", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Op  func() error
+		Err error
+	}{
+		{ // 1
+			Op:  func() error { return f.WriteFile("new", nil, 0o644) },
+			Err: &fs.PathError{Op: "writefile", Path: "new", Err: ErrDirFull},
+		},
+		{ // 2
+			Op:  func() error { return f.Mkdir("new", 0o755) },
+			Err: &fs.PathError{Op: "mkdir", Path: "new", Err: ErrDirFull},
+		},
+		{ // 3
+			Op:  func() error { return f.Link("file", "new") },
+			Err: &fs.PathError{Op: "link", Path: "new", Err: ErrDirFull},
+		},
+		{ // 4
+			Op: func() error { return f.WriteFile("file", []byte("data"), 0o644) },
+		},
+		{ // 5
+			Op: func() error { return f.Rename("link", "file") },
+		},
+		{ // 6
+			Op: func() error { return f.WriteFile("new", nil, 0o644) },
+		},
+		{ // 7
+			Op: func() error { return f.WriteFile("dir/a", nil, 0o644) },
+		},
+		{ // 8
+			Op: func() error { return f.WriteFile("dir/b", nil, 0o644) },
+		},
+		{ // 9
+			Op: func() error { return f.Mkdir("dir/c", 0o755) },
+		},
+		{ // 10
+			Op:  func() error { return f.Rename("new", "dir/new") },
+			Err: &fs.PathError{Op: "rename", Path: "dir/new", Err: ErrDirFull},
+		},
+		{ // 11
+			Op: func() error { return f.Remove("dir/a") },
+		},
+		{ // 12
+			Op: func() error { return f.Rename("new", "dir/new") },
+		},
+		{ // 13
+			Op: func() error { return f.Rename("dir/b", "dir/d") },
+		},
+		{ // 14
+			Op: func() error { return f.Rename("dir/c", "dir/b") },
+		},
+		{ // 15
+			Op:  func() error { return f.WriteFile("dir/e", nil, 0o644) },
+			Err: &fs.PathError{Op: "writefile", Path: "dir/e", Err: ErrDirFull},
+		},
+	} {
+		if err := test.Op(); !reflect.DeepEqual(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		}
+	}
+
+	layer, err := Layer(f.Seal(), WithMaxDirEntries(3))
+	if err != nil {
+		t.Fatalf("test 16: unexpected error: %s", err)
+	} else if err := layer.WriteFile("dir/new2", nil, 0o644); !errors.Is(err, ErrDirFull) {
+		t.Errorf("test 16: expecting error %v, got %v", ErrDirFull, err)
+	} else if err := layer.WriteFile("new2", nil, 0o644); err != nil {
+		t.Errorf("test 17: unexpected error: %s", err)
+	}
+
+	if err := New(WithMaxDirEntries(0)).WriteFile("file", nil, 0o644); err != nil {
+		t.Errorf("test 18: unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,103 @@
+package memfs
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"time"
+)
+
+// WithLogger sets a Logger to which each operation on the FS, and on any FS
+// created from it with Sub, is logged, at the Debug level, once it completes.
+//
+// Each record has the name of the operation, the path it was given, any new
+// path, mode, or number of bytes read or written, along with the duration of
+// the operation and any error returned.
+//
+// Reads and writes through open Files are not logged.
+func WithLogger(l *slog.Logger) Option {
+	return func(f *FS) {
+		f.logger = l
+	}
+}
+
+type opLog struct {
+	logger   *slog.Logger
+	op, path string
+	newPath  string
+	mode     fs.FileMode
+	hasMode  bool
+	bytes    int
+	hasBytes bool
+	start    time.Time
+}
+
+// logOp starts the logging of an operation, returning nil when there is no
+// logger, or when it would discard the record.
+func (c *config) logOp(op, path string) *opLog {
+	if c.logger == nil || !c.logger.Enabled(context.Background(), slog.LevelDebug) {
+		return nil
+	}
+
+	return &opLog{
+		logger: c.logger,
+		op:     op,
+		path:   path,
+		start:  time.Now(),
+	}
+}
+
+func (o *opLog) withMode(mode fs.FileMode) *opLog {
+	if o != nil {
+		o.mode = mode
+		o.hasMode = true
+	}
+
+	return o
+}
+
+func (o *opLog) withNewPath(p string) *opLog {
+	if o != nil {
+		o.newPath = p
+	}
+
+	return o
+}
+
+func (o *opLog) setBytes(n int) {
+	if o != nil {
+		o.bytes = n
+		o.hasBytes = true
+	}
+}
+
+// end logs the operation, along with the error pointed to by err.
+func (o *opLog) end(err *error) {
+	if o == nil {
+		return
+	}
+
+	attrs := make([]slog.Attr, 2, 7)
+	attrs[0] = slog.String("op", o.op)
+	attrs[1] = slog.String("path", o.path)
+
+	if o.newPath != "" {
+		attrs = append(attrs, slog.String("newpath", o.newPath))
+	}
+
+	if o.hasMode {
+		attrs = append(attrs, slog.Any("mode", o.mode))
+	}
+
+	if o.hasBytes {
+		attrs = append(attrs, slog.Int("bytes", o.bytes))
+	}
+
+	attrs = append(attrs, slog.Duration("duration", time.Since(o.start)))
+
+	if *err != nil {
+		attrs = append(attrs, slog.Any("error", *err))
+	}
+
+	o.logger.LogAttrs(context.Background(), slog.LevelDebug, "memfs", attrs...)
+}
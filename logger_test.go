@@ -0,0 +1,50 @@
+package memfs
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+)
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+
+	f := New(WithLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration" {
+				return slog.Attr{}
+			}
+
+			return a
+		},
+	}))))
+
+	f.Mkdir("dir", 0o755)
+	f.WriteFile("dir/file", []byte("data"), 0o644)
+	f.ReadFile("dir/file")
+	f.Rename("dir/file", "dir/other")
+
+	s, _ := f.Sub("dir")
+	s.(*FS).Stat("missing")
+
+	const expected = "level=DEBUG msg=memfs op=mkdir path=dir mode=-rwxr-xr-x\n" +
+		"level=DEBUG msg=memfs op=writefile path=dir/file mode=-rw-r--r-- bytes=4\n" +
+		"level=DEBUG msg=memfs op=readfile path=dir/file bytes=4\n" +
+		"level=DEBUG msg=memfs op=rename path=dir/file newpath=dir/other\n" +
+		"level=DEBUG msg=memfs op=stat path=missing error=\"stat missing: file does not exist\"\n"
+
+	if got := buf.String(); got != expected {
+		t.Errorf("test 1: expecting log:\n%s\ngot:\n%s", expected, got)
+	}
+
+	buf.Reset()
+
+	g := New(WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+
+	g.Mkdir("dir", 0o755)
+
+	if buf.Len() != 0 {
+		t.Errorf("test 2: expecting no log at the Info level, got %q", buf.String())
+	}
+}
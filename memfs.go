@@ -6,14 +6,25 @@ import (
 	"errors"
 	"io/fs"
 	"path"
+	"strings"
 )
 
 type fsRO struct {
 	de directoryEntry
+	resolveConfig
+	cache *statCache
+
+	// partial is set when the root of the FS is not the root of the tree
+	// it was created from, as with Sub, such that some names of its files
+	// may be outside of the FS.
+	partial bool
 }
 
-func (f *fsRO) joinRoot(p string) string {
-	return path.Join(slash, p)
+type resolveConfig struct {
+	noFollow        bool
+	trace           bool
+	maxSymlinks     int
+	normInsensitive bool
 }
 
 func (f *fsRO) Open(p string) (fs.File, error) {
@@ -32,20 +43,49 @@ func (f *fsRO) Open(p string) (fs.File, error) {
 	return of, nil
 }
 
+// OpenDir opens the named directory for reading, following any symlinks,
+// returning ErrNotDir when the entry is not a directory, in the manner of
+// opening with O_DIRECTORY.
+//
+// This allows a directory to be opened, and then listed, without a separate
+// check of its type that could be invalidated by a concurrent change.
+func (f *fsRO) OpenDir(p string) (fs.ReadDirFile, error) {
+	de, err := f.getEntry(p)
+	if err != nil {
+		return nil, &fs.PathError{Op: "opendir", Path: p, Err: err}
+	} else if !de.IsDir() {
+		return nil, &fs.PathError{Op: "opendir", Path: p, Err: ErrNotDir}
+	}
+
+	_, fileName := path.Split(p)
+
+	of, err := de.open(fileName, opRead|opSeek)
+	if err != nil {
+		return nil, &fs.PathError{Op: "opendir", Path: p, Err: err}
+	}
+
+	d, ok := of.(fs.ReadDirFile)
+	if !ok {
+		return nil, &fs.PathError{Op: "opendir", Path: p, Err: ErrNotDir}
+	}
+
+	return d, nil
+}
+
 func (f *fsRO) getDirEnt(path string) (dNode, error) {
 	de, err := f.getEntry(path)
 	if err != nil {
 		return nil, err
 	} else if d, ok := de.(dNode); !ok {
-		return nil, fs.ErrInvalid
+		return nil, ErrNotDir
 	} else {
 		return d, nil
 	}
 }
 
 const (
-	maxRedirects uint8 = 255
-	slash              = "/"
+	maxRedirects = 255
+	slash        = "/"
 )
 
 func (f *fsRO) getEntry(path string) (directoryEntry, error) {
@@ -53,7 +93,17 @@ func (f *fsRO) getEntry(path string) (directoryEntry, error) {
 		return nil, fs.ErrInvalid
 	}
 
-	return f.getEntryWithoutCheck(path)
+	de, at := f.cache.get(path)
+	if de != nil {
+		return de, nil
+	}
+
+	de, err := f.getEntryWithoutCheck(path)
+	if err == nil {
+		f.cache.set(path, de, at)
+	}
+
+	return de, err
 }
 
 func (f *fsRO) getLEntry(p string) (*dirEnt, error) {
@@ -61,22 +111,37 @@ func (f *fsRO) getLEntry(p string) (*dirEnt, error) {
 		return nil, fs.ErrInvalid
 	}
 
-	jpath := f.joinRoot(p)
-	dirName, fileName := path.Split(jpath)
+	dirName, fileName := "", p
+
+	if pos := strings.LastIndexByte(p, '/'); pos >= 0 {
+		dirName, fileName = p[:pos], p[pos+1:]
+	}
 
-	de, err := f.getEntryWithoutCheck(dirName)
+	r := f.newResolver(dirName)
+	r.orig = p
+
+	de, err := r.resolve(f.de)
 	if err != nil {
-		return nil, err
+		return nil, r.wrap(err)
 	}
 
-	if jpath == slash {
+	if p == "." {
+		if de.perm()&modeRead == 0 {
+			return nil, fs.ErrPermission
+		}
+
 		return &dirEnt{
 			directoryEntry: f.de,
-			name:           slash,
+			name:           ".",
 		}, nil
 	}
 
-	return de.getEntry(fileName)
+	e, err := getEntryNorm(de, fileName, f.normInsensitive)
+	if err != nil {
+		return nil, r.wrap(r.fail(path.Join(r.fullPath, fileName), err))
+	}
+
+	return e, nil
 }
 
 type exists byte
@@ -98,7 +163,7 @@ func (f *fsRO) getEntryWithParent(path string, exists exists) (dNode, *dirEnt, e
 		return nil, nil, err
 	}
 
-	c, err := d.getEntry(child)
+	c, err := getEntryNorm(d, child, f.normInsensitive)
 	if !errors.Is(err, fs.ErrNotExist) || exists == mustExist {
 		if err != nil {
 			return nil, nil, err
@@ -124,6 +189,64 @@ func (f *fsRO) ReadDir(path string) ([]fs.DirEntry, error) {
 	return es, nil
 }
 
+// ScanDir calls the given func with each of the entries of the directory at
+// the given path, in the order they would be returned by ReadDir, stopping
+// early when the func returns false, without allocating a slice of all of the
+// entries.
+func (f *fsRO) ScanDir(path string, fn func(fs.DirEntry) bool) error {
+	d, err := f.getDirEnt(path)
+	if err != nil {
+		return &fs.PathError{Op: "scandir", Path: path, Err: err}
+	}
+
+	if err := d.scanEntries(fn); err != nil {
+		return &fs.PathError{Op: "scandir", Path: path, Err: err}
+	}
+
+	return nil
+}
+
+// LReadDir reads the directory at the given path without following a final
+// symlink, as with FS.LReadDir.
+func (f *fsRO) LReadDir(path string) ([]fs.DirEntry, error) {
+	de, err := f.getLEntry(path)
+	if err != nil {
+		return nil, &fs.PathError{Op: "lreaddir", Path: path, Err: err}
+	}
+
+	d, ok := de.directoryEntry.(dNode)
+	if !ok {
+		return nil, &fs.PathError{Op: "lreaddir", Path: path, Err: ErrNotDir}
+	}
+
+	es, err := d.getEntries()
+	if err != nil {
+		return nil, &fs.PathError{Op: "lreaddir", Path: path, Err: err}
+	}
+
+	return es, nil
+}
+
+// ReadDirInfo returns the fs.FileInfo of each of the entries of the directory
+// at the given path, as with calling Info on each of the entries returned by
+// ReadDir.
+func (f *fsRO) ReadDirInfo(path string) ([]fs.FileInfo, error) {
+	es, err := f.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fis := make([]fs.FileInfo, len(es))
+
+	for n, e := range es {
+		if fis[n], err = e.Info(); err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: path, Err: err}
+		}
+	}
+
+	return fis, nil
+}
+
 func (f *fsRO) ReadFile(path string) ([]byte, error) {
 	de, err := f.getEntry(path)
 	if err != nil {
@@ -138,24 +261,73 @@ func (f *fsRO) ReadFile(path string) ([]byte, error) {
 	return data, nil
 }
 
-func (f *fsRO) Stat(p string) (fs.FileInfo, error) {
-	de, err := f.getEntry(p)
+// ErrFileTooLarge is returned by ReadFileLimit when the file is larger than the
+// given limit, and when a file would be grown larger than can be allocated.
+var ErrFileTooLarge = errors.New("file too large")
+
+// ReadFileLimit reads the named file, as with ReadFile, returning
+// ErrFileTooLarge, without reading the file, when it is larger than max bytes.
+func (f *fsRO) ReadFileLimit(path string, max int64) ([]byte, error) {
+	de, err := f.getEntry(path)
 	if err != nil {
-		return nil, &fs.PathError{Op: "stat", Path: p, Err: err}
+		return nil, &fs.PathError{Op: "readfile", Path: path, Err: err}
+	}
+
+	if de.Size() > max {
+		return nil, &fs.PathError{Op: "readfile", Path: path, Err: ErrFileTooLarge}
 	}
 
-	base := path.Base(p)
+	data, err := de.bytes()
+	if err != nil {
+		return nil, &fs.PathError{Op: "readfile", Path: path, Err: err}
+	} else if int64(len(data)) > max {
+		return nil, &fs.PathError{Op: "readfile", Path: path, Err: ErrFileTooLarge}
+	}
+
+	return data, nil
+}
+
+// ReadFileString reads the named file, returning its contents as a string, as
+// with FS.ReadFileString.
+func (f *fsRO) ReadFileString(path string) (string, error) {
+	de, err := f.getEntry(path)
+	if err != nil {
+		return "", &fs.PathError{Op: "readfile", Path: path, Err: err}
+	}
 
-	if base == "." {
-		base = slash
+	data, err := de.string()
+	if err != nil {
+		return "", &fs.PathError{Op: "readfile", Path: path, Err: err}
+	}
+
+	return data, nil
+}
+
+func (f *fsRO) Stat(p string) (fs.FileInfo, error) {
+	de, err := f.getEntry(p)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: p, Err: err}
 	}
 
 	return &dirEnt{
-		name:           base,
+		name:           path.Base(p),
 		directoryEntry: de,
 	}, nil
 }
 
+// StatAll returns the fs.FileInfo of each of the given paths, as with Stat,
+// along with the error, if any, for each path.
+func (f *fsRO) StatAll(paths []string) ([]fs.FileInfo, []error) {
+	fis := make([]fs.FileInfo, len(paths))
+	errs := make([]error, len(paths))
+
+	for n, p := range paths {
+		fis[n], errs[n] = f.Stat(p)
+	}
+
+	return fis, errs
+}
+
 func (f *fsRO) LStat(path string) (fs.FileInfo, error) {
 	de, err := f.getLEntry(path)
 	if err != nil {
@@ -183,6 +355,100 @@ func (f *fsRO) Readlink(path string) (string, error) {
 	return b, nil
 }
 
+// ResolveLink returns the path of the target of the symlink at the given path,
+// as with FS.ResolveLink.
+func (f *fsRO) ResolveLink(p string, depth int) (string, error) {
+	target, err := f.resolveLink(p, depth)
+	if err != nil {
+		return "", &fs.PathError{Op: "resolvelink", Path: p, Err: err}
+	}
+
+	return target, nil
+}
+
+func (f *fsRO) resolveLink(p string, depth int) (string, error) {
+	de, err := f.getLEntry(p)
+	if err != nil {
+		return "", err
+	} else if de.Mode()&fs.ModeSymlink == 0 {
+		return "", fs.ErrInvalid
+	}
+
+	limit := f.symlinkLimit()
+	target := p
+
+	for n := 1; ; n++ {
+		if n > limit {
+			return "", &SymlinkLimitError{Path: target, Limit: limit}
+		}
+
+		link, err := de.string()
+		if err != nil {
+			return "", err
+		}
+
+		if !strings.HasPrefix(link, slash) {
+			link = path.Dir(target) + slash + link
+		}
+
+		if target = string(cleanPath([]byte(link))); target == "" {
+			target = "."
+		}
+
+		if n == depth {
+			return target, nil
+		}
+
+		de, err = f.getLEntry(target)
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, ErrNotDir) {
+			return target, nil
+		} else if err != nil {
+			return "", err
+		} else if de.Mode()&fs.ModeSymlink == 0 {
+			return target, nil
+		}
+	}
+}
+
+// Exists reports whether an entry exists at the given path, as with FS.Exists.
+func (f *fsRO) Exists(path string) (bool, error) {
+	_, err := f.getEntry(path)
+
+	return found(err)
+}
+
+// IsDir reports whether the entry at the given path is a directory, as with
+// FS.IsDir.
+func (f *fsRO) IsDir(path string) (bool, error) {
+	de, err := f.getEntry(path)
+	if err != nil {
+		return found(err)
+	}
+
+	return de.IsDir(), nil
+}
+
+// IsSymlink reports whether the entry at the given path is a symlink, as with
+// FS.IsSymlink.
+func (f *fsRO) IsSymlink(path string) (bool, error) {
+	de, err := f.getLEntry(path)
+	if err != nil {
+		return found(err)
+	}
+
+	return de.Mode()&fs.ModeSymlink != 0, nil
+}
+
+func found(err error) (bool, error) {
+	if err == nil {
+		return true, nil
+	} else if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+
+	return false, err
+}
+
 func (f *fsRO) sub(path string) (directoryEntry, error) {
 	de, err := f.getEntry(path)
 	if err != nil {
@@ -201,6 +467,8 @@ func (f *fsRO) Sub(path string) (fs.FS, error) {
 	}
 
 	return &fsRO{
-		de: de,
+		de:            de,
+		resolveConfig: f.resolveConfig,
+		partial:       true,
 	}, nil
 }
@@ -2,59 +2,204 @@ package memfs
 
 import (
 	"errors"
+	"io"
 	"io/fs"
 	"path"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
+// ErrIsDir is returned when a directory is given to a method, such as OpenFile,
+// Create, or WriteFile, that can only operate on a file. Directories can only
+// be opened for reading, with Open.
+var ErrIsDir = errors.New("is a directory")
+
+// ErrNotDir is returned when an entry that is not a directory is found where a
+// directory is required, such as a non-final component of a path being resolved,
+// or of a path given to MkdirAll.
+var ErrNotDir = errors.New("not a directory")
+
+// ErrInvalidMode is returned by OpenFile when given a Mode that is not Valid.
+var ErrInvalidMode = errors.New("invalid open mode")
+
 // FS represents an in-memory fs.FS implementation, with additional methods for
 // a more 'OS' like experience.
 type FS struct {
 	mu sync.RWMutex
 	fsRO
+	config
 }
 
-func New() *FS {
-	return &FS{
-		fsRO: fsRO{
-			de: &dnodeRW{
-				dnode: dnode{
-					mode:    fs.ModeDir | fs.ModePerm,
-					modtime: time.Now(),
-				},
-			},
+// New creates a new, empty, FS, configured with the given Options.
+func New(opts ...Option) *FS {
+	f := &FS{
+		config: config{
+			tree: new(treeLock),
+		},
+	}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	now := f.deterministic.now()
+
+	f.de = &dnodeRW{
+		dnode: dnode{
+			mode:       fs.ModeDir | fs.ModePerm,
+			modtime:    now,
+			ctime:      now,
+			size:       f.dirSize,
+			maxEntries: f.maxEntries,
 		},
 	}
+
+	return f
 }
 
 // FSRO represents all of the methods on a read-only FS implementation.
+//
+// Any regular file, other than one created with CreateLazy, or symlink,
+// returned from the Open method can be type asserted to a FileRO, and any named
+// pipe to a *PipeFile.
 type FSRO interface {
 	fs.FS
 	fs.ReadDirFS
 	fs.ReadFileFS
 	fs.StatFS
 	fs.SubFS
+	Check() error
+	ContentsEqual(path1, path2 string) (bool, error)
+	Exists(path string) (bool, error)
+	GetACL(path string) (ACL, error)
+	GlobStar(pattern string) ([]string, error)
+	IsDir(path string) (bool, error)
+	IsSymlink(path string) (bool, error)
+	LReadDir(path string) ([]fs.DirEntry, error)
 	LStat(path string) (fs.FileInfo, error)
+	OpenDir(path string) (fs.ReadDirFile, error)
+	ReadDirInfo(path string) ([]fs.FileInfo, error)
+	ReadFileLimit(path string, max int64) ([]byte, error)
+	ReadFileString(path string) (string, error)
 	Readlink(path string) (string, error)
+	ResolveLink(path string, depth int) (string, error)
+	ScanDir(path string, fn func(fs.DirEntry) bool) error
+	SecureJoin(root, unsafe string) (string, error)
+	StatAll(paths []string) ([]fs.FileInfo, []error)
+	Stats(n int) Stats
+	WalkParallel(root string, workers int, fn fs.WalkDirFunc) error
+	WriteImage(w io.Writer) (int64, error)
+	WriteObjects(store ObjectStore, prefix string) error
+	WriteTar(w io.Writer, opts ...TarOption) error
+	WriteTo(w io.Writer) (int64, error)
+}
+
+// FileRO represents all of the methods on a file opened from a read-only FS.
+//
+// The WriteTo and Peek methods of a file opened from a sealed FS do not copy the
+// underlying data, and the slice returned by Peek must not be modified.
+type FileRO interface {
+	fs.File
+	io.ReaderAt
+	io.Seeker
+	io.WriterTo
+	io.RuneScanner
+	io.ByteScanner
+	ReadBytes(delim byte) ([]byte, error)
+	ReadString(delim byte) (string, error)
+	Peek(n int) ([]byte, error)
+	Discard(n int) (int, error)
+	Borrow(fn func(data []byte)) error
 }
 
 // Seal converts the Read-Write FS into a Read-only one.
 //
 // The resulting FSRO cannot be changed, and has no locking. As the current
-// implementation doesn't copy any data, it destroys the current FS in order to
-// remove the need for locks on the resulting FSRO.
+// implementation doesn't copy any data, the nodes are sealed in place; the
+// current FS, and any FS created from it with Sub, can still be read from, but
+// any attempt to modify it will return fs.ErrPermission.
+//
+// Files that were opened before the FS was sealed remain valid and continue to
+// read the sealed data, but any attempt to write to them will return
+// fs.ErrPermission.
 func (f *FS) Seal() FSRO {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	f.tree.lock()
+	defer f.tree.unlock()
+
+	return &fsRO{
+		de:            f.de.seal(),
+		resolveConfig: f.resolveConfig,
+		partial:       f.partial,
+	}
+}
+
+// SealSubtree converts the directory at the given path, following any
+// symlinks, into a Read-only FS, in the same way as Seal, leaving the rest of
+// the FS writable.
+//
+// As with Seal, the subtree is sealed in place, and so any attempt to modify
+// any entry within it, through the current FS, or through a file that is hard
+// linked from elsewhere in the FS, will return fs.ErrPermission, though the
+// directory itself can still be renamed. For a copy of a subtree that leaves
+// the FS unchanged, use SealCompact on an FS created with Sub.
+func (f *FS) SealSubtree(path string) (FSRO, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.tree.lock()
+	defer f.tree.unlock()
+
+	de, err := f.getEntry(path)
+	if err != nil {
+		return nil, &fs.PathError{Op: "sealsubtree", Path: path, Err: err}
+	} else if !de.IsDir() {
+		return nil, &fs.PathError{Op: "sealsubtree", Path: path, Err: ErrNotDir}
+	}
+
 	return &fsRO{
-		de: f.de.seal(),
+		de:            de.seal(),
+		resolveConfig: f.resolveConfig,
+		partial:       true,
+	}, nil
+}
+
+// SealCompact creates a Read-only copy of the FS, packing all of the names,
+// metadata and file data into a small number of large allocations, greatly
+// reducing the number of objects that need to be tracked by the garbage
+// collector for large trees.
+//
+// Unlike Seal, all of the data is copied and the current FS remains unchanged.
+//
+// The entries of each directory in the resulting FSRO are sorted by name, and
+// files that were hard linked share their data.
+//
+// Any error in producing the contents of a file created with CreateLazy will
+// be returned.
+func (f *FS) SealCompact() (FSRO, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	p, err := pack("sealcompact", f.de)
+	if err != nil {
+		return nil, err
 	}
+
+	return &fsRO{
+		de:            packedNode{packed: p},
+		resolveConfig: f.resolveConfig,
+	}, nil
 }
 
-func (f *FS) ReadDir(path string) ([]fs.DirEntry, error) {
+func (f *FS) ReadDir(path string) (_ []fs.DirEntry, err error) {
+	defer f.logOp("readdir", path).end(&err)
+
+	f.throttle.op()
+
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
@@ -71,24 +216,241 @@ func (f *FS) ReadDir(path string) ([]fs.DirEntry, error) {
 	return des, nil
 }
 
-func (f *FS) ReadFile(path string) ([]byte, error) {
+// Open opens the named file or directory for reading.
+//
+// The ReadDir method of an opened directory reads the entries as they were
+// when the directory was opened, unaffected by any later changes, so that
+// paginated calls neither skip nor repeat entries. As with fs.ReadDirFile, a
+// call with n <= 0 returns all remaining entries, returning no entries, and no
+// error, once all have been read.
+//
+// An opened directory also implements io.Seeker, allowing the iteration to be
+// restarted, with a fresh snapshot of the entries, by seeking to the start.
+func (f *FS) Open(path string) (_ fs.File, err error) {
+	defer f.logOp("open", path).end(&err)
+
+	f.throttle.open()
+
+	if err := f.handles.acquire(); err != nil {
+		return nil, &fs.PathError{Op: "open", Path: path, Err: err}
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	of, err := f.fsRO.Open(path)
+	if err != nil {
+		f.handles.release()
+
+		return nil, err
+	}
+
+	switch of := of.(type) {
+	case *File:
+		of.alloc = f.alloc
+		of.throttle = f.throttle
+		of.accounts = f.accounts(path)
+		of.handles = f.handles
+		of.orphans = f.orphans
+	case *directoryRW:
+		of.handles = f.handles
+	case *PipeFile:
+		of.handles = f.handles
+	default:
+		f.handles.release()
+	}
+
+	return of, nil
+}
+
+// OpenDir opens the named directory for reading, following any symlinks,
+// returning ErrNotDir when the entry is not a directory, in the manner of
+// opening with O_DIRECTORY.
+//
+// This allows a directory to be opened, and then listed, without a separate
+// check of its type that could be invalidated by a concurrent change. The
+// returned directory behaves as one returned by Open.
+func (f *FS) OpenDir(path string) (_ fs.ReadDirFile, err error) {
+	defer f.logOp("opendir", path).end(&err)
+
+	f.throttle.open()
+
+	if err := f.handles.acquire(); err != nil {
+		return nil, &fs.PathError{Op: "opendir", Path: path, Err: err}
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	d, err := f.fsRO.OpenDir(path)
+	if err != nil {
+		f.handles.release()
+
+		return nil, err
+	}
+
+	if d, ok := d.(*directoryRW); ok {
+		d.handles = f.handles
+	} else {
+		f.handles.release()
+	}
+
+	return d, nil
+}
+
+// ScanDir calls the given func with each of the entries of the directory at
+// the given path, in the order they would be returned by ReadDir, stopping
+// early when the func returns false, without allocating a slice of all of the
+// entries, making it suitable for very large directories.
+//
+// The FS lock is held for reading while the func is called, and so the func
+// must not modify the FS, which would deadlock.
+func (f *FS) ScanDir(path string, fn func(fs.DirEntry) bool) (err error) {
+	defer f.logOp("scandir", path).end(&err)
+
+	f.throttle.op()
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.fsRO.ScanDir(path, fn)
+}
+
+// LReadDir reads the directory at the given path, as with ReadDir, but without
+// following a symlink as the final element of the path, returning ErrNotDir
+// for a symlink.
+func (f *FS) LReadDir(path string) (_ []fs.DirEntry, err error) {
+	defer f.logOp("lreaddir", path).end(&err)
+
+	f.throttle.op()
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.fsRO.LReadDir(path)
+}
+
+// ReadDirInfo returns the fs.FileInfo of each of the entries of the directory
+// at the given path, as with calling Info on each of the entries returned by
+// ReadDir.
+//
+// All of the entries are read with a single acquisition of the FS lock.
+func (f *FS) ReadDirInfo(path string) ([]fs.FileInfo, error) {
+	f.throttle.op()
+
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
-	return f.fsRO.ReadFile(path)
+	return f.fsRO.ReadDirInfo(path)
+}
+
+func (f *FS) ReadFile(path string) (data []byte, err error) {
+	l := f.logOp("readfile", path)
+	defer l.end(&err)
+
+	f.throttle.open()
+
+	f.mu.RLock()
+	data, err = f.fsRO.ReadFile(path)
+	f.mu.RUnlock()
+
+	n := len(data)
+
+	l.setBytes(n)
+
+	f.throttle.read(&n)
+	f.accounts(path).read(&n)
+
+	return data, err
+}
+
+// ReadFileLimit reads the named file, as with ReadFile, returning
+// ErrFileTooLarge, without reading the file, when it is larger than max bytes.
+//
+// A file that grows beyond max bytes while being read is also refused.
+func (f *FS) ReadFileLimit(path string, max int64) (data []byte, err error) {
+	l := f.logOp("readfile", path)
+	defer l.end(&err)
+
+	f.throttle.open()
+
+	f.mu.RLock()
+	data, err = f.fsRO.ReadFileLimit(path, max)
+	f.mu.RUnlock()
+
+	n := len(data)
+
+	l.setBytes(n)
+
+	f.throttle.read(&n)
+	f.accounts(path).read(&n)
+
+	return data, err
+}
+
+// ReadFileString reads the named file, as with ReadFile, returning its contents
+// as a string.
+func (f *FS) ReadFileString(path string) (data string, err error) {
+	l := f.logOp("readfile", path)
+	defer l.end(&err)
+
+	f.throttle.open()
+
+	f.mu.RLock()
+	data, err = f.fsRO.ReadFileString(path)
+	f.mu.RUnlock()
+
+	n := len(data)
+
+	l.setBytes(n)
+
+	f.throttle.read(&n)
+	f.accounts(path).read(&n)
+
+	return data, err
 }
 
-func (f *FS) Stat(path string) (fs.FileInfo, error) {
+func (f *FS) Stat(path string) (_ fs.FileInfo, err error) {
+	defer f.logOp("stat", path).end(&err)
+
+	f.throttle.op()
+
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
 	return f.fsRO.Stat(path)
 }
 
-func (f *FS) Mkdir(path string, perm fs.FileMode) error {
+// StatAll returns the fs.FileInfo of each of the given paths, as with Stat,
+// along with the error, if any, for each path, such that the error for
+// paths[n] is errs[n].
+//
+// All of the paths are resolved with a single acquisition of the FS lock.
+func (f *FS) StatAll(paths []string) (fis []fs.FileInfo, errs []error) {
+	f.throttle.op()
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.fsRO.StatAll(paths)
+}
+
+func (f *FS) Mkdir(path string, perm fs.FileMode) (err error) {
+	defer f.logOp("mkdir", path).withMode(perm).end(&err)
+
+	f.throttle.op()
+
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	return f.makeDir(path, perm)
+}
+
+func (f *FS) makeDir(path string, perm fs.FileMode) error {
+	if err := f.limit.op(); err != nil {
+		return &fs.PathError{Op: "mkdir", Path: path, Err: err}
+	}
+
 	return f.mkdir("mkdir", path, path, perm)
 }
 
@@ -98,58 +460,107 @@ func (f *FS) mkdir(op, opath, p string, perm fs.FileMode) error {
 		return &fs.PathError{Op: op, Path: opath, Err: err}
 	}
 
+	now := f.deterministic.now()
+
 	if err := d.setEntry(&dirEnt{
 		directoryEntry: &dnodeRW{
 			dnode: dnode{
-				modtime: time.Now(),
-				mode:    fs.ModeDir | perm,
+				modtime:    now,
+				ctime:      now,
+				mode:       fs.ModeDir | perm,
+				size:       f.dirSize,
+				maxEntries: f.maxEntries,
 			},
 		},
-		name: path.Base(p),
-	}); err != nil {
+		name: entryName(p),
+	}, f.deterministic); err != nil {
 		return &fs.PathError{Op: op, Path: opath, Err: err}
 	}
 
+	f.record(Event{Op: "mkdir", Path: p, Mode: perm})
+
 	return nil
 }
 
-func (f *FS) MkdirAll(p string, perm fs.FileMode) error {
+// MkdirAll creates a directory at the given path, along with any missing
+// parents, following any symlinks.
+//
+// As with os.MkdirAll, it is not an error for the directory to already exist,
+// and write permission is only required on the directories in which new
+// directories are created. When an existing entry on the path is not a
+// directory, the returned fs.PathError contains ErrNotDir and the path of that
+// entry.
+func (f *FS) MkdirAll(p string, perm fs.FileMode) (err error) {
+	defer f.logOp("mkdirall", p).withMode(perm).end(&err)
+
+	f.throttle.op()
+
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	cpath := path.Join(slash, p)
-	last := 0
+	if err := f.limit.op(); err != nil {
+		return &fs.PathError{Op: "mkdirall", Path: p, Err: err}
+	}
 
-	for {
-		pos := strings.IndexRune(cpath[last:], '/')
-		if pos < 0 {
-			break
-		} else if pos == 0 {
-			last++
+	cpath := path.Join(slash, p)[1:]
+	if cpath == "" {
+		return &fs.PathError{Op: "mkdirall", Path: p, Err: fs.ErrInvalid}
+	}
 
-			continue
+	for last := 0; last < len(cpath); {
+		if pos := strings.IndexByte(cpath[last+1:], '/'); pos < 0 {
+			last = len(cpath)
+		} else {
+			last += pos + 1
 		}
 
-		last += pos
-
-		if err := f.mkdir("mkdirall", p, cpath[:last], perm); err != nil && !errors.Is(err, fs.ErrExist) {
+		if err := f.mkdirAllPart(p, cpath[:last], perm); err != nil {
 			return err
 		}
 	}
 
-	return f.mkdir("mkdirall", p, cpath, perm)
+	return nil
+}
+
+// mkdirAllPart creates the directory at the given path, unless there is
+// already a directory there.
+func (f *FS) mkdirAllPart(opath, p string, perm fs.FileMode) error {
+	de, err := f.getEntry(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f.mkdir("mkdirall", opath, p, perm)
+	} else if err != nil {
+		return &fs.PathError{Op: "mkdirall", Path: opath, Err: err}
+	} else if !de.IsDir() {
+		return &fs.PathError{Op: "mkdirall", Path: p, Err: ErrNotDir}
+	}
+
+	return nil
 }
 
 const defaultPerms = 0o666
 
+// Create creates, or truncates, the named file, opening it for reading and
+// writing. A new file is given the permissions set with WithDefaultPerms, or
+// 0o666 if none were set.
 func (f *FS) Create(path string) (*File, error) {
-	return f.openFile("create", path, ReadWrite|Create|Truncate, defaultPerms)
+	return f.openFile("create", path, ReadWrite|Create|Truncate, f.defaultPerms())
 }
 
 // Mode is used to determine how a file is opened.
 //
 // Each value of Mode matches the intention of its similarly named OS
 // counterpart.
+//
+// For a file opened with Append, every write is made at the end of the file,
+// as it is at the time of the write, so that writes made through other handles
+// are never overwritten. The only exception is WriteAt, which, as with pwrite,
+// always writes at the given offset.
+//
+// A symlink at the final element of the path is followed, with the target of a
+// dangling symlink being created when Create is set. Opening a file with
+// NoFollow instead fails with ErrNoFollow when the final element of the path is
+// a symlink, as with O_NOFOLLOW; symlinks among the parent directories are
+// still followed.
 type Mode uint8
 
 const (
@@ -159,10 +570,57 @@ const (
 	Create
 	Excl
 	Truncate
+	NoFollow
 
 	ReadWrite = ReadOnly | WriteOnly
 )
 
+var modeNames = [...]string{"ReadOnly", "WriteOnly", "Append", "Create", "Excl", "Truncate", "NoFollow"}
+
+// String returns the names of the flags set in the Mode, separated by '|', with
+// ReadWrite used when both ReadOnly and WriteOnly are set, and any unknown
+// flags given in hexadecimal.
+func (m Mode) String() string {
+	var names []string
+
+	if m&ReadWrite == ReadWrite {
+		names = append(names, "ReadWrite")
+		m &^= ReadWrite
+	}
+
+	for n, name := range modeNames {
+		if flag := Mode(1) << n; m&flag != 0 {
+			names = append(names, name)
+			m &^= flag
+		}
+	}
+
+	if m != 0 {
+		names = append(names, "0x"+strconv.FormatUint(uint64(m), 16))
+	} else if len(names) == 0 {
+		return "0"
+	}
+
+	return strings.Join(names, "|")
+}
+
+// Valid returns true if the Mode is a valid combination of flags.
+//
+// A valid Mode includes at least one of ReadOnly and WriteOnly, only includes
+// Append and Truncate along with WriteOnly, only includes Excl along with
+// Create, and includes no unknown flags.
+func (m Mode) Valid() bool {
+	switch {
+	case m&ReadWrite == 0,
+		m&(Append|Truncate) != 0 && m&WriteOnly == 0,
+		m&Excl != 0 && m&Create == 0,
+		m&^(ReadWrite|Append|Create|Excl|Truncate|NoFollow) != 0:
+		return false
+	}
+
+	return true
+}
+
 func existCheck(mode Mode) exists {
 	if mode&Excl != 0 {
 		return mustNotExist
@@ -183,52 +641,148 @@ func openMode(mode Mode) opMode {
 		openMode |= opWrite
 	}
 
+	if mode&Append != 0 {
+		openMode |= opAppend
+	}
+
 	return openMode
 }
 
-func (f *FS) openOrCreateFile(p string, mode Mode, perm fs.FileMode) (fs.File, error) {
-	d, existingFile, err := f.getEntryWithParent(p, existCheck(mode))
-	if err != nil {
-		return nil, err
+// getFileWithParent acts as getEntryWithParent, but follows a symlink at the
+// final element of the path, unless noFollow is set, returning the parent and
+// entry of its target, which need not exist, along with the path of the
+// target.
+func (f *FS) getFileWithParent(p string, exists exists, noFollow bool) (dNode, *dirEnt, string, error) {
+	d, de, err := f.getEntryWithParent(p, exists)
+	if err != nil || de == nil || de.Type() != fs.ModeSymlink {
+		return d, de, p, err
+	} else if noFollow || f.noFollow {
+		return nil, nil, p, ErrNoFollow
 	}
 
-	fileName := path.Base(p)
+	limit := f.symlinkLimit()
 
-	if existingFile == nil {
-		existingFile = &dirEnt{
-			directoryEntry: &inodeRW{
-				inode: inode{
-					modtime: time.Now(),
-					mode:    perm,
-				},
-			},
-			name: fileName,
+	for n := 0; de != nil && de.Type() == fs.ModeSymlink; n++ {
+		if n == limit {
+			return nil, nil, p, &SymlinkLimitError{Path: p, Limit: limit}
+		}
+
+		link, err := de.string()
+		if err != nil {
+			return nil, nil, p, err
+		}
+
+		if !strings.HasPrefix(link, slash) {
+			dir, _ := splitPath(p)
+			link = f.resolveDir(dir) + slash + link
+		}
+
+		if p = string(cleanPath([]byte(link))); p == "" {
+			p = "."
+		}
+
+		if d, de, err = f.getEntryWithParent(p, exists); err != nil {
+			return nil, nil, p, err
 		}
+	}
+
+	return d, de, p, nil
+}
 
-		if err = d.setEntry(existingFile); err != nil {
-			return nil, err
+// openOrCreateFile opens, or creates, the file at the given path, following a
+// symlink at the final element of the path unless NoFollow is set, and returns
+// the path of the file that was opened.
+func (f *FS) openOrCreateFile(p string, mode Mode, perm fs.FileMode) (fs.File, string, error) {
+	name := entryName(p)
+
+	d, existingFile, p, err := f.getFileWithParent(p, existCheck(mode), mode&NoFollow != 0)
+	if err != nil {
+		return nil, p, err
+	} else if existingFile != nil && existingFile.IsDir() {
+		return nil, p, ErrIsDir
+	} else if existingFile != nil && existingFile.Type() == fs.ModeNamedPipe {
+		return nil, p, fs.ErrInvalid
+	}
+
+	if existingFile == nil {
+		existingFile = newFileEntry(entryName(p), inode{
+			modtime: f.deterministic.now(),
+			mode:    perm,
+		})
+
+		if err = d.setEntry(existingFile, f.deterministic); err != nil {
+			return nil, p, err
 		}
 	}
 
-	return existingFile.open(fileName, openMode(mode))
+	of, err := existingFile.open(name, openMode(mode))
+
+	return of, path.Join(path.Dir(p), existingFile.name), err
 }
 
-func (f *FS) openFile(op, path string, mode Mode, perm fs.FileMode) (*File, error) {
+func (f *FS) openFile(op, path string, mode Mode, perm fs.FileMode) (_ *File, err error) {
+	defer f.logOp(op, path).withMode(perm).end(&err)
+
+	if !mode.Valid() {
+		return nil, &fs.PathError{Op: op, Path: path, Err: ErrInvalidMode}
+	}
+
+	f.throttle.open()
+
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	of, err := f.openOrCreateFile(path, mode, perm)
+	return f.openFileEntry(op, path, mode, perm)
+}
+
+func (f *FS) openFileEntry(op, path string, mode Mode, perm fs.FileMode) (*File, error) {
+	if mode&(WriteOnly|Create|Truncate) != 0 {
+		if err := f.limit.op(); err != nil {
+			return nil, &fs.PathError{Op: op, Path: path, Err: err}
+		}
+	}
+
+	if err := f.handles.acquire(); err != nil {
+		return nil, &fs.PathError{Op: op, Path: path, Err: err}
+	}
+
+	of, target, err := f.openOrCreateFile(path, mode, perm)
 	if err != nil {
+		f.handles.release()
+
 		return nil, &fs.PathError{Op: op, Path: path, Err: err}
 	}
 
 	ef, ok := of.(*File)
 	if !ok {
+		f.handles.release()
+
 		return nil, &fs.PathError{Op: op, Path: path, Err: fs.ErrInvalid}
 	}
 
+	ef.growth = f.growth
+	ef.alloc = f.alloc
+	ef.limit = f.limit
+	ef.throttle = f.throttle
+	ef.accounts = f.accounts(target)
+	ef.handles = f.handles
+	ef.orphans = f.orphans
+	ef.clock = f.deterministic
+
 	ef.handleOpenMode(mode)
 
+	if f.journal != nil || f.objects != nil {
+		ef.journal = f.journal
+		ef.objects = f.objects
+		if mode&WriteOnly != 0 {
+			ef.tracked = f.opens.open(f.realPath(splitPath(target)))
+		}
+
+		if mode&(Create|Truncate) != 0 {
+			f.record(Event{Op: "openfile", Path: path, Mode: perm, Flags: mode})
+		}
+	}
+
 	return ef, nil
 }
 
@@ -236,78 +790,564 @@ func (f *FS) OpenFile(path string, mode Mode, perm fs.FileMode) (*File, error) {
 	return f.openFile("openfile", path, mode, perm)
 }
 
-func (f *FS) Link(oldPath, newPath string) error {
-	f.mu.Lock()
-	defer f.mu.Unlock()
+// WriteFile writes the given data to the named file, creating it with the
+// given permissions if necessary, and truncating it otherwise. A symlink at the
+// given path is followed, as with os.WriteFile.
+//
+// When the file does not already exist, the directory entry and inode are
+// allocated together, the data is copied to a buffer of the exact size, and no
+// File is opened, making this the fastest way to create many small files.
+func (f *FS) WriteFile(path string, data []byte, perm fs.FileMode) (err error) {
+	n := len(data)
 
-	if oe, err := f.getLEntry(oldPath); err != nil {
-		return &fs.PathError{Op: "link", Path: oldPath, Err: err}
-	} else if oe.IsDir() {
-		return &fs.PathError{Op: "link", Path: oldPath, Err: fs.ErrInvalid}
-	} else if d, _, err := f.getEntryWithParent(newPath, mustNotExist); err != nil {
-		return &fs.PathError{Op: "link", Path: newPath, Err: err}
-	} else if err := d.setEntry(&dirEnt{directoryEntry: oe.directoryEntry, name: path.Base(newPath)}); err != nil {
-		return &fs.PathError{Op: "link", Path: newPath, Err: err}
-	}
+	l := f.logOp("writefile", path).withMode(perm)
+	defer l.end(&err)
 
-	return nil
-}
+	l.setBytes(n)
+
+	f.throttle.open()
+	f.throttle.write(&n)
 
-func (f *FS) Symlink(oldPath, newPath string) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	d, _, err := f.getEntryWithParent(newPath, mustNotExist)
-	if err != nil {
-		return &fs.PathError{Op: "symlink", Path: newPath, Err: err}
-	}
+	return f.writeFile("writefile", path, data, perm, opWrite|opSeek)
+}
 
-	if err = d.setEntry(&dirEnt{
-		directoryEntry: &inodeRW{
-			inode: inode{
-				data:    []byte(path.Clean(oldPath)),
-				modtime: time.Now(),
-				mode:    fs.ModeSymlink | fs.ModePerm,
-			},
-		},
-		name: path.Base(newPath),
-	}); err != nil {
-		return &fs.PathError{Op: "symlink", Path: newPath, Err: err}
-	}
+// AppendFile appends the given data to the named file, creating it with the
+// given permissions if necessary, as with opening the file with
+// WriteOnly|Create|Append, writing the data, and closing it, but with a single
+// acquisition of the FS lock and without opening a File.
+func (f *FS) AppendFile(path string, data []byte, perm fs.FileMode) (err error) {
+	n := len(data)
 
-	return nil
-}
+	l := f.logOp("appendfile", path).withMode(perm)
+	defer l.end(&err)
 
-const canWrite = 0o222
+	l.setBytes(n)
+
+	f.throttle.open()
+	f.throttle.write(&n)
 
-func (f *FS) Rename(oldPath, newPath string) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	if od, oldFile, err := f.getEntryWithParent(oldPath, mustExist); err != nil {
-		return &fs.PathError{Op: "rename", Path: oldPath, Err: err}
-	} else if nd, _, err := f.getEntryWithParent(newPath, mustNotExist); err != nil {
-		return &fs.PathError{Op: "rename", Path: newPath, Err: err}
-	} else if nd.Mode()&canWrite == 0 {
-		return &fs.PathError{Op: "rename", Path: newPath, Err: fs.ErrPermission}
-	} else if err = od.removeEntry(oldFile.name); err != nil {
-		return &fs.PathError{Op: "rename", Path: newPath, Err: err}
-	} else if err = nd.setEntry(&dirEnt{
-		directoryEntry: oldFile.directoryEntry,
-		name:           path.Base(newPath),
-	}); err != nil {
-		return &fs.PathError{Op: "rename", Path: newPath, Err: err}
-	}
+	return f.writeFile("appendfile", path, data, perm, opWrite|opAppend)
+}
 
-	return nil
+// WriteFileString writes the given string to the named file, as with
+// WriteFile.
+func (f *FS) WriteFileString(path, data string, perm fs.FileMode) error {
+	return f.WriteFile(path, []byte(data), perm)
 }
 
-func (f *FS) Remove(path string) error {
+// CreateFromBytes creates a new file, with the given permissions, at the given
+// path, which must not already exist, using the given data as its contents
+// without copying it, allowing for large data that is already in memory, such
+// as a memory-mapped file, to be added to the FS cheaply.
+//
+// When readOnly is true, the data is never modified by the FS, with the file
+// being given its own copy of the data when it is first modified, and the data
+// is never given to any Allocator set with WithAllocator.
+//
+// Otherwise, the FS takes ownership of the data, which is modified in place by
+// writes to the file, and which should not be used by the caller afterwards.
+func (f *FS) CreateFromBytes(path string, data []byte, perm fs.FileMode, readOnly bool) (err error) {
+	l := f.logOp("createfrombytes", path).withMode(perm)
+	defer l.end(&err)
+
+	l.setBytes(len(data))
+
+	f.throttle.op()
+
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	d, de, err := f.getEntryWithParent(path, mustExist)
-	if err != nil {
+	if _, err := f.limit.write(len(data)); err != nil {
+		return &fs.PathError{Op: "createfrombytes", Path: path, Err: err}
+	}
+
+	d, _, err := f.getEntryWithParent(path, mustNotExist)
+	if err != nil {
+		return &fs.PathError{Op: "createfrombytes", Path: path, Err: err}
+	}
+
+	if readOnly {
+		data = data[:len(data):len(data)]
+	}
+
+	if err := d.setEntry(newFileEntry(entryName(path), inode{
+		data:    data,
+		modtime: f.deterministic.now(),
+		mode:    perm,
+		shared:  readOnly,
+	}), f.deterministic); err != nil {
+		return &fs.PathError{Op: "createfrombytes", Path: path, Err: err}
+	}
+
+	f.record(Event{Op: "writefile", Path: path, Mode: perm, Data: data})
+
+	n := len(data)
+
+	f.accounts(path).write(&n)
+
+	return f.writeThrough("createfrombytes", path)
+}
+
+func (f *FS) writeFile(op, path string, data []byte, perm fs.FileMode, mode opMode) error {
+	if _, err := f.limit.write(len(data)); err != nil {
+		return &fs.PathError{Op: op, Path: path, Err: err}
+	}
+
+	d, existingFile, target, err := f.getFileWithParent(path, doesntMatter, false)
+	if err != nil {
+		return &fs.PathError{Op: op, Path: path, Err: err}
+	}
+
+	if existingFile == nil {
+		err = d.setEntry(newFileEntry(entryName(target), inode{
+			data:    f.copyData(data),
+			modtime: f.deterministic.now(),
+			mode:    perm,
+		}), f.deterministic)
+	} else {
+		err = f.overwrite(existingFile, data, mode)
+	}
+
+	if err != nil {
+		return &fs.PathError{Op: op, Path: path, Err: err}
+	}
+
+	f.record(Event{Op: op, Path: path, Mode: perm, Data: data})
+
+	n := len(data)
+
+	f.accounts(target).write(&n)
+
+	return f.writeThrough(op, target)
+}
+
+// Touch creates an empty file, with the permissions set with WithDefaultPerms,
+// or 0o666 if none were set, at the given path if nothing exists there,
+// otherwise it sets the modification time of the existing entry, following any
+// symlinks, to the current time.
+//
+// Updating the time of an existing entry requires that it is writable.
+func (f *FS) Touch(path string) (err error) {
+	defer f.logOp("touch", path).end(&err)
+
+	f.throttle.op()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.limit.op(); err != nil {
+		return &fs.PathError{Op: "touch", Path: path, Err: err}
+	}
+
+	if err := f.touch(path); err != nil {
+		return &fs.PathError{Op: "touch", Path: path, Err: err}
+	}
+
+	f.record(Event{Op: "touch", Path: path})
+
+	return f.writeThrough("touch", path)
+}
+
+func (f *FS) touch(path string) error {
+	now := f.deterministic.now()
+
+	de, err := f.getEntry(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		d, _, err := f.getEntryWithParent(path, mustNotExist)
+		if err != nil {
+			return err
+		}
+
+		return d.setEntry(newFileEntry(entryName(path), inode{
+			modtime: now,
+			mode:    f.defaultPerms(),
+		}), f.deterministic)
+	} else if err != nil {
+		return err
+	} else if de.perm()&modeWrite == 0 {
+		return fs.ErrPermission
+	} else if err := de.setTimes(now, now); err != nil {
+		return err
+	}
+
+	de.touch(now)
+
+	return nil
+}
+
+func (f *FS) copyData(data []byte) []byte {
+	if len(data) == 0 {
+		return nil
+	} else if f.alloc == nil {
+		return append(make([]byte, 0, len(data)), data...)
+	}
+
+	buf := f.alloc.Alloc(len(data), len(data))
+
+	copy(buf, data)
+
+	return buf
+}
+
+func (f *FS) overwrite(de *dirEnt, data []byte, mode opMode) error {
+	if de.IsDir() {
+		return ErrIsDir
+	} else if de.Type() == fs.ModeNamedPipe {
+		return fs.ErrInvalid
+	}
+
+	of, err := de.open(de.name, mode)
+	if err != nil {
+		return err
+	}
+
+	ef, ok := of.(*File)
+	if !ok {
+		return fs.ErrInvalid
+	}
+
+	ef.growth = f.growth
+	ef.alloc = f.alloc
+	ef.limit = f.limit
+	ef.orphans = f.orphans
+	ef.clock = f.deterministic
+
+	if mode&opAppend == 0 {
+		err = ef.Truncate(0)
+	}
+
+	if err == nil {
+		_, err = ef.Write(data)
+	}
+
+	if cerr := ef.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+func (f *FS) Link(oldPath, newPath string) (err error) {
+	defer f.logOp("link", oldPath).withNewPath(newPath).end(&err)
+
+	f.throttle.op()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.tree.lock()
+	defer f.tree.unlock()
+
+	if err := f.limit.op(); err != nil {
+		return &fs.PathError{Op: "link", Path: newPath, Err: err}
+	}
+
+	oe, err := f.getLEntry(oldPath)
+	if err != nil {
+		return &fs.PathError{Op: "link", Path: oldPath, Err: err}
+	} else if oe.IsDir() {
+		return &fs.PathError{Op: "link", Path: oldPath, Err: fs.ErrInvalid}
+	} else if d, _, err := f.getEntryWithParent(newPath, mustNotExist); err != nil {
+		return &fs.PathError{Op: "link", Path: newPath, Err: err}
+	} else if err := d.setEntry(&dirEnt{directoryEntry: oe.directoryEntry, name: entryName(newPath)}, f.deterministic); err != nil {
+		return &fs.PathError{Op: "link", Path: newPath, Err: err}
+	} else if i, ok := oe.directoryEntry.(*inodeRW); ok {
+		i.link()
+	} else if l, ok := oe.directoryEntry.(*lazyNode); ok {
+		l.link()
+	}
+
+	oe.touch(f.deterministic.current())
+
+	f.record(Event{Op: "link", Path: oldPath, NewPath: newPath})
+
+	return f.writeThrough("link", newPath)
+}
+
+// Symlink creates a symbolic link at newPath pointing to oldPath.
+//
+// The target is stored exactly as given, and is only resolved when the link is
+// followed; it must not be empty, nor contain a NUL byte.
+func (f *FS) Symlink(oldPath, newPath string) (err error) {
+	defer f.logOp("symlink", oldPath).withNewPath(newPath).end(&err)
+
+	f.throttle.op()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.limit.op(); err != nil {
+		return &fs.PathError{Op: "symlink", Path: newPath, Err: err}
+	} else if !validTarget(oldPath) {
+		return &fs.PathError{Op: "symlink", Path: newPath, Err: fs.ErrInvalid}
+	}
+
+	d, _, err := f.getEntryWithParent(newPath, mustNotExist)
+	if err != nil {
+		return &fs.PathError{Op: "symlink", Path: newPath, Err: err}
+	}
+
+	if err = d.setEntry(newFileEntry(entryName(newPath), inode{
+		data:    []byte(oldPath),
+		modtime: f.deterministic.now(),
+		mode:    fs.ModeSymlink | fs.ModePerm,
+	}), f.deterministic); err != nil {
+		return &fs.PathError{Op: "symlink", Path: newPath, Err: err}
+	}
+
+	f.record(Event{Op: "symlink", Path: newPath, Data: []byte(oldPath)})
+
+	return nil
+}
+
+func validTarget(target string) bool {
+	return target != "" && strings.IndexByte(target, 0) < 0
+}
+
+const canWrite = 0o222
+
+// Rename moves the entry at oldPath to newPath, replacing any existing entry,
+// matching the semantics of the rename syscall.
+//
+// A directory can only replace an empty directory, and a non-directory cannot
+// replace a directory. A directory cannot be moved to within itself.
+func (f *FS) Rename(oldPath, newPath string) error {
+	return f.rename("rename", oldPath, newPath, doesntMatter)
+}
+
+// RenameNoReplace moves the entry at oldPath to newPath, which must not exist,
+// matching the semantics of renameat2 with the RENAME_NOREPLACE flag.
+//
+// A directory cannot be moved to within itself.
+func (f *FS) RenameNoReplace(oldPath, newPath string) error {
+	return f.rename("renamenoreplace", oldPath, newPath, mustNotExist)
+}
+
+func (f *FS) rename(op, oldPath, newPath string, exists exists) (err error) {
+	defer f.logOp(op, oldPath).withNewPath(newPath).end(&err)
+
+	f.throttle.op()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.tree.lock()
+	defer f.tree.unlock()
+
+	if err := f.limit.op(); err != nil {
+		return &fs.PathError{Op: op, Path: oldPath, Err: err}
+	}
+
+	od, oldFile, err := f.getEntryWithParent(oldPath, mustExist)
+	if err != nil {
+		return &fs.PathError{Op: op, Path: oldPath, Err: err}
+	}
+
+	nd, newFile, err := f.getEntryWithParent(newPath, exists)
+	if err != nil {
+		return &fs.PathError{Op: op, Path: newPath, Err: err}
+	} else if nd.perm()&canWrite == 0 {
+		return &fs.PathError{Op: op, Path: newPath, Err: fs.ErrPermission}
+	} else if newFile != nil && newFile.directoryEntry == oldFile.directoryEntry {
+		return nil
+	} else if oldFile.IsDir() && contains(oldFile.directoryEntry, nd) {
+		return &fs.PathError{Op: op, Path: newPath, Err: fs.ErrInvalid}
+	} else if err = canReplace(oldFile, newFile); err != nil {
+		return &fs.PathError{Op: op, Path: newPath, Err: err}
+	}
+
+	switch {
+	case newFile == nil && od == nd:
+		// remove the old name first, so that renaming within a full
+		// directory doesn't exceed its entry limit.
+		if err = od.removeEntry(oldFile.name, f.deterministic); err != nil {
+			return &fs.PathError{Op: op, Path: oldPath, Err: err}
+		} else if err = nd.setEntry(&dirEnt{
+			directoryEntry: oldFile.directoryEntry,
+			name:           entryName(newPath),
+		}, f.deterministic); err != nil {
+			od.setEntry(oldFile, f.deterministic)
+
+			return &fs.PathError{Op: op, Path: newPath, Err: err}
+		}
+	case newFile == nil:
+		err = nd.setEntry(&dirEnt{
+			directoryEntry: oldFile.directoryEntry,
+			name:           entryName(newPath),
+		}, f.deterministic)
+	default:
+		err = nd.replaceEntry(&dirEnt{
+			directoryEntry: oldFile.directoryEntry,
+			name:           newFile.name,
+		}, f.deterministic)
+	}
+
+	if err != nil {
+		return &fs.PathError{Op: op, Path: newPath, Err: err}
+	} else if newFile != nil || od != nd {
+		if err = od.removeEntry(oldFile.name, f.deterministic); err != nil {
+			if newFile == nil {
+				nd.removeEntry(entryName(newPath), f.deterministic)
+			} else {
+				nd.replaceEntry(newFile, f.deterministic)
+			}
+
+			return &fs.PathError{Op: op, Path: oldPath, Err: err}
+		}
+	}
+
+	f.cache.invalidate()
+
+	now := f.deterministic.current()
+
+	oldFile.touch(now)
+
+	if newFile != nil {
+		newFile.touch(now)
+		unlinkAll(newFile.directoryEntry, f.alloc)
+		f.orphans.add(newFile.directoryEntry)
+	}
+
+	if f.opens.tracking() {
+		oldDir, _ := splitPath(oldPath)
+		newDir, newName := splitPath(newPath)
+
+		if newFile != nil {
+			newName = newFile.name
+		}
+
+		f.opens.rename(f.realPath(oldDir, oldFile.name), f.realPath(newDir, newName))
+	}
+
+	f.record(Event{Op: op, Path: oldPath, NewPath: newPath})
+
+	return f.writeThrough(op, oldPath, newPath)
+}
+
+func canReplace(oldFile, newFile *dirEnt) error {
+	if newFile == nil {
+		return nil
+	} else if !oldFile.IsDir() {
+		if newFile.IsDir() {
+			return fs.ErrInvalid
+		}
+	} else if d, ok := newFile.directoryEntry.(dNode); !ok {
+		return fs.ErrInvalid
+	} else if d.hasEntries() {
+		return fs.ErrExist
+	}
+
+	return nil
+}
+
+// RenameExchange atomically swaps the entries at the two given paths, both of
+// which must exist, matching the semantics of renameat2 with the
+// RENAME_EXCHANGE flag.
+//
+// Neither path may be within the other.
+func (f *FS) RenameExchange(path1, path2 string) (err error) {
+	defer f.logOp("renameexchange", path1).withNewPath(path2).end(&err)
+
+	f.throttle.op()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.tree.lock()
+	defer f.tree.unlock()
+
+	if err := f.limit.op(); err != nil {
+		return &fs.PathError{Op: "renameexchange", Path: path1, Err: err}
+	}
+
+	d1, e1, err := f.getEntryWithParent(path1, mustExist)
+	if err != nil {
+		return &fs.PathError{Op: "renameexchange", Path: path1, Err: err}
+	}
+
+	d2, e2, err := f.getEntryWithParent(path2, mustExist)
+	if err != nil {
+		return &fs.PathError{Op: "renameexchange", Path: path2, Err: err}
+	} else if e1 == e2 {
+		return nil
+	} else if d1.perm()&canWrite == 0 {
+		return &fs.PathError{Op: "renameexchange", Path: path1, Err: fs.ErrPermission}
+	} else if d2.perm()&canWrite == 0 {
+		return &fs.PathError{Op: "renameexchange", Path: path2, Err: fs.ErrPermission}
+	} else if contains(e1.directoryEntry, d2) || contains(e2.directoryEntry, d1) {
+		return &fs.PathError{Op: "renameexchange", Path: path2, Err: fs.ErrInvalid}
+	}
+
+	if err := d1.replaceEntry(&dirEnt{directoryEntry: e2.directoryEntry, name: e1.name}, f.deterministic); err != nil {
+		return &fs.PathError{Op: "renameexchange", Path: path1, Err: err}
+	} else if err := d2.replaceEntry(&dirEnt{directoryEntry: e1.directoryEntry, name: e2.name}, f.deterministic); err != nil {
+		d1.replaceEntry(e1, f.deterministic)
+
+		return &fs.PathError{Op: "renameexchange", Path: path2, Err: err}
+	}
+
+	f.cache.invalidate()
+
+	now := f.deterministic.current()
+
+	e1.touch(now)
+	e2.touch(now)
+
+	if f.opens.tracking() {
+		dir1, _ := splitPath(path1)
+		dir2, _ := splitPath(path2)
+
+		f.opens.exchange(f.realPath(dir1, e1.name), f.realPath(dir2, e2.name))
+	}
+
+	f.record(Event{Op: "renameexchange", Path: path1, NewPath: path2})
+
+	return f.writeThrough("renameexchange", path1, path2)
+}
+
+// contains returns true if the given directory is, or is below, the given
+// entry.
+func contains(de directoryEntry, d dNode) bool {
+	if any(de) == any(d) {
+		return true
+	}
+
+	for _, e := range childEntries(de) {
+		if e.IsDir() && contains(e.directoryEntry, d) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f *FS) Remove(path string) (err error) {
+	defer f.logOp("remove", path).end(&err)
+
+	f.throttle.op()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.remove(path)
+}
+
+func (f *FS) remove(path string) error {
+	f.tree.lock()
+	defer f.tree.unlock()
+
+	if err := f.limit.op(); err != nil {
+		return &fs.PathError{Op: "remove", Path: path, Err: err}
+	}
+
+	d, de, err := f.getEntryWithParent(path, mustExist)
+	if err != nil {
 		return &fs.PathError{Op: "remove", Path: path, Err: err}
 	}
 
@@ -315,14 +1355,36 @@ func (f *FS) Remove(path string) error {
 		return &fs.PathError{Op: "remove", Path: path, Err: fs.ErrInvalid}
 	}
 
-	if err = d.removeEntry(de.name); err != nil {
+	if err = d.removeEntry(de.name, f.deterministic); err != nil {
 		return &fs.PathError{Op: "remove", Path: path, Err: err}
 	}
 
-	return nil
+	f.cache.invalidate()
+
+	de.touch(f.deterministic.current())
+	unlinkAll(de.directoryEntry, f.alloc)
+	f.orphans.add(de.directoryEntry)
+
+	if f.opens.tracking() {
+		dir, _ := splitPath(path)
+
+		f.opens.remove(f.realPath(dir, de.name))
+	}
+
+	f.record(Event{Op: "remove", Path: path})
+
+	return f.writeThrough("remove", path)
 }
 
 func splitPath(p string) (string, string) {
+	if p != "." && fs.ValidPath(p) {
+		if pos := strings.LastIndexByte(p, '/'); pos >= 0 {
+			return p[:pos], p[pos+1:]
+		}
+
+		return ".", p
+	}
+
 	dirName, fileName := path.Split(path.Join("/", p))
 
 	if dirName == "/" {
@@ -334,10 +1396,21 @@ func splitPath(p string) (string, string) {
 	return strings.TrimSuffix(dirName, "/"), fileName
 }
 
-func (f *FS) RemoveAll(path string) error {
+func (f *FS) RemoveAll(path string) (err error) {
+	defer f.logOp("removeall", path).end(&err)
+
+	f.throttle.op()
+
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	f.tree.lock()
+	defer f.tree.unlock()
+
+	if err := f.limit.op(); err != nil {
+		return &fs.PathError{Op: "removeall", Path: path, Err: err}
+	}
+
 	dirName, fileName := splitPath(path)
 
 	d, err := f.getDirEnt(dirName)
@@ -345,91 +1418,253 @@ func (f *FS) RemoveAll(path string) error {
 		return &fs.PathError{Op: "removeall", Path: path, Err: err}
 	}
 
-	if err := d.removeEntry(fileName); err != nil {
+	de, _ := getEntryNorm(d, fileName, f.normInsensitive)
+	if de != nil {
+		fileName = de.name
+	}
+
+	if err := d.removeEntry(fileName, f.deterministic); err != nil {
 		return &fs.PathError{Op: "removeall", Path: path, Err: err}
 	}
 
-	return nil
+	f.cache.invalidate()
+
+	if de != nil {
+		de.touch(f.deterministic.current())
+		unlinkAll(de.directoryEntry, f.alloc)
+		f.orphans.add(de.directoryEntry)
+
+		if f.opens.tracking() {
+			f.opens.remove(f.realPath(dirName, fileName))
+		}
+	}
+
+	f.record(Event{Op: "removeall", Path: path})
+
+	return f.writeThrough("removeall", path)
 }
 
-func (f *FS) LStat(path string) (fs.FileInfo, error) {
+func (f *FS) LStat(path string) (_ fs.FileInfo, err error) {
+	defer f.logOp("lstat", path).end(&err)
+
+	f.throttle.op()
+
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
 	return f.fsRO.LStat(path)
 }
 
-func (f *FS) Readlink(path string) (string, error) {
+func (f *FS) Readlink(path string) (_ string, err error) {
+	defer f.logOp("readlink", path).end(&err)
+
+	f.throttle.op()
+
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
 	return f.fsRO.Readlink(path)
 }
 
+// ResolveLink returns the path of the target of the symlink at the given path,
+// with a relative target being resolved from the directory containing the
+// symlink. When the target is itself a symlink it is followed in turn, up to a
+// total of depth symlinks, or, with a depth of zero or less, until reaching a
+// target that is not a symlink. The target need not exist.
+//
+// As when resolving a path, an absolute target, and any '..' elements that
+// would go above the root, refer to the root. The target is joined to the path
+// of the symlink without the resolution of any of the symlinks among its
+// directories.
+//
+// Returns fs.ErrInvalid when the entry at the given path is not a symlink.
+func (f *FS) ResolveLink(path string, depth int) (_ string, err error) {
+	defer f.logOp("resolvelink", path).end(&err)
+
+	f.throttle.op()
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.fsRO.ResolveLink(path, depth)
+}
+
+// Exists reports whether an entry exists at the given path, following any
+// symlinks.
+//
+// An error is only returned when the existence of the entry cannot be
+// determined, such as when a parent directory is not readable.
+func (f *FS) Exists(path string) (bool, error) {
+	f.throttle.op()
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.fsRO.Exists(path)
+}
+
+// IsDir reports whether the entry at the given path, following any symlinks,
+// is a directory.
+//
+// An error is only returned when the existence of the entry cannot be
+// determined, such as when a parent directory is not readable.
+func (f *FS) IsDir(path string) (bool, error) {
+	f.throttle.op()
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.fsRO.IsDir(path)
+}
+
+// IsSymlink reports whether the entry at the given path is a symlink.
+//
+// An error is only returned when the existence of the entry cannot be
+// determined, such as when a parent directory is not readable.
+func (f *FS) IsSymlink(path string) (bool, error) {
+	f.throttle.op()
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.fsRO.IsSymlink(path)
+}
+
 func (f *FS) Chown(path string, _, _ int) error {
+	f.throttle.op()
+
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
-	if _, err := f.getEntry(path); err != nil {
+	de, err := f.getEntry(path)
+	if err != nil {
 		return &fs.PathError{Op: "chown", Path: path, Err: err}
 	}
 
+	de.touch(f.deterministic.now())
+
 	return nil
 }
 
-func (f *FS) Chmod(path string, mode fs.FileMode) error {
+func (f *FS) Chmod(path string, mode fs.FileMode) (err error) {
+	defer f.logOp("chmod", path).withMode(mode).end(&err)
+
+	f.throttle.op()
+
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
+	if err := f.limit.op(); err != nil {
+		return &fs.PathError{Op: "chmod", Path: path, Err: err}
+	}
+
 	de, err := f.getEntry(path)
 	if err != nil {
 		return &fs.PathError{Op: "chmod", Path: path, Err: err}
 	}
 
-	de.setMode(mode & fs.ModePerm)
+	if err := de.setMode(mode & fs.ModePerm); err != nil {
+		return &fs.PathError{Op: "chmod", Path: path, Err: err}
+	}
+
+	f.cache.invalidate()
+
+	de.touch(f.deterministic.now())
+
+	f.record(Event{Op: "chmod", Path: path, Mode: mode & fs.ModePerm})
 
 	return nil
 }
 
 func (f *FS) Lchown(path string, _, _ int) error {
+	f.throttle.op()
+
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
-	if _, err := f.getLEntry(path); err != nil {
+	de, err := f.getLEntry(path)
+	if err != nil {
 		return &fs.PathError{Op: "lchown", Path: path, Err: err}
 	}
 
+	de.touch(f.deterministic.now())
+
 	return nil
 }
 
-func (f *FS) Chtimes(path string, atime time.Time, mtime time.Time) error {
+func (f *FS) Chtimes(path string, atime time.Time, mtime time.Time) (err error) {
+	defer f.logOp("chtimes", path).end(&err)
+
+	f.throttle.op()
+
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
+	if err := f.limit.op(); err != nil {
+		return &fs.PathError{Op: "chtimes", Path: path, Err: err}
+	}
+
 	de, err := f.getEntry(path)
 	if err != nil {
 		return &fs.PathError{Op: "chtimes", Path: path, Err: err}
 	}
 
-	de.setTimes(atime, mtime)
+	if err := de.setTimes(atime, mtime); err != nil {
+		return &fs.PathError{Op: "chtimes", Path: path, Err: err}
+	}
+
+	de.touch(f.deterministic.now())
+
+	f.record(Event{Op: "chtimes", Path: path, Time: mtime})
 
 	return nil
 }
 
-func (f *FS) Lchtimes(path string, atime time.Time, mtime time.Time) error {
+func (f *FS) Lchtimes(path string, atime time.Time, mtime time.Time) (err error) {
+	defer f.logOp("lchtimes", path).end(&err)
+
+	f.throttle.op()
+
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
+	if err := f.limit.op(); err != nil {
+		return &fs.PathError{Op: "lchtimes", Path: path, Err: err}
+	}
+
 	de, err := f.getLEntry(path)
 	if err != nil {
 		return &fs.PathError{Op: "lchtimes", Path: path, Err: err}
 	}
 
-	de.setTimes(atime, mtime)
+	if err := de.setTimes(atime, mtime); err != nil {
+		return &fs.PathError{Op: "lchtimes", Path: path, Err: err}
+	}
+
+	de.touch(f.deterministic.now())
+
+	f.record(Event{Op: "lchtimes", Path: path, Time: mtime})
 
 	return nil
 }
 
+// Sub returns an FS corresponding to the subtree rooted at the given
+// directory, sharing its contents and Options with the current FS.
+//
+// All paths in the returned FS, including the targets of any symlinks followed
+// while resolving them, are resolved relative to the new root, with absolute
+// targets, and '..' elements that would go above the root, referring to the
+// root itself. As such, no entry outside of the subtree can be read, linked,
+// renamed, or otherwise modified, through the returned FS.
+//
+// The returned FS has its own lock, allowing independent lock domains to be
+// declared at chosen directories, such as per-tenant roots; operations on FSs
+// created with Sub from different directories do not contend with each other,
+// nor with operations on the current FS, beyond the locking of the individual
+// directories and files that they share. Operations that change the structure
+// of the tree, being renames, links, removals and sealing, are serialised
+// across the FS and all of the FSs created from it with Sub, so that they
+// cannot interleave to create a directory cycle.
 func (f *FS) Sub(path string) (fs.FS, error) {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
@@ -439,9 +1674,56 @@ func (f *FS) Sub(path string) (fs.FS, error) {
 		return nil, err
 	}
 
-	return &FS{
+	sub := &FS{
 		fsRO: fsRO{
-			de: de,
+			de:            de,
+			resolveConfig: f.resolveConfig,
+			partial:       true,
 		},
-	}, nil
+		config: f.config,
+	}
+
+	if f.cache != nil {
+		sub.cache = newStatCache(f.cache.size, f.cache.changes)
+	}
+
+	if f.journal != nil || f.objects != nil || f.accounting != nil {
+		sub.root = f.rootPath(path)
+	}
+
+	return sub, nil
+}
+
+// treeLock serialises the changes to the structure of a tree, being shared by
+// an FS and all of the FSs created from it with Sub, each of which has its own
+// lock.
+type treeLock struct {
+	mu sync.Mutex
+}
+
+func (t *treeLock) lock() {
+	if t != nil {
+		t.mu.Lock()
+	}
+}
+
+func (t *treeLock) unlock() {
+	if t != nil {
+		t.mu.Unlock()
+	}
+}
+
+// rootPath returns the given path relative to the root of the FS created with
+// New, rather than to that of an FS created with Sub.
+func (f *FS) rootPath(p string) string {
+	return path.Join(f.root, p)
+}
+
+// accounts returns the counters of any Accounting that match the given path.
+func (f *FS) accounts(p string) accounts {
+	if f.accounting == nil {
+		return nil
+	}
+
+	return f.accounting.match(f.rootPath(p))
 }
@@ -3,13 +3,17 @@ package memfs
 import (
 	"bytes"
 	"errors"
+	"io"
 	"io/fs"
 	"reflect"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
 )
 
+var _ FSRO = &FS{}
+
 func newFSRW(d dnode) FS {
 	return FS{
 		fsRO: fsRO{
@@ -83,7 +87,8 @@ func TestOpenRW(t *testing.T) {
 				file: file{
 					name: "file",
 					inode: &inode{
-						mode: fs.ModeDir | fs.ModePerm,
+						mode:  fs.ModeDir | fs.ModePerm,
+						opens: 1,
 					},
 					opMode: opRead | opSeek,
 				},
@@ -140,7 +145,8 @@ func TestOpenRW(t *testing.T) {
 				file: file{
 					name: "deepFile",
 					inode: &inode{
-						mode: fs.ModeDir | fs.ModePerm,
+						mode:  fs.ModeDir | fs.ModePerm,
+						opens: 1,
 					},
 					opMode: opRead | opSeek,
 				},
@@ -587,7 +593,7 @@ func TestStatRW(t *testing.T) {
 						mode:    fs.ModeDir | fs.ModePerm,
 					},
 				},
-				name: "/",
+				name: ".",
 			},
 		},
 		{ // 3
@@ -769,6 +775,9 @@ func TestStatRW(t *testing.T) {
 		},
 	} {
 		stat, err := test.FS.Stat(test.Path)
+
+		clearVolatile(stat)
+
 		if !reflect.DeepEqual(err, test.Err) {
 			t.Errorf("test %d: expecting error %s, got %s", n+1, test.Err, err)
 		} else if !reflect.DeepEqual(test.Output, stat) {
@@ -937,7 +946,7 @@ func TestMkdir(t *testing.T) {
 			Err: &fs.PathError{
 				Op:   "mkdir",
 				Path: "a/b",
-				Err:  fs.ErrInvalid,
+				Err:  ErrNotDir,
 			},
 		},
 		{ // 8
@@ -990,6 +999,7 @@ func TestMkdir(t *testing.T) {
 			t.Errorf("test %d: expecting error %s, got %s", n+1, test.Err, err)
 		} else {
 			fixTimes(test.FS.de.(*dnodeRW), now)
+			clearVolatile(&test.FS)
 
 			if !reflect.DeepEqual(&test.Output, &test.FS) {
 				t.Errorf("test %d: expecting to get %v, got %v", n+1, &test.Output, &test.FS)
@@ -1016,6 +1026,34 @@ func fixTimes(d *dnodeRW, now time.Time) {
 	}
 }
 
+// clearVolatile removes the lock-free views, the generation numbers, and the
+// change times of all of the entries in the given tree, so that it can be
+// compared against a fixture.
+func clearVolatile(v any) {
+	switch v := v.(type) {
+	case *FS:
+		clearVolatile(v.de)
+	case *dirEnt:
+		v.gen = 0
+
+		clearVolatile(v.directoryEntry)
+	case *dnodeRW:
+		v.snapshot.Store(nil)
+		v.subtree.Store(0)
+		v.parents.dirs = nil
+		v.gen = 0
+		v.ctime = time.Time{}
+
+		for _, e := range v.entries {
+			clearVolatile(e)
+		}
+	case *inodeRW:
+		v.parents = nil
+		v.gen = 0
+		v.ctime = time.Time{}
+	}
+}
+
 func TestMkdirAll(t *testing.T) {
 	now := time.Now()
 	for n, test := range [...]*struct {
@@ -1186,8 +1224,8 @@ func TestMkdirAll(t *testing.T) {
 			Path: "a/b",
 			Err: &fs.PathError{
 				Op:   "mkdirall",
-				Path: "a/b",
-				Err:  fs.ErrInvalid,
+				Path: "a",
+				Err:  ErrNotDir,
 			},
 		},
 		{ // 8
@@ -1274,6 +1312,7 @@ func TestMkdirAll(t *testing.T) {
 			t.Errorf("test %d: expecting error %s, got %s", n+1, test.Err, err)
 		} else {
 			fixTimes(test.FS.de.(*dnodeRW), now)
+			clearVolatile(&test.FS)
 
 			if !reflect.DeepEqual(&test.Output, &test.FS) {
 				t.Errorf("test %d: expecting to get %v, got %v", n+1, &test.Output, &test.FS)
@@ -1282,6 +1321,95 @@ func TestMkdirAll(t *testing.T) {
 	}
 }
 
+func TestMkdirAllExisting(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("a/b/c", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("a/b", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Chmod("a/b", 0o555); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Chmod("a", 0o555); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Path string
+		Err  error
+	}{
+		{ // 1
+			Path: "a",
+		},
+		{ // 2
+			Path: "a/b/c",
+		},
+		{ // 3
+			Path: "a/b/c/d/e",
+		},
+		{ // 4
+			Path: "link/c/f",
+		},
+		{ // 5
+			Path: "a/b/g",
+			Err:  fs.ErrPermission,
+		},
+		{ // 6
+			Path: "a/h/c",
+			Err:  fs.ErrPermission,
+		},
+	} {
+		if err := f.MkdirAll(test.Path, 0o755); !errors.Is(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if err == nil {
+			if isDir, err := f.IsDir(test.Path); err != nil {
+				t.Errorf("test %d: unexpected error: %s", n+1, err)
+			} else if !isDir {
+				t.Errorf("test %d: expecting directory to exist", n+1)
+			}
+		}
+	}
+}
+
+func TestMkdirAllNotDir(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("a/b", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("a/b/file", nil, 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("a/b/file", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Path, ErrPath string
+	}{
+		{ // 1
+			Path:    "a/b/file",
+			ErrPath: "a/b/file",
+		},
+		{ // 2
+			Path:    "a/b/file/c/d",
+			ErrPath: "a/b/file",
+		},
+		{ // 3
+			Path:    "link/c",
+			ErrPath: "link",
+		},
+	} {
+		var pe *fs.PathError
+
+		if err := f.MkdirAll(test.Path, 0o755); !errors.As(err, &pe) {
+			t.Errorf("test %d: expecting PathError, got %v", n+1, err)
+		} else if !errors.Is(err, ErrNotDir) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, ErrNotDir, err)
+		} else if pe.Path != test.ErrPath {
+			t.Errorf("test %d: expecting path %q, got %q", n+1, test.ErrPath, pe.Path)
+		}
+	}
+}
+
 func TestCreate(t *testing.T) {
 	now := time.Now()
 	for n, test := range [...]*struct {
@@ -1350,6 +1478,8 @@ func TestCreate(t *testing.T) {
 							inode: inode{
 								modtime: now,
 								mode:    defaultPerms,
+								links:   1,
+								opens:   1,
 							},
 						},
 						name: "a",
@@ -1364,6 +1494,8 @@ func TestCreate(t *testing.T) {
 					inode: &inode{
 						modtime: now,
 						mode:    defaultPerms,
+						links:   1,
+						opens:   1,
 					},
 					name:   "a",
 					opMode: opRead | opWrite | opSeek,
@@ -1396,6 +1528,7 @@ func TestCreate(t *testing.T) {
 								data:    ([]byte("Hello"))[:0],
 								modtime: now,
 								mode:    fs.ModePerm,
+								opens:   1,
 							},
 						},
 						name: "a",
@@ -1411,6 +1544,7 @@ func TestCreate(t *testing.T) {
 						data:    ([]byte("Hello"))[:0],
 						modtime: now,
 						mode:    fs.ModePerm,
+						opens:   1,
 					},
 					name:   "a",
 					opMode: opRead | opWrite | opSeek,
@@ -1445,6 +1579,8 @@ func TestCreate(t *testing.T) {
 											inode: inode{
 												modtime: now,
 												mode:    defaultPerms,
+												links:   1,
+												opens:   1,
 											},
 										},
 										name: "b",
@@ -1466,6 +1602,8 @@ func TestCreate(t *testing.T) {
 					inode: &inode{
 						modtime: now,
 						mode:    defaultPerms,
+						links:   1,
+						opens:   1,
 					},
 					name:   "b",
 					opMode: opRead | opWrite | opSeek,
@@ -1516,6 +1654,7 @@ func TestCreate(t *testing.T) {
 			t.Errorf("test %d: expecting error %s, got %s", n+1, test.Err, err)
 		} else {
 			fixTimes(test.FS.de.(*dnodeRW), now)
+			clearVolatile(&test.FS)
 
 			if !reflect.DeepEqual(test.OutputFile, f) {
 				t.Errorf("test %d: expecting to get file %v, got %v", n+1, test.OutputFile, f)
@@ -1667,7 +1806,8 @@ func TestLink(t *testing.T) {
 						name: "a",
 						directoryEntry: &inodeRW{
 							inode: inode{
-								data: []byte("Hello"),
+								data:  []byte("Hello"),
+								links: 1,
 							},
 						},
 					},
@@ -1680,7 +1820,8 @@ func TestLink(t *testing.T) {
 						name: "a",
 						directoryEntry: &inodeRW{
 							inode: inode{
-								data: []byte("Hello"),
+								data:  []byte("Hello"),
+								links: 2,
 							},
 						},
 					},
@@ -1688,7 +1829,8 @@ func TestLink(t *testing.T) {
 						name: "b",
 						directoryEntry: &inodeRW{
 							inode: inode{
-								data: []byte("Hello"),
+								data:  []byte("Hello"),
+								links: 2,
 							},
 						},
 					},
@@ -1711,7 +1853,8 @@ func TestLink(t *testing.T) {
 										name: "b",
 										directoryEntry: &inodeRW{
 											inode: inode{
-												data: []byte("Hello"),
+												data:  []byte("Hello"),
+												links: 1,
 											},
 										},
 									},
@@ -1734,7 +1877,8 @@ func TestLink(t *testing.T) {
 										name: "b",
 										directoryEntry: &inodeRW{
 											inode: inode{
-												data: []byte("Hello"),
+												data:  []byte("Hello"),
+												links: 2,
 											},
 										},
 									},
@@ -1747,7 +1891,8 @@ func TestLink(t *testing.T) {
 						name: "c",
 						directoryEntry: &inodeRW{
 							inode: inode{
-								data: []byte("Hello"),
+								data:  []byte("Hello"),
+								links: 2,
 							},
 						},
 					},
@@ -1765,7 +1910,8 @@ func TestLink(t *testing.T) {
 						name: "a",
 						directoryEntry: &inodeRW{
 							inode: inode{
-								data: []byte("Hello"),
+								data:  []byte("Hello"),
+								links: 1,
 							},
 						},
 					},
@@ -1786,7 +1932,8 @@ func TestLink(t *testing.T) {
 						name: "a",
 						directoryEntry: &inodeRW{
 							inode: inode{
-								data: []byte("Hello"),
+								data:  []byte("Hello"),
+								links: 2,
 							},
 						},
 					},
@@ -1799,7 +1946,8 @@ func TestLink(t *testing.T) {
 										name: "c",
 										directoryEntry: &inodeRW{
 											inode: inode{
-												data: []byte("Hello"),
+												data:  []byte("Hello"),
+												links: 2,
 											},
 										},
 									},
@@ -1820,6 +1968,7 @@ func TestLink(t *testing.T) {
 			t.Errorf("test %d: expecting error %s, got %s", n+1, test.Err, err)
 		} else {
 			fixTimes(test.FS.de.(*dnodeRW), now)
+			clearVolatile(&test.FS)
 
 			if !reflect.DeepEqual(&test.Output, &test.FS) {
 				t.Errorf("test %d: expecting to get FS %v, got %v", n+1, &test.Output, &test.FS)
@@ -1991,6 +2140,7 @@ func TestSymlink(t *testing.T) {
 								data:    []byte("/a"),
 								modtime: now,
 								mode:    fs.ModeSymlink | fs.ModePerm,
+								links:   1,
 							},
 						},
 					},
@@ -2051,6 +2201,7 @@ func TestSymlink(t *testing.T) {
 							inode: inode{
 								data:    []byte("/a/b"),
 								mode:    fs.ModeSymlink | fs.ModePerm,
+								links:   1,
 								modtime: now,
 							},
 						},
@@ -2106,6 +2257,7 @@ func TestSymlink(t *testing.T) {
 												data:    []byte("/a"),
 												modtime: now,
 												mode:    fs.ModeSymlink | fs.ModePerm,
+												links:   1,
 											},
 										},
 									},
@@ -2126,6 +2278,7 @@ func TestSymlink(t *testing.T) {
 			t.Errorf("test %d: expecting error %s, got %s", n+1, test.Err, err)
 		} else {
 			fixTimes(test.FS.de.(*dnodeRW), now)
+			clearVolatile(&test.FS)
 
 			if !reflect.DeepEqual(&test.Output, &test.FS) {
 				t.Errorf("test %d: expecting to get FS %v, got %v", n+1, &test.Output, &test.FS)
@@ -2698,6 +2851,7 @@ func TestRemove(t *testing.T) {
 			t.Errorf("test %d: expecting error %s, got %s", n+1, test.Err, err)
 		} else {
 			fixTimes(test.FS.de.(*dnodeRW), now)
+			clearVolatile(&test.FS)
 
 			if !reflect.DeepEqual(&test.Output, &test.FS) {
 				t.Errorf("test %d: expecting to get FS %v, got %v", n+1, &test.Output, &test.FS)
@@ -2808,6 +2962,7 @@ func TestRemoveAll(t *testing.T) {
 			t.Errorf("test %d: expecting error %s, got %s", n+1, test.Err, err)
 		} else {
 			fixTimes(test.FS.de.(*dnodeRW), now)
+			clearVolatile(&test.FS)
 
 			if !reflect.DeepEqual(&test.Output, &test.FS) {
 				t.Errorf("test %d: expecting to get FS %v, got %v", n+1, &test.Output, &test.FS)
@@ -2845,7 +3000,7 @@ func TestLStatRW(t *testing.T) {
 						mode:    fs.ModeDir | fs.ModePerm,
 					},
 				},
-				name: "/",
+				name: ".",
 			},
 		},
 		{ // 3
@@ -3026,7 +3181,11 @@ func TestLStatRW(t *testing.T) {
 			},
 		},
 	} {
-		if f, err := test.FS.LStat(test.Path); !reflect.DeepEqual(err, test.Err) {
+		f, err := test.FS.LStat(test.Path)
+
+		clearVolatile(f)
+
+		if !reflect.DeepEqual(err, test.Err) {
 			t.Errorf("test %d: expecting error %s, got %s", n+1, test.Err, err)
 		} else if !reflect.DeepEqual(f, test.Output) {
 			t.Errorf("test %d: expected FileInfo %v, got %v", n+1, test.Output, f)
@@ -3446,7 +3605,11 @@ func TestChmod(t *testing.T) {
 			}),
 		},
 	} {
-		if err := test.FS.Chmod(test.Path, test.Mode); !reflect.DeepEqual(err, test.Err) {
+		err := test.FS.Chmod(test.Path, test.Mode)
+
+		clearVolatile(&test.FS)
+
+		if !reflect.DeepEqual(err, test.Err) {
 			t.Errorf("test %d: expecting error %s, got %s", n+1, test.Err, err)
 		} else if !reflect.DeepEqual(&test.FS, &test.Output) {
 			t.Errorf("test %d: expected %v, got %v", n+1, &test.Output, &test.FS)
@@ -3713,7 +3876,11 @@ func TestChtimes(t *testing.T) {
 			}),
 		},
 	} {
-		if err := test.FS.Chtimes(test.Path, time.Time{}, test.MTime); !reflect.DeepEqual(err, test.Err) {
+		err := test.FS.Chtimes(test.Path, time.Time{}, test.MTime)
+
+		clearVolatile(&test.FS)
+
+		if !reflect.DeepEqual(err, test.Err) {
 			t.Errorf("test %d: expecting error %s, got %s", n+1, test.Err, err)
 		} else if !reflect.DeepEqual(&test.FS, &test.Output) {
 			t.Errorf("test %d: expected %v, got %v", n+1, &test.Output, &test.FS)
@@ -3895,7 +4062,11 @@ func TestLchtimes(t *testing.T) {
 			}),
 		},
 	} {
-		if err := test.FS.Lchtimes(test.Path, time.Time{}, test.MTime); !reflect.DeepEqual(err, test.Err) {
+		err := test.FS.Lchtimes(test.Path, time.Time{}, test.MTime)
+
+		clearVolatile(&test.FS)
+
+		if !reflect.DeepEqual(err, test.Err) {
 			t.Errorf("test %d: expecting error %s, got %s", n+1, test.Err, err)
 		} else if !reflect.DeepEqual(&test.FS, &test.Output) {
 			t.Errorf("test %d: expected %v, got %v", n+1, &test.Output, &test.FS)
@@ -3961,13 +4132,16 @@ func TestSeal(t *testing.T) {
 		de: &dnode{
 			modtime: time.Unix(1, 2),
 			mode:    0,
+			sealed:  true,
 			entries: []*dirEnt{
 				{
 					name: "a",
 					directoryEntry: &inode{
 						modtime: time.Unix(3, 4),
 						mode:    1,
+						sealed:  true,
 						data:    []byte("Foo"),
+						etag:    new(etagOnce),
 					},
 				},
 				{
@@ -3975,13 +4149,16 @@ func TestSeal(t *testing.T) {
 					directoryEntry: &dnode{
 						modtime: time.Unix(5, 6),
 						mode:    2,
+						sealed:  true,
 						entries: []*dirEnt{
 							{
 								name: "c",
 								directoryEntry: &inode{
 									modtime: time.Unix(7, 8),
 									mode:    3,
+									sealed:  true,
 									data:    []byte("Hello"),
+									etag:    new(etagOnce),
 								},
 							},
 							{
@@ -3989,7 +4166,9 @@ func TestSeal(t *testing.T) {
 								directoryEntry: &inode{
 									modtime: time.Unix(9, 10),
 									mode:    4,
+									sealed:  true,
 									data:    []byte("World"),
+									etag:    new(etagOnce),
 								},
 							},
 						},
@@ -4109,6 +4288,7 @@ func TestSubRW(t *testing.T) {
 							},
 						},
 					},
+					partial: true,
 				},
 			},
 		},
@@ -4174,7 +4354,11 @@ func TestSubRW(t *testing.T) {
 			},
 		},
 	} {
-		if output, err := test.FS.Sub(test.Path); !reflect.DeepEqual(err, test.Err) {
+		output, err := test.FS.Sub(test.Path)
+
+		clearVolatile(output)
+
+		if !reflect.DeepEqual(err, test.Err) {
 			t.Errorf("test %d: expecting error %s, got %s", n+1, test.Err, err)
 		} else if !reflect.DeepEqual(output, test.Output) {
 			t.Errorf("test %d: expected FS %v, got %v", n+1, test.Output, output)
@@ -4277,6 +4461,7 @@ func TestSubRWRemove(t *testing.T) {
 							},
 						},
 					},
+					partial: true,
 				},
 			},
 		},
@@ -4289,6 +4474,8 @@ func TestSubRWRemove(t *testing.T) {
 			} else {
 				fs.de.(*dnodeRW).modtime = time.Unix(5, 6)
 
+				clearVolatile(fs)
+
 				if !reflect.DeepEqual(fs, test.Output) {
 					t.Errorf("test %d: expected FS %v, got %v", n+1, test.Output, fs)
 				}
@@ -4382,6 +4569,7 @@ func TestSubRWRemoveAll(t *testing.T) {
 							entries: []*dirEnt{},
 						},
 					},
+					partial: true,
 				},
 			},
 		},
@@ -4394,6 +4582,8 @@ func TestSubRWRemoveAll(t *testing.T) {
 			} else {
 				fs.de.(*dnodeRW).modtime = time.Unix(5, 6)
 
+				clearVolatile(fs)
+
 				if !reflect.DeepEqual(fs, test.Output) {
 					t.Errorf("test %d: expected FS %v, got %v", n+1, test.Output, fs)
 				}
@@ -4401,3 +4591,1746 @@ func TestSubRWRemoveAll(t *testing.T) {
 		}
 	}
 }
+
+func TestSubRWConfined(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("outside", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.MkdirAll("jail", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("outside/file", []byte("outside"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("/outside", "jail/abs"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("../outside", "jail/rel"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("../../..", "jail/up"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s, err := f.Sub("jail")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	sub := s.(*FS)
+
+	for n, test := range [...]func() error{
+		func() error { return sub.Link("abs/file", "stolen") },
+		func() error { return sub.Rename("rel/file", "moved") },
+		func() error { return sub.Remove("up/outside/file") },
+		func() error { return sub.RemoveAll("up/outside") },
+		func() error { return sub.WriteFile("abs/new", []byte("new"), 0o644) },
+		func() error { return sub.Symlink("target", "rel/link") },
+		func() error { return sub.Chmod("abs/file", 0o777) },
+	} {
+		if err := test(); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, fs.ErrNotExist, err)
+		}
+	}
+
+	if err := sub.Mkdir("outside", fs.ModePerm); err != nil {
+		t.Fatalf("test 8: unexpected error: %s", err)
+	} else if err := sub.WriteFile("abs/new", []byte("new"), 0o644); err != nil {
+		t.Fatalf("test 8: unexpected error: %s", err)
+	} else if _, err := f.Stat("jail/outside/new"); err != nil {
+		t.Errorf("test 8: unexpected error: %s", err)
+	} else if _, err := f.Stat("outside/new"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("test 8: expecting error %v, got %v", fs.ErrNotExist, err)
+	}
+
+	if data, err := f.ReadFile("outside/file"); err != nil {
+		t.Errorf("test 9: unexpected error: %s", err)
+	} else if string(data) != "outside" {
+		t.Errorf("test 9: expecting contents %q, got %q", "outside", data)
+	} else if fi, err := f.Stat("outside/file"); err != nil {
+		t.Errorf("test 9: unexpected error: %s", err)
+	} else if fi.Mode() != 0o644 {
+		t.Errorf("test 9: expecting mode %s, got %s", fs.FileMode(0o644), fi.Mode())
+	}
+}
+
+func TestSealOpenFiles(t *testing.T) {
+	f := New()
+
+	of, err := f.Create("a")
+	if err != nil {
+		t.Fatalf("unexpected error creating file: %s", err)
+	} else if _, err = of.WriteString("Hello, World"); err != nil {
+		t.Fatalf("unexpected error writing file: %s", err)
+	} else if _, err = of.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("unexpected error seeking file: %s", err)
+	} else if err = f.Link("a", "b"); err != nil {
+		t.Fatalf("unexpected error linking file: %s", err)
+	}
+
+	ro := f.Seal()
+
+	if data, err := io.ReadAll(of); err != nil {
+		t.Errorf("unexpected error reading open file: %s", err)
+	} else if string(data) != "Hello, World" {
+		t.Errorf("expecting to read %q from open file, got %q", "Hello, World", data)
+	}
+
+	if _, err := of.WriteString("!"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expecting error writing to sealed file %s, got %s", fs.ErrPermission, err)
+	} else if err = of.Close(); err != nil {
+		t.Errorf("unexpected error closing file: %s", err)
+	}
+
+	if data, err := ro.ReadFile("b"); err != nil {
+		t.Errorf("unexpected error reading linked file: %s", err)
+	} else if string(data) != "Hello, World" {
+		t.Errorf("expecting to read %q from linked file, got %q", "Hello, World", data)
+	}
+
+	if data, err := f.ReadFile("a"); err != nil {
+		t.Errorf("unexpected error reading from sealed FS: %s", err)
+	} else if string(data) != "Hello, World" {
+		t.Errorf("expecting to read %q from sealed FS, got %q", "Hello, World", data)
+	}
+
+	for n, fn := range [...]func() error{
+		func() error { return f.Mkdir("c", fs.ModePerm) },
+		func() error { _, err := f.Create("a"); return err },
+		func() error { return f.Remove("a") },
+		func() error { return f.Rename("a", "c") },
+		func() error { return f.Chmod("a", 0) },
+		func() error { return f.Chtimes(".", time.Now(), time.Now()) },
+	} {
+		if err := fn(); !errors.Is(err, fs.ErrPermission) {
+			t.Errorf("test %d: expecting error %s, got %s", n+1, fs.ErrPermission, err)
+		}
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	f := New()
+
+	if err := f.Mkdir("dir", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err = f.Mkdir("ro", 0o555); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Path     string
+		Data     string
+		Perm     fs.FileMode
+		Err      error
+		Contents string
+	}{
+		{ // 1
+			Path:     "a",
+			Data:     "Hello, World",
+			Perm:     0o600,
+			Contents: "Hello, World",
+		},
+		{ // 2
+			Path:     "a",
+			Data:     "Foo",
+			Perm:     0o644,
+			Contents: "Foo",
+		},
+		{ // 3
+			Path:     "dir/b",
+			Perm:     0o644,
+			Contents: "",
+		},
+		{ // 4
+			Path: "dir",
+			Data: "Bar",
+			Err: &fs.PathError{
+				Op:   "writefile",
+				Path: "dir",
+				Err:  ErrIsDir,
+			},
+		},
+		{ // 5
+			Path: "missing/c",
+			Err: &fs.PathError{
+				Op:   "writefile",
+				Path: "missing/c",
+				Err:  fs.ErrNotExist,
+			},
+		},
+		{ // 6
+			Path: "ro/d",
+			Err: &fs.PathError{
+				Op:   "writefile",
+				Path: "ro/d",
+				Err:  fs.ErrPermission,
+			},
+		},
+	} {
+		if err := f.WriteFile(test.Path, []byte(test.Data), test.Perm); !reflect.DeepEqual(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if test.Err != nil {
+			continue
+		} else if data, err := f.ReadFile(test.Path); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if string(data) != test.Contents {
+			t.Errorf("test %d: expecting contents %q, got %q", n+1, test.Contents, data)
+		}
+	}
+
+	if fi, err := f.Stat("a"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	} else if fi.Mode() != 0o600 {
+		t.Errorf("expecting existing file to keep mode %s, got %s", fs.FileMode(0o600), fi.Mode())
+	}
+}
+
+func TestReadFileLimit(t *testing.T) {
+	f := New()
+
+	if err := f.Mkdir("dir", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("file", []byte("12345"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("empty", nil, 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Path     string
+		Max      int64
+		Contents string
+		Err      error
+	}{
+		{ // 1
+			Path:     "file",
+			Max:      5,
+			Contents: "12345",
+		},
+		{ // 2
+			Path: "file",
+			Max:  4,
+			Err: &fs.PathError{
+				Op:   "readfile",
+				Path: "file",
+				Err:  ErrFileTooLarge,
+			},
+		},
+		{ // 3
+			Path: "empty",
+		},
+		{ // 4
+			Path: "dir",
+			Max:  10,
+			Err: &fs.PathError{
+				Op:   "readfile",
+				Path: "dir",
+				Err:  fs.ErrInvalid,
+			},
+		},
+		{ // 5
+			Path: "missing",
+			Max:  10,
+			Err: &fs.PathError{
+				Op:   "readfile",
+				Path: "missing",
+				Err:  fs.ErrNotExist,
+			},
+		},
+	} {
+		if data, err := f.ReadFileLimit(test.Path, test.Max); !reflect.DeepEqual(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if string(data) != test.Contents {
+			t.Errorf("test %d: expecting contents %q, got %q", n+1, test.Contents, data)
+		}
+	}
+}
+
+func TestFileString(t *testing.T) {
+	f := New()
+
+	if err := f.Mkdir("dir", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Path     string
+		Data     string
+		Err      error
+		Contents string
+		ReadErr  error
+	}{
+		{ // 1
+			Path:     "a",
+			Data:     "Hello, World",
+			Contents: "Hello, World",
+		},
+		{ // 2
+			Path:     "dir/b",
+			Contents: "",
+		},
+		{ // 3
+			Path: "dir",
+			Data: "Foo",
+			Err: &fs.PathError{
+				Op:   "writefile",
+				Path: "dir",
+				Err:  ErrIsDir,
+			},
+			ReadErr: &fs.PathError{
+				Op:   "readfile",
+				Path: "dir",
+				Err:  fs.ErrInvalid,
+			},
+		},
+		{ // 4
+			Path: "missing/c",
+			Err: &fs.PathError{
+				Op:   "writefile",
+				Path: "missing/c",
+				Err:  fs.ErrNotExist,
+			},
+			ReadErr: &fs.PathError{
+				Op:   "readfile",
+				Path: "missing/c",
+				Err:  fs.ErrNotExist,
+			},
+		},
+	} {
+		if err := f.WriteFileString(test.Path, test.Data, 0o644); !reflect.DeepEqual(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if data, err := f.ReadFileString(test.Path); !reflect.DeepEqual(err, test.ReadErr) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.ReadErr, err)
+		} else if data != test.Contents {
+			t.Errorf("test %d: expecting contents %q, got %q", n+1, test.Contents, data)
+		}
+	}
+}
+
+func TestAppendFile(t *testing.T) {
+	var buf bytes.Buffer
+
+	f := New(WithJournal(&buf))
+
+	if err := f.Mkdir("dir", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Mkdir("ro", 0o555); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Path     string
+		Data     string
+		Perm     fs.FileMode
+		Err      error
+		Contents string
+	}{
+		{ // 1
+			Path:     "a",
+			Data:     "Hello",
+			Perm:     0o600,
+			Contents: "Hello",
+		},
+		{ // 2
+			Path:     "a",
+			Data:     ", World",
+			Perm:     0o644,
+			Contents: "Hello, World",
+		},
+		{ // 3
+			Path:     "a",
+			Contents: "Hello, World",
+		},
+		{ // 4
+			Path: "dir",
+			Data: "Bar",
+			Err: &fs.PathError{
+				Op:   "appendfile",
+				Path: "dir",
+				Err:  ErrIsDir,
+			},
+		},
+		{ // 5
+			Path: "ro/b",
+			Data: "Bar",
+			Err: &fs.PathError{
+				Op:   "appendfile",
+				Path: "ro/b",
+				Err:  fs.ErrPermission,
+			},
+		},
+	} {
+		if err := f.AppendFile(test.Path, []byte(test.Data), test.Perm); !reflect.DeepEqual(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if test.Err != nil {
+			continue
+		} else if data, err := f.ReadFile(test.Path); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if string(data) != test.Contents {
+			t.Errorf("test %d: expecting contents %q, got %q", n+1, test.Contents, data)
+		}
+	}
+
+	if fi, err := f.Stat("a"); err != nil {
+		t.Errorf("test 6: unexpected error: %s", err)
+	} else if fi.Mode() != 0o600 {
+		t.Errorf("test 6: expecting existing file to keep mode %s, got %s", fs.FileMode(0o600), fi.Mode())
+	}
+
+	g := New()
+
+	if err := g.Replay(&buf); err != nil {
+		t.Errorf("test 7: unexpected error: %s", err)
+	} else if data, err := g.ReadFile("a"); err != nil {
+		t.Errorf("test 7: unexpected error: %s", err)
+	} else if string(data) != "Hello, World" {
+		t.Errorf("test 7: expecting contents %q, got %q", "Hello, World", data)
+	}
+}
+
+func TestWriteFileSymlink(t *testing.T) {
+	f := New()
+
+	if err := f.Mkdir("dir", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("dir/file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("dir/file", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("file", "dir/relative"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("new", "dir/dangling"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.MkdirAll("real/dir", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("/real/dir", "l"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("../x", "real/dir/link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Write          func(string, []byte, fs.FileMode) error
+		Path, Data     string
+		Target, Result string
+	}{
+		{ // 1
+			Write:  f.WriteFile,
+			Path:   "link",
+			Data:   "Hello",
+			Target: "dir/file",
+			Result: "Hello",
+		},
+		{ // 2
+			Write:  f.AppendFile,
+			Path:   "dir/relative",
+			Data:   ", World",
+			Target: "dir/file",
+			Result: "Hello, World",
+		},
+		{ // 3
+			Write: func(p string, data []byte, perm fs.FileMode) error {
+				return f.WriteFileString(p, string(data), perm)
+			},
+			Path:   "link",
+			Data:   "Foo",
+			Target: "dir/file",
+			Result: "Foo",
+		},
+		{ // 4
+			Write:  f.WriteFile,
+			Path:   "dir/dangling",
+			Data:   "Bar",
+			Target: "dir/new",
+			Result: "Bar",
+		},
+		{ // 5
+			Write:  f.WriteFile,
+			Path:   "l/link",
+			Data:   "Baz",
+			Target: "real/x",
+			Result: "Baz",
+		},
+	} {
+		if err := test.Write(test.Path, []byte(test.Data), 0o644); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if data, err := f.ReadFile(test.Target); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if string(data) != test.Result {
+			t.Errorf("test %d: expecting contents %q, got %q", n+1, test.Result, data)
+		} else if fi, err := f.LStat(test.Path); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if fi.Mode()&fs.ModeSymlink == 0 {
+			t.Errorf("test %d: expecting symlink to remain, got mode %s", n+1, fi.Mode())
+		}
+	}
+
+	g := New(WithNoFollow())
+
+	if err := g.WriteFile("file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := g.Symlink("file", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := g.WriteFile("link", []byte("new"), 0o644); !errors.Is(err, ErrNoFollow) {
+		t.Errorf("test 6: expecting error %v, got %v", ErrNoFollow, err)
+	}
+}
+
+func TestTouch(t *testing.T) {
+	f := New()
+	old := time.Unix(1, 0)
+
+	if err := f.Mkdir("ro", 0o555); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err = f.WriteFile("file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err = f.WriteFile("rofile", nil, 0o444); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err = f.Symlink("file", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, p := range [...]string{"file", "rofile", "link", "ro"} {
+		if err := f.Lchtimes(p, old, old); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	for n, test := range [...]struct {
+		Path, Check string
+		Err         error
+	}{
+		{ // 1
+			Path:  "new",
+			Check: "new",
+		},
+		{ // 2
+			Path:  "file",
+			Check: "file",
+		},
+		{ // 3
+			Path:  "link",
+			Check: "file",
+		},
+		{ // 4
+			Path: "rofile",
+			Err: &fs.PathError{
+				Op:   "touch",
+				Path: "rofile",
+				Err:  fs.ErrPermission,
+			},
+		},
+		{ // 5
+			Path: "ro/new",
+			Err: &fs.PathError{
+				Op:   "touch",
+				Path: "ro/new",
+				Err:  fs.ErrPermission,
+			},
+		},
+		{ // 6
+			Path: "missing/new",
+			Err: &fs.PathError{
+				Op:   "touch",
+				Path: "missing/new",
+				Err:  fs.ErrNotExist,
+			},
+		},
+	} {
+		if err := f.Lchtimes("file", old, old); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if err := f.Touch(test.Path); !reflect.DeepEqual(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if test.Err != nil {
+			continue
+		} else if fi, err := f.LStat(test.Check); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if !withinRange(time.Since(fi.ModTime())) {
+			t.Errorf("test %d: expecting modtime to be now, got %s", n+1, fi.ModTime())
+		}
+	}
+
+	if data, err := f.ReadFile("file"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	} else if string(data) != "data" {
+		t.Errorf("expecting touched file to keep its contents, got %q", data)
+	} else if fi, err := f.LStat("link"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	} else if !fi.ModTime().Equal(old) {
+		t.Errorf("expecting symlink to keep its modtime, got %s", fi.ModTime())
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("a/b", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err = f.WriteFile("a/file", nil, 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err = f.Symlink("a/b", "dirLink"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err = f.Symlink("missing", "badLink"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err = f.Mkdir("private", 0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	type result struct {
+		Exists, IsDir, IsSymlink bool
+		Err                      error
+	}
+
+	for n, test := range [...]struct {
+		Path string
+		result
+	}{
+		{ // 1
+			Path:   ".",
+			result: result{Exists: true, IsDir: true},
+		},
+		{ // 2
+			Path:   "a/b",
+			result: result{Exists: true, IsDir: true},
+		},
+		{ // 3
+			Path:   "a/file",
+			result: result{Exists: true},
+		},
+		{ // 4
+			Path:   "dirLink",
+			result: result{Exists: true, IsDir: true, IsSymlink: true},
+		},
+		{ // 5
+			Path:   "badLink",
+			result: result{IsSymlink: true},
+		},
+		{ // 6
+			Path: "missing/file",
+		},
+		{ // 7
+			Path:   "private/file",
+			result: result{Err: fs.ErrPermission},
+		},
+		{ // 8
+			Path:   "/a",
+			result: result{Err: fs.ErrInvalid},
+		},
+	} {
+		var r result
+
+		exists, err := f.Exists(test.Path)
+		isDir, errDir := f.IsDir(test.Path)
+		isSymlink, errSymlink := f.IsSymlink(test.Path)
+
+		r.Exists, r.IsDir, r.IsSymlink, r.Err = exists, isDir, isSymlink, err
+
+		if r != test.result {
+			t.Errorf("test %d: expecting %v, got %v", n+1, test.result, r)
+		} else if errDir != err || errSymlink != err {
+			t.Errorf("test %d: expecting all errors to be %v, got %v and %v", n+1, err, errDir, errSymlink)
+		}
+	}
+
+	if allocs := testing.AllocsPerRun(100, func() {
+		f.Exists("a/b")
+		f.IsDir("a/file")
+		f.IsSymlink("dirLink")
+		f.Exists("missing/file")
+	}); allocs != 0 {
+		t.Errorf("expecting no allocations, got %f", allocs)
+	}
+}
+
+func newRenameFS(t *testing.T) *FS {
+	t.Helper()
+
+	f := New()
+
+	for _, dir := range [...]string{"a/sub", "b", "empty", "ro"} {
+		if err := f.MkdirAll(dir, fs.ModePerm); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	for file, contents := range map[string]string{
+		"a/file":     "A",
+		"a/other":    "other",
+		"a/sub/file": "sub",
+		"b/file":     "B",
+		"ro/file":    "RO",
+	} {
+		if err := f.WriteFile(file, []byte(contents), 0o644); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if err := f.Link("a/file", "a/link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Chmod("ro", 0o555); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	return f
+}
+
+func TestRename(t *testing.T) {
+	for n, test := range [...]struct {
+		From, To  string
+		NoReplace bool
+		Err       error
+		Contents  map[string]string
+		Missing   []string
+	}{
+		{ // 1
+			From:     "a/file",
+			To:       "b/new",
+			Contents: map[string]string{"b/new": "A"},
+			Missing:  []string{"a/file"},
+		},
+		{ // 2
+			From:     "a/file",
+			To:       "b/file",
+			Contents: map[string]string{"b/file": "A"},
+			Missing:  []string{"a/file"},
+		},
+		{ // 3
+			From:      "a/file",
+			To:        "b/file",
+			NoReplace: true,
+			Err: &fs.PathError{
+				Op:   "renamenoreplace",
+				Path: "b/file",
+				Err:  fs.ErrExist,
+			},
+			Contents: map[string]string{"a/file": "A", "b/file": "B"},
+		},
+		{ // 4
+			From:      "a/file",
+			To:        "b/new",
+			NoReplace: true,
+			Contents:  map[string]string{"b/new": "A"},
+			Missing:   []string{"a/file"},
+		},
+		{ // 5
+			From:     "a",
+			To:       "empty",
+			Contents: map[string]string{"empty/file": "A", "empty/sub/file": "sub"},
+			Missing:  []string{"a"},
+		},
+		{ // 6
+			From: "a",
+			To:   "b",
+			Err: &fs.PathError{
+				Op:   "rename",
+				Path: "b",
+				Err:  fs.ErrExist,
+			},
+		},
+		{ // 7
+			From: "a/file",
+			To:   "empty",
+			Err: &fs.PathError{
+				Op:   "rename",
+				Path: "empty",
+				Err:  fs.ErrInvalid,
+			},
+		},
+		{ // 8
+			From: "empty",
+			To:   "a/file",
+			Err: &fs.PathError{
+				Op:   "rename",
+				Path: "a/file",
+				Err:  fs.ErrInvalid,
+			},
+		},
+		{ // 9
+			From: "a",
+			To:   "a/sub/a",
+			Err: &fs.PathError{
+				Op:   "rename",
+				Path: "a/sub/a",
+				Err:  fs.ErrInvalid,
+			},
+		},
+		{ // 10
+			From:     "a/file",
+			To:       "a/link",
+			Contents: map[string]string{"a/file": "A", "a/link": "A"},
+		},
+		{ // 11
+			From: "a/file",
+			To:   "ro/file",
+			Err: &fs.PathError{
+				Op:   "rename",
+				Path: "ro/file",
+				Err:  fs.ErrPermission,
+			},
+		},
+		{ // 12
+			From: "ro/file",
+			To:   "a/new",
+			Err: &fs.PathError{
+				Op:   "rename",
+				Path: "ro/file",
+				Err:  fs.ErrPermission,
+			},
+			Missing: []string{"a/new"},
+		},
+	} {
+		f := newRenameFS(t)
+
+		var err error
+
+		if test.NoReplace {
+			err = f.RenameNoReplace(test.From, test.To)
+		} else {
+			err = f.Rename(test.From, test.To)
+		}
+
+		if !reflect.DeepEqual(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if err = f.Check(); err != nil {
+			t.Errorf("test %d: unexpected inconsistency: %s", n+1, err)
+		}
+
+		for file, contents := range test.Contents {
+			if data, err := f.ReadFile(file); err != nil {
+				t.Errorf("test %d: unexpected error: %s", n+1, err)
+			} else if string(data) != contents {
+				t.Errorf("test %d: expecting %s to contain %q, got %q", n+1, file, contents, data)
+			}
+		}
+
+		for _, file := range test.Missing {
+			if ok, err := f.Exists(file); ok || err != nil {
+				t.Errorf("test %d: expecting %s to not exist, got %v, %v", n+1, file, ok, err)
+			}
+		}
+	}
+}
+
+func TestRenameExchange(t *testing.T) {
+	for n, test := range [...]struct {
+		A, B     string
+		Err      error
+		Contents map[string]string
+	}{
+		{ // 1
+			A: "a/file",
+			B: "b/file",
+			Contents: map[string]string{
+				"a/file": "B",
+				"b/file": "A",
+			},
+		},
+		{ // 2
+			A: "a/file",
+			B: "a/other",
+			Contents: map[string]string{
+				"a/file":  "other",
+				"a/other": "A",
+			},
+		},
+		{ // 3
+			A: "a",
+			B: "b",
+			Contents: map[string]string{
+				"a/file":     "B",
+				"b/file":     "A",
+				"b/sub/file": "sub",
+			},
+		},
+		{ // 4
+			A: "a/file",
+			B: "a/file",
+			Contents: map[string]string{
+				"a/file": "A",
+			},
+		},
+		{ // 5
+			A: "a/file",
+			B: "missing",
+			Err: &fs.PathError{
+				Op:   "renameexchange",
+				Path: "missing",
+				Err:  fs.ErrNotExist,
+			},
+		},
+		{ // 6
+			A: "a/file",
+			B: "ro/file",
+			Err: &fs.PathError{
+				Op:   "renameexchange",
+				Path: "ro/file",
+				Err:  fs.ErrPermission,
+			},
+		},
+		{ // 7
+			A: "a",
+			B: "a/sub/file",
+			Err: &fs.PathError{
+				Op:   "renameexchange",
+				Path: "a/sub/file",
+				Err:  fs.ErrInvalid,
+			},
+		},
+		{ // 8
+			A: "a/sub",
+			B: "a",
+			Err: &fs.PathError{
+				Op:   "renameexchange",
+				Path: "a",
+				Err:  fs.ErrInvalid,
+			},
+		},
+	} {
+		f := newRenameFS(t)
+
+		if err := f.RenameExchange(test.A, test.B); !reflect.DeepEqual(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if err = f.Check(); err != nil {
+			t.Errorf("test %d: unexpected inconsistency: %s", n+1, err)
+		}
+
+		for file, contents := range test.Contents {
+			if data, err := f.ReadFile(file); err != nil {
+				t.Errorf("test %d: unexpected error: %s", n+1, err)
+			} else if string(data) != contents {
+				t.Errorf("test %d: expecting %s to contain %q, got %q", n+1, file, contents, data)
+			}
+		}
+	}
+}
+
+func BenchmarkCreateSmallFiles(b *testing.B) {
+	data := bytes.Repeat([]byte{'a'}, 1024)
+	names := make([]string, 1000)
+
+	for n := range names {
+		names[n] = "file" + strconv.Itoa(n)
+	}
+
+	b.Run("Create", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for n := 0; n < b.N; n++ {
+			f := New()
+
+			for _, name := range names {
+				of, err := f.Create(name)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				of.Write(data)
+				of.Close()
+			}
+		}
+	})
+
+	b.Run("WriteFile", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for n := 0; n < b.N; n++ {
+			f := New()
+
+			for _, name := range names {
+				if err := f.WriteFile(name, data, defaultPerms); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+func TestRootInfo(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("a/b", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Chtimes(".", time.Time{}, time.Unix(1, 0)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	sub, err := f.Sub("a")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, fsys := range [...]FSRO{f, sub.(FSRO), sealCompact(t, f), f.Seal()} {
+		stat, err := fsys.Stat(".")
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		}
+
+		lstat, err := fsys.LStat(".")
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		}
+
+		of, err := fsys.Open(".")
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		}
+
+		open, err := of.Stat()
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		}
+
+		for _, fi := range [...]fs.FileInfo{stat, lstat, open} {
+			if fi.Name() != "." {
+				t.Errorf("test %d: expecting root to be named \".\", got %q", n+1, fi.Name())
+			} else if !fi.IsDir() || fi.Mode() != stat.Mode() {
+				t.Errorf("test %d: expecting directory mode %s, got %s", n+1, stat.Mode(), fi.Mode())
+			} else if !fi.ModTime().Equal(stat.ModTime()) {
+				t.Errorf("test %d: expecting modtime %s, got %s", n+1, stat.ModTime(), fi.ModTime())
+			}
+		}
+
+		entries, err := fsys.ReadDir(".")
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		}
+
+		read, err := of.(fs.ReadDirFile).ReadDir(-1)
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		} else if len(entries) != 1 || len(read) != 1 || entries[0].Name() != read[0].Name() {
+			t.Errorf("test %d: expecting ReadDir and Open to list the same single entry, got %v and %v", n+1, entries, read)
+		}
+	}
+}
+
+func TestOpenFileDir(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("dir/sub", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Path string
+		Mode Mode
+	}{
+		{ // 1
+			Path: "dir",
+			Mode: WriteOnly,
+		},
+		{ // 2
+			Path: "dir/sub",
+			Mode: ReadWrite | Create,
+		},
+		{ // 3
+			Path: "dir",
+			Mode: WriteOnly | Truncate,
+		},
+		{ // 4
+			Path: "dir",
+			Mode: ReadOnly,
+		},
+	} {
+		if _, err := f.OpenFile(test.Path, test.Mode, 0o644); !errors.Is(err, ErrIsDir) {
+			t.Errorf("test %d: expecting ErrIsDir, got %v", n+1, err)
+		}
+	}
+
+	if _, err := f.Create("dir"); !errors.Is(err, ErrIsDir) {
+		t.Errorf("test 5: expecting ErrIsDir, got %v", err)
+	} else if fi, err := f.Stat("dir"); err != nil {
+		t.Errorf("test 6: unexpected error: %s", err)
+	} else if !fi.IsDir() {
+		t.Errorf("test 6: expecting directory to be unchanged")
+	}
+}
+
+func TestOpenDir(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("dir/sub", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("dir/file", nil, 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("dir", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("dir/file", "filelink"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Mkdir("wo", 0o333); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Path    string
+		Entries []string
+		Err     error
+	}{
+		{ // 1
+			Path:    "dir",
+			Entries: []string{"sub", "file"},
+		},
+		{ // 2
+			Path:    "link",
+			Entries: []string{"sub", "file"},
+		},
+		{ // 3
+			Path: "dir/file",
+			Err: &fs.PathError{
+				Op:   "opendir",
+				Path: "dir/file",
+				Err:  ErrNotDir,
+			},
+		},
+		{ // 4
+			Path: "filelink",
+			Err: &fs.PathError{
+				Op:   "opendir",
+				Path: "filelink",
+				Err:  ErrNotDir,
+			},
+		},
+		{ // 5
+			Path: "missing",
+			Err: &fs.PathError{
+				Op:   "opendir",
+				Path: "missing",
+				Err:  fs.ErrNotExist,
+			},
+		},
+		{ // 6
+			Path: "wo",
+			Err: &fs.PathError{
+				Op:   "opendir",
+				Path: "wo",
+				Err:  fs.ErrPermission,
+			},
+		},
+	} {
+		d, err := f.OpenDir(test.Path)
+		if !reflect.DeepEqual(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if err != nil {
+			continue
+		}
+
+		des, err := d.ReadDir(-1)
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		}
+
+		var names []string
+
+		for _, de := range des {
+			names = append(names, de.Name())
+		}
+
+		if !reflect.DeepEqual(names, test.Entries) {
+			t.Errorf("test %d: expecting entries %v, got %v", n+1, test.Entries, names)
+		}
+
+		d.Close()
+	}
+}
+
+func TestOpenFileNoFollow(t *testing.T) {
+	f := New()
+
+	if err := f.Mkdir("dir", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("dir/file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("dir", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("file", "dir/symlink"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Path string
+		Mode Mode
+		Err  error
+	}{
+		{ // 1
+			Path: "dir/file",
+			Mode: ReadOnly | NoFollow,
+		},
+		{ // 2
+			Path: "link/file",
+			Mode: WriteOnly | NoFollow,
+		},
+		{ // 3
+			Path: "dir/new",
+			Mode: WriteOnly | Create | NoFollow,
+		},
+		{ // 4
+			Path: "dir/symlink",
+			Mode: ReadOnly | NoFollow,
+			Err: &fs.PathError{
+				Op:   "openfile",
+				Path: "dir/symlink",
+				Err:  ErrNoFollow,
+			},
+		},
+		{ // 5
+			Path: "link",
+			Mode: WriteOnly | Create | Truncate | NoFollow,
+			Err: &fs.PathError{
+				Op:   "openfile",
+				Path: "link",
+				Err:  ErrNoFollow,
+			},
+		},
+	} {
+		if of, err := f.OpenFile(test.Path, test.Mode, 0o644); !reflect.DeepEqual(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if err == nil {
+			of.Close()
+		}
+	}
+
+	if target, err := f.Readlink("link"); err != nil {
+		t.Errorf("test 6: unexpected error: %s", err)
+	} else if target != "dir" {
+		t.Errorf("test 6: expecting symlink to be unchanged, got target %q", target)
+	}
+}
+
+func TestOpenFileSymlink(t *testing.T) {
+	f := New()
+
+	if err := f.Mkdir("dir", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("dir/file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("dir/file", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("link", "chain"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("new", "dir/dangling"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("dir", "dirlink"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("loop", "loop"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.MkdirAll("real/dir", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("/real/dir", "rl"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("../x", "real/dir/up"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Path, Write string
+		Mode        Mode
+		Contents    map[string]string
+		Err         error
+	}{
+		{ // 1
+			Path:     "link",
+			Mode:     ReadOnly,
+			Contents: map[string]string{"dir/file": "data"},
+		},
+		{ // 2
+			Path:     "chain",
+			Mode:     ReadOnly,
+			Contents: map[string]string{"dir/file": "data"},
+		},
+		{ // 3
+			Path:     "link",
+			Mode:     WriteOnly | Truncate,
+			Write:    "new data",
+			Contents: map[string]string{"dir/file": "new data"},
+		},
+		{ // 4
+			Path:     "chain",
+			Mode:     WriteOnly | Append,
+			Write:    "!",
+			Contents: map[string]string{"dir/file": "new data!"},
+		},
+		{ // 5
+			Path: "dir/dangling",
+			Mode: ReadOnly,
+			Err: &fs.PathError{
+				Op:   "openfile",
+				Path: "dir/dangling",
+				Err:  fs.ErrNotExist,
+			},
+		},
+		{ // 6
+			Path:     "dir/dangling",
+			Mode:     WriteOnly | Create,
+			Write:    "created",
+			Contents: map[string]string{"dir/new": "created"},
+		},
+		{ // 7
+			Path: "link",
+			Mode: WriteOnly | Create | Excl,
+			Err: &fs.PathError{
+				Op:   "openfile",
+				Path: "link",
+				Err:  fs.ErrExist,
+			},
+		},
+		{ // 8
+			Path: "dirlink",
+			Mode: ReadOnly,
+			Err: &fs.PathError{
+				Op:   "openfile",
+				Path: "dirlink",
+				Err:  ErrIsDir,
+			},
+		},
+		{ // 9
+			Path: "loop",
+			Mode: ReadOnly,
+			Err: &fs.PathError{
+				Op:   "openfile",
+				Path: "loop",
+				Err:  &SymlinkLimitError{Path: "loop", Limit: maxRedirects},
+			},
+		},
+		{ // 10
+			Path:     "rl/up",
+			Mode:     ReadWrite | Create,
+			Write:    "up",
+			Contents: map[string]string{"real/x": "up"},
+		},
+	} {
+		of, err := f.OpenFile(test.Path, test.Mode, 0o644)
+		if !reflect.DeepEqual(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+
+			continue
+		} else if err != nil {
+			continue
+		}
+
+		if test.Write != "" {
+			if _, err := of.WriteString(test.Write); err != nil {
+				t.Errorf("test %d: unexpected error writing: %s", n+1, err)
+			}
+		} else if data, err := io.ReadAll(of); err != nil {
+			t.Errorf("test %d: unexpected error reading: %s", n+1, err)
+		} else if string(data) != test.Contents["dir/file"] {
+			t.Errorf("test %d: expecting to read %q, got %q", n+1, test.Contents["dir/file"], data)
+		}
+
+		of.Close()
+
+		for p, contents := range test.Contents {
+			if data, err := f.ReadFile(p); err != nil {
+				t.Errorf("test %d: unexpected error reading %s: %s", n+1, p, err)
+			} else if string(data) != contents {
+				t.Errorf("test %d: expecting %s to contain %q, got %q", n+1, p, contents, data)
+			}
+		}
+	}
+
+	for n, link := range [...][2]string{
+		{"link", "dir/file"},
+		{"chain", "link"},
+		{"dir/dangling", "new"},
+	} {
+		if target, err := f.Readlink(link[0]); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+11, err)
+		} else if target != link[1] {
+			t.Errorf("test %d: expecting symlink %s to be unchanged, got target %q", n+11, link[0], target)
+		}
+	}
+}
+
+func TestMode(t *testing.T) {
+	for n, test := range [...]struct {
+		Mode   Mode
+		String string
+		Valid  bool
+	}{
+		{ // 1
+			String: "0",
+		},
+		{ // 2
+			Mode:   ReadOnly,
+			String: "ReadOnly",
+			Valid:  true,
+		},
+		{ // 3
+			Mode:   WriteOnly | Append,
+			String: "WriteOnly|Append",
+			Valid:  true,
+		},
+		{ // 4
+			Mode:   ReadWrite | Create | Excl | Truncate,
+			String: "ReadWrite|Create|Excl|Truncate",
+			Valid:  true,
+		},
+		{ // 5
+			Mode:   ReadOnly | Create,
+			String: "ReadOnly|Create",
+			Valid:  true,
+		},
+		{ // 6
+			Mode:   ReadOnly | Truncate,
+			String: "ReadOnly|Truncate",
+		},
+		{ // 7
+			Mode:   ReadOnly | Append,
+			String: "ReadOnly|Append",
+		},
+		{ // 8
+			Mode:   WriteOnly | Excl,
+			String: "WriteOnly|Excl",
+		},
+		{ // 9
+			Mode:   Create,
+			String: "Create",
+		},
+		{ // 10
+			Mode:   ReadOnly | 0xc0,
+			String: "ReadOnly|NoFollow|0x80",
+		},
+		{ // 11
+			Mode:   WriteOnly | Create | NoFollow,
+			String: "WriteOnly|Create|NoFollow",
+			Valid:  true,
+		},
+	} {
+		if s := test.Mode.String(); s != test.String {
+			t.Errorf("test %d: expecting string %q, got %q", n+1, test.String, s)
+		} else if valid := test.Mode.Valid(); valid != test.Valid {
+			t.Errorf("test %d: expecting Valid to return %v, got %v", n+1, test.Valid, valid)
+		}
+	}
+
+	f := New()
+
+	if _, err := f.OpenFile("file", ReadOnly|Create|Truncate, 0o644); !errors.Is(err, ErrInvalidMode) {
+		t.Errorf("test 11: expecting ErrInvalidMode, got %v", err)
+	} else if exists, err := f.Exists("file"); err != nil {
+		t.Errorf("test 12: unexpected error: %s", err)
+	} else if exists {
+		t.Errorf("test 12: expecting file not to have been created")
+	}
+}
+
+func TestSymlinkTarget(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("a/b", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("a/b/file", []byte("contents"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Target, Link string
+		Err          error
+	}{
+		{ // 1
+			Target: "./b/../b/file",
+			Link:   "a/rel",
+		},
+		{ // 2
+			Target: "/a//b/./file",
+			Link:   "abs",
+		},
+		{ // 3
+			Target: "../a/b/file",
+			Link:   "above",
+		},
+		{ // 4
+			Target: "b/",
+			Link:   "a/dir",
+		},
+		{ // 5
+			Link: "empty",
+			Err:  fs.ErrInvalid,
+		},
+		{ // 6
+			Target: "a/b\x00/file",
+			Link:   "nul",
+			Err:    fs.ErrInvalid,
+		},
+	} {
+		if err := f.Symlink(test.Target, test.Link); !errors.Is(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if test.Err != nil {
+			if _, err := f.LStat(test.Link); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("test %d: expecting link not to be created, got %v", n+1, err)
+			}
+		} else if target, err := f.Readlink(test.Link); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if target != test.Target {
+			t.Errorf("test %d: expecting target %q, got %q", n+1, test.Target, target)
+		} else if fi, err := f.Stat(test.Link); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if fi.IsDir() {
+			if _, err := f.Stat(test.Link + "/file"); err != nil {
+				t.Errorf("test %d: unexpected error: %s", n+1, err)
+			}
+		} else if data, err := f.ReadFile(test.Link); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if string(data) != "contents" {
+			t.Errorf("test %d: expecting to read %q, got %q", n+1, "contents", data)
+		}
+	}
+
+	if err := f.Check(); err != nil {
+		t.Errorf("test 7: unexpected error: %s", err)
+	}
+}
+
+func TestSealSubtree(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("out/bin", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("out/bin/app", []byte("app"), 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Mkdir("work", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("out", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	sealed, err := f.SealSubtree("link")
+	if err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	}
+
+	if data, err := sealed.ReadFile("bin/app"); err != nil {
+		t.Errorf("test 2: unexpected error: %s", err)
+	} else if string(data) != "app" {
+		t.Errorf("test 2: expecting contents %q, got %q", "app", data)
+	} else if err := f.WriteFile("out/bin/app", []byte("new"), 0o755); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("test 3: expecting error %v, got %v", fs.ErrPermission, err)
+	} else if err := f.Mkdir("out/lib", 0o755); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("test 4: expecting error %v, got %v", fs.ErrPermission, err)
+	} else if err := f.WriteFile("work/file", []byte("data"), 0o644); err != nil {
+		t.Errorf("test 5: unexpected error: %s", err)
+	} else if err := f.Rename("out", "work/out"); err != nil {
+		t.Errorf("test 6: unexpected error: %s", err)
+	} else if data, err := f.ReadFile("work/out/bin/app"); err != nil {
+		t.Errorf("test 7: unexpected error: %s", err)
+	} else if string(data) != "app" {
+		t.Errorf("test 7: expecting contents %q, got %q", "app", data)
+	} else if _, err := f.SealSubtree("work/file"); !errors.Is(err, ErrNotDir) {
+		t.Errorf("test 8: expecting error %v, got %v", ErrNotDir, err)
+	} else if _, err := f.SealSubtree("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("test 9: expecting error %v, got %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestSubLockDomains(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("tenants/a", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.MkdirAll("tenants/b", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	a, err := f.Sub("tenants/a")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	b, err := f.Sub("tenants/b")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	f.mu.Lock()
+	a.(*FS).mu.Lock()
+
+	done := make(chan error)
+
+	go func() {
+		done <- b.(*FS).WriteFile("file", []byte("data"), 0o644)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("test 1: unexpected error: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("test 1: expecting write to not wait on the locks of other domains")
+	}
+
+	a.(*FS).mu.Unlock()
+	f.mu.Unlock()
+
+	var wg sync.WaitGroup
+
+	for _, fsys := range [...]*FS{a.(*FS), b.(*FS), f} {
+		wg.Add(1)
+
+		go func(fsys *FS) {
+			defer wg.Done()
+
+			for n := 0; n < 100; n++ {
+				name := strconv.Itoa(n)
+
+				fsys.WriteFile(name, []byte(name), 0o644)
+				fsys.Rename(name, name+"_")
+				fsys.Remove(name + "_")
+			}
+		}(fsys)
+	}
+
+	wg.Wait()
+
+	if data, err := f.ReadFile("tenants/b/file"); err != nil {
+		t.Errorf("test 2: unexpected error: %s", err)
+	} else if string(data) != "data" {
+		t.Errorf("test 2: expecting contents %q, got %q", "data", data)
+	}
+}
+
+func TestSubConcurrentRename(t *testing.T) {
+	for n := 0; n < 500; n++ {
+		f := New()
+
+		if err := f.MkdirAll("r/A", 0o755); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		} else if err := f.MkdirAll("r/B", 0o755); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		a, err := f.Sub("r")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		b, err := f.Sub("r")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		var (
+			wg         sync.WaitGroup
+			errA, errB error
+		)
+
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			errA = a.(*FS).Rename("A", "B/A")
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			errB = b.(*FS).Rename("B", "A/B")
+		}()
+
+		wg.Wait()
+
+		if errA == nil && errB == nil {
+			t.Fatalf("test %d: expecting one of the renames to fail", n+1)
+		} else if ok, _ := f.Exists("r/A"); !ok {
+			if ok, _ := f.Exists("r/B/A"); !ok {
+				t.Fatalf("test %d: expecting A to remain reachable", n+1)
+			}
+		} else if ok, _ := f.Exists("r/A/B"); !ok {
+			if ok, _ := f.Exists("r/B"); !ok {
+				t.Fatalf("test %d: expecting B to remain reachable", n+1)
+			}
+		}
+	}
+}
+
+func TestCreateFromBytes(t *testing.T) {
+	var c countingAllocator
+
+	f := New(WithAllocator(&c))
+
+	f.Mkdir("dir", fs.ModePerm)
+	f.WriteFile("dir/existing", nil, 0o644)
+
+	for n, test := range [...]struct {
+		Path     string
+		ReadOnly bool
+		Err      error
+		Contents string
+		Frees    int
+	}{
+		{ // 1
+			Path:     "a",
+			ReadOnly: true,
+			Contents: "Hello, World",
+			Frees:    1,
+		},
+		{ // 2
+			Path:     "dir/b",
+			Contents: "Jello, World",
+			Frees:    1,
+		},
+		{ // 3
+			Path: "dir/existing",
+			Err: &fs.PathError{
+				Op:   "createfrombytes",
+				Path: "dir/existing",
+				Err:  fs.ErrExist,
+			},
+		},
+		{ // 4
+			Path: "missing/c",
+			Err: &fs.PathError{
+				Op:   "createfrombytes",
+				Path: "missing/c",
+				Err:  fs.ErrNotExist,
+			},
+		},
+	} {
+		data := []byte("Hello, World")
+		c.frees = 0
+
+		if err := f.CreateFromBytes(test.Path, data, 0o644, test.ReadOnly); !reflect.DeepEqual(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+
+			continue
+		} else if err != nil {
+			continue
+		}
+
+		if de, err := f.getEntry(test.Path); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if i := de.(*inodeRW); &i.data[0] != &data[0] {
+			t.Errorf("test %d: expecting data to not be copied", n+1)
+		}
+
+		if of, err := f.OpenFile(test.Path, WriteOnly, 0); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if _, err := of.Write([]byte("J")); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if err := of.Close(); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		}
+
+		if string(data) != test.Contents {
+			t.Errorf("test %d: expecting data to be %q, got %q", n+1, test.Contents, data)
+		} else if contents, err := f.ReadFileString(test.Path); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if contents != "Jello, World" {
+			t.Errorf("test %d: expecting contents %q, got %q", n+1, "Jello, World", contents)
+		} else if err := f.Remove(test.Path); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if c.frees != test.Frees {
+			t.Errorf("test %d: expecting %d frees, got %d", n+1, test.Frees, c.frees)
+		}
+	}
+
+	c.frees = 0
+
+	if err := f.CreateFromBytes("e", []byte("data"), 0o644, true); err != nil {
+		t.Errorf("test 5: unexpected error: %s", err)
+	} else if err := f.Remove("e"); err != nil {
+		t.Errorf("test 5: unexpected error: %s", err)
+	} else if c.frees != 0 {
+		t.Errorf("test 5: expecting read-only data to not be freed, got %d frees", c.frees)
+	}
+}
@@ -521,7 +521,7 @@ func TestStat(t *testing.T) {
 					modtime: time.Unix(1, 2),
 					mode:    fs.ModeDir | fs.ModePerm,
 				},
-				name: "/",
+				name: ".",
 			},
 		},
 		{ // 3
@@ -1089,7 +1089,7 @@ func TestLStat(t *testing.T) {
 					modtime: time.Unix(1, 2),
 					mode:    fs.ModeDir | fs.ModePerm,
 				},
-				name: "/",
+				name: ".",
 			},
 		},
 		{ // 3
@@ -1457,6 +1457,7 @@ func TestSub(t *testing.T) {
 						},
 					},
 				},
+				partial: true,
 			},
 		},
 		{ // 3
@@ -1564,3 +1565,327 @@ func TestFSTest(t *testing.T) {
 		t.Errorf("error during fstest: %s", err)
 	}
 }
+
+func TestNotDir(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("dir", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("dir/file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("dir/file", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, fsys := range [...]interface {
+		fs.StatFS
+		fs.ReadDirFS
+	}{
+		f,
+		f.Seal(),
+		sealCompact(t, f),
+	} {
+		for m, test := range [...]func() error{
+			func() error {
+				_, err := fsys.Open("dir/file/child")
+
+				return err
+			},
+			func() error {
+				_, err := fsys.Stat("link/child")
+
+				return err
+			},
+			func() error {
+				_, err := fsys.ReadDir("dir/file")
+
+				return err
+			},
+			func() error {
+				_, err := fsys.ReadDir("link")
+
+				return err
+			},
+		} {
+			if err := test(); !errors.Is(err, ErrNotDir) {
+				t.Errorf("test %d.%d: expecting error %v, got %v", n+1, m+1, ErrNotDir, err)
+			}
+		}
+	}
+
+	if _, err := New().OpenFile("dir/file/child", ReadWrite|Create, 0o644); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("test 4: expecting error %v, got %v", fs.ErrNotExist, err)
+	} else if _, err := f.OpenFile("dir/file/child", ReadWrite|Create, 0o644); !errors.Is(err, ErrNotDir) {
+		t.Errorf("test 5: expecting error %v, got %v", ErrNotDir, err)
+	}
+}
+
+func TestReadDirInfo(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("dir/sub", 0o750); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("dir/file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("file", "dir/link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, fsys := range [...]FSRO{
+		f,
+		f.Seal(),
+		sealCompact(t, f),
+	} {
+		es, err := fsys.ReadDir("dir")
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		}
+
+		fis, err := fsys.ReadDirInfo("dir")
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		} else if len(fis) != len(es) {
+			t.Fatalf("test %d: expecting %d entries, got %d", n+1, len(es), len(fis))
+		}
+
+		for m, e := range es {
+			fi, err := e.Info()
+			if err != nil {
+				t.Fatalf("test %d.%d: unexpected error: %s", n+1, m+1, err)
+			} else if fis[m].Name() != fi.Name() || fis[m].Mode() != fi.Mode() || fis[m].Size() != fi.Size() || !fis[m].ModTime().Equal(fi.ModTime()) {
+				t.Errorf("test %d.%d: expecting info %s, got %s", n+1, m+1, fs.FormatFileInfo(fi), fs.FormatFileInfo(fis[m]))
+			}
+		}
+
+		if _, err := fsys.ReadDirInfo("dir/file"); !errors.Is(err, ErrNotDir) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, ErrNotDir, err)
+		} else if _, err := fsys.ReadDirInfo("missing"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, fs.ErrNotExist, err)
+		}
+	}
+}
+
+func TestLReadDir(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("dir/sub", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("dir/file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("dir", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, fsys := range [...]FSRO{
+		f,
+		f.Seal(),
+		sealCompact(t, f),
+	} {
+		if es, err := fsys.LReadDir("dir"); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if len(es) != 2 {
+			t.Errorf("test %d: expecting 2 entries, got %d", n+1, len(es))
+		} else if es, err := fsys.LReadDir("."); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if len(es) != 2 {
+			t.Errorf("test %d: expecting 2 entries, got %d", n+1, len(es))
+		} else if _, err := fsys.ReadDir("link"); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if _, err := fsys.LReadDir("link"); !errors.Is(err, ErrNotDir) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, ErrNotDir, err)
+		} else if _, err := fsys.LReadDir("dir/file"); !errors.Is(err, ErrNotDir) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, ErrNotDir, err)
+		} else if _, err := fsys.LReadDir("missing"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, fs.ErrNotExist, err)
+		}
+	}
+}
+
+func TestScanDir(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("dir/sub", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("dir/file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("file", "dir/link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Mkdir("unreadable", 0o300); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, fsys := range [...]FSRO{
+		f,
+		f.Seal(),
+		sealCompact(t, f),
+	} {
+		es, err := fsys.ReadDir("dir")
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		}
+
+		var names []string
+
+		if err := fsys.ScanDir("dir", func(e fs.DirEntry) bool {
+			names = append(names, e.Name())
+
+			return true
+		}); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if len(names) != len(es) {
+			t.Errorf("test %d: expecting %d entries, got %d", n+1, len(es), len(names))
+		} else {
+			for m, e := range es {
+				if names[m] != e.Name() {
+					t.Errorf("test %d.%d: expecting name %q, got %q", n+1, m+1, e.Name(), names[m])
+				}
+			}
+		}
+
+		var count int
+
+		if err := fsys.ScanDir("dir", func(fs.DirEntry) bool {
+			count++
+
+			return count < 2
+		}); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if count != 2 {
+			t.Errorf("test %d: expecting scan to stop after 2 entries, got %d", n+1, count)
+		}
+
+		noop := func(fs.DirEntry) bool { return true }
+
+		if err := fsys.ScanDir("dir/file", noop); !errors.Is(err, ErrNotDir) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, ErrNotDir, err)
+		} else if err := fsys.ScanDir("missing", noop); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, fs.ErrNotExist, err)
+		} else if err := fsys.ScanDir("unreadable", noop); !errors.Is(err, fs.ErrPermission) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, fs.ErrPermission, err)
+		}
+	}
+}
+
+func TestStatAll(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("dir/sub", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("dir/file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("file", "dir/link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	paths := []string{"dir/file", "missing", "dir/link", "dir/file/child", "dir/sub", "/invalid"}
+
+	for n, fsys := range [...]FSRO{
+		f,
+		f.Seal(),
+		sealCompact(t, f),
+	} {
+		fis, errs := fsys.StatAll(paths)
+		if len(fis) != len(paths) || len(errs) != len(paths) {
+			t.Fatalf("test %d: expecting %d results, got %d and %d", n+1, len(paths), len(fis), len(errs))
+		}
+
+		for m, p := range paths {
+			fi, err := fsys.Stat(p)
+
+			if !reflect.DeepEqual(err, errs[m]) {
+				t.Errorf("test %d.%d: expecting error %v, got %v", n+1, m+1, err, errs[m])
+			} else if err != nil {
+				if fis[m] != nil {
+					t.Errorf("test %d.%d: expecting no info, got %s", n+1, m+1, fs.FormatFileInfo(fis[m]))
+				}
+			} else if fis[m].Name() != fi.Name() || fis[m].Mode() != fi.Mode() || fis[m].Size() != fi.Size() {
+				t.Errorf("test %d.%d: expecting info %s, got %s", n+1, m+1, fs.FormatFileInfo(fi), fs.FormatFileInfo(fis[m]))
+			}
+		}
+	}
+}
+
+func TestResolveLink(t *testing.T) {
+	f := New(WithMaxSymlinks(5))
+
+	if err := f.Mkdir("a", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Mkdir("b", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, link := range [...][2]string{
+		{"../b/link2", "a/link1"},
+		{"/c/missing", "b/link2"},
+		{"../../../top", "a/up"},
+		{"file", "b/file-link"},
+		{"loop2", "b/loop1"},
+		{"loop1", "b/loop2"},
+		{"a", "root-link"},
+	} {
+		if err := f.Symlink(link[0], link[1]); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if err := f.WriteFile("b/file", nil, 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Path   string
+		Depth  int
+		Target string
+		Err    error
+	}{
+		{ // 1
+			Path:   "a/link1",
+			Target: "c/missing",
+		},
+		{ // 2
+			Path:   "a/link1",
+			Depth:  1,
+			Target: "b/link2",
+		},
+		{ // 3
+			Path:   "a/link1",
+			Depth:  2,
+			Target: "c/missing",
+		},
+		{ // 4
+			Path:   "a/up",
+			Target: "top",
+		},
+		{ // 5
+			Path:   "b/file-link",
+			Target: "b/file",
+		},
+		{ // 6
+			Path:   "root-link",
+			Target: "a",
+		},
+		{ // 7
+			Path: "b/loop1",
+			Err:  &SymlinkLimitError{},
+		},
+		{ // 8
+			Path: "b/file",
+			Err:  fs.ErrInvalid,
+		},
+		{ // 9
+			Path: "missing",
+			Err:  fs.ErrNotExist,
+		},
+	} {
+		target, err := f.ResolveLink(test.Path, test.Depth)
+		if sle, ok := test.Err.(*SymlinkLimitError); ok {
+			if !errors.As(err, &sle) {
+				t.Errorf("test %d: expecting SymlinkLimitError, got %v", n+1, err)
+			}
+		} else if !errors.Is(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if target != test.Target {
+			t.Errorf("test %d: expecting target %q, got %q", n+1, test.Target, target)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Package memfsgen generates Go source that reconstructs a read-only memfs at
+// init time.
+package memfsgen // import "vimagination.zapto.org/memfs/memfsgen"
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"go/types"
+	"io"
+	"strconv"
+
+	"vimagination.zapto.org/memfs"
+)
+
+const source = `// Code generated by memfsgen. DO NOT EDIT.
+
+package %s
+
+import "vimagination.zapto.org/memfs"
+
+var %s = func() memfs.FSRO {
+	fsys, err := memfs.LoadImage([]byte(%s))
+	if err != nil {
+		panic(err)
+	}
+
+	return fsys
+}()
+`
+
+// Generate writes Go source to w that, when compiled as part of the named
+// package, declares a variable of the given name holding a memfs.FSRO with the
+// same contents as the given FS.
+//
+// The variable name must be an identifier that can be declared at the package
+// level, and so cannot be '_', 'init', 'memfs', which is the name of the
+// imported package, or a predeclared identifier, such as 'string', nor 'main'
+// in the main package.
+//
+// Unlike embed.FS, the generated FS preserves symlinks, permissions and
+// modification times.
+func Generate(w io.Writer, pkg, name string, f memfs.FSRO) error {
+	if !token.IsIdentifier(pkg) {
+		return fmt.Errorf("invalid package name: %q", pkg)
+	} else if !validName(pkg, name) {
+		return fmt.Errorf("invalid variable name: %q", name)
+	}
+
+	var image bytes.Buffer
+
+	if _, err := f.WriteImage(&image); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, source, pkg, name, strconv.Quote(image.String()))
+
+	return err
+}
+
+func validName(pkg, name string) bool {
+	switch name {
+	case "_", "init", "memfs":
+		return false
+	case "main":
+		return pkg != "main"
+	}
+
+	return token.IsIdentifier(name) && types.Universe.Lookup(name) == nil
+}
@@ -0,0 +1,154 @@
+package memfsgen
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"strconv"
+	"testing"
+	"time"
+
+	"vimagination.zapto.org/memfs"
+)
+
+func TestGenerate(t *testing.T) {
+	f := memfs.New()
+
+	if err := f.Mkdir("dir", 0o750); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if of, err := f.Create("dir/file"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if _, err = of.WriteString("Hello, World\x00\xff"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err = f.Symlink("dir/file", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err = f.Chtimes("dir/file", time.Unix(1, 2), time.Unix(3, 4)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := Generate(&buf, "assets", "FS", f); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	file, err := parser.ParseFile(token.NewFileSet(), "generated.go", buf.Bytes(), 0)
+	if err != nil {
+		t.Fatalf("unexpected error parsing generated code: %s", err)
+	} else if file.Name.Name != "assets" {
+		t.Errorf("expecting package name %q, got %q", "assets", file.Name.Name)
+	}
+
+	var image string
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			image = lit.Value
+		}
+
+		return true
+	})
+
+	data, err := strconv.Unquote(image)
+	if err != nil {
+		t.Fatalf("unexpected error unquoting image: %s", err)
+	}
+
+	ro, err := memfs.LoadImage([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error loading image: %s", err)
+	}
+
+	if contents, err := ro.ReadFile("link"); err != nil {
+		t.Errorf("unexpected error reading file: %s", err)
+	} else if string(contents) != "Hello, World\x00\xff" {
+		t.Errorf("expecting contents %q, got %q", "Hello, World\x00\xff", contents)
+	}
+
+	if fi, err := ro.Stat("dir"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	} else if fi.Mode() != fs.ModeDir|0o750 {
+		t.Errorf("expecting mode %s, got %s", fs.ModeDir|0o750, fi.Mode())
+	}
+
+	if fi, err := ro.Stat("dir/file"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	} else if !fi.ModTime().Equal(time.Unix(3, 4)) {
+		t.Errorf("expecting modtime %s, got %s", time.Unix(3, 4), fi.ModTime())
+	}
+
+	if target, err := ro.Readlink("link"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	} else if target != "dir/file" {
+		t.Errorf("expecting link target %q, got %q", "dir/file", target)
+	}
+}
+
+func TestGenerateInvalid(t *testing.T) {
+	for n, test := range [...][2]string{
+		{"", "FS"},
+		{"assets", ""},
+		{"my-assets", "FS"},
+		{"assets", "1FS"},
+		{"assets", "func"},
+		{"assets", "_"},
+		{"assets", "init"},
+		{"assets", "memfs"},
+		{"assets", "string"},
+		{"assets", "nil"},
+		{"main", "main"},
+	} {
+		if err := Generate(&bytes.Buffer{}, test[0], test[1], memfs.New()); err == nil {
+			t.Errorf("test %d: expecting error, got nil", n+1)
+		}
+	}
+}
+
+func TestGenerateTypeCheck(t *testing.T) {
+	fsro := types.NewInterfaceType(nil, nil)
+	memfsPkg := types.NewPackage("vimagination.zapto.org/memfs", "memfs")
+
+	memfsPkg.Scope().Insert(types.NewTypeName(token.NoPos, memfsPkg, "FSRO", fsro))
+	memfsPkg.Scope().Insert(types.NewFunc(token.NoPos, memfsPkg, "LoadImage", types.NewSignatureType(nil, nil, nil,
+		types.NewTuple(types.NewVar(token.NoPos, memfsPkg, "data", types.NewSlice(types.Typ[types.Byte]))),
+		types.NewTuple(types.NewVar(token.NoPos, memfsPkg, "", fsro), types.NewVar(token.NoPos, memfsPkg, "", types.Universe.Lookup("error").Type())),
+		false)))
+	memfsPkg.MarkComplete()
+
+	conf := types.Config{
+		Importer: importerFunc(func(string) (*types.Package, error) { return memfsPkg, nil }),
+	}
+
+	for n, test := range [...][2]string{
+		{"assets", "FS"},
+		{"assets", "err"},
+		{"assets", "fsys"},
+		{"assets", "main"},
+		{"main", "FS"},
+	} {
+		var buf bytes.Buffer
+
+		if err := Generate(&buf, test[0], test[1], memfs.New()); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+
+			continue
+		}
+
+		fset := token.NewFileSet()
+
+		if file, err := parser.ParseFile(fset, "generated.go", buf.Bytes(), 0); err != nil {
+			t.Errorf("test %d: unexpected error parsing generated code: %s", n+1, err)
+		} else if _, err = conf.Check(test[0], fset, []*ast.File{file}, nil); err != nil {
+			t.Errorf("test %d: unexpected error checking generated code: %s", n+1, err)
+		}
+	}
+}
+
+type importerFunc func(string) (*types.Package, error)
+
+func (i importerFunc) Import(path string) (*types.Package, error) {
+	return i(path)
+}
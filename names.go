@@ -0,0 +1,140 @@
+package memfs
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNameTooLong is returned when creating an entry whose name is longer than
+// allowed by the NameLimits set with WithNameLimits, in the manner of
+// ENAMETOOLONG.
+var ErrNameTooLong = errors.New("file name too long")
+
+// NameLimits describes the restrictions placed upon the names of new entries
+// in an FS, as set with WithNameLimits.
+type NameLimits struct {
+	// MaxLength is the maximum length of a name, in bytes. A length of zero
+	// or less is unlimited.
+	MaxLength int
+
+	// MaxBase and MaxExt, when MaxBase is greater than zero, restrict names
+	// to a non-empty base name of at most MaxBase bytes, optionally followed
+	// by a single dot and a non-empty extension of at most MaxExt bytes, as
+	// with the 8.3 names of a FAT file system.
+	MaxBase, MaxExt int
+
+	// Valid, when not nil, reports whether the given character is allowed in
+	// a name.
+	Valid func(r rune) bool
+
+	// Windows, when true, rejects names that are not valid on Windows, being
+	// those containing a control character or any of < > : " \ | ? *, those
+	// ending with a dot or a space, and the reserved device names, such as
+	// CON, NUL, COM1, and LPT1, in any case, with or without an extension.
+	Windows bool
+}
+
+// WithNameLimits restricts the names of the entries created in the FS, and in
+// any FS created from it with Sub, returning ErrNameTooLong for a name that is
+// too long and ErrInvalidName for one that is otherwise not allowed, for
+// testing the handling of the limits of other file systems by code that
+// generates names.
+//
+// Existing entries, including those of an FS made with Layer, are unaffected.
+func WithNameLimits(limits NameLimits) Option {
+	return func(f *FS) {
+		f.names = &limits
+	}
+}
+
+// ShortNameChar reports whether the given character is allowed in an 8.3 name
+// of a FAT file system, being an upper case letter, a digit, one of
+// ! # $ % & ' ( ) - @ ^ _ ` { } ~, or the dot that separates the extension.
+func ShortNameChar(r rune) bool {
+	return r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("!#$%&'()-@^_`{}~.", r)
+}
+
+// PortableNameChar reports whether the given character is in the POSIX
+// portable filename character set, being a letter, a digit, or one of . _ -.
+func PortableNameChar(r rune) bool {
+	return r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-'
+}
+
+func (n *NameLimits) check(name string) error {
+	if n == nil {
+		return nil
+	} else if n.MaxLength > 0 && len(name) > n.MaxLength {
+		return ErrNameTooLong
+	}
+
+	if n.MaxBase > 0 {
+		base, ext, hasExt := strings.Cut(name, ".")
+
+		if base == "" || hasExt && (ext == "" || strings.Contains(ext, ".")) {
+			return ErrInvalidName
+		} else if len(base) > n.MaxBase || len(ext) > n.MaxExt {
+			return ErrNameTooLong
+		}
+	}
+
+	if n.Valid != nil {
+		for _, r := range name {
+			if !n.Valid(r) {
+				return ErrInvalidName
+			}
+		}
+	}
+
+	if n.Windows && !validWindowsName(name) {
+		return ErrInvalidName
+	}
+
+	return nil
+}
+
+var windowsDevices = [...]string{
+	"CON", "PRN", "AUX", "NUL", "CONIN$", "CONOUT$",
+	"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9", "COM¹", "COM²", "COM³",
+	"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9", "LPT¹", "LPT²", "LPT³",
+}
+
+func validWindowsName(name string) bool {
+	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+		return false
+	}
+
+	for _, r := range name {
+		if r < 0x20 || strings.ContainsRune(`<>:"\|?*`, r) {
+			return false
+		}
+	}
+
+	base, _, _ := strings.Cut(name, ".")
+	base = strings.TrimRight(base, " ")
+
+	for _, device := range windowsDevices {
+		if strings.EqualFold(base, device) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// getEntryWithParent gets the parent directory, and any existing entry, of
+// the given path, as with the method of fsRO, also checking that the name is
+// allowed when there is no existing entry and one may be created.
+func (f *FS) getEntryWithParent(path string, exists exists) (dNode, *dirEnt, error) {
+	d, de, err := f.fsRO.getEntryWithParent(path, exists)
+	if err != nil || de != nil || exists == mustExist {
+		return d, de, err
+	}
+
+	_, name := splitPath(path)
+
+	if err := f.names.check(name); err != nil {
+		return nil, nil, err
+	}
+
+	return d, nil, nil
+}
@@ -0,0 +1,197 @@
+package memfs
+
+import (
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNameLimits(t *testing.T) {
+	b := New()
+
+	b.Mkdir("dir", 0o755)
+	b.WriteFile("existing-file", nil, 0o644)
+
+	base := b.Seal()
+	shortNames := NameLimits{MaxBase: 8, MaxExt: 3, Valid: ShortNameChar}
+	windows := NameLimits{Windows: true}
+
+	for n, test := range [...]struct {
+		Limits NameLimits
+		Op     func(*FS) error
+		Err    error
+	}{
+		{ // 1
+			Limits: NameLimits{MaxLength: 5},
+			Op:     func(f *FS) error { return f.WriteFile("12345", nil, 0o644) },
+		},
+		{ // 2
+			Limits: NameLimits{MaxLength: 5},
+			Op:     func(f *FS) error { return f.WriteFile("123456", nil, 0o644) },
+			Err:    &fs.PathError{Op: "writefile", Path: "123456", Err: ErrNameTooLong},
+		},
+		{ // 3
+			Limits: NameLimits{MaxLength: 5},
+			Op:     func(f *FS) error { return f.Mkdir("dir/123456", 0o755) },
+			Err:    &fs.PathError{Op: "mkdir", Path: "dir/123456", Err: ErrNameTooLong},
+		},
+		{ // 4
+			Limits: NameLimits{MaxLength: 5},
+			Op:     func(f *FS) error { return f.MkdirAll("dir/a/123456", 0o755) },
+			Err:    &fs.PathError{Op: "mkdirall", Path: "dir/a/123456", Err: ErrNameTooLong},
+		},
+		{ // 5
+			Limits: NameLimits{MaxLength: 5},
+			Op:     func(f *FS) error { return f.Symlink("dir", "symlink") },
+			Err:    &fs.PathError{Op: "symlink", Path: "symlink", Err: ErrNameTooLong},
+		},
+		{ // 6
+			Limits: NameLimits{MaxLength: 5},
+			Op:     func(f *FS) error { return f.Rename("dir", "directory") },
+			Err:    &fs.PathError{Op: "rename", Path: "directory", Err: ErrNameTooLong},
+		},
+		{ // 7
+			Limits: NameLimits{MaxLength: 5},
+			Op: func(f *FS) error {
+				_, err := f.Create("dir/" + strings.Repeat("a", 6))
+
+				return err
+			},
+			Err: &fs.PathError{Op: "create", Path: "dir/aaaaaa", Err: ErrNameTooLong},
+		},
+		{ // 8
+			Limits: NameLimits{MaxLength: 3},
+			Op:     func(f *FS) error { return f.WriteFile("existing-file", []byte("data"), 0o644) },
+		},
+		{ // 9
+			Limits: shortNames,
+			Op:     func(f *FS) error { return f.WriteFile("README.TXT", nil, 0o644) },
+		},
+		{ // 10
+			Limits: shortNames,
+			Op:     func(f *FS) error { return f.WriteFile("MAKEFILE", nil, 0o644) },
+		},
+		{ // 11
+			Limits: shortNames,
+			Op:     func(f *FS) error { return f.WriteFile("LONGFILENAME.TXT", nil, 0o644) },
+			Err:    &fs.PathError{Op: "writefile", Path: "LONGFILENAME.TXT", Err: ErrNameTooLong},
+		},
+		{ // 12
+			Limits: shortNames,
+			Op:     func(f *FS) error { return f.WriteFile("FILE.TEXT", nil, 0o644) },
+			Err:    &fs.PathError{Op: "writefile", Path: "FILE.TEXT", Err: ErrNameTooLong},
+		},
+		{ // 13
+			Limits: shortNames,
+			Op:     func(f *FS) error { return f.WriteFile("ARCHIVE.TAR.GZ", nil, 0o644) },
+			Err:    &fs.PathError{Op: "writefile", Path: "ARCHIVE.TAR.GZ", Err: ErrInvalidName},
+		},
+		{ // 14
+			Limits: shortNames,
+			Op:     func(f *FS) error { return f.WriteFile(".GIT", nil, 0o644) },
+			Err:    &fs.PathError{Op: "writefile", Path: ".GIT", Err: ErrInvalidName},
+		},
+		{ // 15
+			Limits: shortNames,
+			Op:     func(f *FS) error { return f.WriteFile("FILE.", nil, 0o644) },
+			Err:    &fs.PathError{Op: "writefile", Path: "FILE.", Err: ErrInvalidName},
+		},
+		{ // 16
+			Limits: shortNames,
+			Op:     func(f *FS) error { return f.WriteFile("file.txt", nil, 0o644) },
+			Err:    &fs.PathError{Op: "writefile", Path: "file.txt", Err: ErrInvalidName},
+		},
+		{ // 17
+			Limits: NameLimits{Valid: PortableNameChar},
+			Op:     func(f *FS) error { return f.WriteFile("file-name_1.txt", nil, 0o644) },
+		},
+		{ // 18
+			Limits: NameLimits{Valid: PortableNameChar},
+			Op:     func(f *FS) error { return f.WriteFile("file name", nil, 0o644) },
+			Err:    &fs.PathError{Op: "writefile", Path: "file name", Err: ErrInvalidName},
+		},
+		{ // 19
+			Limits: NameLimits{Valid: PortableNameChar},
+			Op:     func(f *FS) error { return f.Link("existing-file", "café") },
+			Err:    &fs.PathError{Op: "link", Path: "café", Err: ErrInvalidName},
+		},
+		{ // 20
+			Limits: windows,
+			Op:     func(f *FS) error { return f.WriteFile("file.txt", nil, 0o644) },
+		},
+		{ // 21
+			Limits: windows,
+			Op:     func(f *FS) error { return f.WriteFile("CON", nil, 0o644) },
+			Err:    &fs.PathError{Op: "writefile", Path: "CON", Err: ErrInvalidName},
+		},
+		{ // 22
+			Limits: windows,
+			Op:     func(f *FS) error { return f.WriteFile("nul.txt", nil, 0o644) },
+			Err:    &fs.PathError{Op: "writefile", Path: "nul.txt", Err: ErrInvalidName},
+		},
+		{ // 23
+			Limits: windows,
+			Op:     func(f *FS) error { return f.WriteFile("Com1.tar.gz", nil, 0o644) },
+			Err:    &fs.PathError{Op: "writefile", Path: "Com1.tar.gz", Err: ErrInvalidName},
+		},
+		{ // 24
+			Limits: windows,
+			Op:     func(f *FS) error { return f.WriteFile("LPT¹", nil, 0o644) },
+			Err:    &fs.PathError{Op: "writefile", Path: "LPT¹", Err: ErrInvalidName},
+		},
+		{ // 25
+			Limits: windows,
+			Op:     func(f *FS) error { return f.WriteFile("AUX .txt", nil, 0o644) },
+			Err:    &fs.PathError{Op: "writefile", Path: "AUX .txt", Err: ErrInvalidName},
+		},
+		{ // 26
+			Limits: windows,
+			Op:     func(f *FS) error { return f.WriteFile("CONSOLE", nil, 0o644) },
+		},
+		{ // 27
+			Limits: windows,
+			Op:     func(f *FS) error { return f.WriteFile("file.", nil, 0o644) },
+			Err:    &fs.PathError{Op: "writefile", Path: "file.", Err: ErrInvalidName},
+		},
+		{ // 28
+			Limits: windows,
+			Op:     func(f *FS) error { return f.WriteFile("file ", nil, 0o644) },
+			Err:    &fs.PathError{Op: "writefile", Path: "file ", Err: ErrInvalidName},
+		},
+		{ // 29
+			Limits: windows,
+			Op:     func(f *FS) error { return f.WriteFile("a:b", nil, 0o644) },
+			Err:    &fs.PathError{Op: "writefile", Path: "a:b", Err: ErrInvalidName},
+		},
+		{ // 30
+			Limits: windows,
+			Op:     func(f *FS) error { return f.WriteFile("a\\b", nil, 0o644) },
+			Err:    &fs.PathError{Op: "writefile", Path: "a\\b", Err: ErrInvalidName},
+		},
+		{ // 31
+			Limits: windows,
+			Op:     func(f *FS) error { return f.WriteFile("what?", nil, 0o644) },
+			Err:    &fs.PathError{Op: "writefile", Path: "what?", Err: ErrInvalidName},
+		},
+		{ // 32
+			Limits: windows,
+			Op:     func(f *FS) error { return f.WriteFile("tab\tname", nil, 0o644) },
+			Err:    &fs.PathError{Op: "writefile", Path: "tab\tname", Err: ErrInvalidName},
+		},
+		{ // 33
+			Limits: windows,
+			Op:     func(f *FS) error { return f.Rename("dir", "PRN") },
+			Err:    &fs.PathError{Op: "rename", Path: "PRN", Err: ErrInvalidName},
+		},
+	} {
+		f, err := Layer(base, WithNameLimits(test.Limits))
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		}
+
+		if err := test.Op(f); !reflect.DeepEqual(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+	"unicode/utf8"
+)
+
+//go:generate go run ./internal/normgen -o normtables.go
+
+// WithNormalizationInsensitive makes the lookup of names in the FS, and in any
+// FS, or FSRO, created from it, insensitive to Unicode normalization, such that
+// names that are canonically equivalent, such as the NFC and NFD forms of the
+// same name, refer to the same entry, as with the APFS and HFS+ file systems.
+//
+// The name of each entry is stored in the form with which it was created, and
+// creating an entry with a name that is equivalent to that of an existing
+// entry refers to the existing entry, allowing for name collisions between
+// platforms to be reproduced on any platform.
+func WithNormalizationInsensitive() Option {
+	return func(f *FS) {
+		f.normInsensitive = true
+	}
+}
+
+type entryGetter interface {
+	getEntry(string) (*dirEnt, error)
+}
+
+// getEntryNorm gets the named entry from the given directory, falling back,
+// when insensitive is true, to the first entry whose name is canonically
+// equivalent.
+func getEntryNorm(d entryGetter, name string, insensitive bool) (*dirEnt, error) {
+	de, err := d.getEntry(name)
+	if !insensitive || !errors.Is(err, fs.ErrNotExist) {
+		return de, err
+	} else if dir, ok := d.(dNode); !ok {
+		return nil, err
+	} else if de = getEquivalentEntry(dir, name); de == nil {
+		return nil, err
+	}
+
+	return de, nil
+}
+
+func getEquivalentEntry(d dNode, name string) *dirEnt {
+	var (
+		key = decompose(name)
+		de  *dirEnt
+	)
+
+	d.scanEntries(func(e fs.DirEntry) bool {
+		if decompose(e.Name()) == key {
+			de = e.(*dirEnt)
+
+			return false
+		}
+
+		return true
+	})
+
+	return de
+}
+
+const (
+	hangulBase   = 0xAC00
+	hangulCount  = 11172
+	hangulLBase  = 0x1100
+	hangulVBase  = 0x1161
+	hangulTBase  = 0x11A7
+	hangulTCount = 28
+	hangulNCount = 588
+)
+
+// decompose returns the canonical decomposition, NFD, of the given name, such
+// that canonically equivalent names have the same decomposition.
+//
+// Names that are not valid UTF-8 are returned unchanged.
+func decompose(name string) string {
+	simple := true
+
+	for n := 0; n < len(name); n++ {
+		if name[n] >= utf8.RuneSelf {
+			simple = false
+
+			break
+		}
+	}
+
+	if simple || !utf8.ValidString(name) {
+		return name
+	}
+
+	runes := make([]rune, 0, len(name))
+
+	for _, r := range name {
+		if s := r - hangulBase; s >= 0 && s < hangulCount {
+			runes = append(runes, hangulLBase+s/hangulNCount, hangulVBase+s%hangulNCount/hangulTCount)
+
+			if t := s % hangulTCount; t != 0 {
+				runes = append(runes, hangulTBase+t)
+			}
+		} else if d, ok := decompositions[r]; ok {
+			runes = append(runes, []rune(d)...)
+		} else {
+			runes = append(runes, r)
+		}
+	}
+
+	// order each run of combining marks by combining class, stably.
+	for n := 1; n < len(runes); n++ {
+		ccc := combiningClassOf(runes[n])
+		if ccc == 0 {
+			continue
+		}
+
+		for m := n; m > 0; m-- {
+			if prev := combiningClassOf(runes[m-1]); prev <= ccc {
+				break
+			}
+
+			runes[m], runes[m-1] = runes[m-1], runes[m]
+		}
+	}
+
+	return string(runes)
+}
+
+type combiningClass struct {
+	first, last rune
+	class       uint8
+}
+
+func combiningClassOf(r rune) uint8 {
+	n, found := slices.BinarySearchFunc(combiningClasses[:], r, func(c combiningClass, r rune) int {
+		if c.last < r {
+			return -1
+		} else if c.first > r {
+			return 1
+		}
+
+		return 0
+	})
+	if !found {
+		return 0
+	}
+
+	return combiningClasses[n].class
+}
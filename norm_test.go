@@ -0,0 +1,148 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+)
+
+func TestDecompose(t *testing.T) {
+	for n, test := range [...]struct {
+		A, B  string
+		Equal bool
+	}{
+		{ // 1
+			A:     "abc",
+			B:     "abc",
+			Equal: true,
+		},
+		{ // 2
+			A: "abc",
+			B: "abd",
+		},
+		{ // 3
+			A:     "caf\u00e9",
+			B:     "cafe\u0301",
+			Equal: true,
+		},
+		{ // 4
+			A: "caf\u00e9",
+			B: "cafe",
+		},
+		{ // 5
+			A:     "\u212b",
+			B:     "A\u030a",
+			Equal: true,
+		},
+		{ // 6
+			A:     "\ud55c",
+			B:     "\u1112\u1161\u11ab",
+			Equal: true,
+		},
+		{ // 7
+			A:     "\uac00",
+			B:     "\u1100\u1161",
+			Equal: true,
+		},
+		{ // 8
+			A:     "a\u0307\u0323",
+			B:     "a\u0323\u0307",
+			Equal: true,
+		},
+		{ // 9
+			A:     "\u1ea1\u0307",
+			B:     "a\u0307\u0323",
+			Equal: true,
+		},
+		{ // 10
+			A: "\xff",
+			B: "\xfe",
+		},
+	} {
+		if equal := decompose(test.A) == decompose(test.B); equal != test.Equal {
+			t.Errorf("test %d: expecting equality of %q and %q to be %v", n+1, test.A, test.B, test.Equal)
+		}
+	}
+}
+
+func TestNormalizationInsensitive(t *testing.T) {
+	const (
+		nfc = "caf\u00e9"
+		nfd = "cafe\u0301"
+	)
+
+	f := New(WithNormalizationInsensitive())
+
+	if err := f.Mkdir(nfc, 0o755); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if err := f.WriteFile(nfd+"/file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if err := f.Mkdir(nfd, 0o755); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("test 3: expecting error %v, got %v", fs.ErrExist, err)
+	} else if es, err := f.ReadDir("."); err != nil {
+		t.Errorf("test 4: unexpected error: %s", err)
+	} else if len(es) != 1 || es[0].Name() != nfc {
+		t.Errorf("test 4: expecting single entry named %q, got %v", nfc, es)
+	}
+
+	if err := f.WriteFile(nfc+"/"+nfc, []byte("1"), 0o644); err != nil {
+		t.Fatalf("test 5: unexpected error: %s", err)
+	} else if err := f.WriteFile(nfd+"/"+nfd, []byte("2"), 0o644); err != nil {
+		t.Fatalf("test 5: unexpected error: %s", err)
+	} else if es, err := f.ReadDir(nfd); err != nil {
+		t.Errorf("test 5: unexpected error: %s", err)
+	} else if len(es) != 2 || es[0].Name() != "file" || es[1].Name() != nfc {
+		t.Errorf("test 5: expecting entries named %q and %q, got %v", "file", nfc, es)
+	} else if data, err := f.ReadFile(nfc + "/" + nfc); err != nil {
+		t.Errorf("test 5: unexpected error: %s", err)
+	} else if string(data) != "2" {
+		t.Errorf("test 5: expecting file to have been overwritten, got %q", data)
+	}
+
+	if err := f.Remove(nfc + "/" + nfd); err != nil {
+		t.Errorf("test 6: unexpected error: %s", err)
+	} else if err := f.RemoveAll(nfd); err != nil {
+		t.Errorf("test 7: unexpected error: %s", err)
+	} else if es, _ := f.ReadDir("."); len(es) != 0 {
+		t.Errorf("test 7: expecting no entries, got %v", es)
+	}
+
+	if err := f.Mkdir(nfc, 0o755); err != nil {
+		t.Fatalf("test 8: unexpected error: %s", err)
+	} else if err := f.WriteFile(nfd+"/file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("test 8: unexpected error: %s", err)
+	}
+
+	for n, fsys := range [...]FSRO{
+		f,
+		f.Seal(),
+		sealCompact(t, f),
+	} {
+		if data, err := fsys.ReadFile(nfd + "/file"); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+9, err)
+		} else if string(data) != "data" {
+			t.Errorf("test %d: expecting %q, got %q", n+9, "data", data)
+		} else if fi, err := fsys.Stat(nfd); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+9, err)
+		} else if !fi.IsDir() {
+			t.Errorf("test %d: expecting directory", n+9)
+		} else if matches, err := fsys.GlobStar(nfd + "/file"); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+9, err)
+		} else if !reflect.DeepEqual(matches, []string{nfc + "/file"}) {
+			t.Errorf("test %d: expecting match %q, got %v", n+9, nfc+"/file", matches)
+		}
+	}
+
+	g := New()
+
+	if err := g.WriteFile(nfc, nil, 0o644); err != nil {
+		t.Fatalf("test 12: unexpected error: %s", err)
+	} else if _, err := g.Stat(nfd); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("test 12: expecting error %v, got %v", fs.ErrNotExist, err)
+	} else if err := g.WriteFile(nfd, nil, 0o644); err != nil {
+		t.Errorf("test 13: unexpected error: %s", err)
+	} else if es, _ := g.ReadDir("."); len(es) != 2 {
+		t.Errorf("test 13: expecting 2 entries, got %d", len(es))
+	}
+}
@@ -0,0 +1,228 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// ObjectStore is a minimal interface to a flat store of objects, such as an
+// S3-compatible bucket, that can be used to hold the regular files of an FS.
+//
+// Each file is stored as an object whose key is its slash separated path,
+// appended to a prefix. Directories are implied by the keys of the files they
+// contain.
+type ObjectStore interface {
+	// Get returns the contents of the object with the given key.
+	Get(key string) ([]byte, error)
+
+	// Put stores the given data as the object with the given key, replacing
+	// any existing object. The data must not be retained after Put returns.
+	Put(key string, data []byte) error
+
+	// List returns the keys of all objects that begin with the given
+	// prefix.
+	List(prefix string) ([]string, error)
+
+	// Delete removes the object with the given key. Deleting an object that
+	// does not exist is not an error.
+	Delete(key string) error
+}
+
+type objectStore struct {
+	ObjectStore
+	prefix string
+}
+
+// WithObjectStore sets an ObjectStore that will have every change to the
+// regular files of the FS, and of any FS created from it with Sub, written
+// through to it, with the key of each file being its path appended to the
+// given prefix, which should normally end with a slash.
+//
+// Files that are created, written, linked, renamed, or removed, by methods on
+// the FS are stored or deleted before the method returns, with any error from
+// the ObjectStore being returned by the method. Data written to a File is
+// stored when the File is closed, using the path at which the file is then
+// found, following any renames; it is not stored when that path has since been
+// removed.
+//
+// Directories, permissions, modification times and symlinks are not stored,
+// and files that are hard linked are stored as separate objects, only the
+// object with the changed path being updated.
+func WithObjectStore(store ObjectStore, prefix string) Option {
+	return func(f *FS) {
+		f.objects = &objectStore{ObjectStore: store, prefix: prefix}
+		f.root = "."
+
+		if f.opens == nil {
+			f.opens = new(openPaths)
+		}
+	}
+}
+
+// FromObjectStore creates a new FS containing each of the objects in the given
+// ObjectStore whose key begins with the given prefix, with the rest of the key
+// being used as the path of the file.
+//
+// Files are created with permissions 0o644, and directories with 0o755. Keys
+// ending with a slash are ignored; any other key that is not a valid path
+// results in an error.
+func FromObjectStore(store ObjectStore, prefix string, opts ...Option) (*FS, error) {
+	f := New(opts...)
+	objects := f.objects
+	f.objects = nil
+
+	keys, err := store.List(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		name := strings.TrimPrefix(key, prefix)
+		if name == "" || strings.HasSuffix(name, slash) {
+			continue
+		} else if !fs.ValidPath(name) || name == "." {
+			return nil, &fs.PathError{Op: "fromobjectstore", Path: key, Err: fs.ErrInvalid}
+		}
+
+		data, err := store.Get(key)
+		if err != nil {
+			return nil, &fs.PathError{Op: "fromobjectstore", Path: key, Err: err}
+		}
+
+		if dir := path.Dir(name); dir != "." {
+			if err := f.MkdirAll(dir, 0o755); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := f.WriteFile(name, data, 0o644); err != nil {
+			return nil, err
+		}
+	}
+
+	f.objects = objects
+
+	return f, nil
+}
+
+// WriteObjects stores each of the regular files of the FS in the given
+// ObjectStore, as with FS.WriteObjects.
+func (f *fsRO) WriteObjects(store ObjectStore, prefix string) error {
+	return syncObjects(&objectStore{ObjectStore: store, prefix: prefix}, ".", f.de)
+}
+
+// WriteObjects stores each of the regular files of the FS in the given
+// ObjectStore, with the key of each file being its path appended to the given
+// prefix, which should normally end with a slash.
+//
+// Any other objects whose keys begin with the prefix are deleted, so that the
+// ObjectStore matches the FS.
+//
+// Directories, permissions, modification times and symlinks are not stored,
+// and files that are hard linked are stored as separate objects.
+//
+// The objects are written from a Snapshot of the FS, and so match the FS as it
+// was when WriteObjects was called, with the FS only being locked while the
+// Snapshot is taken.
+func (f *FS) WriteObjects(store ObjectStore, prefix string) error {
+	return f.snapshot().WriteObjects(store, prefix)
+}
+
+// writeThrough updates the objects of the ObjectStore set with WithObjectStore,
+// if there is one, to match the entries at the given paths.
+func (f *FS) writeThrough(op string, paths ...string) error {
+	if f.objects == nil {
+		return nil
+	}
+
+	for _, p := range paths {
+		var de directoryEntry
+
+		if e, err := f.getLEntry(p); err == nil {
+			de = e.directoryEntry
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return &fs.PathError{Op: op, Path: p, Err: err}
+		}
+
+		if err := syncObjects(f.objects, f.rootPath(p), de); err != nil {
+			return &fs.PathError{Op: op, Path: p, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// syncObjects makes the objects at and below the given path match the given
+// entry, which is nil when there is no longer an entry at the path.
+func syncObjects(o *objectStore, p string, de directoryEntry) error {
+	key := o.prefix + p
+
+	if de != nil && de.Mode().IsRegular() {
+		return o.put(key, de)
+	}
+
+	dirPrefix := o.prefix
+
+	if p != "." {
+		if err := o.Delete(key); err != nil {
+			return err
+		}
+
+		dirPrefix = key + slash
+	}
+
+	stored := make(map[string]struct{})
+
+	if de != nil && de.IsDir() {
+		if err := o.putDir(stored, dirPrefix, de); err != nil {
+			return err
+		}
+	}
+
+	keys, err := o.List(dirPrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if _, ok := stored[key]; !ok {
+			if err := o.Delete(key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (o *objectStore) putDir(stored map[string]struct{}, prefix string, de directoryEntry) error {
+	for _, e := range childEntries(de) {
+		key := prefix + e.name
+
+		switch mode := e.Mode(); {
+		case mode.IsRegular():
+			if err := o.put(key, e.directoryEntry); err != nil {
+				return err
+			}
+
+			stored[key] = struct{}{}
+		case mode.IsDir():
+			if err := o.putDir(stored, key+slash, e.directoryEntry); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (o *objectStore) put(key string, de directoryEntry) error {
+	return withData(de, func(data []byte) error {
+		return o.Put(key, data)
+	})
+}
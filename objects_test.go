@@ -0,0 +1,288 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type memStore map[string]string
+
+func (m memStore) Get(key string) ([]byte, error) {
+	data, ok := m[key]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+
+	return []byte(data), nil
+}
+
+func (m memStore) Put(key string, data []byte) error {
+	if strings.Contains(key, "fail") {
+		return fs.ErrPermission
+	}
+
+	m[key] = string(data)
+
+	return nil
+}
+
+func (m memStore) List(prefix string) ([]string, error) {
+	var keys []string
+
+	for key := range m {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+func (m memStore) Delete(key string) error {
+	delete(m, key)
+
+	return nil
+}
+
+func TestObjectStore(t *testing.T) {
+	store := memStore{
+		"other/file":         "not imported",
+		"ws/a/b/file":        "file",
+		"ws/a/dir/":          "",
+		"ws/top":             "top",
+		"ws/a/b/c/deep/file": "deep",
+	}
+
+	f, err := FromObjectStore(store, "ws/", WithObjectStore(store, "ws/"))
+	if err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	}
+
+	if data, err := f.ReadFile("a/b/c/deep/file"); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if string(data) != "deep" {
+		t.Errorf("test 2: expecting contents %q, got %q", "deep", data)
+	} else if _, err := f.Stat("a/dir"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("test 3: expecting directory marker to be ignored, got %v", err)
+	}
+
+	if err := f.WriteFile("new", []byte("new"), 0o644); err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	} else if err := f.Rename("a/b", "a/moved"); err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	} else if err := f.Remove("top"); err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	} else if err := f.Link("new", "a/linked"); err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	} else if err := f.Symlink("new", "symlink"); err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	} else if err := f.Mkdir("empty", 0o755); err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	}
+
+	of, err := f.Create("written")
+	if err != nil {
+		t.Fatalf("test 5: unexpected error: %s", err)
+	} else if _, err := of.WriteString("before close"); err != nil {
+		t.Fatalf("test 5: unexpected error: %s", err)
+	} else if _, ok := store["ws/written"]; ok {
+		t.Errorf("test 5: expecting file not to be stored before Close")
+	} else if err := of.Close(); err != nil {
+		t.Fatalf("test 5: unexpected error: %s", err)
+	}
+
+	sub, err := f.Sub("a/moved")
+	if err != nil {
+		t.Fatalf("test 6: unexpected error: %s", err)
+	} else if err := sub.(*FS).RemoveAll("c"); err != nil {
+		t.Fatalf("test 6: unexpected error: %s", err)
+	}
+
+	expected := memStore{
+		"other/file":      "not imported",
+		"ws/a/dir/":       "",
+		"ws/a/moved/file": "file",
+		"ws/a/linked":     "new",
+		"ws/new":          "new",
+		"ws/written":      "before close",
+	}
+
+	if !reflect.DeepEqual(store, expected) {
+		t.Errorf("test 7: expecting store %v, got %v", expected, store)
+	}
+
+	mirror := memStore{"stale": "stale"}
+
+	if err := sealCompact(t, f).WriteObjects(mirror, ""); err != nil {
+		t.Fatalf("test 8: unexpected error: %s", err)
+	} else if keys := slices.Sorted(maps.Keys(mirror)); !reflect.DeepEqual(keys, []string{"a/linked", "a/moved/file", "new", "written"}) {
+		t.Errorf("test 8: expecting keys %v, got %v", []string{"a/linked", "a/moved/file", "new", "written"}, keys)
+	}
+
+	if err := f.WriteFile("fail", []byte("data"), 0o644); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("test 9: expecting permission error, got %v", err)
+	}
+
+	if _, err := FromObjectStore(memStore{"../bad": ""}, ""); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("test 10: expecting invalid path error, got %v", err)
+	}
+}
+
+type modifyingStore struct {
+	memStore
+	modify func() error
+}
+
+func (m *modifyingStore) Put(key string, data []byte) error {
+	if m.modify != nil {
+		if err := m.modify(); err != nil {
+			return err
+		}
+
+		m.modify = nil
+	}
+
+	return m.memStore.Put(key, data)
+}
+
+func TestWriteObjectsSnapshot(t *testing.T) {
+	f := New()
+
+	if err := f.WriteFile("a", []byte("a"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("b", []byte("b"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	store := modifyingStore{
+		memStore: memStore{},
+		modify: func() error {
+			if err := f.WriteFile("a", []byte("changed"), 0o644); err != nil {
+				return err
+			} else if err := f.WriteFile("b", []byte("changed"), 0o644); err != nil {
+				return err
+			}
+
+			return f.WriteFile("c", []byte("c"), 0o644)
+		},
+	}
+
+	if err := f.WriteObjects(&store, ""); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if expected := (memStore{"a": "a", "b": "b"}); !maps.Equal(store.memStore, expected) {
+		t.Errorf("test 1: expecting objects %v, got %v", expected, store.memStore)
+	}
+
+	if err := f.WriteObjects(store.memStore, ""); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if expected := (memStore{"a": "changed", "b": "changed", "c": "c"}); !maps.Equal(store.memStore, expected) {
+		t.Errorf("test 2: expecting objects %v, got %v", expected, store.memStore)
+	}
+}
+
+func TestObjectStoreOpenFile(t *testing.T) {
+	store := memStore{}
+	f := New(WithObjectStore(store, ""))
+
+	if err := f.Mkdir("dir", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Mkdir("sub", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("dir", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("y", []byte("y"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s, err := f.Sub("sub")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	sub := s.(*FS)
+
+	for n, test := range [...]struct {
+		Path   string
+		Open   *FS
+		Change func() error
+		Store  memStore
+	}{
+		{ // 1
+			Path: "a",
+			Change: func() error {
+				return f.Remove("a")
+			},
+			Store: memStore{"y": "y"},
+		},
+		{ // 2
+			Path: "b",
+			Change: func() error {
+				return f.Rename("b", "c")
+			},
+			Store: memStore{"c": "data", "y": "y"},
+		},
+		{ // 3
+			Path: "link/d",
+			Change: func() error {
+				return f.Rename("dir", "moved")
+			},
+			Store: memStore{"c": "data", "moved/d": "data", "y": "y"},
+		},
+		{ // 4
+			Path: "x",
+			Change: func() error {
+				return f.RenameExchange("x", "y")
+			},
+			Store: memStore{"c": "data", "moved/d": "data", "x": "y", "y": "data"},
+		},
+		{ // 5
+			Path: "e",
+			Open: sub,
+			Change: func() error {
+				return f.Rename("sub", "sub2")
+			},
+			Store: memStore{"c": "data", "moved/d": "data", "sub2/e": "data", "x": "y", "y": "data"},
+		},
+		{ // 6
+			Path: "c",
+			Change: func() error {
+				return f.RemoveAll("moved")
+			},
+			Store: memStore{"c": "data", "sub2/e": "data", "x": "y", "y": "data"},
+		},
+		{ // 7
+			Path: "h",
+			Change: func() error {
+				if err := f.WriteFile("i", []byte("i"), 0o644); err != nil {
+					return err
+				}
+
+				return f.Rename("i", "h")
+			},
+			Store: memStore{"c": "data", "h": "i", "sub2/e": "data", "x": "y", "y": "data"},
+		},
+	} {
+		fsys := test.Open
+		if fsys == nil {
+			fsys = f
+		}
+
+		of, err := fsys.Create(test.Path)
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		} else if _, err := of.WriteString("data"); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		} else if err := test.Change(); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		} else if err := of.Close(); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		} else if !maps.Equal(store, test.Store) {
+			t.Errorf("test %d: expecting objects %v, got %v", n+1, test.Store, store)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package memfs
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrTooManyOpenFiles is returned when opening a file or directory would
+// exceed the limit set with WithMaxOpenFiles, in the manner of EMFILE.
+var ErrTooManyOpenFiles = errors.New("too many open files")
+
+// WithMaxOpenFiles limits the number of files, directories, and pipes that can
+// be held open at once, from the FS and any FS created from it with Sub, to
+// simulate the exhaustion of file descriptors. A limit of zero or less is
+// treated as unlimited.
+//
+// Each handle returned by Open, OpenDir, OpenFile, Create, and OpenPipe counts
+// against the limit until it is closed; once the limit is reached, those
+// methods return ErrTooManyOpenFiles. Methods that do not return a handle, such
+// as ReadFile and WriteFile, are unaffected.
+func WithMaxOpenFiles(n int) Option {
+	return func(f *FS) {
+		if n <= 0 {
+			f.handles = nil
+		} else {
+			f.handles = &openLimit{max: n}
+		}
+	}
+}
+
+// openLimit tracks the number of open handles of an FS.
+type openLimit struct {
+	mu   sync.Mutex
+	max  int
+	open int
+}
+
+// acquire records the opening of a handle, failing if the limit has been
+// reached.
+func (o *openLimit) acquire() error {
+	if o == nil {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.open >= o.max {
+		return ErrTooManyOpenFiles
+	}
+
+	o.open++
+
+	return nil
+}
+
+// release records the closing of a handle.
+func (o *openLimit) release() {
+	if o == nil {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.open--
+}
+
+// count returns the number of open handles.
+func (o *openLimit) count() int {
+	if o == nil {
+		return 0
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.open
+}
@@ -0,0 +1,98 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestMaxOpenFiles(t *testing.T) {
+	f := New(WithMaxOpenFiles(3))
+
+	if err := f.Mkdir("dir", fs.ModePerm); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if err := f.WriteFile("dir/file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if err := f.Mkfifo("pipe", 0o644); err != nil {
+		t.Fatalf("test 3: unexpected error: %s", err)
+	}
+
+	a, err := f.Open("dir")
+	if err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	}
+
+	sub, err := f.Sub("dir")
+	if err != nil {
+		t.Fatalf("test 5: unexpected error: %s", err)
+	}
+
+	b, err := sub.(*FS).OpenFile("file", ReadWrite, 0)
+	if err != nil {
+		t.Fatalf("test 6: unexpected error: %s", err)
+	}
+
+	c, err := f.OpenPipe("pipe", ReadOnly)
+	if err != nil {
+		t.Fatalf("test 7: unexpected error: %s", err)
+	}
+
+	if _, err := f.Open("dir/file"); !errors.Is(err, ErrTooManyOpenFiles) {
+		t.Errorf("test 8: expecting error ErrTooManyOpenFiles, got %v", err)
+	} else if _, err := f.Create("other"); !errors.Is(err, ErrTooManyOpenFiles) {
+		t.Errorf("test 9: expecting error ErrTooManyOpenFiles, got %v", err)
+	} else if _, err := f.Stat("other"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("test 10: expecting error ErrNotExist, got %v", err)
+	} else if _, err := sub.Open("."); !errors.Is(err, ErrTooManyOpenFiles) {
+		t.Errorf("test 11: expecting error ErrTooManyOpenFiles, got %v", err)
+	} else if _, err := f.OpenPipe("pipe", WriteOnly); !errors.Is(err, ErrTooManyOpenFiles) {
+		t.Errorf("test 12: expecting error ErrTooManyOpenFiles, got %v", err)
+	} else if data, err := f.ReadFile("dir/file"); err != nil {
+		t.Errorf("test 13: unexpected error: %s", err)
+	} else if string(data) != "data" {
+		t.Errorf("test 13: expecting to read %q, got %q", "data", data)
+	}
+
+	var opened []fs.File
+
+	for n, c := range [...]interface{ Close() error }{a, b, c} {
+		if err := c.Close(); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+14, err)
+		}
+
+		c.Close()
+
+		of, err := f.Open("dir/file")
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+14, err)
+		} else if _, err := f.Open("dir/file"); !errors.Is(err, ErrTooManyOpenFiles) {
+			t.Errorf("test %d: expecting error ErrTooManyOpenFiles, got %v", n+14, err)
+		}
+
+		opened = append(opened, of)
+	}
+
+	for _, of := range opened {
+		of.Close()
+	}
+
+	if _, err := f.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("test 17: expecting error ErrNotExist, got %v", err)
+	}
+
+	if _, err := f.SealSubtree("dir"); err != nil {
+		t.Fatalf("test 18: unexpected error: %s", err)
+	}
+
+	for n := 0; n < 4; n++ {
+		if _, err := f.Open("dir/file"); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+19, err)
+		}
+	}
+
+	for n := 0; n < 3; n++ {
+		if _, err := f.Open("."); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+23, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package memfs
+
+import (
+	"path"
+	"strings"
+	"sync"
+)
+
+// openPaths tracks the paths of the Files opened for writing on an FS created
+// with WithJournal or WithObjectStore, relative to the root of the FS created
+// with New, updating them as entries are renamed and removed, so that changes
+// made through each File can be recorded, and its data stored when it is
+// closed, with the path at which the file is then found.
+type openPaths struct {
+	mu    sync.Mutex
+	paths map[*openPath]struct{}
+}
+
+type openPath struct {
+	paths   *openPaths
+	path    string
+	removed bool
+}
+
+func (o *openPaths) open(p string) *openPath {
+	if o == nil {
+		return nil
+	}
+
+	op := &openPath{paths: o, path: p}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.paths == nil {
+		o.paths = make(map[*openPath]struct{})
+	}
+
+	o.paths[op] = struct{}{}
+
+	return op
+}
+
+// tracking returns true if any paths are being tracked.
+func (o *openPaths) tracking() bool {
+	if o == nil {
+		return false
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return len(o.paths) > 0
+}
+
+// rename moves the tracked paths at, or below, oldPath to newPath, with any at,
+// or below, newPath being marked as removed.
+func (o *openPaths) rename(oldPath, newPath string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for op := range o.paths {
+		if rest, ok := below(op.path, oldPath); ok {
+			op.path = newPath + rest
+		} else if _, ok := below(op.path, newPath); ok {
+			op.removed = true
+		}
+	}
+}
+
+// exchange swaps the tracked paths at, or below, the given paths.
+func (o *openPaths) exchange(path1, path2 string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for op := range o.paths {
+		if rest, ok := below(op.path, path1); ok {
+			op.path = path2 + rest
+		} else if rest, ok := below(op.path, path2); ok {
+			op.path = path1 + rest
+		}
+	}
+}
+
+// remove marks the tracked paths at, or below, the given path as removed.
+func (o *openPaths) remove(p string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for op := range o.paths {
+		if _, ok := below(op.path, p); ok {
+			op.removed = true
+		}
+	}
+}
+
+// below returns the remainder of p after dir, when p is dir or is below it.
+func below(p, dir string) (string, bool) {
+	if rest, ok := strings.CutPrefix(p, dir); ok && (rest == "" || rest[0] == '/') {
+		return rest, true
+	}
+
+	return "", false
+}
+
+// current returns the current path, and whether the file can still be found
+// there.
+func (op *openPath) current() (string, bool) {
+	if op == nil {
+		return "", false
+	}
+
+	op.paths.mu.Lock()
+	defer op.paths.mu.Unlock()
+
+	return op.path, !op.removed
+}
+
+// close stops the tracking of the path, returning the current path, and
+// whether the file can still be found there.
+func (op *openPath) close() (string, bool) {
+	if op == nil {
+		return "", false
+	}
+
+	op.paths.mu.Lock()
+	defer op.paths.mu.Unlock()
+
+	delete(op.paths.paths, op)
+
+	return op.path, !op.removed
+}
+
+// realPath returns the path, relative to the root of the FS created with New,
+// of the entry with the given name in the given directory, with any symlinks
+// in the path of the directory resolved.
+func (f *FS) realPath(dir, name string) string {
+	return f.rootPath(path.Join(f.resolveDir(dir), name))
+}
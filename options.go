@@ -0,0 +1,206 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"math/bits"
+	"sync"
+)
+
+// Option is used to configure an FS created with New.
+type Option func(*FS)
+
+type config struct {
+	growth   GrowthFunc
+	alloc    Allocator
+	orphans  *orphans
+	limit    *writeLimit
+	throttle *throttle
+	handles  *openLimit
+	tree     *treeLock
+	perms    fs.FileMode
+	dirSize  DirSizeFunc
+
+	journal *journal
+	objects *objectStore
+	opens   *openPaths
+	root    string
+
+	accounting    *Accounting
+	maxEntries    int
+	names         *NameLimits
+	logger        *slog.Logger
+	deterministic *deterministic
+}
+
+// WithDefaultPerms sets the permissions given to files created by Create and
+// Touch, for the FS and any FS created from it with Sub, in place of the
+// default of 0o666. A value of zero restores the default.
+func WithDefaultPerms(perm fs.FileMode) Option {
+	return func(f *FS) {
+		f.perms = perm & fs.ModePerm
+	}
+}
+
+// ErrNoFollow is returned when a path would require following a symlink on an
+// FS created with the WithNoFollow Option, and by OpenFile, for a file opened
+// with NoFollow, when the final element of the path is a symlink.
+var ErrNoFollow = errors.New("symlinks are not followed")
+
+// WithNoFollow disables the following of symlinks in the FS, and in any FS
+// created from it with Sub, Seal, or SealCompact, such that any operation on a
+// path that would require following a symlink fails with ErrNoFollow.
+//
+// Symlinks can still be created, and can be inspected with LStat and Readlink,
+// removed, and renamed, as long as no symlink is followed to reach them,
+// closing off any path traversal through symlinks, such as those extracted
+// from an untrusted archive.
+func WithNoFollow() Option {
+	return func(f *FS) {
+		f.noFollow = true
+	}
+}
+
+// WithMaxSymlinks sets the maximum number of symlinks that can be followed while
+// resolving a single path in the FS, and in any FS created from it with Sub,
+// Seal, or SealCompact, with any path requiring more returning a
+// SymlinkLimitError. A value of zero or less restores the default of 255.
+func WithMaxSymlinks(n int) Option {
+	return func(f *FS) {
+		f.maxSymlinks = n
+	}
+}
+
+// WithResolveTrace records the steps taken while resolving each path in the
+// FS, and in any FS created from it with Sub, Seal, or SealCompact, such that
+// an error caused by a failure to resolve a path wraps a ResolveError listing
+// each entry reached, each symlink followed, and the step at which resolution
+// failed.
+//
+// As the steps are recorded for every path resolved, this is intended for
+// debugging, and not for general use.
+func WithResolveTrace() Option {
+	return func(f *FS) {
+		f.trace = true
+	}
+}
+
+// defaultPerms returns the permissions for files created without explicit
+// permissions.
+func (c *config) defaultPerms() fs.FileMode {
+	if c.perms == 0 {
+		return defaultPerms
+	}
+
+	return c.perms
+}
+
+// GrowthFunc is used to determine the new capacity of the data of a file that
+// needs to grow beyond its current capacity.
+//
+// It is given the current size of the file and the size that is required, and
+// should return a capacity of at least the required size; any smaller value is
+// treated as the required size.
+type GrowthFunc func(size, required int) int
+
+const (
+	simpleGrowLimit = 512
+	growShift       = 2
+)
+
+// DefaultGrowth is the GrowthFunc used when no other is specified.
+//
+// It doubles the required size for small files, and adds 25% to the required
+// size for larger files.
+func DefaultGrowth(size, required int) int {
+	if size < simpleGrowLimit {
+		return required << 1
+	}
+
+	return required + (required >> growShift)
+}
+
+// WithGrowth sets the GrowthFunc used for files opened from the FS.
+func WithGrowth(fn GrowthFunc) Option {
+	return func(f *FS) {
+		f.growth = fn
+	}
+}
+
+// Allocator is used to allocate, and release, the buffers that hold the data
+// of files.
+type Allocator interface {
+	// Alloc returns a slice with the given length, and at least the given
+	// capacity, with all bytes up to the length set to zero.
+	Alloc(size, capacity int) []byte
+
+	// Free is called with a buffer, previously returned from Alloc, that is
+	// no longer used by the FS.
+	Free([]byte)
+}
+
+// WithAllocator sets the Allocator used to allocate data for files opened from
+// the FS.
+func WithAllocator(a Allocator) Option {
+	return func(f *FS) {
+		f.alloc = a
+	}
+}
+
+const (
+	minPoolShift = 12
+	maxPoolShift = 26
+)
+
+type poolAllocator [maxPoolShift - minPoolShift + 1]sync.Pool
+
+// NewPoolAllocator returns an Allocator that keeps freed buffers in a set of
+// sync.Pools, one for each power of two between 4KB and 64MB, to be reused by
+// later allocations.
+//
+// Buffers outside of that range are allocated normally and are not kept.
+func NewPoolAllocator() Allocator {
+	return new(poolAllocator)
+}
+
+func poolClass(capacity int) int {
+	if capacity <= 1<<minPoolShift {
+		return 0
+	}
+
+	return bits.Len(uint(capacity-1)) - minPoolShift
+}
+
+func (p *poolAllocator) Alloc(size, capacity int) []byte {
+	if capacity < size {
+		capacity = size
+	}
+
+	class := poolClass(capacity)
+	if class >= len(p) {
+		return make([]byte, size, capacity)
+	}
+
+	if buf, ok := p[class].Get().(*[]byte); ok {
+		b := (*buf)[:size]
+
+		for n := range b {
+			b[n] = 0
+		}
+
+		return b
+	}
+
+	return make([]byte, size, 1<<(class+minPoolShift))
+}
+
+func (p *poolAllocator) Free(buf []byte) {
+	c := cap(buf)
+
+	if class := poolClass(c); class < len(p) && c == 1<<(class+minPoolShift) {
+		buf = buf[:0]
+
+		p[class].Put(&buf)
+	}
+}
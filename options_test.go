@@ -0,0 +1,320 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestWithGrowth(t *testing.T) {
+	f := New(WithGrowth(func(_, required int) int {
+		return required + 100
+	}))
+
+	of, err := f.Create("file")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if _, err = of.WriteString("Hello"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if c := cap(of.data); c != 105 {
+		t.Errorf("expecting capacity 105, got %d", c)
+	}
+
+	sub, err := f.Sub(".")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if of, err = sub.(*FS).Create("other"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if _, err = of.WriteString("Hello"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if c := cap(of.data); c != 105 {
+		t.Errorf("expecting capacity of file from Sub to be 105, got %d", c)
+	}
+}
+
+type countingAllocator struct {
+	allocs, frees int
+}
+
+func (c *countingAllocator) Alloc(size, capacity int) []byte {
+	c.allocs++
+
+	return make([]byte, size, capacity)
+}
+
+func (c *countingAllocator) Free(_ []byte) {
+	c.frees++
+}
+
+func TestWithAllocator(t *testing.T) {
+	var c countingAllocator
+
+	f := New(WithAllocator(&c), WithGrowth(func(_, required int) int { return required }))
+
+	of, err := f.Create("file")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n := 1; n <= 3; n++ {
+		if _, err := of.WriteString("Hello"); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n, err)
+		} else if c.allocs != n {
+			t.Errorf("test %d: expecting %d allocations, got %d", n, n, c.allocs)
+		} else if c.frees != n-1 {
+			t.Errorf("test %d: expecting %d frees, got %d", n, n-1, c.frees)
+		}
+	}
+
+	if data, err := f.ReadFile("file"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	} else if string(data) != "HelloHelloHello" {
+		t.Errorf("expecting data %q, got %q", "HelloHelloHello", data)
+	}
+}
+
+func TestPoolAllocator(t *testing.T) {
+	p := NewPoolAllocator()
+
+	for n, test := range [...]struct {
+		Size, Capacity, Cap int
+	}{
+		{Size: 0, Capacity: 0, Cap: 4096},
+		{Size: 10, Capacity: 20, Cap: 4096},
+		{Size: 4096, Capacity: 4096, Cap: 4096},
+		{Size: 4097, Capacity: 4097, Cap: 8192},
+		{Size: 5000, Capacity: 100, Cap: 8192},
+		{Size: 1 << 26, Capacity: 1 << 26, Cap: 1 << 26},
+		{Size: 10, Capacity: 1<<26 + 1, Cap: 1<<26 + 1},
+	} {
+		if buf := p.Alloc(test.Size, test.Capacity); len(buf) != test.Size {
+			t.Errorf("test %d: expecting length %d, got %d", n+1, test.Size, len(buf))
+		} else if cap(buf) != test.Cap {
+			t.Errorf("test %d: expecting capacity %d, got %d", n+1, test.Cap, cap(buf))
+		}
+	}
+
+	buf := p.Alloc(100, 100)
+
+	for n := range buf {
+		buf[n] = 1
+	}
+
+	p.Free(buf)
+
+	if reused := p.Alloc(200, 200); cap(reused) != 4096 {
+		t.Errorf("expecting capacity 4096, got %d", cap(reused))
+	} else {
+		for n, b := range reused {
+			if b != 0 {
+				t.Errorf("expecting byte %d to be zero, got %d", n, b)
+
+				break
+			}
+		}
+	}
+}
+
+func TestWithDefaultPerms(t *testing.T) {
+	f := New(WithDefaultPerms(0o600))
+
+	sub, err := f.Sub(".")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, create := range [...]func() error{
+		func() error {
+			_, err := f.Create("created")
+
+			return err
+		},
+		func() error {
+			return f.Touch("touched")
+		},
+		func() error {
+			_, err := sub.(*FS).Create("sub")
+
+			return err
+		},
+	} {
+		if err := create(); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		}
+	}
+
+	for n, p := range [...]string{"created", "touched", "sub"} {
+		if fi, err := f.Stat(p); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if perm := fi.Mode(); perm != 0o600 {
+			t.Errorf("test %d: expecting permissions %s, got %s", n+1, fs.FileMode(0o600), perm)
+		}
+	}
+
+	if of, err := New(WithDefaultPerms(0)).Create("file"); err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	} else if fi, err := of.Stat(); err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	} else if perm := fi.Mode(); perm != defaultPerms {
+		t.Errorf("test 4: expecting permissions %s, got %s", fs.FileMode(defaultPerms), perm)
+	}
+}
+
+func TestWithNoFollow(t *testing.T) {
+	f := New(WithNoFollow())
+
+	if err := f.Mkdir("dir", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("dir/file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("dir/file", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("dir", "dirlink"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	sub, err := f.Sub(".")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]func() error{
+		func() error {
+			_, err := f.Open("link")
+
+			return err
+		},
+		func() error {
+			_, err := f.Stat("link")
+
+			return err
+		},
+		func() error {
+			_, err := f.OpenFile("link", ReadOnly, 0)
+
+			return err
+		},
+		func() error {
+			_, err := f.ReadFile("dirlink/file")
+
+			return err
+		},
+		func() error {
+			_, err := f.LStat("dirlink/file")
+
+			return err
+		},
+		func() error {
+			_, err := sub.(*FS).Stat("link")
+
+			return err
+		},
+		func() error {
+			_, err := f.Seal().ReadFile("link")
+
+			return err
+		},
+		func() error {
+			ro, err := f.SealCompact()
+			if err != nil {
+				return err
+			}
+
+			_, err = ro.Stat("dirlink/file")
+
+			return err
+		},
+	} {
+		if err := test(); !errors.Is(err, ErrNoFollow) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, ErrNoFollow, err)
+		}
+	}
+
+	if fi, err := f.LStat("link"); err != nil {
+		t.Errorf("test 9: unexpected error: %s", err)
+	} else if fi.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("test 9: expecting symlink, got mode %s", fi.Mode())
+	} else if target, err := f.Readlink("link"); err != nil {
+		t.Errorf("test 10: unexpected error: %s", err)
+	} else if target != "dir/file" {
+		t.Errorf("test 10: expecting target %q, got %q", "dir/file", target)
+	} else if data, err := f.ReadFile("dir/file"); err != nil {
+		t.Errorf("test 11: unexpected error: %s", err)
+	} else if string(data) != "data" {
+		t.Errorf("test 11: expecting contents %q, got %q", "data", data)
+	}
+}
+
+func TestWithMaxSymlinks(t *testing.T) {
+	for n, test := range [...]struct {
+		Max, Limit int
+		Path, Err  string
+	}{
+		{ // 1
+			Max: 3,
+		},
+		{ // 2
+			Max:   2,
+			Limit: 2,
+			Path:  "dir/l3",
+			Err:   "stat l1/file: too many levels of symbolic links (2) at dir/l3",
+		},
+		{ // 3
+			Max:   1,
+			Limit: 1,
+			Path:  "l2",
+			Err:   "stat l1/file: too many levels of symbolic links (1) at l2",
+		},
+		{ // 4
+			Max: 0,
+		},
+	} {
+		f := New(WithMaxSymlinks(test.Max))
+
+		if err := f.MkdirAll("dir/sub", fs.ModePerm); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		} else if err := f.WriteFile("dir/sub/file", []byte("data"), 0o644); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		} else if err := f.Symlink("sub", "dir/l3"); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		} else if err := f.Symlink("dir/l3", "l2"); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		} else if err := f.Symlink("l2", "l1"); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		}
+
+		_, err := f.Stat("l1/file")
+
+		var sle *SymlinkLimitError
+
+		if test.Path == "" {
+			if err != nil {
+				t.Errorf("test %d: unexpected error: %s", n+1, err)
+			}
+		} else if !errors.As(err, &sle) {
+			t.Errorf("test %d: expecting SymlinkLimitError, got %v", n+1, err)
+		} else if !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("test %d: expecting error to match fs.ErrInvalid", n+1)
+		} else if sle.Path != test.Path {
+			t.Errorf("test %d: expecting path %q, got %q", n+1, test.Path, sle.Path)
+		} else if sle.Limit != test.Limit {
+			t.Errorf("test %d: expecting limit %d, got %d", n+1, test.Limit, sle.Limit)
+		} else if err.Error() != test.Err {
+			t.Errorf("test %d: expecting error %q, got %q", n+1, test.Err, err)
+		}
+	}
+
+	f := New()
+
+	if err := f.Symlink("loop", "loop"); err != nil {
+		t.Fatalf("test 5: unexpected error: %s", err)
+	}
+
+	var sle *SymlinkLimitError
+
+	if _, err := f.Seal().Open("loop"); !errors.As(err, &sle) {
+		t.Errorf("test 5: expecting SymlinkLimitError, got %v", err)
+	} else if sle.Limit != maxRedirects || sle.Path != "loop" {
+		t.Errorf("test 5: expecting limit %d at %q, got %d at %q", maxRedirects, "loop", sle.Limit, sle.Path)
+	}
+}
@@ -0,0 +1,157 @@
+package memfs
+
+import "sync"
+
+// WithOrphanTracking enables the tracking of files that have been removed from
+// the FS, allowing the Orphans and ReleaseOrphans methods to report on, and
+// release, those that are still being kept in memory.
+func WithOrphanTracking() Option {
+	return func(f *FS) {
+		f.orphans = &orphans{
+			nodes: make(map[*inode]directoryEntry),
+		}
+	}
+}
+
+// orphans tracks files that have been removed from the tree, but which are
+// still kept alive by open Files, forgetting each once its last File is
+// closed.
+type orphans struct {
+	mu    sync.Mutex
+	nodes map[*inode]directoryEntry
+}
+
+// fileNode returns the inodeRW of the given entry if it is a file that can be
+// held open, being either a plain file or one created with CreateLazy.
+func fileNode(de directoryEntry) (*inodeRW, bool) {
+	switch de := de.(type) {
+	case *inodeRW:
+		return de, true
+	case *lazyNode:
+		return &de.inodeRW, true
+	}
+
+	return nil, false
+}
+
+// add records each of the files at and below the given entry that, having
+// been unlinked, no longer have any names but are still held open.
+func (o *orphans) add(de directoryEntry) {
+	if o == nil {
+		return
+	}
+
+	if i, ok := fileNode(de); ok {
+		i.mu.RLock()
+		defer i.mu.RUnlock()
+
+		if i.links == 0 && i.opens > 0 {
+			o.mu.Lock()
+			o.nodes[&i.inode] = de
+			o.mu.Unlock()
+		}
+	} else if d, ok := de.(*dnodeRW); ok {
+		for _, e := range d.view().entries {
+			o.add(e.directoryEntry)
+		}
+	}
+}
+
+// remove forgets the file with the given inode once it has neither any names
+// nor any open Files; must be called with the lock of the file held.
+func (o *orphans) remove(i *inode) {
+	if o == nil || i.links > 0 || i.opens > 0 {
+		return
+	}
+
+	o.mu.Lock()
+	delete(o.nodes, i)
+	o.mu.Unlock()
+}
+
+// each calls the given func for each tracked file that has no remaining names
+// but is still held open.
+func (o *orphans) each(fn func(directoryEntry, *inodeRW)) {
+	o.mu.Lock()
+
+	nodes := make([]directoryEntry, 0, len(o.nodes))
+
+	for _, de := range o.nodes {
+		nodes = append(nodes, de)
+	}
+
+	o.mu.Unlock()
+
+	for _, de := range nodes {
+		if i, _ := fileNode(de); i.orphaned() {
+			fn(de, i)
+		}
+	}
+}
+
+// Orphans returns the number of files, and the total size of their data, that
+// have had all of their names removed from the FS but are still being kept in
+// memory by a File that is still open.
+//
+// The data of a file is released as soon as it has neither any names nor any
+// open Files, and so such a file is never an orphan.
+//
+// Returns zero unless the FS was created with the WithOrphanTracking Option.
+func (f *FS) Orphans() (int, int64) {
+	if f.orphans == nil {
+		return 0, 0
+	}
+
+	var (
+		count int
+		size  int64
+	)
+
+	f.orphans.each(func(de directoryEntry, _ *inodeRW) {
+		count++
+		size += de.Size()
+	})
+
+	return count, size
+}
+
+// ReleaseOrphans discards the data of all files that would be reported by
+// Orphans, returning their number and the total size of the data released.
+//
+// Any File still open on a released file will see it as empty.
+func (f *FS) ReleaseOrphans() (int, int64) {
+	if f.orphans == nil {
+		return 0, 0
+	}
+
+	var (
+		count int
+		size  int64
+	)
+
+	f.orphans.each(func(de directoryEntry, i *inodeRW) {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+
+		count++
+
+		if l, ok := de.(*lazyNode); ok {
+			size += l.size
+			l.size, l.base, l.chunks = 0, 0, nil
+		} else {
+			size += int64(len(i.data))
+
+			if f.alloc != nil && i.data != nil && !i.shared {
+				f.alloc.Free(i.data)
+			}
+
+			i.data = nil
+		}
+
+		f.orphans.mu.Lock()
+		delete(f.orphans.nodes, &i.inode)
+		f.orphans.mu.Unlock()
+	})
+
+	return count, size
+}
@@ -0,0 +1,202 @@
+package memfs
+
+import (
+	"io/fs"
+	"testing"
+)
+
+func TestOrphans(t *testing.T) {
+	f := New(WithOrphanTracking())
+
+	if err := f.MkdirAll("a/b", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for file, contents := range map[string]string{
+		"file":       "12345",
+		"linked":     "123",
+		"a/b/open":   "1234567",
+		"a/b/closed": "1234567890",
+	} {
+		if err := f.WriteFile(file, []byte(contents), 0o644); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	of, err := f.Open("file")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	od, err := f.Open("a/b/open")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := f.Link("linked", "a/link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Remove("file"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Remove("linked"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.RemoveAll("a/b"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if count, size := f.Orphans(); count != 2 || size != 12 {
+		t.Errorf("test 1: expecting 2 orphans of 12 bytes, got %d of %d bytes", count, size)
+	}
+
+	if err := od.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if count, size := f.Orphans(); count != 1 || size != 5 {
+		t.Errorf("test 2: expecting 1 orphan of 5 bytes, got %d of %d bytes", count, size)
+	} else if n := len(f.orphans.nodes); n != 1 {
+		t.Errorf("test 2: expecting closed file to be forgotten, tracking %d files", n)
+	}
+
+	if count, size := f.ReleaseOrphans(); count != 1 || size != 5 {
+		t.Errorf("test 3: expecting to release 1 orphan of 5 bytes, got %d of %d bytes", count, size)
+	} else if count, size := f.Orphans(); count != 0 || size != 0 {
+		t.Errorf("test 4: expecting no orphans, got %d of %d bytes", count, size)
+	} else if n, err := of.Read(make([]byte, 5)); n != 0 {
+		t.Errorf("test 5: expecting to read nothing from released file, read %d bytes, err = %v", n, err)
+	}
+
+	if count, size := New().Orphans(); count != 0 || size != 0 {
+		t.Errorf("test 6: expecting no orphans without tracking, got %d of %d bytes", count, size)
+	}
+}
+
+func TestOrphansLazy(t *testing.T) {
+	f := New(WithOrphanTracking())
+
+	if err := f.Mkdir("dir", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.CreateSparse("dir/sparse", 100); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.CreateLazy("lazy", 10, new(patternFill).fill); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	sf, err := f.Open("dir/sparse")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ol, err := f.Open("lazy")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := f.RemoveAll("dir"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Remove("lazy"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if count, size := f.Orphans(); count != 2 || size != 110 {
+		t.Errorf("test 1: expecting 2 orphans of 110 bytes, got %d of %d bytes", count, size)
+	} else if err := sf.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if count, size := f.Orphans(); count != 1 || size != 10 {
+		t.Errorf("test 2: expecting 1 orphan of 10 bytes, got %d of %d bytes", count, size)
+	} else if count, size := f.ReleaseOrphans(); count != 1 || size != 10 {
+		t.Errorf("test 3: expecting to release 1 orphan of 10 bytes, got %d of %d bytes", count, size)
+	} else if count, size := f.Orphans(); count != 0 || size != 0 {
+		t.Errorf("test 4: expecting no orphans, got %d of %d bytes", count, size)
+	} else if n, err := ol.Read(make([]byte, 10)); n != 0 {
+		t.Errorf("test 5: expecting to read nothing from released file, read %d bytes, err = %v", n, err)
+	}
+}
+
+func TestLinks(t *testing.T) {
+	var c countingAllocator
+
+	f := New(WithAllocator(&c), WithOrphanTracking())
+
+	links := func(p string) int {
+		t.Helper()
+
+		fi, err := f.LStat(p)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		l, ok := fi.Sys().(Linked)
+		if !ok {
+			t.Fatalf("expecting Sys of %s to be Linked", p)
+		}
+
+		return l.Links()
+	}
+
+	if err := f.Mkdir("dir", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("a", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if n := links("a"); n != 1 {
+		t.Errorf("test 1: expecting 1 link, got %d", n)
+	} else if err := f.Link("a", "dir/b"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Link("a", "c"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if n := links("dir/b"); n != 3 {
+		t.Errorf("test 2: expecting 3 links, got %d", n)
+	} else if err := f.Remove("a"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if n := links("c"); n != 2 {
+		t.Errorf("test 3: expecting 2 links, got %d", n)
+	} else if err := f.RemoveAll("dir"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if n := links("c"); n != 1 {
+		t.Errorf("test 4: expecting 1 link, got %d", n)
+	} else if c.frees != 0 {
+		t.Errorf("test 4: expecting no frees, got %d", c.frees)
+	}
+
+	of, err := f.Open("c")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Remove("c"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if c.frees != 0 {
+		t.Errorf("test 5: expecting no frees while open, got %d", c.frees)
+	} else if count, size := f.Orphans(); count != 1 || size != 4 {
+		t.Errorf("test 5: expecting 1 orphan of 4 bytes, got %d of %d bytes", count, size)
+	}
+
+	buf := make([]byte, 4)
+
+	if _, err := of.Read(buf); err != nil {
+		t.Fatalf("test 6: unexpected error: %s", err)
+	} else if string(buf) != "data" {
+		t.Errorf("test 6: expecting to read %q, got %q", "data", buf)
+	} else if err := of.Close(); err != nil {
+		t.Fatalf("test 7: unexpected error: %s", err)
+	} else if c.frees != 1 {
+		t.Errorf("test 7: expecting 1 free, got %d", c.frees)
+	} else if count, size := f.Orphans(); count != 0 || size != 0 {
+		t.Errorf("test 7: expecting no orphans, got %d of %d bytes", count, size)
+	}
+
+	if err := f.WriteFile("d", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("e", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Rename("d", "e"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if c.frees != 2 {
+		t.Errorf("test 8: expecting 2 frees, got %d", c.frees)
+	} else if n := links("e"); n != 1 {
+		t.Errorf("test 8: expecting 1 link, got %d", n)
+	}
+
+	if fi, err := f.Seal().LStat("e"); err != nil {
+		t.Fatalf("test 9: unexpected error: %s", err)
+	} else if l, ok := fi.Sys().(Linked); !ok {
+		t.Errorf("test 9: expecting Sys of sealed file to be Linked")
+	} else if n := l.Links(); n != 1 {
+		t.Errorf("test 9: expecting 1 link, got %d", n)
+	}
+}
@@ -0,0 +1,343 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// PatchAction determines the change made by a PatchOp.
+type PatchAction uint8
+
+// Patch Actions.
+const (
+	// PatchCreate creates a new entry, the type of which is determined by
+	// the Mode of the PatchOp, with Data being the contents of a file or
+	// the target of a symlink.
+	PatchCreate PatchAction = iota
+
+	// PatchDelete removes an entry, if it exists, and anything below it.
+	PatchDelete
+
+	// PatchContents replaces the contents of an existing file with Data.
+	PatchContents
+
+	// PatchMetadata sets the permissions, for all but symlinks, and the
+	// modification time of an existing entry.
+	PatchMetadata
+
+	// PatchRetain removes every entry of an existing directory that is
+	// not named in Names.
+	PatchRetain
+)
+
+// PatchOp is a single change to be made to an FS.
+type PatchOp struct {
+	Action  PatchAction
+	Path    string
+	Mode    fs.FileMode
+	ModTime time.Time
+	Data    []byte
+	Names   []string `json:",omitempty"`
+}
+
+// Patch is an ordered list of changes, as created by Diff and applied by
+// ApplyPatch.
+type Patch []PatchOp
+
+// ApplyPatch makes each of the changes in the given Patch to the FS, in order,
+// stopping at the first error.
+//
+// A zero ModTime leaves the modification time of the changed entry as the
+// current time.
+func ApplyPatch(f *FS, patch Patch) error {
+	for _, op := range patch {
+		if err := op.apply(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *PatchOp) apply(f *FS) error {
+	var err error
+
+	switch p.Action {
+	case PatchCreate:
+		switch p.Mode.Type() {
+		case fs.ModeDir:
+			err = f.Mkdir(p.Path, p.Mode.Perm())
+		case fs.ModeSymlink:
+			err = f.Symlink(string(p.Data), p.Path)
+		case fs.ModeNamedPipe:
+			err = f.Mkfifo(p.Path, p.Mode.Perm())
+		case 0:
+			err = f.WriteFile(p.Path, p.Data, p.Mode.Perm())
+		default:
+			return &fs.PathError{Op: "applypatch", Path: p.Path, Err: fs.ErrInvalid}
+		}
+	case PatchDelete:
+		if err := f.RemoveAll(p.Path); !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		return nil
+	case PatchContents:
+		err = f.WriteFile(p.Path, p.Data, p.Mode.Perm())
+	case PatchMetadata:
+		if p.Mode&fs.ModeSymlink == 0 {
+			err = f.Chmod(p.Path, p.Mode.Perm())
+		}
+	case PatchRetain:
+		return p.retain(f)
+	default:
+		return &fs.PathError{Op: "applypatch", Path: p.Path, Err: fs.ErrInvalid}
+	}
+
+	if err != nil || p.ModTime.IsZero() {
+		return err
+	}
+
+	return f.Lchtimes(p.Path, time.Time{}, p.ModTime)
+}
+
+func (p *PatchOp) retain(f *FS) error {
+	entries, err := f.ReadDir(p.Path)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if !slices.Contains(p.Names, e.Name()) {
+			if err := f.RemoveAll(path.Join(p.Path, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Diff returns a Patch that, when applied to an FS matching from, results in an
+// FS matching to, considering the directories, regular files and symlinks of
+// each. Other types of entry are ignored.
+//
+// Deletions come first, and the permissions and modification times of
+// directories are set last, after all of their contents have been created.
+// Copying a symlink requires that the to FS has a ReadLink or Readlink method.
+func Diff(from, to fs.FS) (Patch, error) {
+	old := make(map[string]fs.FileInfo)
+
+	if err := fs.WalkDir(from, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		old[p] = fi
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	d := differ{
+		from:  from,
+		to:    to,
+		old:   old,
+		dirty: make(map[string]bool),
+	}
+
+	if err := fs.WalkDir(to, ".", d.diff); err != nil {
+		return nil, err
+	}
+
+	deleted := make([]string, 0, len(old))
+
+	for p := range old {
+		if _, ok := old[path.Dir(p)]; !ok {
+			deleted = append(deleted, p)
+		}
+	}
+
+	slices.Sort(deleted)
+
+	patch := make(Patch, 0, len(deleted)+len(d.patch)+len(d.dirs))
+
+	for _, p := range deleted {
+		patch = append(patch, PatchOp{Action: PatchDelete, Path: p})
+		d.dirty[path.Dir(p)] = true
+	}
+
+	patch = append(patch, d.patch...)
+
+	for n := len(d.dirs) - 1; n >= 0; n-- {
+		if op := d.dirs[n]; d.dirty[op.Path] {
+			patch = append(patch, op)
+		}
+	}
+
+	return patch, nil
+}
+
+func isBelow(p, dir string) bool {
+	return strings.HasPrefix(p, dir+"/")
+}
+
+type differ struct {
+	from, to    fs.FS
+	old         map[string]fs.FileInfo
+	patch, dirs Patch
+	dirty       map[string]bool
+}
+
+// add appends the given operations to the Patch, marking the parent directory
+// as needing its metadata to be reset if the operation adds or removes an
+// entry.
+func (d *differ) add(ops ...PatchOp) {
+	for _, op := range ops {
+		if op.Action == PatchCreate || op.Action == PatchDelete {
+			d.dirty[path.Dir(op.Path)] = true
+		}
+
+		d.patch = append(d.patch, op)
+	}
+}
+
+func (d *differ) diff(p string, de fs.DirEntry, err error) error {
+	if err != nil {
+		return err
+	}
+
+	fi, err := de.Info()
+	if err != nil {
+		return err
+	}
+
+	mode := fi.Mode()
+	if !mode.IsDir() && !mode.IsRegular() && mode&fs.ModeSymlink == 0 {
+		return nil
+	}
+
+	ofi, ok := d.old[p]
+	if ok {
+		delete(d.old, p)
+
+		if ofi.Mode().Type() != mode.Type() {
+			d.add(PatchOp{Action: PatchDelete, Path: p})
+			ok = false
+
+			for q := range d.old {
+				if isBelow(q, p) {
+					delete(d.old, q)
+				}
+			}
+		}
+	}
+
+	if !ok {
+		return d.create(p, fi)
+	}
+
+	return d.update(p, ofi, fi)
+}
+
+func (d *differ) create(p string, fi fs.FileInfo) error {
+	op := PatchOp{
+		Action:  PatchCreate,
+		Path:    p,
+		Mode:    fi.Mode(),
+		ModTime: fi.ModTime(),
+	}
+
+	switch {
+	case fi.IsDir():
+		d.add(PatchOp{Action: PatchCreate, Path: p, Mode: fs.ModeDir | fs.ModePerm})
+		op.Action = PatchMetadata
+		d.dirs = append(d.dirs, op)
+		d.dirty[p] = true
+
+		return nil
+	case fi.Mode()&fs.ModeSymlink != 0:
+		target, err := readLink(d.to, p)
+		if err != nil {
+			return err
+		}
+
+		op.Data = []byte(target)
+	default:
+		data, err := fs.ReadFile(d.to, p)
+		if err != nil {
+			return err
+		}
+
+		op.Data = data
+	}
+
+	d.add(op)
+
+	return nil
+}
+
+func (d *differ) update(p string, ofi, fi fs.FileInfo) error {
+	op := PatchOp{
+		Action:  PatchMetadata,
+		Path:    p,
+		Mode:    fi.Mode(),
+		ModTime: fi.ModTime(),
+	}
+	changed := ofi.Mode() != fi.Mode() || !ofi.ModTime().Equal(fi.ModTime())
+
+	switch {
+	case fi.IsDir():
+		d.dirs = append(d.dirs, op)
+
+		if changed {
+			d.dirty[p] = true
+		}
+
+		return nil
+	case fi.Mode()&fs.ModeSymlink != 0:
+		target, err := readLink(d.to, p)
+		if err != nil {
+			return err
+		}
+
+		if oldTarget, err := readLink(d.from, p); err != nil || oldTarget != target {
+			d.add(PatchOp{Action: PatchDelete, Path: p})
+			op.Action = PatchCreate
+			op.Data = []byte(target)
+			changed = true
+		}
+	default:
+		equal, err := ContentsEqual(d.from, p, d.to, p)
+		if err != nil {
+			return err
+		}
+
+		if !equal {
+			data, err := fs.ReadFile(d.to, p)
+			if err != nil {
+				return err
+			}
+
+			d.add(PatchOp{Action: PatchContents, Path: p, Mode: fi.Mode(), ModTime: fi.ModTime(), Data: data})
+
+			changed = ofi.Mode() != fi.Mode()
+		}
+	}
+
+	if changed {
+		d.add(op)
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package memfs
+
+import (
+	"bytes"
+	"io/fs"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type patchEntry struct {
+	Path, Contents string
+	Mode           fs.FileMode
+}
+
+func makePatchFS(t *testing.T, entries []patchEntry) *FS {
+	t.Helper()
+
+	f := New()
+	modtime := time.Unix(1000, 0)
+
+	for _, e := range entries {
+		var err error
+
+		switch e.Mode.Type() {
+		case fs.ModeDir:
+			err = f.Mkdir(e.Path, fs.ModePerm)
+		case fs.ModeSymlink:
+			err = f.Symlink(e.Contents, e.Path)
+		default:
+			err = f.WriteFile(e.Path, []byte(e.Contents), e.Mode)
+		}
+
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	for n := len(entries) - 1; n >= 0; n-- {
+		e := entries[n]
+
+		if e.Mode.Type() != fs.ModeSymlink {
+			if err := f.Chmod(e.Path, e.Mode); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		}
+
+		if err := f.Lchtimes(e.Path, time.Time{}, modtime); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if err := f.Chtimes(".", time.Time{}, modtime); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	return f
+}
+
+func TestDiffApplyPatch(t *testing.T) {
+	from := []patchEntry{
+		{Path: "a", Mode: fs.ModeDir | 0o755},
+		{Path: "a/same", Contents: "same", Mode: 0o644},
+		{Path: "a/changed", Contents: "old", Mode: 0o644},
+		{Path: "a/chmod", Contents: "chmod", Mode: 0o644},
+		{Path: "a/link", Contents: "same", Mode: fs.ModeSymlink},
+		{Path: "a-b", Mode: fs.ModeDir | 0o755},
+		{Path: "a-b/gone", Contents: "gone", Mode: 0o644},
+		{Path: "dir", Mode: fs.ModeDir | 0o755},
+		{Path: "dir/file", Contents: "file", Mode: 0o644},
+		{Path: "gone", Mode: fs.ModeDir | 0o755},
+		{Path: "gone/file", Contents: "file", Mode: 0o644},
+	}
+
+	for n, test := range [...]struct {
+		To      []patchEntry
+		Actions []PatchAction
+	}{
+		{ // 1
+			To:      from,
+			Actions: []PatchAction{},
+		},
+		{ // 2
+			To: []patchEntry{
+				{Path: "a", Mode: fs.ModeDir | 0o555},
+				{Path: "a/same", Contents: "same", Mode: 0o644},
+				{Path: "a/changed", Contents: "new contents", Mode: 0o644},
+				{Path: "a/chmod", Contents: "chmod", Mode: 0o600},
+				{Path: "a/link", Contents: "changed", Mode: fs.ModeSymlink},
+				{Path: "a/new", Mode: fs.ModeDir | 0o500},
+				{Path: "a/new/file", Contents: "new", Mode: 0o400},
+				{Path: "dir", Contents: "now a file", Mode: 0o644},
+			},
+			Actions: []PatchAction{
+				PatchDelete, PatchDelete,
+				PatchContents, PatchMetadata, PatchDelete, PatchCreate, PatchCreate, PatchCreate, PatchDelete, PatchCreate,
+				PatchMetadata, PatchMetadata, PatchMetadata,
+			},
+		},
+	} {
+		fromFS := makePatchFS(t, from)
+		toFS := makePatchFS(t, test.To)
+
+		patch, err := Diff(fromFS, toFS)
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+
+			continue
+		}
+
+		actions := []PatchAction{}
+
+		for _, op := range patch {
+			actions = append(actions, op.Action)
+		}
+
+		if !reflect.DeepEqual(actions, test.Actions) {
+			t.Errorf("test %d: expecting actions %v, got %v", n+1, test.Actions, actions)
+		}
+
+		if err := ApplyPatch(fromFS, patch); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+
+			continue
+		}
+
+		var got, expected bytes.Buffer
+
+		if err := fromFS.WriteTar(&got); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		} else if err := toFS.WriteTar(&expected); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		} else if !bytes.Equal(got.Bytes(), expected.Bytes()) {
+			t.Errorf("test %d: patched FS does not match", n+1)
+		} else if fi, err := fromFS.Stat("."); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		} else if !fi.ModTime().Equal(time.Unix(1000, 0)) {
+			t.Errorf("test %d: expecting root modtime to be restored, got %s", n+1, fi.ModTime())
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package memfs
+
+import (
+	"io"
+	"io/fs"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PipeFile is one end of a pipe, as returned by Pipe, or as opened from a named
+// pipe created with Mkfifo.
+//
+// Reading from the read end returns the data written to the write end, with
+// each write blocking until the data has been read, as with io.Pipe. Once all
+// of the write ends open on a pipe are closed, reads return io.EOF, and once
+// all of the read ends are closed, writes return io.ErrClosedPipe.
+type PipeFile struct {
+	name    string
+	node    *fifo
+	r       *io.PipeReader
+	w       *io.PipeWriter
+	closed  atomic.Bool
+	handles *openLimit
+}
+
+// Pipe returns a connected pair of PipeFiles; data written to w can be read
+// from r.
+func Pipe() (r, w *PipeFile) {
+	now := time.Now()
+
+	p := &fifo{
+		modtime: now,
+		ctime:   now,
+		mode:    fs.ModeNamedPipe | 0o600,
+	}
+
+	r, _ = p.attach("pipe", opRead)
+	w, _ = p.attach("pipe", opWrite)
+
+	return r, w
+}
+
+// Read reads from the read end of the pipe, returning ErrNotOpenForRead for the
+// write end.
+func (p *PipeFile) Read(b []byte) (int, error) {
+	if p.closed.Load() {
+		return 0, fs.ErrClosed
+	} else if p.r == nil {
+		return 0, ErrNotOpenForRead
+	}
+
+	return p.r.Read(b)
+}
+
+// Write writes to the write end of the pipe, returning ErrNotOpenForWrite for
+// the read end.
+func (p *PipeFile) Write(b []byte) (int, error) {
+	if p.closed.Load() {
+		return 0, fs.ErrClosed
+	} else if p.w == nil {
+		return 0, ErrNotOpenForWrite
+	}
+
+	return p.w.Write(b)
+}
+
+// Stat returns the fs.FileInfo of the pipe.
+func (p *PipeFile) Stat() (fs.FileInfo, error) {
+	if p.closed.Load() {
+		return nil, fs.ErrClosed
+	}
+
+	return &dirEnt{
+		directoryEntry: p.node,
+		name:           p.name,
+	}, nil
+}
+
+// Close closes this end of the pipe.
+func (p *PipeFile) Close() error {
+	if p.closed.Swap(true) {
+		return fs.ErrClosed
+	}
+
+	p.node.detach(p)
+	p.handles.release()
+
+	return nil
+}
+
+type fifo struct {
+	mu      sync.RWMutex
+	modtime time.Time
+	ctime   time.Time
+	mode    fs.FileMode
+	sealed  bool
+	gen     uint64
+	acl     ACL
+	parents parents
+
+	r                *io.PipeReader
+	w                *io.PipeWriter
+	readers, writers int
+}
+
+// Mkfifo creates a named pipe at the given path, with the given permissions.
+//
+// Opening the named pipe with Open returns the read end of a pipe, as a
+// PipeFile, and the write end can be opened with OpenPipe; all of the ends
+// opened on a named pipe, until all are closed, are connected to the same pipe.
+// Named pipes cannot be opened with OpenFile, nor read or written with
+// ReadFile or WriteFile.
+func (f *FS) Mkfifo(path string, perm fs.FileMode) (err error) {
+	defer f.logOp("mkfifo", path).withMode(perm).end(&err)
+
+	f.throttle.op()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.limit.op(); err != nil {
+		return &fs.PathError{Op: "mkfifo", Path: path, Err: err}
+	}
+
+	d, _, err := f.getEntryWithParent(path, mustNotExist)
+	if err != nil {
+		return &fs.PathError{Op: "mkfifo", Path: path, Err: err}
+	}
+
+	now := f.deterministic.now()
+
+	if err := d.setEntry(&dirEnt{
+		directoryEntry: &fifo{
+			modtime: now,
+			ctime:   now,
+			mode:    fs.ModeNamedPipe | perm,
+		},
+		name: entryName(path),
+	}, f.deterministic); err != nil {
+		return &fs.PathError{Op: "mkfifo", Path: path, Err: err}
+	}
+
+	f.record(Event{Op: "mkfifo", Path: path, Mode: perm})
+
+	return nil
+}
+
+// OpenPipe opens the named pipe at the given path, as created with Mkfifo,
+// following any symlinks, returning the read end for the ReadOnly Mode, and the
+// write end for the WriteOnly Mode.
+//
+// As the FS is not locked while the PipeFile is in use, reads and writes can
+// block without preventing the opening of the other end.
+func (f *FS) OpenPipe(path string, mode Mode) (_ *PipeFile, err error) {
+	defer f.logOp("openpipe", path).end(&err)
+
+	if mode != ReadOnly && mode != WriteOnly {
+		return nil, &fs.PathError{Op: "openpipe", Path: path, Err: ErrInvalidMode}
+	}
+
+	f.throttle.open()
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	de, err := f.getEntry(path)
+	if err != nil {
+		return nil, &fs.PathError{Op: "openpipe", Path: path, Err: err}
+	}
+
+	p, ok := de.(*fifo)
+	if !ok {
+		return nil, &fs.PathError{Op: "openpipe", Path: path, Err: fs.ErrInvalid}
+	}
+
+	if err := f.handles.acquire(); err != nil {
+		return nil, &fs.PathError{Op: "openpipe", Path: path, Err: err}
+	}
+
+	pf, err := p.attach(entryName(path), openMode(mode))
+	if err != nil {
+		f.handles.release()
+
+		return nil, &fs.PathError{Op: "openpipe", Path: path, Err: err}
+	}
+
+	pf.handles = f.handles
+
+	return pf, nil
+}
+
+func (p *fifo) attach(name string, mode opMode) (*PipeFile, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if mode&opRead > 0 && mode&opWrite > 0 {
+		return nil, fs.ErrInvalid
+	} else if mode&opRead > 0 && p.lockedPerm()&modeRead == 0 || mode&opWrite > 0 && (p.lockedPerm()&modeWrite == 0 || p.sealed) {
+		return nil, fs.ErrPermission
+	}
+
+	if p.r == nil {
+		p.r, p.w = io.Pipe()
+	}
+
+	pf := &PipeFile{name: name, node: p}
+
+	if mode&opWrite > 0 {
+		pf.w = p.w
+		p.writers++
+	} else {
+		pf.r = p.r
+		p.readers++
+	}
+
+	return pf, nil
+}
+
+func (p *fifo) detach(pf *PipeFile) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pf.w != nil {
+		if p.writers--; p.writers == 0 {
+			p.w.Close()
+		}
+	} else if p.readers--; p.readers == 0 {
+		p.r.Close()
+	}
+
+	if p.readers == 0 && p.writers == 0 {
+		p.r, p.w = nil, nil
+	}
+}
+
+func (p *fifo) open(name string, mode opMode) (fs.File, error) {
+	return p.attach(name, mode&^opSeek)
+}
+
+func (p *fifo) IsDir() bool {
+	return false
+}
+
+func (p *fifo) ModTime() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.modtime
+}
+
+func (p *fifo) Type() fs.FileMode {
+	return fs.ModeNamedPipe
+}
+
+func (p *fifo) Mode() fs.FileMode {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.mode
+}
+
+func (p *fifo) Size() int64 {
+	return 0
+}
+
+func (p *fifo) bytes() ([]byte, error) {
+	return nil, fs.ErrInvalid
+}
+
+func (p *fifo) string() (string, error) {
+	return "", fs.ErrInvalid
+}
+
+func (p *fifo) appendData(_ []byte) ([]byte, error) {
+	return nil, fs.ErrInvalid
+}
+
+func (p *fifo) setMode(mode fs.FileMode) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.sealed {
+		return fs.ErrPermission
+	}
+
+	p.mode = fs.ModeNamedPipe | mode
+	p.gen = nextGeneration()
+
+	if p.acl != nil {
+		p.acl = p.acl.withMode(mode)
+	}
+
+	p.parents.changed(p.gen)
+
+	return nil
+}
+
+func (p *fifo) setTimes(_, mtime time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.sealed {
+		return fs.ErrPermission
+	}
+
+	p.modtime = mtime
+	p.gen = nextGeneration()
+
+	p.parents.changed(p.gen)
+
+	return nil
+}
+
+func (p *fifo) seal() directoryEntry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sealed = true
+
+	return p
+}
+
+func (p *fifo) getEntry(_ string) (*dirEnt, error) {
+	return nil, ErrNotDir
+}
+
+func (p *fifo) Generation() uint64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.gen
+}
+
+func (p *fifo) getACL() ACL {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return slices.Clone(p.acl)
+}
+
+func (p *fifo) setACL(acl ACL) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.sealed {
+		return fs.ErrPermission
+	}
+
+	p.acl = acl.extended()
+	p.mode = fs.ModeNamedPipe | acl.mode()
+	p.gen = nextGeneration()
+
+	p.parents.changed(p.gen)
+
+	return nil
+}
+
+func (p *fifo) perm() fs.FileMode {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.lockedPerm()
+}
+
+// lockedPerm returns the permissions of the pipe, and must be called with the
+// lock held.
+func (p *fifo) lockedPerm() fs.FileMode {
+	if p.acl != nil {
+		return p.acl.perm()
+	}
+
+	return p.mode
+}
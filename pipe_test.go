@@ -0,0 +1,144 @@
+package memfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+)
+
+func TestPipe(t *testing.T) {
+	r, w := Pipe()
+
+	go func() {
+		w.Write([]byte("hello, "))
+		w.Write([]byte("world"))
+		w.Close()
+	}()
+
+	if data, err := io.ReadAll(r); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if string(data) != "hello, world" {
+		t.Errorf("test 1: expecting to read %q, got %q", "hello, world", data)
+	} else if fi, err := r.Stat(); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if fi.Mode().Type() != fs.ModeNamedPipe {
+		t.Errorf("test 2: expecting named pipe, got %s", fi.Mode())
+	} else if _, err := r.Write([]byte("data")); !errors.Is(err, ErrNotOpenForWrite) {
+		t.Errorf("test 3: expecting error %v, got %v", ErrNotOpenForWrite, err)
+	} else if _, err := w.Read(make([]byte, 1)); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("test 4: expecting error %v, got %v", fs.ErrClosed, err)
+	} else if err := r.Close(); err != nil {
+		t.Errorf("test 5: unexpected error: %s", err)
+	} else if err := r.Close(); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("test 5: expecting error %v, got %v", fs.ErrClosed, err)
+	}
+
+	r, w = Pipe()
+
+	r.Close()
+
+	if _, err := w.Write([]byte("data")); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("test 6: expecting error %v, got %v", io.ErrClosedPipe, err)
+	} else if _, err := w.Read(make([]byte, 1)); !errors.Is(err, ErrNotOpenForRead) {
+		t.Errorf("test 7: expecting error %v, got %v", ErrNotOpenForRead, err)
+	}
+}
+
+func TestMkfifo(t *testing.T) {
+	f := New()
+
+	if err := f.Mkdir("dir", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Mkfifo("dir/fifo", 0o644); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if err := f.Mkfifo("dir/fifo", 0o644); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("test 2: expecting error %v, got %v", fs.ErrExist, err)
+	} else if fi, err := f.Stat("dir/fifo"); err != nil {
+		t.Fatalf("test 3: unexpected error: %s", err)
+	} else if fi.Mode() != fs.ModeNamedPipe|0o644 {
+		t.Errorf("test 3: expecting mode %s, got %s", fs.ModeNamedPipe|0o644, fi.Mode())
+	}
+
+	for n := 0; n < 2; n++ {
+		of, err := f.Open("dir/fifo")
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+4, err)
+		}
+
+		w, err := f.OpenPipe("dir/fifo", WriteOnly)
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+4, err)
+		}
+
+		go func() {
+			io.WriteString(w, "data")
+			w.Close()
+		}()
+
+		if data, err := io.ReadAll(of); err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+4, err)
+		} else if string(data) != "data" {
+			t.Errorf("test %d: expecting to read %q, got %q", n+4, "data", data)
+		} else if err := of.Close(); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+4, err)
+		}
+	}
+
+	if _, err := f.OpenPipe("dir/fifo", ReadWrite); !errors.Is(err, ErrInvalidMode) {
+		t.Errorf("test 6: expecting error %v, got %v", ErrInvalidMode, err)
+	} else if _, err := f.OpenPipe("dir", ReadOnly); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("test 7: expecting error %v, got %v", fs.ErrInvalid, err)
+	} else if _, err := f.OpenFile("dir/fifo", WriteOnly, 0); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("test 8: expecting error %v, got %v", fs.ErrInvalid, err)
+	} else if err := f.WriteFile("dir/fifo", []byte("data"), 0o644); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("test 9: expecting error %v, got %v", fs.ErrInvalid, err)
+	} else if _, err := f.ReadFile("dir/fifo"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("test 10: expecting error %v, got %v", fs.ErrInvalid, err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := f.WriteTar(&buf); err != nil {
+		t.Fatalf("test 11: unexpected error: %s", err)
+	}
+
+	tr := tar.NewReader(&buf)
+
+	if _, err := tr.Next(); err != nil {
+		t.Fatalf("test 11: unexpected error: %s", err)
+	} else if hdr, err := tr.Next(); err != nil {
+		t.Fatalf("test 11: unexpected error: %s", err)
+	} else if hdr.Name != "dir/fifo" || hdr.Typeflag != tar.TypeFifo {
+		t.Errorf("test 11: expecting fifo header for %q, got %q of type %c", "dir/fifo", hdr.Name, hdr.Typeflag)
+	}
+
+	if fi, err := sealCompact(t, f).Stat("dir/fifo"); err != nil {
+		t.Fatalf("test 12: unexpected error: %s", err)
+	} else if fi.Mode().Type() != fs.ModeNamedPipe {
+		t.Errorf("test 12: expecting named pipe, got %s", fi.Mode())
+	}
+
+	g := New()
+	patch, _ := f.Changes(0)
+
+	if err := ApplyPatch(g, patch); err != nil {
+		t.Fatalf("test 13: unexpected error: %s", err)
+	} else if fi, err := g.Stat("dir/fifo"); err != nil {
+		t.Fatalf("test 13: unexpected error: %s", err)
+	} else if fi.Mode() != fs.ModeNamedPipe|0o644 {
+		t.Errorf("test 13: expecting mode %s, got %s", fs.ModeNamedPipe|0o644, fi.Mode())
+	}
+
+	sealed := f.Seal()
+
+	if _, err := f.OpenPipe("dir/fifo", WriteOnly); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("test 14: expecting error %v, got %v", fs.ErrPermission, err)
+	} else if of, err := sealed.Open("dir/fifo"); err != nil {
+		t.Errorf("test 15: unexpected error: %s", err)
+	} else {
+		of.Close()
+	}
+}
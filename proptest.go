@@ -0,0 +1,393 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"maps"
+	"math/rand/v2"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Target is a file system to which Events can be applied by CompareEvents,
+// such as an FS, or a directory of the OS, as returned by DirTarget.
+type Target interface {
+	fs.FS
+	Mkdir(path string, perm fs.FileMode) error
+	WriteFile(path string, data []byte, perm fs.FileMode) error
+	Link(oldPath, newPath string) error
+	Symlink(oldPath, newPath string) error
+	Rename(oldPath, newPath string) error
+	Remove(path string) error
+	RemoveAll(path string) error
+	Chmod(path string, mode fs.FileMode) error
+	LStat(path string) (fs.FileInfo, error)
+	Readlink(path string) (string, error)
+}
+
+// DirTarget returns a Target that applies Events to the given directory of the
+// OS.
+//
+// An FS does not match the OS in every respect; for example, RemoveAll returns
+// an error for a path that does not exist. Such differences are reported by
+// CompareEvents as any other divergence.
+func DirTarget(dir string) Target {
+	return &dirTarget{
+		FS:  os.DirFS(dir),
+		dir: dir,
+	}
+}
+
+type dirTarget struct {
+	fs.FS
+	dir string
+}
+
+func (d *dirTarget) join(op, p string) (string, error) {
+	if !fs.ValidPath(p) {
+		return "", &fs.PathError{Op: op, Path: p, Err: fs.ErrInvalid}
+	}
+
+	return filepath.Join(d.dir, filepath.FromSlash(p)), nil
+}
+
+func (d *dirTarget) Mkdir(p string, perm fs.FileMode) error {
+	name, err := d.join("mkdir", p)
+	if err != nil {
+		return err
+	}
+
+	return os.Mkdir(name, perm)
+}
+
+func (d *dirTarget) WriteFile(p string, data []byte, perm fs.FileMode) error {
+	name, err := d.join("writefile", p)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(name, data, perm)
+}
+
+func (d *dirTarget) Link(oldPath, newPath string) error {
+	oldName, err := d.join("link", oldPath)
+	if err != nil {
+		return err
+	}
+
+	newName, err := d.join("link", newPath)
+	if err != nil {
+		return err
+	}
+
+	return os.Link(oldName, newName)
+}
+
+func (d *dirTarget) Symlink(oldPath, newPath string) error {
+	name, err := d.join("symlink", newPath)
+	if err != nil {
+		return err
+	}
+
+	return os.Symlink(filepath.FromSlash(oldPath), name)
+}
+
+func (d *dirTarget) Rename(oldPath, newPath string) error {
+	oldName, err := d.join("rename", oldPath)
+	if err != nil {
+		return err
+	}
+
+	newName, err := d.join("rename", newPath)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(oldName, newName)
+}
+
+func (d *dirTarget) Remove(p string) error {
+	name, err := d.join("remove", p)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(name)
+}
+
+func (d *dirTarget) RemoveAll(p string) error {
+	name, err := d.join("removeall", p)
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(name)
+}
+
+func (d *dirTarget) Chmod(p string, mode fs.FileMode) error {
+	name, err := d.join("chmod", p)
+	if err != nil {
+		return err
+	}
+
+	return os.Chmod(name, mode)
+}
+
+func (d *dirTarget) LStat(p string) (fs.FileInfo, error) {
+	name, err := d.join("lstat", p)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Lstat(name)
+}
+
+func (d *dirTarget) Readlink(p string) (string, error) {
+	name, err := d.join("readlink", p)
+	if err != nil {
+		return "", err
+	}
+
+	target, err := os.Readlink(name)
+
+	return filepath.ToSlash(target), err
+}
+
+var (
+	randomNames = [...]string{"a", "b", "c"}
+	randomDirs  = [...]fs.FileMode{0o755, 0o700}
+	randomFiles = [...]fs.FileMode{0o644, 0o600, 0o755}
+)
+
+// RandomEvents returns n Events, chosen using the given source of randomness,
+// that make changes to a small tree, of up to three levels, such that they are
+// likely to interact with one another.
+//
+// Only the mkdir, writefile, link, symlink, rename, remove, removeall, and
+// chmod operations are generated, with no permissions that would deny access
+// to the owner, nor that would be affected by a typical umask, and with
+// symlink targets that never leave the tree.
+func RandomEvents(r *rand.Rand, n int) []Event {
+	events := make([]Event, n)
+
+	for i := range events {
+		p := randomPath(r)
+
+		switch r.IntN(8) {
+		case 0:
+			events[i] = Event{Op: "mkdir", Path: p, Mode: randomDirs[r.IntN(len(randomDirs))]}
+		case 1:
+			events[i] = Event{Op: "writefile", Path: p, Mode: randomFiles[r.IntN(len(randomFiles))], Data: []byte(strconv.Itoa(i))}
+		case 2:
+			events[i] = Event{Op: "link", Path: p, NewPath: randomPath(r)}
+		case 3:
+			target := strings.Repeat("../", strings.Count(p, "/")) + randomPath(r)
+
+			events[i] = Event{Op: "symlink", Path: p, Data: []byte(target)}
+		case 4:
+			events[i] = Event{Op: "rename", Path: p, NewPath: randomPath(r)}
+		case 5:
+			events[i] = Event{Op: "remove", Path: p}
+		case 6:
+			events[i] = Event{Op: "removeall", Path: p}
+		case 7:
+			events[i] = Event{Op: "chmod", Path: p, Mode: randomDirs[r.IntN(len(randomDirs))]}
+		}
+	}
+
+	return events
+}
+
+func randomPath(r *rand.Rand) string {
+	parts := make([]string, 1+r.IntN(3))
+
+	for n := range parts {
+		parts[n] = randomNames[r.IntN(len(randomNames))]
+	}
+
+	return path.Join(parts...)
+}
+
+// DivergenceError is returned by CompareEvents when the two Targets diverge.
+type DivergenceError struct {
+	// Index is the index of the Event after which the Targets diverged.
+	Index int
+	Event Event
+
+	// Path is the path of the entry that differs between the Targets, and
+	// is empty when only the results of applying the Event differ.
+	Path string
+
+	// A and B describe the result of applying the Event, or the entry at
+	// Path, in each of the Targets.
+	A, B string
+}
+
+func (d *DivergenceError) Error() string {
+	var sb strings.Builder
+
+	sb.WriteString("targets diverge after event ")
+	sb.WriteString(strconv.Itoa(d.Index))
+	sb.WriteString(" (")
+	sb.WriteString(d.Event.Op)
+	sb.WriteString(" ")
+	sb.WriteString(strconv.Quote(d.Event.Path))
+
+	if d.Event.NewPath != "" {
+		sb.WriteString(" ")
+		sb.WriteString(strconv.Quote(d.Event.NewPath))
+	}
+
+	sb.WriteString(")")
+
+	if d.Path != "" {
+		sb.WriteString(" at ")
+		sb.WriteString(strconv.Quote(d.Path))
+	}
+
+	sb.WriteString(": ")
+	sb.WriteString(strconv.Quote(d.A))
+	sb.WriteString(" != ")
+	sb.WriteString(strconv.Quote(d.B))
+
+	return sb.String()
+}
+
+// CompareEvents applies each of the given Events to both of the given Targets,
+// returning a DivergenceError when the Event succeeds on one Target and fails
+// on the other, or when, after the Event, the Targets contain different
+// entries.
+//
+// Entries are compared by their type, their contents, or symlink target, and
+// the permissions of directories and regular files; the kinds of errors, the
+// modification times, and the sizes of directories are not compared.
+func CompareEvents(a, b Target, events []Event) error {
+	for n, e := range events {
+		errA, errB := applyTo(a, e), applyTo(b, e)
+
+		if (errA == nil) != (errB == nil) {
+			return &DivergenceError{Index: n, Event: e, A: result(errA), B: result(errB)}
+		}
+
+		treeA, err := describeTree(a)
+		if err != nil {
+			return err
+		}
+
+		treeB, err := describeTree(b)
+		if err != nil {
+			return err
+		}
+
+		if p, ok := firstDifference(treeA, treeB); ok {
+			return &DivergenceError{Index: n, Event: e, Path: p, A: treeA[p], B: treeB[p]}
+		}
+	}
+
+	return nil
+}
+
+// firstDifference returns the first path, in sorted order, whose description
+// differs between the given trees.
+func firstDifference(a, b map[string]string) (string, bool) {
+	paths := slices.Collect(maps.Keys(a))
+
+	for p := range b {
+		if _, ok := a[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+
+	slices.Sort(paths)
+
+	for _, p := range paths {
+		if a[p] != b[p] {
+			return p, true
+		}
+	}
+
+	return "", false
+}
+
+func result(err error) string {
+	if err == nil {
+		return "ok"
+	}
+
+	return err.Error()
+}
+
+func applyTo(t Target, e Event) error {
+	switch e.Op {
+	case "mkdir":
+		return t.Mkdir(e.Path, e.Mode)
+	case "writefile":
+		return t.WriteFile(e.Path, e.Data, e.Mode)
+	case "link":
+		return t.Link(e.Path, e.NewPath)
+	case "symlink":
+		return t.Symlink(string(e.Data), e.Path)
+	case "rename":
+		return t.Rename(e.Path, e.NewPath)
+	case "remove":
+		return t.Remove(e.Path)
+	case "removeall":
+		return t.RemoveAll(e.Path)
+	case "chmod":
+		return t.Chmod(e.Path, e.Mode)
+	}
+
+	return &fs.PathError{Op: e.Op, Path: e.Path, Err: errors.ErrUnsupported}
+}
+
+// describeTree returns a description of each entry in the Target, other than
+// the root, keyed by path.
+func describeTree(t Target) (map[string]string, error) {
+	tree := make(map[string]string)
+
+	if err := fs.WalkDir(t, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		} else if p == "." {
+			return nil
+		}
+
+		fi, err := t.LStat(p)
+		if err != nil {
+			return err
+		}
+
+		mode := fi.Mode()
+
+		switch {
+		case mode.IsDir():
+			tree[p] = "dir " + mode.Perm().String()
+		case mode&fs.ModeSymlink != 0:
+			target, err := t.Readlink(p)
+			if err != nil {
+				return err
+			}
+
+			tree[p] = "symlink -> " + target
+		case mode.IsRegular():
+			data, err := fs.ReadFile(t, p)
+			if err != nil {
+				return err
+			}
+
+			tree[p] = "file " + mode.Perm().String() + " " + strconv.Quote(string(data))
+		default:
+			tree[p] = mode.String()
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
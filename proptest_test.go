@@ -0,0 +1,90 @@
+package memfs
+
+import (
+	"errors"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+)
+
+func TestCompareEvents(t *testing.T) {
+	extra := New()
+
+	extra.WriteFile("x", []byte("1"), 0o644)
+
+	for n, test := range [...]struct {
+		A, B   Target
+		Events []Event
+		Err    *DivergenceError
+	}{
+		{ // 1
+			A: New(),
+			B: DirTarget(t.TempDir()),
+			Events: []Event{
+				{Op: "mkdir", Path: "a", Mode: 0o755},
+				{Op: "writefile", Path: "a/b", Mode: 0o644, Data: []byte("1")},
+				{Op: "link", Path: "a/b", NewPath: "c"},
+				{Op: "symlink", Path: "d", Data: []byte("a/b")},
+				{Op: "rename", Path: "a/b", NewPath: "a/e"},
+				{Op: "chmod", Path: "a", Mode: 0o700},
+				{Op: "mkdir", Path: "c", Mode: 0o755},
+				{Op: "remove", Path: "c"},
+				{Op: "remove", Path: "a"},
+				{Op: "writefile", Path: "a/e", Mode: 0o644, Data: []byte("2")},
+				{Op: "removeall", Path: "a"},
+			},
+		},
+		{ // 2
+			A: New(),
+			B: DirTarget(t.TempDir()),
+			Events: []Event{
+				{Op: "mkdir", Path: "a", Mode: 0o755},
+				{Op: "removeall", Path: "b"},
+			},
+			Err: &DivergenceError{Index: 1, Event: Event{Op: "removeall", Path: "b"}, A: "removeall b: file does not exist", B: "ok"},
+		},
+		{ // 3
+			A: extra,
+			B: New(),
+			Events: []Event{
+				{Op: "mkdir", Path: "a", Mode: 0o755},
+			},
+			Err: &DivergenceError{Index: 0, Event: Event{Op: "mkdir", Path: "a", Mode: 0o755}, Path: "x", A: "file -rw-r--r-- \"1\""},
+		},
+	} {
+		err := CompareEvents(test.A, test.B, test.Events)
+
+		var de *DivergenceError
+
+		if test.Err == nil {
+			if err != nil {
+				t.Errorf("test %d: unexpected error: %s", n+1, err)
+			}
+		} else if !errors.As(err, &de) {
+			t.Errorf("test %d: expecting DivergenceError, got %v", n+1, err)
+		} else if !reflect.DeepEqual(de, test.Err) {
+			t.Errorf("test %d: expecting error %#v, got %#v", n+1, test.Err, de)
+		}
+	}
+}
+
+func TestRandomEvents(t *testing.T) {
+	a := RandomEvents(rand.New(rand.NewPCG(1, 2)), 1000)
+	b := RandomEvents(rand.New(rand.NewPCG(1, 2)), 1000)
+
+	if !reflect.DeepEqual(a, b) {
+		t.Fatalf("test 1: expecting the same events from the same seed")
+	}
+
+	for n, e := range a {
+		switch e.Op {
+		case "mkdir", "writefile", "link", "symlink", "rename", "remove", "removeall", "chmod":
+		default:
+			t.Fatalf("test 2: event %d has unexpected op %q", n, e.Op)
+		}
+	}
+
+	if err := CompareEvents(New(), New(), a); err != nil {
+		t.Errorf("test 3: unexpected error: %s", err)
+	}
+}
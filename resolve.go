@@ -2,50 +2,224 @@ package memfs
 
 import (
 	"io/fs"
-	"path"
+	"strconv"
 	"strings"
 )
 
+// SymlinkLimitError is returned, wrapped in an fs.PathError, when resolving a
+// path would require following more symlinks than the limit set with
+// WithMaxSymlinks.
+//
+// It matches fs.ErrInvalid with errors.Is.
+type SymlinkLimitError struct {
+	// Path is the partially resolved path, ending with the symlink that
+	// would have exceeded the limit.
+	Path string
+
+	// Limit is the maximum number of symlinks that could be followed.
+	Limit int
+}
+
+func (s *SymlinkLimitError) Error() string {
+	return "too many levels of symbolic links (" + strconv.Itoa(s.Limit) + ") at " + s.Path
+}
+
+func (*SymlinkLimitError) Unwrap() error {
+	return fs.ErrInvalid
+}
+
+// ResolveError is returned, wrapped in an fs.PathError, when resolving a path
+// fails on an FS created with the WithResolveTrace Option, recording each of
+// the steps taken while resolving the path.
+//
+// It matches the error that caused the failure with errors.Is and errors.As.
+type ResolveError struct {
+	// Path is the path that was being resolved.
+	Path string
+
+	// Steps lists each of the entries reached while resolving the path, in
+	// order, with the last step being the one that failed.
+	Steps []ResolveStep
+
+	// Err is the error that caused the failure.
+	Err error
+}
+
+// ResolveStep is a single step in the resolution of a path.
+type ResolveStep struct {
+	// Path is the path of the entry reached, as resolved so far.
+	Path string
+
+	// Mode is the mode of the entry, which is zero when the entry could
+	// not be reached.
+	Mode fs.FileMode
+
+	// Target is the target of a symlink that was followed.
+	Target string
+
+	// Err is the error encountered at this step, if any.
+	Err error
+}
+
+// Error returns the error that caused the failure, followed by each of the
+// steps taken, with symlinks shown along with their targets.
+func (r *ResolveError) Error() string {
+	var sb strings.Builder
+
+	sb.WriteString(r.Err.Error())
+	sb.WriteString(" (resolving ")
+	sb.WriteString(strconv.Quote(r.Path))
+	sb.WriteString(":")
+
+	for n, step := range r.Steps {
+		if n > 0 {
+			sb.WriteString(";")
+		}
+
+		sb.WriteString(" ")
+		sb.WriteString(step.Path)
+
+		if step.Target != "" {
+			sb.WriteString(" -> ")
+			sb.WriteString(step.Target)
+		}
+
+		if step.Err != nil {
+			sb.WriteString(" [")
+			sb.WriteString(step.Err.Error())
+			sb.WriteString("]")
+		}
+	}
+
+	sb.WriteString(")")
+
+	return sb.String()
+}
+
+func (r *ResolveError) Unwrap() error {
+	return r.Err
+}
+
 type resolver struct {
 	fullPath, path     string
 	cutAt              int
-	redirectsRemaining uint8
+	redirectsRemaining int
+	limit              int
+	noFollow           bool
+	normInsensitive    bool
+	buf                []byte
+
+	tracing bool
+	orig    string
+	steps   []ResolveStep
 }
 
-func (f *fsRO) getEntryWithoutCheck(path string) (directoryEntry, error) {
-	r := resolver{
+func (f *fsRO) newResolver(path string) resolver {
+	limit := f.symlinkLimit()
+
+	return resolver{
 		fullPath:           path,
 		path:               path,
-		redirectsRemaining: maxRedirects,
+		redirectsRemaining: limit,
+		limit:              limit,
+		noFollow:           f.noFollow,
+		normInsensitive:    f.normInsensitive,
+		tracing:            f.trace,
+		orig:               path,
 	}
+}
 
-	return r.resolve(f.de)
+func (f *fsRO) getEntryWithoutCheck(path string) (directoryEntry, error) {
+	r := f.newResolver(path)
+
+	de, err := r.resolve(f.de)
+	if err != nil {
+		return nil, r.wrap(err)
+	}
+
+	return de, nil
 }
 
 func (r *resolver) resolve(root directoryEntry) (directoryEntry, error) {
 	curr := root
+	at := "."
 
 	for r.path != "" {
-		if curr.Mode()&modeRead == 0 {
-			return nil, fs.ErrPermission
+		if curr.perm()&modeRead == 0 {
+			return nil, r.fail(at, fs.ErrPermission)
 		} else if name := r.splitOffNamePart(); isEmptyName(name) {
 			continue
-		} else if next, err := curr.getEntry(name); err != nil {
-			return nil, err
-		} else if next.Mode()&fs.ModeSymlink == 0 {
+		} else if next, err := getEntryNorm(curr, name, r.normInsensitive); err != nil {
+			return nil, r.fail(r.current(), err)
+		} else if at = r.current(); next.Mode()&fs.ModeSymlink == 0 {
+			r.step(at, next)
+
 			curr = next.directoryEntry
 
 			continue
+		} else if r.noFollow {
+			r.step(at, next)
+
+			return nil, r.fail(at, ErrNoFollow)
 		} else if err = r.handleSymlink(next); err != nil {
-			return nil, err
+			return nil, r.fail(at, err)
 		}
 
 		curr = root
+		at = "."
 	}
 
 	return curr, nil
 }
 
+// current returns the path resolved so far, up to and including the last
+// name split off of the path.
+func (r *resolver) current() string {
+	if r.path == "" {
+		return r.fullPath
+	}
+
+	return r.fullPath[:r.cutAt-1]
+}
+
+// step records the reaching of the given entry, when tracing.
+func (r *resolver) step(p string, de *dirEnt) {
+	if !r.tracing {
+		return
+	}
+
+	step := ResolveStep{Path: p, Mode: de.Mode()}
+
+	if step.Mode&fs.ModeSymlink != 0 {
+		step.Target, _ = de.string()
+	}
+
+	r.steps = append(r.steps, step)
+}
+
+// fail records the given error against the last step, or as a new step when
+// the last step was not for the given path, and returns the error.
+func (r *resolver) fail(p string, err error) error {
+	if r.tracing {
+		if n := len(r.steps) - 1; n >= 0 && r.steps[n].Path == p && r.steps[n].Err == nil {
+			r.steps[n].Err = err
+		} else {
+			r.steps = append(r.steps, ResolveStep{Path: p, Err: err})
+		}
+	}
+
+	return err
+}
+
+// wrap wraps the given error in a ResolveError, when tracing.
+func (r *resolver) wrap(err error) error {
+	if !r.tracing {
+		return err
+	}
+
+	return &ResolveError{Path: r.orig, Steps: r.steps, Err: err}
+}
+
 func (r *resolver) splitOffNamePart() string {
 	slashPos := strings.Index(r.path, "/")
 
@@ -65,25 +239,99 @@ func isEmptyName(name string) bool {
 	return name == "" || name == "."
 }
 
+// symlinkLimit returns the maximum number of symlinks that can be followed
+// while resolving a single path.
+func (r resolveConfig) symlinkLimit() int {
+	if r.maxSymlinks > 0 {
+		return r.maxSymlinks
+	}
+
+	return maxRedirects
+}
+
 func (r *resolver) handleSymlink(sym *dirEnt) error {
-	r.redirectsRemaining--
+	r.step(r.current(), sym)
+
+	dir := r.fullPath[:r.cutAt]
+
+	if r.path != "" {
+		dir = r.fullPath[:r.cutAt-len(sym.name)-1]
+	}
+
 	if r.redirectsRemaining == 0 {
-		return fs.ErrInvalid
+		return &SymlinkLimitError{Path: dir + sym.name, Limit: r.limit}
 	}
 
-	symPath, err := sym.string()
+	r.redirectsRemaining--
+
+	if size := len(r.fullPath) + int(sym.Size()) + 2; cap(r.buf) < size {
+		r.buf = make([]byte, 0, size)
+	}
+
+	buf := append(append(r.buf[:0], dir...), '/')
+	start := len(buf)
+
+	buf, err := sym.appendData(buf)
 	if err != nil {
 		return err
-	} else if strings.HasPrefix(symPath, "/") {
-		r.fullPath = path.Clean(symPath)[1:]
-	} else if r.path == "" {
-		r.fullPath = path.Join(r.fullPath[:r.cutAt], symPath)
-	} else {
-		r.fullPath = path.Join(r.fullPath[:r.cutAt-len(sym.name)-1], symPath, r.path)
+	} else if len(buf) > start && buf[start] == '/' {
+		buf = buf[:copy(buf, buf[start:])]
 	}
 
+	r.buf = cleanPath(append(append(buf, '/'), r.path...))
+	r.fullPath = string(r.buf)
 	r.path = r.fullPath
 	r.cutAt = 0
 
 	return nil
 }
+
+// cleanPath cleans the given path in place, treating it as being relative to
+// the root, so that any '..' elements that would go above the root are
+// removed.
+//
+// The resulting path has no leading or trailing slashes.
+// resolveDir returns the given path of a directory with any symlinks in it
+// resolved, or the path unchanged when it cannot be resolved.
+func (f *fsRO) resolveDir(dir string) string {
+	r := f.newResolver(dir)
+
+	if _, err := r.resolve(f.de); err == nil {
+		return r.fullPath
+	}
+
+	return dir
+}
+
+func cleanPath(p []byte) []byte {
+	var w int
+
+	for r := 0; r < len(p); {
+		switch {
+		case p[r] == '/':
+			r++
+		case p[r] == '.' && (r+1 == len(p) || p[r+1] == '/'):
+			r++
+		case p[r] == '.' && p[r+1] == '.' && (r+2 == len(p) || p[r+2] == '/'):
+			r += 2
+
+			for w > 0 {
+				if w--; p[w] == '/' {
+					break
+				}
+			}
+		default:
+			if w > 0 {
+				p[w] = '/'
+				w++
+			}
+
+			for ; r < len(p) && p[r] != '/'; r++ {
+				p[w] = p[r]
+				w++
+			}
+		}
+	}
+
+	return p[:w]
+}
@@ -0,0 +1,197 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+)
+
+func TestCleanPath(t *testing.T) {
+	for n, test := range [...]struct {
+		Input, Output string
+	}{
+		{"", ""},
+		{"/", ""},
+		{".", ""},
+		{"..", ""},
+		{"a", "a"},
+		{"/a/", "a"},
+		{"a//b", "a/b"},
+		{"a/./b", "a/b"},
+		{"a/../b", "b"},
+		{"../../a/b/../../c", "c"},
+		{"a/b/c/../../d/.", "a/d"},
+		{"abc/..d/.e/f..", "abc/..d/.e/f.."},
+	} {
+		if output := string(cleanPath([]byte(test.Input))); output != test.Output {
+			t.Errorf("test %d: expecting %q, got %q", n+1, test.Output, output)
+		}
+	}
+}
+
+func TestResolveSymlinks(t *testing.T) {
+	f := newBenchFS(t)
+
+	for n, test := range [...]struct {
+		Path, Contents string
+	}{
+		{"a/b/c/d/e/file", "data"},
+		{"link/d/e/file", "data"},
+		{"abs/d/e/file", "data"},
+		{"a/b/c/rel/e/file", "data"},
+		{"a/b/c/up/a/b/c/d/e/file", "data"},
+		{"a/b/c/above/a/b/c/d/e/file", "data"},
+		{"filelink", "data"},
+	} {
+		if data, err := f.ReadFile(test.Path); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if string(data) != test.Contents {
+			t.Errorf("test %d: expecting contents %q, got %q", n+1, test.Contents, data)
+		}
+	}
+}
+
+func newBenchFS(tb testing.TB) *FS {
+	tb.Helper()
+
+	f := New()
+
+	for n, fn := range [...]func() error{
+		func() error { return f.MkdirAll("a/b/c/d/e", fs.ModePerm) },
+		func() error { return writeFile(f, "a/b/c/d/e/file", "data") },
+		func() error { return f.Symlink("a/b/c", "link") },
+		func() error { return f.Symlink("/a/b/c", "abs") },
+		func() error { return f.Symlink("../c/d", "a/b/c/rel") },
+		func() error { return f.Symlink("../../..", "a/b/c/up") },
+		func() error { return f.Symlink("../../../../..", "a/b/c/above") },
+		func() error { return f.Symlink("a/b/c/rel/e/file", "filelink") },
+	} {
+		if err := fn(); err != nil {
+			tb.Fatalf("test %d: unexpected error: %s", n+1, err)
+		}
+	}
+
+	return f
+}
+
+func BenchmarkResolve(b *testing.B) {
+	f := newBenchFS(b)
+
+	for _, path := range [...]string{
+		"a/b/c/d/e/file",
+		"link/d/e/file",
+		"abs/d/e/file",
+		"a/b/c/rel/e/file",
+		"filelink",
+	} {
+		b.Run(path, func(b *testing.B) {
+			b.ReportAllocs()
+
+			for n := 0; n < b.N; n++ {
+				if _, err := f.getEntry(path); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkLStat(b *testing.B) {
+	f := newBenchFS(b)
+
+	for _, path := range [...]string{
+		".",
+		"a/b/c/d/e/file",
+		"link/d/e/file",
+		"a/b/c/rel",
+	} {
+		b.Run(path, func(b *testing.B) {
+			b.ReportAllocs()
+
+			for n := 0; n < b.N; n++ {
+				if _, err := f.getLEntry(path); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestWithResolveTrace(t *testing.T) {
+	f := New(WithResolveTrace())
+
+	if err := f.MkdirAll("a/b", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("../b", "a/link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Mkdir("b", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Mkdir("a/b/private", 0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Path  string
+		LStat bool
+		Steps []ResolveStep
+		Err   error
+	}{
+		{ // 1
+			Path: "a/link/missing/file",
+			Steps: []ResolveStep{
+				{Path: "a", Mode: fs.ModeDir | 0o755},
+				{Path: "a/link", Mode: fs.ModeSymlink | fs.ModePerm, Target: "../b"},
+				{Path: "b", Mode: fs.ModeDir | 0o755},
+				{Path: "b/missing", Err: fs.ErrNotExist},
+			},
+			Err: fs.ErrNotExist,
+		},
+		{ // 2
+			Path: "a/b/private/file",
+			Steps: []ResolveStep{
+				{Path: "a", Mode: fs.ModeDir | 0o755},
+				{Path: "a/b", Mode: fs.ModeDir | 0o755},
+				{Path: "a/b/private", Mode: fs.ModeDir, Err: fs.ErrPermission},
+			},
+			Err: fs.ErrPermission,
+		},
+		{ // 3
+			Path:  "a/link/missing",
+			LStat: true,
+			Steps: []ResolveStep{
+				{Path: "a", Mode: fs.ModeDir | 0o755},
+				{Path: "a/link", Mode: fs.ModeSymlink | fs.ModePerm, Target: "../b"},
+				{Path: "b", Mode: fs.ModeDir | 0o755},
+				{Path: "b/missing", Err: fs.ErrNotExist},
+			},
+			Err: fs.ErrNotExist,
+		},
+	} {
+		var err error
+
+		if test.LStat {
+			_, err = f.LStat(test.Path)
+		} else {
+			_, err = f.Stat(test.Path)
+		}
+
+		var re *ResolveError
+
+		if !errors.Is(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if !errors.As(err, &re) {
+			t.Errorf("test %d: expecting ResolveError, got %v", n+1, err)
+		} else if re.Path != test.Path {
+			t.Errorf("test %d: expecting path %q, got %q", n+1, test.Path, re.Path)
+		} else if !reflect.DeepEqual(re.Steps, test.Steps) {
+			t.Errorf("test %d: expecting steps %v, got %v", n+1, test.Steps, re.Steps)
+		}
+	}
+
+	if _, err := f.Seal().Open("a/link/missing"); !errors.As(err, new(*ResolveError)) {
+		t.Errorf("test 4: expecting ResolveError from sealed FS, got %v", err)
+	} else if _, err := New().Stat("missing/file"); errors.As(err, new(*ResolveError)) {
+		t.Errorf("test 5: expecting no ResolveError without tracing, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// SecureJoin joins the given untrusted path to the given root path, as with
+// FS.SecureJoin.
+func (f *fsRO) SecureJoin(root, unsafe string) (string, error) {
+	if !fs.ValidPath(root) {
+		return "", &fs.PathError{Op: "securejoin", Path: root, Err: fs.ErrInvalid}
+	}
+
+	var (
+		resolved  []string
+		limit     = f.symlinkLimit()
+		redirects = limit
+	)
+
+	for unsafe != "" {
+		var name string
+
+		name, unsafe, _ = strings.Cut(unsafe, slash)
+
+		if isEmptyName(name) {
+			continue
+		} else if name == ".." {
+			if len(resolved) > 0 {
+				resolved = resolved[:len(resolved)-1]
+			}
+
+			continue
+		}
+
+		p := path.Join(root, path.Join(resolved...), name)
+
+		de, err := f.getLEntry(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			resolved = append(resolved, name)
+
+			continue
+		} else if err != nil {
+			return "", &fs.PathError{Op: "securejoin", Path: p, Err: err}
+		} else if de.Mode()&fs.ModeSymlink == 0 {
+			resolved = append(resolved, name)
+
+			continue
+		}
+
+		if redirects == 0 {
+			return "", &fs.PathError{Op: "securejoin", Path: p, Err: &SymlinkLimitError{Path: p, Limit: limit}}
+		}
+
+		redirects--
+
+		target, err := de.string()
+		if err != nil {
+			return "", &fs.PathError{Op: "securejoin", Path: p, Err: err}
+		} else if strings.HasPrefix(target, slash) {
+			resolved = resolved[:0]
+		}
+
+		unsafe = target + slash + unsafe
+	}
+
+	return path.Join(root, path.Join(resolved...)), nil
+}
+
+// SecureJoin joins the given untrusted path to the given root path, resolving
+// any symlinks along the way, in the manner of filepath-securejoin, such that
+// the returned path is always at or below the root.
+//
+// Any '..' elements that would go above the root, and any absolute symlink
+// targets, are treated as referring to the root itself. Components that do not
+// exist are joined without resolution, and so the returned path need not
+// exist.
+//
+// The root must be a valid path, and is not itself resolved.
+func (f *FS) SecureJoin(root, unsafe string) (string, error) {
+	f.throttle.op()
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.fsRO.SecureJoin(root, unsafe)
+}
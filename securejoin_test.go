@@ -0,0 +1,109 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestSecureJoin(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("root/dir/sub", fs.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("root/dir/file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("secret", []byte("secret"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for target, link := range map[string]string{
+		"../../secret": "root/escape",
+		"/dir":         "root/absolute",
+		"sub":          "root/dir/relative",
+		"../..":        "root/dir/up",
+		"loop":         "root/loop",
+		"/etc/passwd":  "root/missing",
+	} {
+		if err := f.Symlink(target, link); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	for n, test := range [...]struct {
+		Root, Unsafe, Output string
+		Err                  error
+	}{
+		{ // 1
+			Root:   "root",
+			Unsafe: "dir/file",
+			Output: "root/dir/file",
+		},
+		{ // 2
+			Root:   "root",
+			Unsafe: "../../../secret",
+			Output: "root/secret",
+		},
+		{ // 3
+			Root:   "root",
+			Unsafe: "/dir/./sub/",
+			Output: "root/dir/sub",
+		},
+		{ // 4
+			Root:   "root",
+			Unsafe: "escape",
+			Output: "root/secret",
+		},
+		{ // 5
+			Root:   "root",
+			Unsafe: "absolute/file",
+			Output: "root/dir/file",
+		},
+		{ // 6
+			Root:   "root",
+			Unsafe: "dir/relative/../file",
+			Output: "root/dir/file",
+		},
+		{ // 7
+			Root:   "root",
+			Unsafe: "dir/up/../../secret",
+			Output: "root/secret",
+		},
+		{ // 8
+			Root:   "root",
+			Unsafe: "missing",
+			Output: "root/etc/passwd",
+		},
+		{ // 9
+			Root:   "root",
+			Unsafe: "new/../new/file",
+			Output: "root/new/file",
+		},
+		{ // 10
+			Root:   "root",
+			Unsafe: "",
+			Output: "root",
+		},
+		{ // 11
+			Root:   ".",
+			Unsafe: "../root/escape",
+			Output: "secret",
+		},
+		{ // 12
+			Root:   "root",
+			Unsafe: "loop",
+			Err:    fs.ErrInvalid,
+		},
+		{ // 13
+			Root:   "../root",
+			Unsafe: "dir",
+			Err:    fs.ErrInvalid,
+		},
+	} {
+		if output, err := f.SecureJoin(test.Root, test.Unsafe); !errors.Is(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if output != test.Output {
+			t.Errorf("test %d: expecting output %q, got %q", n+1, test.Output, output)
+		}
+	}
+}
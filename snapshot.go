@@ -0,0 +1,140 @@
+package memfs
+
+import "slices"
+
+// Snapshot creates a Read-only copy of the FS as it is at the time of the call,
+// leaving the FS writable.
+//
+// Only the directory structure is copied, with the data of each file being
+// shared between the FS and the snapshot until the file is next modified in
+// the FS, including through a File opened before the snapshot was taken, at
+// which point the file in the FS is given its own copy, leaving the data seen
+// by the snapshot unchanged. Each file is copied at most once per snapshot,
+// regardless of the number of writes made to it.
+//
+// Files that are hard linked in the FS remain linked in the snapshot.
+//
+// Data shared with a snapshot is not returned to any Allocator set with
+// WithAllocator.
+func (f *FS) Snapshot() FSRO {
+	return f.snapshot()
+}
+
+// snapshot takes a Snapshot of the FS, holding the lock only while the
+// directory structure is copied, such that long running exports can read a
+// consistent view of the FS without blocking any writers.
+func (f *FS) snapshot() *fsRO {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	s := snapshotter{
+		files: make(map[*inodeRW]*inode),
+		lazy:  make(map[*lazyNode]*lazyNode),
+	}
+
+	return &fsRO{
+		de:            s.entry(f.de),
+		resolveConfig: f.resolveConfig,
+		partial:       f.partial,
+	}
+}
+
+type snapshotter struct {
+	files map[*inodeRW]*inode
+	lazy  map[*lazyNode]*lazyNode
+}
+
+func (s *snapshotter) entry(de directoryEntry) directoryEntry {
+	switch de := de.(type) {
+	case *dnodeRW:
+		return s.dir(de.view())
+	case *inodeRW:
+		return s.file(de)
+	case *fifo:
+		return s.fifo(de)
+	case *lazyNode:
+		return s.lazyNode(de)
+	}
+
+	return de
+}
+
+func (s *snapshotter) dir(v *dnode) *dnode {
+	d := &dnode{
+		entries: make([]*dirEnt, len(v.entries)),
+		modtime: v.modtime,
+		ctime:   v.ctime,
+		mode:    v.mode,
+		sealed:  true,
+		gen:     v.gen,
+		acl:     v.acl,
+		size:    v.size,
+	}
+
+	for n, e := range v.entries {
+		d.entries[n] = &dirEnt{
+			directoryEntry: s.entry(e.directoryEntry),
+			name:           e.name,
+			gen:            e.gen,
+		}
+	}
+
+	return d
+}
+
+func (s *snapshotter) file(i *inodeRW) *inode {
+	if c, ok := s.files[i]; ok {
+		return c
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	c := &inode{
+		modtime: i.modtime,
+		ctime:   i.ctime,
+		data:    i.data[:len(i.data):len(i.data)],
+		mode:    i.mode,
+		sealed:  true,
+		gen:     i.gen,
+		links:   i.links,
+		shared:  true,
+		etag:    new(etagOnce),
+	}
+
+	if i.meta != nil {
+		meta := *i.meta
+		meta.acl = slices.Clone(meta.acl)
+		c.meta = &meta
+	}
+
+	i.shared = true
+	s.files[i] = c
+
+	return c
+}
+
+func (s *snapshotter) fifo(p *fifo) *fifo {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return &fifo{
+		modtime: p.modtime,
+		ctime:   p.ctime,
+		mode:    p.mode,
+		sealed:  true,
+		gen:     p.gen,
+		acl:     p.acl,
+	}
+}
+
+func (s *snapshotter) lazyNode(l *lazyNode) *lazyNode {
+	if c, ok := s.lazy[l]; ok {
+		return c
+	}
+
+	c := l.share(true)
+	s.lazy[l] = c
+
+	return c
+}
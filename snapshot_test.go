@@ -0,0 +1,184 @@
+package memfs
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	var alloc freeCounter
+
+	f := New(WithAllocator(&alloc))
+
+	if err := f.Mkdir("dir", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("file", []byte("before"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Link("file", "dir/link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("dir/other", []byte("other"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	of, err := f.OpenFile("file", ReadWrite, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	a := f.Snapshot()
+
+	if _, err := of.WriteAt([]byte("after!"), 0); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	}
+
+	b := f.Snapshot()
+
+	if err := of.Truncate(3); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if err := f.WriteFile("dir/other", []byte("changed"), 0o600); err != nil {
+		t.Fatalf("test 3: unexpected error: %s", err)
+	} else if err := f.Remove("dir/link"); err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	} else if err := f.Mkdir("new", 0o755); err != nil {
+		t.Fatalf("test 5: unexpected error: %s", err)
+	} else if err := of.Close(); err != nil {
+		t.Fatalf("test 6: unexpected error: %s", err)
+	} else if err := f.Remove("file"); err != nil {
+		t.Fatalf("test 7: unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		FS       fs.FS
+		Path     string
+		Contents string
+		Err      error
+	}{
+		{ // 8
+			FS:       a,
+			Path:     "file",
+			Contents: "before",
+		},
+		{ // 9
+			FS:       a,
+			Path:     "dir/link",
+			Contents: "before",
+		},
+		{ // 10
+			FS:       a,
+			Path:     "dir/other",
+			Contents: "other",
+		},
+		{ // 11
+			FS:   a,
+			Path: "new",
+			Err:  fs.ErrNotExist,
+		},
+		{ // 12
+			FS:       b,
+			Path:     "file",
+			Contents: "after!",
+		},
+		{ // 13
+			FS:       b,
+			Path:     "dir/link",
+			Contents: "after!",
+		},
+		{ // 14
+			FS:       f,
+			Path:     "dir/other",
+			Contents: "changed",
+		},
+		{ // 15
+			FS:   f,
+			Path: "file",
+			Err:  fs.ErrNotExist,
+		},
+	} {
+		if data, err := fs.ReadFile(test.FS, test.Path); !errors.Is(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+8, test.Err, err)
+		} else if string(data) != test.Contents {
+			t.Errorf("test %d: expecting to read %q, got %q", n+8, test.Contents, data)
+		}
+	}
+
+	if fi, err := fs.Stat(a, "file"); err != nil {
+		t.Errorf("test 16: unexpected error: %s", err)
+	} else if l, ok := fi.Sys().(Linked); !ok {
+		t.Errorf("test 16: expecting Sys to implement Linked")
+	} else if l.Links() != 2 {
+		t.Errorf("test 16: expecting 2 links, got %d", l.Links())
+	}
+
+	if l, err := Layer(a); err != nil {
+		t.Errorf("test 17: unexpected error: %s", err)
+	} else if err := l.WriteFile("file", []byte("layer"), 0o644); err != nil {
+		t.Errorf("test 17: unexpected error: %s", err)
+	} else if data, err := a.ReadFile("dir/link"); err != nil {
+		t.Errorf("test 17: unexpected error: %s", err)
+	} else if string(data) != "before" {
+		t.Errorf("test 17: expecting to read %q, got %q", "before", data)
+	}
+
+	if alloc.frees != 0 {
+		t.Errorf("test 18: expecting shared data not to be freed, got %d frees", alloc.frees)
+	}
+}
+
+func TestSnapshotConcurrent(t *testing.T) {
+	const (
+		files  = 4
+		size   = 64
+		writes = 200
+	)
+
+	f := New()
+
+	for n := 0; n < files; n++ {
+		if err := f.WriteFile(strconv.Itoa(n), bytes.Repeat([]byte{'a'}, size), 0o644); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+
+	for n := 0; n < files; n++ {
+		of, err := f.OpenFile(strconv.Itoa(n), ReadWrite, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer of.Close()
+
+			for m := 0; m < writes; m++ {
+				of.WriteAt(bytes.Repeat([]byte{'a' + byte(m%26)}, size), 0)
+			}
+		}()
+	}
+
+	var snapshots []FSRO
+
+	for i := 0; i < writes; i++ {
+		snapshots = append(snapshots, f.Snapshot())
+	}
+
+	wg.Wait()
+
+	for n, s := range snapshots {
+		for m := 0; m < files; m++ {
+			data, err := s.ReadFile(strconv.Itoa(m))
+			if err != nil {
+				t.Fatalf("test %d: unexpected error: %s", n+1, err)
+			} else if len(data) != size || !bytes.Equal(data, bytes.Repeat(data[:1], size)) {
+				t.Errorf("test %d: expecting file %d to contain a single complete write, got %q", n+1, m, data)
+			}
+		}
+	}
+}
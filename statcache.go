@@ -0,0 +1,106 @@
+package memfs
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// WithStatCache enables a cache, of up to the given number of paths, of the
+// entries found by resolving the paths given to Stat, Open, ReadFile, and the
+// other methods that follow symlinks, in the FS and in any FS created from it
+// with Sub, so that repeated access to the same paths does not require each
+// path to be resolved again.
+//
+// The cache is emptied whenever an entry is renamed or removed, or has its
+// permissions changed, by the FS or by any FS created from it with Sub, but not
+// by writes to files or by the creation of new entries, so it is of most
+// benefit to an FS whose existing entries are rarely moved. A size of zero or
+// less disables the cache.
+func WithStatCache(size int) Option {
+	return func(f *FS) {
+		f.cache = newStatCache(size, new(atomic.Uint64))
+	}
+}
+
+type statCache struct {
+	mu      sync.Mutex
+	at      uint64
+	size    int
+	entries map[string]directoryEntry
+
+	// changes is shared with the caches of any FS created with Sub, and is
+	// incremented by invalidate.
+	changes *atomic.Uint64
+}
+
+func newStatCache(size int, changes *atomic.Uint64) *statCache {
+	if size <= 0 {
+		return nil
+	}
+
+	return &statCache{
+		size:    size,
+		entries: make(map[string]directoryEntry),
+		changes: changes,
+	}
+}
+
+// invalidate empties the cache, and those of any related FS, to be called after
+// any change that could alter the entry, or the permissions checked, when
+// resolving a path.
+func (s *statCache) invalidate() {
+	if s != nil {
+		s.changes.Add(1)
+	}
+}
+
+// get returns the cached entry for the given path, if any, along with the
+// current count of changes, which should be given to set when caching a newly
+// resolved entry.
+func (s *statCache) get(p string) (directoryEntry, uint64) {
+	if s == nil {
+		return nil, 0
+	}
+
+	at := s.changes.Load()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if at != s.at {
+		for p := range s.entries {
+			delete(s.entries, p)
+		}
+
+		s.at = at
+
+		return nil, at
+	}
+
+	return s.entries[p], at
+}
+
+// set caches the entry for the given path, as resolved at the given count of
+// changes.
+func (s *statCache) set(p string, de directoryEntry, at uint64) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if at != s.at {
+		return
+	}
+
+	if len(s.entries) >= s.size {
+		for k := range s.entries {
+			delete(s.entries, k)
+
+			break
+		}
+	}
+
+	s.entries[p] = de
+}
@@ -0,0 +1,106 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestWithStatCache(t *testing.T) {
+	f := New(WithStatCache(2))
+
+	if err := f.MkdirAll("a/b", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("a/b/file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("a/b", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := f.Stat("link/file"); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	} else if _, ok := f.cache.entries["link/file"]; !ok {
+		t.Errorf("test 1: expecting path to be cached")
+	} else if data, err := f.ReadFile("link/file"); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if string(data) != "data" {
+		t.Errorf("test 2: expecting contents %q, got %q", "data", data)
+	} else if _, err := f.Stat("a"); err != nil {
+		t.Fatalf("test 3: unexpected error: %s", err)
+	} else if _, err := f.Stat("a/b"); err != nil {
+		t.Fatalf("test 3: unexpected error: %s", err)
+	} else if l := len(f.cache.entries); l != 2 {
+		t.Errorf("test 3: expecting %d cached paths, got %d", 2, l)
+	}
+
+	if err := f.Rename("a/b/file", "a/b/moved"); err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	} else if _, err := f.Stat("link/file"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("test 4: expecting error %v, got %v", fs.ErrNotExist, err)
+	} else if err := f.WriteFile("a/b/file", []byte("new"), 0o644); err != nil {
+		t.Fatalf("test 5: unexpected error: %s", err)
+	} else if data, err := f.ReadFile("link/file"); err != nil {
+		t.Fatalf("test 5: unexpected error: %s", err)
+	} else if string(data) != "new" {
+		t.Errorf("test 5: expecting contents %q, got %q", "new", data)
+	} else if err := f.Chmod("a", 0); err != nil {
+		t.Fatalf("test 6: unexpected error: %s", err)
+	} else if _, err := f.Stat("link/file"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("test 6: expecting error %v, got %v", fs.ErrPermission, err)
+	} else if err := f.Chmod("a", 0o755); err != nil {
+		t.Fatalf("test 7: unexpected error: %s", err)
+	}
+
+	sub, err := f.Sub("a")
+	if err != nil {
+		t.Fatalf("test 7: unexpected error: %s", err)
+	}
+
+	sf := sub.(*FS)
+
+	if sf.cache == nil || sf.cache == f.cache {
+		t.Errorf("test 7: expecting Sub to have its own cache")
+	} else if data, err := fs.ReadFile(sub, "b/file"); err != nil {
+		t.Fatalf("test 8: unexpected error: %s", err)
+	} else if string(data) != "new" {
+		t.Errorf("test 8: expecting contents %q, got %q", "new", data)
+	} else if _, ok := sf.cache.entries["b/file"]; !ok {
+		t.Errorf("test 8: expecting path to be cached")
+	}
+
+	other := New()
+
+	if err := f.WriteFile("a/b/file", []byte("changed"), 0o644); err != nil {
+		t.Fatalf("test 9: unexpected error: %s", err)
+	} else if err := f.WriteFile("a/b/new", []byte("new"), 0o644); err != nil {
+		t.Fatalf("test 9: unexpected error: %s", err)
+	} else if err := other.Mkdir("dir", 0o755); err != nil {
+		t.Fatalf("test 9: unexpected error: %s", err)
+	} else if err := other.Remove("dir"); err != nil {
+		t.Fatalf("test 9: unexpected error: %s", err)
+	} else if _, ok := sf.cache.entries["b/file"]; !ok {
+		t.Errorf("test 9: expecting path to remain cached after writes and changes to another FS")
+	} else if data, err := fs.ReadFile(sub, "b/file"); err != nil {
+		t.Fatalf("test 10: unexpected error: %s", err)
+	} else if string(data) != "changed" {
+		t.Errorf("test 10: expecting contents %q, got %q", "changed", data)
+	} else if _, err := fs.Stat(sub, "b"); err != nil {
+		t.Fatalf("test 11: unexpected error: %s", err)
+	} else if err := f.Remove("a/b/new"); err != nil {
+		t.Fatalf("test 11: unexpected error: %s", err)
+	} else if _, err := fs.ReadFile(sub, "b/file"); err != nil {
+		t.Fatalf("test 11: unexpected error: %s", err)
+	} else if l := len(sf.cache.entries); l != 1 {
+		t.Errorf("test 11: expecting removal through the parent to empty the cache of the Sub, got %d cached paths", l)
+	} else if _, err := f.Stat("link/file"); err != nil {
+		t.Fatalf("test 12: unexpected error: %s", err)
+	} else if err := sf.Rename("b/file", "b/moved"); err != nil {
+		t.Fatalf("test 12: unexpected error: %s", err)
+	} else if _, err := f.Stat("link/file"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("test 12: expecting error %v, got %v", fs.ErrNotExist, err)
+	}
+
+	if New(WithStatCache(0)).cache != nil {
+		t.Errorf("test 13: expecting no cache for a size of zero")
+	}
+}
@@ -0,0 +1,151 @@
+package memfs
+
+import (
+	"cmp"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Stats contains a summary of the contents of an FS.
+type Stats struct {
+	Dirs, Files, Symlinks int
+
+	// Size is the total size of all regular files, with files that are hard
+	// linked only being counted once.
+	Size int64
+
+	// Largest contains the largest regular files, largest first.
+	Largest []PathSize
+
+	// Deepest contains the paths of the most deeply nested entries, deepest
+	// first.
+	Deepest []string
+}
+
+// PathSize is the path and size of a file.
+type PathSize struct {
+	Path string
+	Size int64
+}
+
+// Stats walks the entire FS, ignoring permissions, and returns a summary of its
+// contents, as with FS.Stats.
+func (f *fsRO) Stats(n int) Stats {
+	s := statter{
+		n:    n,
+		seen: make(map[any]bool),
+	}
+
+	s.walk(".", f.de)
+
+	return s.Stats
+}
+
+// Stats walks the entire FS, ignoring permissions, and returns the counts of
+// each type of entry, the total size of all regular files, and the paths of the
+// n largest files and the n most deeply nested entries.
+func (f *FS) Stats(n int) Stats {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.fsRO.Stats(n)
+}
+
+type statter struct {
+	Stats
+	n     int
+	depth []int
+	seen  map[any]bool
+}
+
+func (s *statter) walk(p string, de directoryEntry) {
+	for _, e := range childEntries(de) {
+		cp := path.Join(p, e.name)
+
+		s.addDeepest(cp)
+
+		switch m := e.Mode(); {
+		case m.IsDir():
+			s.Dirs++
+
+			s.walk(cp, e.directoryEntry)
+		case m&fs.ModeSymlink != 0:
+			s.Symlinks++
+		default:
+			s.Files++
+
+			size := e.Size()
+
+			if key := nodeKey(e.directoryEntry); !s.seen[key] {
+				s.seen[key] = true
+				s.Size += size
+			}
+
+			s.addLargest(PathSize{Path: cp, Size: size})
+		}
+	}
+}
+
+func (s *statter) addLargest(ps PathSize) {
+	pos, _ := slices.BinarySearchFunc(s.Largest, ps, func(a, b PathSize) int {
+		if c := cmp.Compare(b.Size, a.Size); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	if pos < s.n {
+		if s.Largest = slices.Insert(s.Largest, pos, ps); len(s.Largest) > s.n {
+			s.Largest = s.Largest[:s.n]
+		}
+	}
+}
+
+func (s *statter) addDeepest(p string) {
+	depth := strings.Count(p, "/") + 1
+
+	pos, _ := slices.BinarySearchFunc(s.depth, depth, func(a, b int) int {
+		return b - a
+	})
+
+	for pos < len(s.Deepest) && s.depth[pos] == depth && s.Deepest[pos] < p {
+		pos++
+	}
+
+	if pos < s.n {
+		s.Deepest = slices.Insert(s.Deepest, pos, p)
+		s.depth = slices.Insert(s.depth, pos, depth)
+
+		if len(s.Deepest) > s.n {
+			s.Deepest = s.Deepest[:s.n]
+			s.depth = s.depth[:s.n]
+		}
+	}
+}
+
+func childEntries(de directoryEntry) []*dirEnt {
+	switch de := de.(type) {
+	case *dnodeRW:
+		return de.view().entries
+	case *dnode:
+		return de.entries
+	case packedNode:
+		if de.IsDir() {
+			return de.children()
+		}
+	}
+
+	return nil
+}
+
+// nodeKey returns a value that is the same for all hard links to a file.
+func nodeKey(de directoryEntry) any {
+	if p, ok := de.(packedNode); ok {
+		return packedData{packed: p.packed, off: p.field(packedDataOff), len: p.field(packedDataLen)}
+	}
+
+	return de
+}
@@ -0,0 +1,65 @@
+package memfs
+
+import (
+	"io/fs"
+	"reflect"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	f := New()
+
+	for _, dir := range [...]string{"a/b/c", "d/e"} {
+		if err := f.MkdirAll(dir, fs.ModePerm); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	for file, contents := range map[string]string{
+		"file":      "12345",
+		"a/b/c/big": "1234567890",
+		"d/e/small": "1",
+		"d/medium":  "1234567",
+	} {
+		if err := f.WriteFile(file, []byte(contents), 0o644); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if err := f.Link("a/b/c/big", "d/e/bigLink"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("a/b/c", "link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Chmod("a/b", 0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := Stats{
+		Dirs:     5,
+		Files:    5,
+		Symlinks: 1,
+		Size:     23,
+		Largest: []PathSize{
+			{Path: "a/b/c/big", Size: 10},
+			{Path: "d/e/bigLink", Size: 10},
+			{Path: "d/medium", Size: 7},
+		},
+		Deepest: []string{
+			"a/b/c/big",
+			"a/b/c",
+			"d/e/bigLink",
+		},
+	}
+
+	if s := f.Stats(3); !reflect.DeepEqual(s, expected) {
+		t.Errorf("test 1: expecting stats %v, got %v", expected, s)
+	}
+
+	if s := sealCompact(t, f).Stats(3); !reflect.DeepEqual(s, expected) {
+		t.Errorf("test 2: expecting stats %v, got %v", expected, s)
+	}
+
+	if s := f.Stats(0); s.Largest != nil || s.Deepest != nil || s.Files != 5 {
+		t.Errorf("test 3: expecting no paths and 5 files, got %v", s)
+	}
+}
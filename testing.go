@@ -0,0 +1,93 @@
+package memfs
+
+import (
+	"math"
+	"path"
+	"strings"
+	"testing"
+)
+
+// NewTest creates a new FS, configured with the given Options, for use in the
+// test, or benchmark, given as tb, failing it if any File, directory, or pipe
+// opened from the FS, or from any FS created from it with Sub, is still open
+// once it, and all of its subtests, have completed.
+//
+// Files that were removed while still open are included, and so the FS is
+// always created with the WithOrphanTracking Option. Open handles are counted
+// as with the WithMaxOpenFiles Option, which, if not given, is set to be
+// unlimited.
+func NewTest(tb testing.TB, opts ...Option) *FS {
+	tb.Helper()
+
+	f := New(append([]Option{WithOrphanTracking()}, opts...)...)
+
+	if f.handles == nil {
+		f.handles = &openLimit{max: math.MaxInt}
+	}
+
+	tb.Cleanup(func() {
+		open, files := f.openFiles()
+
+		if len(open) > 0 {
+			tb.Errorf("memfs: %d file(s) left open: %s", len(open), strings.Join(open, ", "))
+		}
+
+		if n := f.handles.count() - files; n > 0 {
+			tb.Errorf("memfs: %d directory or pipe handle(s) left open", n)
+		}
+	})
+
+	return f
+}
+
+// openFiles returns the paths of all of the files in the FS that have open
+// Files, along with a placeholder for each removed file that is still open,
+// and the total number of open Files.
+func (f *FS) openFiles() ([]string, int) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var (
+		open  []string
+		files int
+		seen  = make(map[*inodeRW]struct{})
+	)
+
+	var walk func(string, directoryEntry)
+
+	walk = func(p string, de directoryEntry) {
+		for _, e := range childEntries(de) {
+			name := path.Join(p, e.name)
+
+			if i, ok := fileNode(e.directoryEntry); ok {
+				i.mu.RLock()
+				opens := i.opens
+				i.mu.RUnlock()
+
+				if _, ok := seen[i]; !ok && opens > 0 {
+					seen[i] = struct{}{}
+					open = append(open, name)
+					files += opens
+				}
+			} else {
+				walk(name, e.directoryEntry)
+			}
+		}
+	}
+
+	walk("", f.de)
+
+	if f.orphans != nil {
+		f.orphans.each(func(_ directoryEntry, i *inodeRW) {
+			if _, ok := seen[i]; !ok {
+				i.mu.RLock()
+				files += i.opens
+				i.mu.RUnlock()
+
+				open = append(open, "(removed)")
+			}
+		})
+	}
+
+	return open, files
+}
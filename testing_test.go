@@ -0,0 +1,126 @@
+package memfs
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type fakeTB struct {
+	testing.TB
+	cleanups []func()
+	errors   []string
+}
+
+func (f *fakeTB) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) cleanup() {
+	for _, fn := range f.cleanups {
+		fn()
+	}
+}
+
+func TestNewTest(t *testing.T) {
+	tb := &fakeTB{TB: t}
+	f := NewTest(tb)
+
+	if err := f.WriteFile("closed", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if of, err := f.Open("closed"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := of.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tb.cleanup()
+
+	if len(tb.errors) != 0 {
+		t.Errorf("test 1: expecting no errors, got %v", tb.errors)
+	}
+
+	tb = &fakeTB{TB: t}
+	f = NewTest(tb)
+
+	if err := f.Mkdir("dir", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if _, err := f.Create("dir/open"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if _, err := f.Create("removed"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Remove("removed"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tb.cleanup()
+
+	if expected := "memfs: 2 file(s) left open: dir/open, (removed)"; len(tb.errors) != 1 || tb.errors[0] != expected {
+		t.Errorf("test 2: expecting error %q, got %v", expected, tb.errors)
+	}
+
+	tb = &fakeTB{TB: t}
+	f = NewTest(tb)
+
+	if err := f.CreateSparse("sparse", 10); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.CreateLazy("lazy", 10, new(patternFill).fill); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if _, err := f.Open("sparse"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if _, err := f.Open("lazy"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Remove("lazy"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tb.cleanup()
+
+	if expected := "memfs: 2 file(s) left open: sparse, (removed)"; len(tb.errors) != 1 || tb.errors[0] != expected {
+		t.Errorf("test 3: expecting error %q, got %v", expected, tb.errors)
+	}
+
+	tb = &fakeTB{TB: t}
+	f = NewTest(tb)
+
+	if err := f.Mkdir("dir", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Mkfifo("dir/fifo", 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if _, err := f.Open("dir"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if _, err := f.OpenDir("."); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if _, err := f.OpenPipe("dir/fifo", WriteOnly); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if _, err := f.Create("file"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if od, err := f.OpenDir("dir"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := od.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tb.cleanup()
+
+	if expected := []string{"memfs: 1 file(s) left open: file", "memfs: 3 directory or pipe handle(s) left open"}; !reflect.DeepEqual(tb.errors, expected) {
+		t.Errorf("test 4: expecting errors %q, got %q", expected, tb.errors)
+	}
+
+	tb = &fakeTB{TB: t}
+	f = NewTest(tb, WithMaxOpenFiles(0))
+
+	if _, err := f.OpenDir("."); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tb.cleanup()
+
+	if expected := []string{"memfs: 1 directory or pipe handle(s) left open"}; !reflect.DeepEqual(tb.errors, expected) {
+		t.Errorf("test 5: expecting errors %q, got %q", expected, tb.errors)
+	}
+}
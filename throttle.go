@@ -0,0 +1,110 @@
+package memfs
+
+import (
+	"sync"
+	"time"
+)
+
+// Throttle describes the simulated performance of the storage behind an FS, as
+// set with WithThrottle.
+type Throttle struct {
+	// OpenLatency is added to each opening of a file or directory,
+	// including by ReadFile and WriteFile.
+	OpenLatency time.Duration
+
+	// ReadLatency and WriteLatency are added to each call to a read or
+	// write method of a File.
+	ReadLatency, WriteLatency time.Duration
+
+	// OpLatency is added to each call to any other method of the FS that
+	// reads or modifies the tree, such as Stat, ReadDir, Mkdir and Remove.
+	OpLatency time.Duration
+
+	// ReadRate and WriteRate are the number of bytes per second that can be
+	// read from, and written to, all of the files of the FS combined. A
+	// rate of zero or less is unlimited.
+	ReadRate, WriteRate int64
+}
+
+// WithThrottle slows down the FS, and any FS created from it with Sub, to
+// simulate the given latencies and throughput of slower storage.
+//
+// The throughput is shared between all concurrent readers, and all concurrent
+// writers, with each transfer waiting for those started before it; no locks
+// are held while waiting.
+func WithThrottle(t Throttle) Option {
+	return func(f *FS) {
+		f.throttle = &throttle{
+			Throttle: t,
+			sleep:    time.Sleep,
+		}
+	}
+}
+
+type throttle struct {
+	Throttle
+	sleep func(time.Duration)
+
+	mu                  sync.Mutex
+	readFree, writeFree time.Time
+}
+
+func (t *throttle) open() {
+	if t != nil {
+		t.sleep(t.OpenLatency)
+	}
+}
+
+func (t *throttle) op() {
+	if t != nil {
+		t.sleep(t.OpLatency)
+	}
+}
+
+// read waits for the read of the pointed to number of bytes, taking a pointer
+// so that it can be deferred.
+func (t *throttle) read(n *int) {
+	if t != nil {
+		t.wait(t.ReadLatency, t.ReadRate, &t.readFree, int64(*n))
+	}
+}
+
+func (t *throttle) read64(n *int64) {
+	if t != nil {
+		t.wait(t.ReadLatency, t.ReadRate, &t.readFree, *n)
+	}
+}
+
+// write waits for the write of the pointed to number of bytes, taking a
+// pointer so that it can be deferred.
+func (t *throttle) write(n *int) {
+	if t != nil {
+		t.wait(t.WriteLatency, t.WriteRate, &t.writeFree, int64(*n))
+	}
+}
+
+func (t *throttle) write64(n *int64) {
+	if t != nil {
+		t.wait(t.WriteLatency, t.WriteRate, &t.writeFree, *n)
+	}
+}
+
+func (t *throttle) wait(latency time.Duration, rate int64, free *time.Time, n int64) {
+	if rate > 0 && n > 0 {
+		t.mu.Lock()
+
+		now := time.Now()
+		start := *free
+
+		if start.Before(now) {
+			start = now
+		}
+
+		*free = start.Add(time.Duration(n * int64(time.Second) / rate))
+		latency += free.Sub(now)
+
+		t.mu.Unlock()
+	}
+
+	t.sleep(latency)
+}
@@ -0,0 +1,106 @@
+package memfs
+
+import (
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	f := New(WithThrottle(Throttle{
+		OpenLatency:  time.Millisecond,
+		ReadLatency:  2 * time.Millisecond,
+		WriteLatency: 3 * time.Millisecond,
+		OpLatency:    4 * time.Millisecond,
+		ReadRate:     1000,
+		WriteRate:    100,
+	}))
+
+	var slept []time.Duration
+
+	f.throttle.sleep = func(d time.Duration) {
+		slept = append(slept, d)
+	}
+
+	const tolerance = 100 * time.Millisecond
+
+	for n, test := range [...]struct {
+		Op     func() error
+		Sleeps []time.Duration
+	}{
+		{ // 1
+			Op: func() error {
+				return f.Mkdir("dir", fs.ModePerm)
+			},
+			Sleeps: []time.Duration{4 * time.Millisecond},
+		},
+		{ // 2
+			Op: func() error {
+				return f.WriteFile("dir/file", []byte(strings.Repeat("a", 500)), 0o644)
+			},
+			Sleeps: []time.Duration{time.Millisecond, 5*time.Second + 3*time.Millisecond},
+		},
+		{ // 3
+			Op: func() error {
+				_, err := f.Stat("dir/file")
+
+				return err
+			},
+			Sleeps: []time.Duration{4 * time.Millisecond},
+		},
+		{ // 4
+			Op: func() error {
+				of, err := f.Open("dir/file")
+				if err != nil {
+					return err
+				}
+
+				buf := make([]byte, 1000)
+
+				if _, err := of.Read(buf); err != nil {
+					return err
+				} else if _, err := of.Read(buf); err != io.EOF {
+					return err
+				}
+
+				return nil
+			},
+			Sleeps: []time.Duration{time.Millisecond, 500*time.Millisecond + 2*time.Millisecond, 2 * time.Millisecond},
+		},
+		{ // 5
+			Op: func() error {
+				_, err := f.ReadFile("dir/file")
+
+				return err
+			},
+			Sleeps: []time.Duration{time.Millisecond, time.Second + 2*time.Millisecond},
+		},
+		{ // 6
+			Op: func() error {
+				sub, err := f.Sub("dir")
+				if err != nil {
+					return err
+				}
+
+				return sub.(*FS).Remove("file")
+			},
+			Sleeps: []time.Duration{4 * time.Millisecond},
+		},
+	} {
+		slept = slept[:0]
+
+		if err := test.Op(); err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if len(slept) != len(test.Sleeps) {
+			t.Errorf("test %d: expecting %d sleeps, got %d", n+1, len(test.Sleeps), len(slept))
+		} else {
+			for m, d := range test.Sleeps {
+				if slept[m] > d || slept[m] < d-tolerance {
+					t.Errorf("test %d.%d: expecting to sleep for %s, slept for %s", n+1, m+1, d, slept[m])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package memfs
+
+import (
+	"io"
+	"io/fs"
+)
+
+// view forwards the read-only methods of an FS, holding it in an unexported
+// field so that it cannot be recovered from the view.
+type view struct {
+	fs *FS
+}
+
+// View returns a read-only view of the FS.
+//
+// Unlike Seal, the FS is not changed and remains writable; any changes made
+// to it are visible through the returned FSRO, which reads with the same
+// locking as the FS. The FSRO itself, any FSRO created from it with Sub, and
+// any File opened from it, cannot be used to modify the FS, making it suitable
+// for passing to untrusted code.
+func (f *FS) View() FSRO {
+	return view{fs: f}
+}
+
+func (v view) Open(path string) (fs.File, error) {
+	return v.fs.Open(path)
+}
+
+func (v view) ReadDir(path string) ([]fs.DirEntry, error) {
+	return v.fs.ReadDir(path)
+}
+
+func (v view) ReadFile(path string) ([]byte, error) {
+	return v.fs.ReadFile(path)
+}
+
+func (v view) Stat(path string) (fs.FileInfo, error) {
+	return v.fs.Stat(path)
+}
+
+func (v view) Sub(path string) (fs.FS, error) {
+	sub, err := v.fs.Sub(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return view{fs: sub.(*FS)}, nil
+}
+
+func (v view) Check() error {
+	return v.fs.Check()
+}
+
+func (v view) ContentsEqual(path1, path2 string) (bool, error) {
+	return v.fs.ContentsEqual(path1, path2)
+}
+
+func (v view) Exists(path string) (bool, error) {
+	return v.fs.Exists(path)
+}
+
+func (v view) GetACL(path string) (ACL, error) {
+	return v.fs.GetACL(path)
+}
+
+func (v view) GlobStar(pattern string) ([]string, error) {
+	return v.fs.GlobStar(pattern)
+}
+
+func (v view) IsDir(path string) (bool, error) {
+	return v.fs.IsDir(path)
+}
+
+func (v view) IsSymlink(path string) (bool, error) {
+	return v.fs.IsSymlink(path)
+}
+
+func (v view) LReadDir(path string) ([]fs.DirEntry, error) {
+	return v.fs.LReadDir(path)
+}
+
+func (v view) LStat(path string) (fs.FileInfo, error) {
+	return v.fs.LStat(path)
+}
+
+func (v view) OpenDir(path string) (fs.ReadDirFile, error) {
+	return v.fs.OpenDir(path)
+}
+
+func (v view) ReadDirInfo(path string) ([]fs.FileInfo, error) {
+	return v.fs.ReadDirInfo(path)
+}
+
+func (v view) ReadFileLimit(path string, max int64) ([]byte, error) {
+	return v.fs.ReadFileLimit(path, max)
+}
+
+func (v view) ReadFileString(path string) (string, error) {
+	return v.fs.ReadFileString(path)
+}
+
+func (v view) Readlink(path string) (string, error) {
+	return v.fs.Readlink(path)
+}
+
+func (v view) ResolveLink(path string, depth int) (string, error) {
+	return v.fs.ResolveLink(path, depth)
+}
+
+func (v view) ScanDir(path string, fn func(fs.DirEntry) bool) error {
+	return v.fs.ScanDir(path, fn)
+}
+
+func (v view) SecureJoin(root, unsafe string) (string, error) {
+	return v.fs.SecureJoin(root, unsafe)
+}
+
+func (v view) StatAll(paths []string) ([]fs.FileInfo, []error) {
+	return v.fs.StatAll(paths)
+}
+
+func (v view) Stats(n int) Stats {
+	return v.fs.Stats(n)
+}
+
+func (v view) WalkParallel(root string, workers int, fn fs.WalkDirFunc) error {
+	return v.fs.WalkParallel(root, workers, fn)
+}
+
+func (v view) WriteImage(w io.Writer) (int64, error) {
+	return v.fs.WriteImage(w)
+}
+
+func (v view) WriteObjects(store ObjectStore, prefix string) error {
+	return v.fs.WriteObjects(store, prefix)
+}
+
+func (v view) WriteTar(w io.Writer, opts ...TarOption) error {
+	return v.fs.WriteTar(w, opts...)
+}
+
+func (v view) WriteTo(w io.Writer) (int64, error) {
+	return v.fs.WriteTo(w)
+}
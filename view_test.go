@@ -0,0 +1,79 @@
+package memfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+)
+
+func TestView(t *testing.T) {
+	f := New()
+
+	if err := f.MkdirAll("dir/sub", 0o755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.WriteFile("dir/file", []byte("data"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	v := f.View()
+
+	if _, ok := v.(interface {
+		WriteFile(string, []byte, fs.FileMode) error
+	}); ok {
+		t.Errorf("test 1: expecting view to have no WriteFile method")
+	} else if _, ok := v.(*FS); ok {
+		t.Errorf("test 1: expecting view not to be an *FS")
+	} else if rv := reflect.ValueOf(v); rv.NumField() != 1 || rv.Field(0).CanInterface() {
+		t.Errorf("test 1: expecting the FS of the view not to be accessible through reflection")
+	}
+
+	if data, err := v.ReadFile("dir/file"); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if string(data) != "data" {
+		t.Errorf("test 2: expecting contents %q, got %q", "data", data)
+	} else if err := f.WriteFile("dir/file", []byte("changed"), 0o644); err != nil {
+		t.Fatalf("test 3: unexpected error: %s", err)
+	} else if data, err := v.ReadFile("dir/file"); err != nil {
+		t.Fatalf("test 3: unexpected error: %s", err)
+	} else if string(data) != "changed" {
+		t.Errorf("test 3: expecting contents %q, got %q", "changed", data)
+	} else if err := f.WriteFile("dir/new", []byte("new"), 0o644); err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	} else if ok, err := v.Exists("dir/new"); err != nil {
+		t.Fatalf("test 4: unexpected error: %s", err)
+	} else if !ok {
+		t.Errorf("test 4: expecting new file to be visible through view")
+	}
+
+	sub, err := v.Sub("dir")
+	if err != nil {
+		t.Fatalf("test 5: unexpected error: %s", err)
+	} else if _, ok := sub.(*FS); ok {
+		t.Errorf("test 5: expecting sub view not to be an *FS")
+	} else if _, ok := sub.(FSRO); !ok {
+		t.Errorf("test 5: expecting sub view to be an FSRO")
+	} else if data, err := fs.ReadFile(sub, "file"); err != nil {
+		t.Fatalf("test 6: unexpected error: %s", err)
+	} else if string(data) != "changed" {
+		t.Errorf("test 6: expecting contents %q, got %q", "changed", data)
+	}
+
+	of, err := v.Open("dir/file")
+	if err != nil {
+		t.Fatalf("test 7: unexpected error: %s", err)
+	}
+
+	defer of.Close()
+
+	if _, err := of.(io.Writer).Write([]byte("write")); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("test 7: expecting error %v, got %v", fs.ErrInvalid, err)
+	} else if err := of.(*File).Truncate(0); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("test 8: expecting error %v, got %v", fs.ErrInvalid, err)
+	} else if data, err := f.ReadFile("dir/file"); err != nil {
+		t.Fatalf("test 9: unexpected error: %s", err)
+	} else if string(data) != "changed" {
+		t.Errorf("test 9: expecting contents %q, got %q", "changed", data)
+	}
+}
@@ -0,0 +1,214 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"runtime"
+	"sync"
+)
+
+// WalkParallel walks the tree rooted at root, concurrently, as with
+// FS.WalkParallel, but without any locking, as an FSRO cannot change.
+func (f *fsRO) WalkParallel(root string, workers int, fn fs.WalkDirFunc) error {
+	return walkParallel(f, root, workers, fn)
+}
+
+// WalkParallel walks the tree rooted at root, calling fn for each file and
+// directory, as with fs.WalkDir, but with the subtrees of each directory
+// being walked concurrently by the given number of workers, or by
+// runtime.GOMAXPROCS workers when workers is less than one.
+//
+// The func is called concurrently, and so must be safe for concurrent use. The
+// func is called for a directory before any of its entries, and the entries
+// of each directory are visited in lexical order, but there is no ordering
+// between the entries of different directories.
+//
+// As with fs.WalkDir, returning fs.SkipDir for a directory skips it, returning
+// fs.SkipDir for a file skips the remaining entries of its directory, though
+// not any subdirectories already visited, and returning fs.SkipAll, or any
+// other error, stops the walk, with the other workers stopping once their
+// current call to the func has returned.
+//
+// The FS lock is only held while reading each directory, and not while the
+// func is called, and so the func may modify the FS, with the walk seeing the
+// entries of each directory as they were when it was read. For a tree that is
+// not modified during the walk, walking a sealed FS, as returned by Seal,
+// avoids any contention on the lock.
+func (f *FS) WalkParallel(root string, workers int, fn fs.WalkDirFunc) error {
+	return walkParallel(f, root, workers, fn)
+}
+
+type walkFS interface {
+	Stat(string) (fs.FileInfo, error)
+	ReadDir(string) ([]fs.DirEntry, error)
+}
+
+func walkParallel(fsys walkFS, root string, workers int, fn fs.WalkDirFunc) error {
+	w := parallelWalker{
+		fsys: fsys,
+		fn:   fn,
+	}
+
+	w.cond.L = &w.mu
+
+	fi, err := fsys.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else if d := fs.FileInfoToDirEntry(fi); d.IsDir() {
+		if err = fn(root, d, nil); err == nil {
+			w.push(walkTask{path: root, d: d})
+		}
+	} else {
+		err = fn(root, d, nil)
+	}
+
+	if err != nil {
+		if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+			return nil
+		}
+
+		return err
+	}
+
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var wg sync.WaitGroup
+
+	for n := 0; n < workers; n++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			w.work()
+		}()
+	}
+
+	wg.Wait()
+
+	return w.err
+}
+
+type walkTask struct {
+	path string
+	d    fs.DirEntry
+}
+
+type parallelWalker struct {
+	fsys walkFS
+	fn   fs.WalkDirFunc
+
+	mu      sync.Mutex
+	cond    sync.Cond
+	queue   []walkTask
+	pending int
+	stopped bool
+	err     error
+}
+
+func (w *parallelWalker) work() {
+	for {
+		t, ok := w.next()
+		if !ok {
+			return
+		}
+
+		w.done(w.dir(t))
+	}
+}
+
+// next returns the next directory to be read, waiting for one to be queued
+// while any others are being read, and returning false once all have been
+// read, or the walk has stopped.
+func (w *parallelWalker) next() (walkTask, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for len(w.queue) == 0 && w.pending > 0 && !w.stopped {
+		w.cond.Wait()
+	}
+
+	if w.stopped || len(w.queue) == 0 {
+		return walkTask{}, false
+	}
+
+	t := w.queue[len(w.queue)-1]
+	w.queue = w.queue[:len(w.queue)-1]
+
+	return t, true
+}
+
+func (w *parallelWalker) push(t walkTask) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.queue = append(w.queue, t)
+	w.pending++
+
+	w.cond.Signal()
+}
+
+func (w *parallelWalker) done(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending--
+
+	if err != nil && !w.stopped {
+		w.stopped = true
+
+		if !errors.Is(err, fs.SkipAll) {
+			w.err = err
+		}
+	}
+
+	if w.pending == 0 || w.stopped {
+		w.cond.Broadcast()
+	}
+}
+
+func (w *parallelWalker) isStopped() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.stopped
+}
+
+// dir calls the walk func for each of the entries of the given directory,
+// queuing each subdirectory to be read.
+func (w *parallelWalker) dir(t walkTask) error {
+	entries, err := w.fsys.ReadDir(t.path)
+	if err != nil {
+		if err = w.fn(t.path, t.d, err); errors.Is(err, fs.SkipDir) {
+			return nil
+		}
+
+		return err
+	}
+
+	for _, e := range entries {
+		if w.isStopped() {
+			return nil
+		}
+
+		p := path.Join(t.path, e.Name())
+
+		err := w.fn(p, e, nil)
+
+		switch {
+		case errors.Is(err, fs.SkipDir):
+			if !e.IsDir() {
+				return nil
+			}
+		case err != nil:
+			return err
+		case e.IsDir():
+			w.push(walkTask{path: p, d: e})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestWalkParallel(t *testing.T) {
+	f := New()
+
+	if err := GenerateTree(f, "root", TreeSpec{Files: 200, FilesPerDir: 5, Fanout: 3}, 1); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := f.Symlink("dir0", "root/link"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	errStop := errors.New("stop")
+
+	for n, test := range [...]struct {
+		root    string
+		workers int
+		fn      func(string, fs.DirEntry, error) error
+		partial bool
+		err     error
+	}{
+		{ // 1
+			root:    "root",
+			workers: 1,
+		},
+		{ // 2
+			root:    "root",
+			workers: 8,
+		},
+		{ // 3
+			root: "root",
+		},
+		{ // 4
+			root:    "root/dir1",
+			workers: 4,
+		},
+		{ // 5
+			root:    "root/file0",
+			workers: 4,
+		},
+		{ // 6
+			root:    "missing",
+			workers: 4,
+		},
+		{ // 7
+			root:    "root",
+			workers: 4,
+			fn: func(p string, _ fs.DirEntry, _ error) error {
+				if path.Base(p) == "dir0" {
+					return fs.SkipDir
+				}
+
+				return nil
+			},
+		},
+		{ // 8
+			root:    "root",
+			workers: 4,
+			fn: func(p string, _ fs.DirEntry, _ error) error {
+				if p == "root/dir1/file20" {
+					return fs.SkipDir
+				}
+
+				return nil
+			},
+		},
+		{ // 9
+			root:    "root",
+			workers: 4,
+			fn: func(p string, _ fs.DirEntry, _ error) error {
+				if p == "root/dir0/dir2" {
+					return errStop
+				}
+
+				return nil
+			},
+			partial: true,
+			err:     errStop,
+		},
+		{ // 10
+			root:    "root",
+			workers: 4,
+			fn: func(p string, _ fs.DirEntry, _ error) error {
+				if p == "root/dir0/dir2" {
+					return fs.SkipAll
+				}
+
+				return nil
+			},
+			partial: true,
+		},
+		{ // 11
+			root:    "root",
+			workers: 4,
+			fn: func(p string, _ fs.DirEntry, _ error) error {
+				if p == "root" {
+					return fs.SkipDir
+				}
+
+				return nil
+			},
+		},
+	} {
+		fn := test.fn
+		if fn == nil {
+			fn = func(string, fs.DirEntry, error) error { return nil }
+		}
+
+		var expected []string
+
+		if err := fs.WalkDir(f, test.root, func(p string, d fs.DirEntry, err error) error {
+			expected = append(expected, p)
+
+			return fn(p, d, err)
+		}); !errors.Is(err, test.err) {
+			t.Fatalf("test %d: expecting error %v from WalkDir, got %v", n+1, test.err, err)
+		}
+
+		for m, fsys := range [...]FSRO{
+			f,
+			f.Seal(),
+			sealCompact(t, f),
+		} {
+			var (
+				mu   sync.Mutex
+				seen []string
+			)
+
+			err := fsys.WalkParallel(test.root, test.workers, func(p string, d fs.DirEntry, err error) error {
+				mu.Lock()
+				seen = append(seen, p)
+				mu.Unlock()
+
+				return fn(p, d, err)
+			})
+
+			if !errors.Is(err, test.err) {
+				t.Errorf("test %d.%d: expecting error %v, got %v", n+1, m+1, test.err, err)
+			} else if test.partial {
+				if !slices.Contains(seen, "root/dir0/dir2") {
+					t.Errorf("test %d.%d: expecting to have seen root/dir0/dir2", n+1, m+1)
+				}
+			} else {
+				slices.Sort(seen)
+				slices.Sort(expected)
+
+				if !reflect.DeepEqual(seen, expected) {
+					t.Errorf("test %d.%d: expecting to see %v, saw %v", n+1, m+1, expected, seen)
+				}
+			}
+		}
+	}
+}
+
+func TestWalkParallelOrder(t *testing.T) {
+	f := New()
+
+	if err := GenerateTree(f, "root", TreeSpec{Files: 100, FilesPerDir: 4, Fanout: 2}, 2); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var (
+		mu   sync.Mutex
+		seen = map[string]bool{}
+	)
+
+	if err := f.WalkParallel("root", 4, func(p string, _ fs.DirEntry, err error) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if p != "root" && !seen[path.Dir(p)] {
+			t.Errorf("saw %q before its parent", p)
+		}
+
+		seen[p] = true
+
+		return err
+	}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestWalkParallelModify(t *testing.T) {
+	f := New()
+
+	if err := GenerateTree(f, "root", TreeSpec{Files: 100, FilesPerDir: 4, Fanout: 2}, 3); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := f.WalkParallel("root", 4, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		return f.Remove(p)
+	}); err != nil {
+		t.Fatalf("test 1: unexpected error: %s", err)
+	}
+
+	var files int
+
+	if err := fs.WalkDir(f, "root", func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files++
+		}
+
+		return err
+	}); err != nil {
+		t.Fatalf("test 2: unexpected error: %s", err)
+	} else if files != 0 {
+		t.Errorf("test 2: expecting no files to remain, found %d", files)
+	}
+}